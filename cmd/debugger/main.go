@@ -17,12 +17,17 @@ import (
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/delve"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/gobinary"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/kube"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/logs"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/pprof"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/process"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/sshexec"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/sshtunnel"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools/sysinfo"
 )
 
@@ -40,10 +45,20 @@ func main() {
 		debug        bool
 		bindAddr     string
 		printVersion bool
+		sshBackend   string
+		sshIdle      time.Duration
+		sshConfig    string
+		sudoAllow    string
+		vulnDB       string
 	)
 	flag.BoolVar(&debug, "debug", false, "debug mode")
 	flag.StringVar(&bindAddr, "bind", "localhost:8899", "bind address (host:port)")
 	flag.BoolVar(&printVersion, "version", false, "print version and exit")
+	flag.DurationVar(&sshIdle, "ssh-idle-timeout", 5*time.Minute, "close pooled ssh connections idle for this long")
+	flag.StringVar(&sshBackend, "ssh-backend", string(ssh.BackendNative), "ssh backend: native (x/crypto/ssh) or openssh (ssh and scp binaries)")
+	flag.StringVar(&sshConfig, "ssh-config", "", "ssh_config file or host inventory to use instead of ~/.ssh/config and /etc/ssh/ssh_config")
+	flag.StringVar(&sudoAllow, "sudo-allow", "", "comma-separated programs (names or absolute paths, globs allowed) that tools may run with sudo -n; empty disables sudo")
+	flag.StringVar(&vulnDB, "vuln-db", "", "directory or zip file of the Go vulnerability database in OSV JSON format, e.g. a mirror of vuln.go.dev")
 	flag.Parse()
 	// Sanitize version
 	version := strings.TrimSpace(Version)
@@ -62,6 +77,26 @@ func main() {
 		logger.Debug().Msg("debug mode enabled")
 	}
 
+	if err := ssh.SetDefaultBackend(sshBackend); err != nil {
+		logger.Fatal().Msgf("%v", err)
+	}
+	ssh.SetConfigLogger(logger)
+	if err := ssh.SetConfigFile(sshConfig); err != nil {
+		logger.Fatal().Msgf("%v", err)
+	}
+	ssh.DefaultPool().SetIdleTimeout(sshIdle)
+	if err := ssh.SetSudoAllowlist(strings.Split(sudoAllow, ",")); err != nil {
+		logger.Fatal().Msgf("%v", err)
+	}
+	ssh.SetSudoAuditLogger(logger)
+	if allowed := ssh.SudoAllowlist(); len(allowed) > 0 {
+		logger.Warn().Strs("allowed", allowed).Msg("sudo elevation enabled")
+	}
+
+	if err := gobinary.SetVulnDB(vulnDB); err != nil {
+		logger.Fatal().Msgf("%v", err)
+	}
+
 	impl := &mcp.Implementation{
 		Name:    ServerName,
 		Version: version,
@@ -72,6 +107,10 @@ func main() {
 		pprof.New(logger),
 		delve.New(logger),
 		sshexec.New(logger),
+		sshtunnel.New(logger),
+		process.New(logger),
+		gobinary.New(logger),
+		logs.New(logger),
 		sysinfo.New(logger),
 		kube.New(logger),
 	}
@@ -114,4 +153,11 @@ func main() {
 	} else {
 		logger.Info().Msgf("%s shutdown complete", ServiceName)
 	}
+	// Close SSH tunnels, then pooled SSH connections
+	tunnels := ssh.DefaultTunnels().List()
+	ssh.DefaultTunnels().CloseAll()
+	logger.Info().Msgf("Closed %d ssh tunnels", len(tunnels))
+	stats := ssh.DefaultPool().Stats()
+	ssh.DefaultPool().CloseAll(ctx)
+	logger.Info().Msgf("Closed %d pooled ssh connections (%d dials, %d reuses)", stats.Open, stats.Dials, stats.Reuses)
 }
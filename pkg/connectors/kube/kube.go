@@ -3,7 +3,9 @@ package kube
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,6 +13,7 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // Config holds Kubernetes connection configuration.
@@ -144,6 +147,41 @@ func (c *Connector) ExecuteCommandWithExitCode(ctx context.Context, command stri
 	return output, exitCode, nil
 }
 
+// ExecuteCommandStream executes a command in the pod, writing its stdout and stderr as they arrive.
+// stdin may be nil. Like ExecuteCommandWithExitCode, a non-zero exit code is not an error.
+// Note: This only works when Pod field contains a pod name, not a resource reference like "deployment/name".
+func (c *Connector) ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	// Extract pod name if it's in format "pod/name"
+	podName := strings.TrimPrefix(c.config.Pod, "pod/")
+
+	args := c.BuildKubectlArgs()
+	args = append(args, "exec")
+	if stdin != nil {
+		args = append(args, "-i")
+	}
+	args = append(args, podName)
+	if c.config.Container != "" {
+		args = append(args, "-c", c.config.Container)
+	}
+	args = append(args, "--", "sh", "-c", command)
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return exitErr.ExitCode(), nil
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
 // CopyFileToPod copies a local file to the pod.
 // Note: This only works when Pod field contains a pod name, not a resource reference like "deployment/name".
 func (c *Connector) CopyFileToPod(ctx context.Context, localPath, remotePath string) error {
@@ -241,6 +279,61 @@ func (c *Connector) GetContainerLogs(ctx context.Context, tail int) (string, err
 	return stdout.String(), nil
 }
 
+// LogOptions selects the container logs of StreamContainerLogs.
+type LogOptions struct {
+	Tail       int       // Lines from the end, 0 for all; with Follow, 0 starts at the end
+	SinceTime  time.Time // Only lines logged at or after this time, when set
+	Timestamps bool      // Prefix each line with its RFC3339 timestamp
+	Previous   bool      // Logs of the previous, terminated instance of the container
+	Follow     bool      // Keep streaming new lines
+}
+
+// LogsArgs builds the kubectl logs arguments for the pod.
+func (c *Connector) LogsArgs(opts LogOptions) []string {
+	// Extract pod name if it's in format "pod/name"
+	podName := strings.TrimPrefix(c.config.Pod, "pod/")
+
+	args := c.BuildKubectlArgs()
+	args = append(args, "logs", podName)
+	if c.config.Container != "" {
+		args = append(args, "-c", c.config.Container)
+	}
+	if opts.Tail > 0 || opts.Follow {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if !opts.SinceTime.IsZero() {
+		args = append(args, "--since-time", opts.SinceTime.UTC().Format(time.RFC3339))
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	if opts.Previous {
+		args = append(args, "--previous")
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	return args
+}
+
+// StreamContainerLogs writes the container logs to stdout as they arrive. With Follow it returns
+// when ctx is done or the container stops.
+// Note: This only works when Pod field contains a pod name, not a resource reference like "deployment/name".
+func (c *Connector) StreamContainerLogs(ctx context.Context, opts LogOptions, stdout io.Writer) error {
+	cmd := exec.CommandContext(ctx, "kubectl", c.LogsArgs(opts)...)
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to get logs: %v - %s", err, stderr.String())
+	}
+	return nil
+}
+
 // PortForward sets up port forwarding to the resource (pod, deployment, or service).
 func (c *Connector) PortForward(ctx context.Context, localPort, remotePort int) error {
 	c.mu.Lock()
@@ -1,6 +1,7 @@
 package kube
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
@@ -398,6 +399,57 @@ func (s *KubeConnectorTestSuite) TestLogsCommandConstruction() {
 	}
 }
 
+func (s *KubeConnectorTestSuite) TestLogsArgs() {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	testCases := []struct {
+		name     string
+		pod      string
+		opts     LogOptions
+		expected []string
+	}{
+		{
+			name:     "all lines",
+			pod:      "test-pod",
+			expected: []string{"logs", "test-pod", "-c", "app"},
+		},
+		{
+			name:     "tail with since and timestamps",
+			pod:      "pod/test-pod",
+			opts:     LogOptions{Tail: 500, SinceTime: since, Timestamps: true, Previous: true},
+			expected: []string{"logs", "test-pod", "-c", "app", "--tail", "500", "--since-time", "2024-05-01T10:00:00Z", "--timestamps", "--previous"},
+		},
+		{
+			name:     "follow from the end",
+			pod:      "test-pod",
+			opts:     LogOptions{Follow: true},
+			expected: []string{"logs", "test-pod", "-c", "app", "--tail", "0", "--follow"},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			c := New("test-ns", tc.pod, "app", "/tmp/kubeconfig")
+			args := c.LogsArgs(tc.opts)
+			s.Equal([]string{"--kubeconfig", "/tmp/kubeconfig", "-n", "test-ns"}, args[:4])
+			s.Equal(tc.expected, args[4:])
+		})
+	}
+}
+
+func (s *KubeConnectorTestSuite) TestStreamContainerLogs() {
+	if testing.Short() {
+		s.T().Skip("Skipping integration test in short mode")
+	}
+
+	c := New("test-ns", "test-pod", "test-container", "")
+	var stdout bytes.Buffer
+	err := c.StreamContainerLogs(context.Background(), LogOptions{Tail: 10}, &stdout)
+	s.Error(err) // Will error without actual kubectl/cluster
+
+	exitCode, err := c.ExecuteCommandStream(context.Background(), "echo test", nil, &stdout, &stdout)
+	s.True(err != nil || exitCode != 0) // kubectl is missing, or fails without a cluster
+}
+
 func (s *KubeConnectorTestSuite) TestPortForwardCommandConstruction() {
 	c := New("test-ns", "test-pod", "", "")
 	ctx := context.Background()
@@ -0,0 +1,101 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// waitDelay bounds the wait for output after a canceled command was killed.
+const waitDelay = time.Second
+
+// Connector runs commands with the local shell. It mirrors the command methods of the ssh and
+// kube connectors so tools can treat the local host as one more target.
+type Connector struct{}
+
+// New creates a new local connector.
+func New() *Connector {
+	return &Connector{}
+}
+
+// GetTarget returns the target name used in tool output.
+func (c *Connector) GetTarget() string {
+	return "localhost"
+}
+
+// ExecuteCommand executes a command with sh -c. Like the remote connectors, stderr is appended to
+// the output and a non-zero exit code is an error.
+func (c *Connector) ExecuteCommand(ctx context.Context, command string) (string, error) {
+	output, _, err := c.run(ctx, command)
+	return output, err
+}
+
+// ExecuteCommandWithExitCode executes a command and returns output with exit code.
+// A non-zero exit code is not an error.
+func (c *Connector) ExecuteCommandWithExitCode(ctx context.Context, command string) (string, int, error) {
+	output, exitCode, err := c.run(ctx, command)
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", -1, err
+	}
+	return output, exitCode, nil
+}
+
+// ExecuteCommandStream executes a command, writing its stdout and stderr as they arrive. stdin may be nil.
+// Like ExecuteCommandWithExitCode, a non-zero exit code is not an error.
+func (c *Connector) ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return exitErr.ExitCode(), nil
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// shellCommand creates a sh -c command in its own process group. Canceling ctx kills the group, so
+// children of the shell such as tail -F don't outlive it and keep its output open.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = waitDelay
+	return cmd
+}
+
+func (c *Connector) run(ctx context.Context, command string) (string, int, error) {
+	cmd := shellCommand(ctx, command)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+
+	output := stdout.String()
+	if stderr.Len() > 0 {
+		if output != "" {
+			output += "\n"
+		}
+		output += "STDERR:\n" + stderr.String()
+	}
+	return output, exitCode, err
+}
@@ -0,0 +1,59 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type LocalConnectorTestSuite struct {
+	suite.Suite
+}
+
+func TestLocalConnectorTestSuite(t *testing.T) {
+	suite.Run(t, new(LocalConnectorTestSuite))
+}
+
+func (s *LocalConnectorTestSuite) TestExecuteCommand() {
+	c := New()
+	ctx := context.Background()
+	s.Equal("localhost", c.GetTarget())
+
+	output, err := c.ExecuteCommand(ctx, "echo out; echo err >&2")
+	s.Require().NoError(err)
+	s.Equal("out\n\nSTDERR:\nerr\n", output)
+
+	output, err = c.ExecuteCommand(ctx, "echo partial; exit 3")
+	s.Error(err)
+	s.Equal("partial\n", output)
+
+	output, exitCode, err := c.ExecuteCommandWithExitCode(ctx, "echo partial; exit 42")
+	s.Require().NoError(err)
+	s.Equal(42, exitCode)
+	s.Equal("partial\n", output)
+}
+
+func (s *LocalConnectorTestSuite) TestContextCancellation() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := New().ExecuteCommandWithExitCode(ctx, "sleep 10")
+	s.Error(err)
+}
+
+func (s *LocalConnectorTestSuite) TestExecuteCommandStream() {
+	c := New()
+	var stdout, stderr bytes.Buffer
+	exitCode, err := c.ExecuteCommandStream(context.Background(), "cat; echo err >&2; exit 4", strings.NewReader("in\n"), &stdout, &stderr)
+	s.Require().NoError(err)
+	s.Equal(4, exitCode)
+	s.Equal("in\n", stdout.String())
+	s.Equal("err\n", stderr.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.ExecuteCommandStream(ctx, "sleep 10", nil, &stdout, &stderr)
+	s.ErrorIs(err, context.Canceled)
+}
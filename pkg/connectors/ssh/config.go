@@ -0,0 +1,327 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevinburke/ssh_config"
+	"github.com/rs/zerolog"
+)
+
+const maxProxyJumps = 8 // Longest bastion chain, also stops ProxyJump loops
+
+// configFile replaces ~/.ssh/config and /etc/ssh/ssh_config when set, like ssh -F. It is set once at startup.
+var configFile string
+
+// configWarnings reports the ssh_config files and blocks the native backend cannot parse, once per file.
+var configWarnings struct {
+	mu     sync.Mutex
+	logger zerolog.Logger
+	warned map[string]string
+}
+
+func init() {
+	configWarnings.logger = zerolog.Nop()
+}
+
+// SetConfigLogger sets the logger ssh_config files and blocks that cannot be parsed are reported with.
+func SetConfigLogger(logger zerolog.Logger) {
+	configWarnings.mu.Lock()
+	defer configWarnings.mu.Unlock()
+	configWarnings.logger = logger.With().Str("component", "ssh_config").Logger()
+}
+
+// warnConfig logs a problem with an ssh_config file unless the same one was already logged.
+func warnConfig(path string, err error) {
+	configWarnings.mu.Lock()
+	defer configWarnings.mu.Unlock()
+	if configWarnings.warned[path] == err.Error() {
+		return
+	}
+	if configWarnings.warned == nil {
+		configWarnings.warned = make(map[string]string)
+	}
+	configWarnings.warned[path] = err.Error()
+	configWarnings.logger.Warn().Err(err).Str("file", path).Msg("native ssh backend falls back to default options")
+}
+
+// SetConfigFile makes connectors read host options from path only, e.g. a server-side inventory
+// written in ssh_config syntax. An empty path restores ~/.ssh/config and /etc/ssh/ssh_config.
+// A file the native backend cannot parse is only reported, as ssh may still read it.
+func SetConfigFile(path string) error {
+	if path == "" {
+		configFile = ""
+		return nil
+	}
+	path = expandHome(path)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	if _, err := parseConfigFile(path); err != nil {
+		warnConfig(path, err)
+	}
+	configFile = path
+	return nil
+}
+
+// configFiles returns the ssh_config files in the order ssh reads them.
+func configFiles() []string {
+	if configFile != "" {
+		return []string{configFile}
+	}
+	var files []string
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".ssh", "config"))
+	}
+	return append(files, "/etc/ssh/ssh_config")
+}
+
+func parseConfigFile(path string) (*ssh_config.Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	config, err := ssh_config.DecodeBytes(content)
+	if err == nil {
+		return config, nil
+	}
+	// The parser only knows "Match all" and "Match host"; drop the other blocks and keep the rest
+	stripped, blocks := stripUnsupportedMatch(content)
+	if blocks == 0 {
+		return nil, fmt.Errorf("failed to parse ssh config %s: %w", path, err)
+	}
+	if config, err = ssh_config.DecodeBytes(stripped); err != nil {
+		return nil, fmt.Errorf("failed to parse ssh config %s: %w", path, err)
+	}
+	warnConfig(path, fmt.Errorf("ignored %d Match block(s) with criteria other than all and host", blocks))
+	return config, nil
+}
+
+// stripUnsupportedMatch blanks out the Match blocks whose criteria are not all or host, such as
+// "Match final all" or "Match exec", up to the next Host or Match line. Line numbers are kept.
+func stripUnsupportedMatch(content []byte) ([]byte, int) {
+	lines := strings.Split(string(content), "\n")
+	blocks, skipping := 0, false
+	for i, line := range lines {
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == '='
+		})
+		if len(fields) > 0 {
+			switch strings.ToLower(fields[0]) {
+			case "host":
+				skipping = false
+			case "match":
+				criterion := ""
+				if len(fields) > 1 {
+					criterion = strings.ToLower(fields[1])
+				}
+				skipping = criterion != "all" && criterion != "host"
+				if skipping {
+					blocks++
+				}
+			}
+		}
+		if skipping {
+			lines[i] = ""
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), blocks
+}
+
+// hostOptions looks up the options of one host in the ssh_config files.
+type hostOptions struct {
+	alias   string
+	configs []*ssh_config.Config
+}
+
+// loadHostOptions reads the ssh_config files of a host. A file that cannot be parsed is reported and
+// skipped, so its options fall back to the defaults rather than failing every connection.
+func loadHostOptions(alias string) (*hostOptions, error) {
+	options := &hostOptions{alias: alias}
+	for _, path := range configFiles() {
+		config, err := parseConfigFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if configFile == "" {
+				continue
+			}
+			return nil, err
+		}
+		if err != nil {
+			warnConfig(path, err)
+			continue
+		}
+		options.configs = append(options.configs, config)
+	}
+	return options, nil
+}
+
+// get returns the first value of key, as ssh uses the first value it obtains.
+func (o *hostOptions) get(key string) string {
+	for _, config := range o.configs {
+		if value, err := config.Get(o.alias, key); err == nil && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// getAll returns every value of a key that can be repeated, such as IdentityFile.
+func (o *hostOptions) getAll(key string) []string {
+	var values []string
+	for _, config := range o.configs {
+		if all, err := config.GetAll(o.alias, key); err == nil {
+			values = append(values, all...)
+		}
+	}
+	return values
+}
+
+// resolveConfig builds the configuration of a host from the ssh_config files. host may be an alias;
+// an explicit port or user takes precedence over the files.
+func resolveConfig(host string, port int, user string) (Config, error) {
+	config := Config{Host: host, HostName: host, Port: port, User: user, Backend: defaultBackend}
+	options, err := loadHostOptions(host)
+	if err != nil {
+		config.fillDefaults()
+		return config, err
+	}
+
+	if config.Port == 0 {
+		if value := options.get("Port"); value != "" {
+			if config.Port, err = strconv.Atoi(value); err != nil {
+				return config, fmt.Errorf("invalid Port %q for %s in ssh config", value, host)
+			}
+		}
+	}
+	if config.User == "" {
+		config.User = options.get("User")
+	}
+	config.fillDefaults()
+	if value := options.get("HostName"); value != "" {
+		config.HostName = config.expandTokens(value)
+	}
+
+	for _, file := range options.getAll("IdentityFile") {
+		if file != "none" {
+			config.IdentityFiles = append(config.IdentityFiles, expandHome(config.expandTokens(file)))
+		}
+	}
+	config.IdentitiesOnly = strings.EqualFold(options.get("IdentitiesOnly"), "yes")
+	if value := options.get("IdentityAgent"); value != "" && value != "SSH_AUTH_SOCK" {
+		config.IdentityAgent = value
+		if value != "none" {
+			config.IdentityAgent = expandHome(config.expandTokens(os.ExpandEnv(value)))
+		}
+	}
+	if value := options.get("ProxyJump"); value != "" && value != "none" {
+		for _, jump := range strings.Split(value, ",") {
+			config.ProxyJump = append(config.ProxyJump, strings.TrimSpace(jump))
+		}
+	}
+	if value := options.get("ProxyCommand"); value != "" && value != "none" {
+		config.ProxyCommand = value
+	}
+	config.HostKeyAlias = options.get("HostKeyAlias")
+	if value := options.get("UserKnownHostsFile"); value != "" {
+		for _, file := range strings.Fields(value) {
+			config.KnownHostsFiles = append(config.KnownHostsFiles, expandHome(config.expandTokens(file)))
+		}
+	}
+	if value := options.get("ConnectTimeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return config, fmt.Errorf("invalid ConnectTimeout %q for %s in ssh config", value, host)
+		}
+		config.ConnectTimeout = time.Duration(seconds) * time.Second
+	}
+	return config, nil
+}
+
+// fillDefaults applies the port and user used when neither the caller nor the ssh config set them.
+func (c *Config) fillDefaults() {
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.User == "" {
+		c.User = os.Getenv("USER")
+		if c.User == "" {
+			// Fallback to root. For the brave.
+			c.User = "root"
+		}
+	}
+}
+
+// expandTokens expands the ssh_config tokens %%, %d, %h, %n, %p, %r and %u.
+func (c *Config) expandTokens(value string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+	home, _ := os.UserHomeDir()
+	replacer := strings.NewReplacer(
+		"%%", "%",
+		"%d", home,
+		"%h", c.HostName,
+		"%n", c.Host,
+		"%p", strconv.Itoa(c.Port),
+		"%r", c.User,
+		"%u", os.Getenv("USER"),
+	)
+	return replacer.Replace(value)
+}
+
+// expandHome replaces a leading ~ with the home directory.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// jumpConfig returns the configuration of the last jump host of chain. The hosts before it become
+// its own ProxyJump, so that ProxyJump a,b reaches b through a, as ssh -J does.
+func jumpConfig(chain []string) (Config, error) {
+	spec := chain[len(chain)-1]
+	user := ""
+	if at := strings.LastIndex(spec, "@"); at >= 0 {
+		user, spec = spec[:at], spec[at+1:]
+	}
+	host, port := spec, 0
+	if h, p, err := net.SplitHostPort(spec); err == nil {
+		parsed, err := strconv.Atoi(p)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid ProxyJump host %q", chain[len(chain)-1])
+		}
+		host, port = h, parsed
+	}
+	if host == "" {
+		return Config{}, fmt.Errorf("invalid ProxyJump host %q", chain[len(chain)-1])
+	}
+
+	config, err := resolveConfig(host, port, user)
+	if err != nil {
+		return config, err
+	}
+	if len(chain) > 1 {
+		config.ProxyJump = chain[:len(chain)-1]
+	}
+	return config, nil
+}
+
+// configArgs returns the ssh options selecting the config file of the connector, if it is not the default.
+func configArgs() []string {
+	if configFile == "" {
+		return nil
+	}
+	return []string{"-F", configFile}
+}
@@ -0,0 +1,205 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const testSSHConfig = `
+Host app
+  HostName 10.0.0.5
+  Port 2200
+  User deploy
+  IdentityFile ~/.ssh/app_key
+  IdentityFile %d/.ssh/%r_%n
+  IdentitiesOnly yes
+  IdentityAgent ~/agent.sock
+  ProxyJump ops@bastion:2222,edge
+  HostKeyAlias app.internal
+  UserKnownHostsFile ~/.ssh/known_hosts_app
+  ConnectTimeout 3
+
+Host bastion
+  HostName bastion.example.com
+  User jump
+
+Host legacy
+  ProxyCommand nc -X connect -x proxy:3128 %h %p
+
+Host broken
+  Port http
+
+Host *
+  User fallback
+`
+
+type ConfigTestSuite struct {
+	suite.Suite
+	home string
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
+
+func (s *ConfigTestSuite) SetupTest() {
+	s.home = s.T().TempDir()
+	s.T().Setenv("HOME", s.home)
+	s.T().Setenv("USER", "local")
+	s.Require().NoError(os.MkdirAll(filepath.Join(s.home, ".ssh"), 0o700))
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "config"), []byte(testSSHConfig), 0o600))
+	s.T().Cleanup(func() {
+		configFile = ""
+	})
+}
+
+func (s *ConfigTestSuite) TestResolveAlias() {
+	c := New("app", 0, "")
+	s.Require().NoError(c.err)
+	s.Equal(Config{
+		Host:     "app",
+		Port:     2200,
+		User:     "deploy",
+		Backend:  BackendNative,
+		HostName: "10.0.0.5",
+		IdentityFiles: []string{
+			filepath.Join(s.home, ".ssh", "app_key"),
+			filepath.Join(s.home, ".ssh", "deploy_app"),
+		},
+		IdentitiesOnly:  true,
+		IdentityAgent:   filepath.Join(s.home, "agent.sock"),
+		ProxyJump:       []string{"ops@bastion:2222", "edge"},
+		HostKeyAlias:    "app.internal",
+		KnownHostsFiles: []string{filepath.Join(s.home, ".ssh", "known_hosts_app")},
+		ConnectTimeout:  3 * time.Second,
+	}, c.config)
+	s.Equal("deploy@app", c.GetTarget())
+	// ssh resolves the alias itself
+	s.Equal([]string{
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=10",
+		"-p", "2200",
+		"deploy@app",
+	}, c.BuildSSHArgs())
+
+	// Explicit values take precedence over the config
+	c = New("app", 22, "admin")
+	s.Equal(22, c.config.Port)
+	s.Equal("admin", c.config.User)
+	s.Equal("10.0.0.5", c.config.HostName)
+
+	// Host * applies to everything else
+	c = New("db.example.com", 0, "")
+	s.Equal("fallback", c.config.User)
+	s.Equal("db.example.com", c.config.HostName)
+	s.Empty(c.config.ProxyJump)
+}
+
+func (s *ConfigTestSuite) TestJumpConfig() {
+	// The last jump host is reached through the ones before it
+	jump, err := jumpConfig([]string{"ops@bastion:2222", "edge"})
+	s.Require().NoError(err)
+	s.Equal("edge", jump.HostName)
+	s.Equal("fallback", jump.User)
+	s.Equal([]string{"ops@bastion:2222"}, jump.ProxyJump)
+
+	jump, err = jumpConfig([]string{"ops@bastion:2222"})
+	s.Require().NoError(err)
+	s.Equal("bastion", jump.Host)
+	s.Equal("bastion.example.com", jump.HostName)
+	s.Equal("ops", jump.User)
+	s.Equal(2222, jump.Port)
+	s.Empty(jump.ProxyJump)
+
+	jump, err = jumpConfig([]string{"bastion"})
+	s.Require().NoError(err)
+	s.Equal("jump", jump.User)
+	s.Equal(22, jump.Port)
+
+	_, err = jumpConfig([]string{"ops@:22"})
+	s.Error(err)
+}
+
+func (s *ConfigTestSuite) TestInvalidConfig() {
+	c := New("broken", 0, "")
+	s.Require().Error(c.err)
+	s.Contains(c.err.Error(), `invalid Port "http"`)
+
+	_, err := c.ExecuteCommand(context.Background(), "true")
+	s.ErrorIs(err, c.err)
+	_, _, err = c.ExecuteCommandWithExitCode(context.Background(), "true")
+	s.ErrorIs(err, c.err)
+
+	c = New("legacy", 0, "")
+	s.Require().NoError(c.err)
+	_, err = dialNative(context.Background(), c.config)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not supported by the native ssh backend")
+}
+
+func (s *ConfigTestSuite) TestUnsupportedMatch() {
+	config := `
+Host app
+  HostName 10.0.0.5
+  User deploy
+
+Match user root exec "test -f /etc/override"
+  Port 2222
+
+Match final all
+  Include /etc/ssh/ssh_config.d/*.conf
+
+Host *
+  User fallback
+`
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "config"), []byte(config), 0o600))
+
+	// The unsupported blocks are dropped, the rest of the file still applies
+	c := New("app", 0, "")
+	s.Require().NoError(c.err)
+	s.Equal("10.0.0.5", c.config.HostName)
+	s.Equal("deploy", c.config.User)
+	s.Equal(22, c.config.Port)
+	s.Equal("fallback", New("db", 0, "").config.User)
+
+	// A file that still cannot be parsed falls back to the defaults
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "config"), []byte("Host app\n  Port 2200\nInclude\n"), 0o600))
+	c = New("app", 0, "")
+	s.Require().NoError(c.err)
+	s.Equal("app", c.config.HostName)
+	s.Equal("local", c.config.User)
+	s.Equal(22, c.config.Port)
+
+	defaultBackend = BackendOpenSSH
+	defer func() { defaultBackend = BackendNative }()
+	c = New("app", 0, "")
+	s.Require().NoError(c.err)
+	s.Equal(BackendOpenSSH, c.config.Backend)
+}
+
+func (s *ConfigTestSuite) TestSetConfigFile() {
+	inventory := filepath.Join(s.T().TempDir(), "inventory")
+	s.Require().NoError(os.WriteFile(inventory, []byte("Host app\n  HostName 10.1.0.1\n  User svc\n"), 0o600))
+
+	s.Require().NoError(SetConfigFile(inventory))
+	c := New("app", 0, "")
+	s.Equal("10.1.0.1", c.config.HostName)
+	s.Equal("svc", c.config.User)
+	s.Equal(22, c.config.Port)
+	// ~/.ssh/config is not read any more
+	s.Empty(c.config.ProxyJump)
+	s.Equal([]string{"-F", inventory}, configArgs())
+
+	s.Error(SetConfigFile(filepath.Join(s.home, "missing")))
+	s.Equal(inventory, configFile)
+
+	s.Require().NoError(SetConfigFile(""))
+	s.Nil(configArgs())
+	s.Equal("10.0.0.5", New("app", 0, "").config.HostName)
+}
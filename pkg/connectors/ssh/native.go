@@ -0,0 +1,426 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const connectTimeout = 10 * time.Second // Same as ConnectTimeout=10 of the OpenSSH backend
+
+// defaultIdentityFiles are tried in the same order as OpenSSH does.
+var defaultIdentityFiles = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// knownHostsFiles returns the known_hosts files that exist, user files first.
+// UserKnownHostsFile from the ssh config replaces ~/.ssh/known_hosts.
+func knownHostsFiles(config Config) []string {
+	candidates := config.KnownHostsFiles
+	if len(candidates) == 0 {
+		if home, err := os.UserHomeDir(); err == nil {
+			candidates = append(candidates, filepath.Join(home, ".ssh", "known_hosts"))
+		}
+	}
+	candidates = append(candidates, "/etc/ssh/ssh_known_hosts")
+
+	var files []string
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			files = append(files, candidate)
+		}
+	}
+	return files
+}
+
+// loadKnownHosts returns a callback that checks host keys against the known_hosts files.
+func loadKnownHosts(config Config) (gossh.HostKeyCallback, error) {
+	files := knownHostsFiles(config)
+	if len(files) == 0 {
+		return nil, errors.New("no known_hosts file found: add the host key to ~/.ssh/known_hosts (e.g. with ssh-keyscan) before connecting")
+	}
+	callback, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// aliasAddr stands in for the remote address when the host key is checked under a HostKeyAlias,
+// so that known_hosts is not also searched for the IP address, like CheckHostIP=no.
+type aliasAddr string
+
+func (a aliasAddr) Network() string { return "tcp" }
+func (a aliasAddr) String() string  { return string(a) }
+
+// hostKeyCallback checks the host key under the HostKeyAlias of config, if any.
+func hostKeyCallback(callback gossh.HostKeyCallback, config Config) gossh.HostKeyCallback {
+	if config.HostKeyAlias == "" {
+		return callback
+	}
+	// ssh looks the alias up without a port
+	alias := net.JoinHostPort(config.HostKeyAlias, "22")
+	return func(_ string, _ net.Addr, key gossh.PublicKey) error {
+		return callback(alias, aliasAddr(alias), key)
+	}
+}
+
+// strictHostKeyCallback verifies host keys like StrictHostKeyChecking=yes:
+// unknown hosts and changed keys are both rejected.
+func strictHostKeyCallback(callback gossh.HostKeyCallback) gossh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return fmt.Errorf("host key verification failed: %s is not in known_hosts", hostname)
+			}
+			return fmt.Errorf("host key verification failed: the %s key of %s does not match known_hosts (%s:%d)",
+				key.Type(), hostname, keyErr.Want[0].Filename, keyErr.Want[0].Line)
+		}
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return fmt.Errorf("host key verification failed: the key of %s is revoked", hostname)
+		}
+		return err
+	}
+}
+
+// knownHostKeyAlgorithms returns the algorithms of the keys known for a host, so that the server
+// offers a key that can be verified instead of its preferred one.
+func knownHostKeyAlgorithms(callback gossh.HostKeyCallback, hostname string, remote net.Addr) []string {
+	// Probing with a key that cannot be known lists the known keys in the error
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil
+	}
+	probe, err := gossh.NewPublicKey(public)
+	if err != nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if !errors.As(callback(hostname, remote, probe), &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	seen := make(map[string]bool)
+	for _, known := range keyErr.Want {
+		keyType := known.Key.Type()
+		candidates := []string{keyType}
+		if keyType == gossh.KeyAlgoRSA {
+			candidates = []string{gossh.KeyAlgoRSASHA512, gossh.KeyAlgoRSASHA256, gossh.KeyAlgoRSA}
+		}
+		for _, algorithm := range candidates {
+			if !seen[algorithm] {
+				seen[algorithm] = true
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+	}
+	return algorithms
+}
+
+// agentSocket returns the agent socket of config: IdentityAgent, else SSH_AUTH_SOCK.
+func agentSocket(config Config) string {
+	switch config.IdentityAgent {
+	case "none":
+		return ""
+	case "":
+		return os.Getenv("SSH_AUTH_SOCK")
+	default:
+		return config.IdentityAgent
+	}
+}
+
+// identityFiles returns the keys to offer: IdentityFile from the ssh config, or the default identity files.
+func identityFiles(config Config) []string {
+	if len(config.IdentityFiles) > 0 {
+		return config.IdentityFiles
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	files := make([]string, 0, len(defaultIdentityFiles))
+	for _, name := range defaultIdentityFiles {
+		files = append(files, filepath.Join(home, ".ssh", name))
+	}
+	return files
+}
+
+// authMethods returns the public key methods OpenSSH would use in batch mode: the agent's keys,
+// then the identity files that are not passphrase protected. With IdentitiesOnly, only the agent
+// keys matching an identity file (or its .pub file) are offered.
+func authMethods(config Config) ([]gossh.AuthMethod, io.Closer) {
+	var signers []gossh.Signer
+	identities := make(map[string]bool)
+	for _, file := range identityFiles(config) {
+		key, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		signer, err := gossh.ParsePrivateKey(key)
+		if err != nil {
+			// Passphrase protected keys need the agent, as with BatchMode=yes
+			if public, err := os.ReadFile(file + ".pub"); err == nil {
+				if key, _, _, _, err := gossh.ParseAuthorizedKey(public); err == nil {
+					identities[string(key.Marshal())] = true
+				}
+			}
+			continue
+		}
+		signers = append(signers, signer)
+		identities[string(signer.PublicKey().Marshal())] = true
+	}
+
+	var methods []gossh.AuthMethod
+	var agentConn io.Closer
+	if socket := agentSocket(config); socket != "" {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			agentConn = conn
+			agentClient := agent.NewClient(conn)
+			agentSigners := agentClient.Signers
+			if config.IdentitiesOnly {
+				agentSigners = func() ([]gossh.Signer, error) {
+					all, err := agentClient.Signers()
+					var selected []gossh.Signer
+					for _, signer := range all {
+						if identities[string(signer.PublicKey().Marshal())] {
+							selected = append(selected, signer)
+						}
+					}
+					return selected, err
+				}
+			}
+			methods = append(methods, gossh.PublicKeysCallback(agentSigners))
+		}
+	}
+
+	if len(signers) > 0 {
+		methods = append(methods, gossh.PublicKeys(signers...))
+	}
+	return methods, agentConn
+}
+
+// dialNative opens an SSH connection with the same checks as the OpenSSH backend, through the
+// ProxyJump hosts of config if any.
+func dialNative(ctx context.Context, config Config) (*gossh.Client, error) {
+	return dialNativeHop(ctx, config, 0)
+}
+
+func dialNativeHop(ctx context.Context, config Config, hops int) (*gossh.Client, error) {
+	if config.ProxyCommand != "" {
+		return nil, fmt.Errorf("ProxyCommand of %s is not supported by the native ssh backend: use ProxyJump or -ssh-backend=openssh", config.Host)
+	}
+	knownHosts, err := loadKnownHosts(config)
+	if err != nil {
+		return nil, err
+	}
+	timeout := connectTimeout
+	if config.ConnectTimeout > 0 {
+		timeout = config.ConnectTimeout
+	}
+
+	hostName := config.HostName
+	if hostName == "" {
+		hostName = config.Host
+	}
+	address := net.JoinHostPort(hostName, strconv.Itoa(config.Port))
+	var conn net.Conn
+	if len(config.ProxyJump) > 0 {
+		if hops >= maxProxyJumps {
+			return nil, fmt.Errorf("too many ProxyJump hops to %s (loop in ssh config?)", config.Host)
+		}
+		jump, err := jumpConfig(config.ProxyJump)
+		if err != nil {
+			return nil, err
+		}
+		jumpClient, err := dialNativeHop(ctx, jump, hops+1)
+		if err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", jump.Host, err)
+		}
+		channel, err := jumpClient.DialContext(ctx, "tcp", address)
+		if err != nil {
+			_ = jumpClient.Close()
+			return nil, fmt.Errorf("failed to connect to %s through %s: %w", address, jump.Host, err)
+		}
+		conn = &jumpConn{Conn: channel, jump: jumpClient}
+	} else {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+		}
+	}
+
+	methods, agentConn := authMethods(config)
+	if agentConn != nil {
+		defer func() {
+			_ = agentConn.Close()
+		}()
+	}
+	if len(methods) == 0 {
+		_ = conn.Close()
+		return nil, errors.New("no SSH keys available: start ssh-agent or add an unencrypted key to ~/.ssh")
+	}
+
+	callback := hostKeyCallback(knownHosts, config)
+	clientConfig := &gossh.ClientConfig{
+		User:              config.User,
+		Auth:              methods,
+		HostKeyCallback:   strictHostKeyCallback(callback),
+		HostKeyAlgorithms: knownHostKeyAlgorithms(callback, address, conn.RemoteAddr()),
+		Timeout:           timeout,
+	}
+
+	// Bound the handshake by the context and the connect timeout. Connections through a jump
+	// host have no deadlines, so they are closed instead.
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	_ = conn.SetDeadline(deadline)
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		_ = conn.Close()
+	})
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	sshConn, channels, requests, err := gossh.NewClientConn(conn, address, clientConfig)
+	stop()
+	timedOut := !timer.Stop()
+	if err != nil {
+		_ = conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if timedOut {
+			return nil, fmt.Errorf("ssh handshake with %s timed out after %s", address, timeout)
+		}
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", address, err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return gossh.NewClient(sshConn, channels, requests), nil
+}
+
+// jumpConn is a connection tunnelled through a jump host, which is closed along with it.
+type jumpConn struct {
+	net.Conn
+	jump *gossh.Client
+}
+
+func (c *jumpConn) Close() error {
+	err := c.Conn.Close()
+	_ = c.jump.Close()
+	return err
+}
+
+// newNativeSession opens a session on the pooled client, reconnecting once if the connection was lost.
+// A session the server refuses is an error; the connection stays in the pool.
+// The returned function releases the client once the session is done.
+func (c *Connector) newNativeSession(ctx context.Context) (*gossh.Session, func(), error) {
+	pool := c.connectionPool()
+	client, release, err := pool.acquire(ctx, c.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, release, nil
+	}
+
+	release()
+	var refused *gossh.OpenChannelError
+	if errors.As(err, &refused) {
+		// The connection is fine, the server refused another session (e.g. MaxSessions reached)
+		return nil, nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+	pool.discard(c.config, client)
+	client, release, err = pool.acquire(ctx, c.config)
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err = client.NewSession()
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+	return session, release, nil
+}
+
+// runNative runs a command over the native client. stdin may be nil; stdout and stderr default to the
+// returned output. A non-zero exit status is returned as *gossh.ExitError together with the exit code.
+func (c *Connector) runNative(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (string, string, int, error) {
+	if err := ctx.Err(); err != nil {
+		return "", "", -1, err
+	}
+	session, release, err := c.newNativeSession(ctx)
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer release()
+	defer func() {
+		_ = session.Close()
+	}()
+
+	var stdoutBuffer, stderrBuffer bytes.Buffer
+	session.Stdin = stdin
+	session.Stdout = &stdoutBuffer
+	if stdout != nil {
+		session.Stdout = stdout
+	}
+	session.Stderr = &stderrBuffer
+	if stderr != nil {
+		session.Stderr = stderr
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = session.Signal(gossh.SIGKILL)
+		_ = session.Close()
+		<-done
+		return stdoutBuffer.String(), stderrBuffer.String(), -1, ctx.Err()
+	}
+
+	var exitErr *gossh.ExitError
+	switch {
+	case err == nil:
+		return stdoutBuffer.String(), stderrBuffer.String(), 0, nil
+	case errors.As(err, &exitErr):
+		return stdoutBuffer.String(), stderrBuffer.String(), exitErr.ExitStatus(), err
+	default:
+		return stdoutBuffer.String(), stderrBuffer.String(), -1, err
+	}
+}
+
+// combineOutput formats stdout and stderr the way the OpenSSH backend does.
+func combineOutput(stdout, stderr string) string {
+	output := stdout
+	if stderr != "" {
+		if output != "" {
+			output += "\n"
+		}
+		output += "STDERR:\n" + stderr
+	}
+	return output
+}
@@ -0,0 +1,494 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/suite"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer runs exec requests with the local shell, like sshd would.
+type testSSHServer struct {
+	listener    net.Listener
+	config      *gossh.ServerConfig
+	hostKey     gossh.Signer
+	connections atomic.Int32
+	refuse      atomic.Bool // Reject session channels, like sshd at MaxSessions
+	stall       atomic.Bool // Leave global requests such as keepalives unanswered
+}
+
+func newTestSSHServer(clientKey gossh.PublicKey) (*testSSHServer, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	hostKey, err := gossh.NewSignerFromKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &gossh.ServerConfig{
+		PublicKeyCallback: func(_ gossh.ConnMetadata, key gossh.PublicKey) (*gossh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), clientKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unknown key")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	server := &testSSHServer{listener: listener, config: config, hostKey: hostKey}
+	go server.serve()
+	return server, nil
+}
+
+func (s *testSSHServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSSHServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testSSHServer) handle(conn net.Conn) {
+	_, channels, requests, err := gossh.NewServerConn(conn, s.config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	s.connections.Add(1)
+	go func() {
+		for request := range requests {
+			if request.WantReply && !s.stall.Load() {
+				_ = request.Reply(false, nil)
+			}
+		}
+	}()
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() == "direct-tcpip" {
+			go s.forward(newChannel)
+			continue
+		}
+		if newChannel.ChannelType() != "session" {
+			_ = newChannel.Reject(gossh.UnknownChannelType, "only sessions and forwarding are supported")
+			continue
+		}
+		if s.refuse.Load() {
+			_ = newChannel.Reject(gossh.ResourceShortage, "no more sessions")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.session(channel, channelRequests)
+	}
+}
+
+// forward serves a direct-tcpip channel, as used by jump hosts.
+func (s *testSSHServer) forward(newChannel gossh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := gossh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		_ = newChannel.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	go gossh.DiscardRequests(requests)
+	go func() {
+		_, _ = io.Copy(conn, channel)
+		_ = conn.Close()
+	}()
+	_, _ = io.Copy(channel, conn)
+	_ = channel.Close()
+}
+
+func (s *testSSHServer) session(channel gossh.Channel, requests <-chan *gossh.Request) {
+	defer func() {
+		_ = channel.Close()
+	}()
+	for request := range requests {
+		if request.Type == "subsystem" && string(request.Payload[4:]) == "sftp" {
+			_ = request.Reply(true, nil)
+			server, err := sftp.NewServer(channel)
+			if err == nil {
+				_ = server.Serve()
+			}
+			return
+		}
+		if request.Type != "exec" {
+			_ = request.Reply(false, nil)
+			continue
+		}
+		length := binary.BigEndian.Uint32(request.Payload)
+		command := string(request.Payload[4 : 4+length])
+		_ = request.Reply(true, nil)
+
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+		stdin, _ := cmd.StdinPipe()
+		go func() {
+			_, _ = io.Copy(stdin, channel)
+			_ = stdin.Close()
+		}()
+
+		status := 0
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			status = 255
+			if errors.As(err, &exitErr) {
+				status = exitErr.ExitCode()
+			}
+		}
+		payload := make([]byte, 4)
+		binary.BigEndian.PutUint32(payload, uint32(status))
+		_, _ = channel.SendRequest("exit-status", false, payload)
+		return
+	}
+}
+
+type NativeConnectorTestSuite struct {
+	suite.Suite
+	home   string
+	server *testSSHServer
+}
+
+func TestNativeConnectorTestSuite(t *testing.T) {
+	suite.Run(t, new(NativeConnectorTestSuite))
+}
+
+func (s *NativeConnectorTestSuite) SetupTest() {
+	s.home = s.T().TempDir()
+	s.T().Setenv("HOME", s.home)
+	s.T().Setenv("SSH_AUTH_SOCK", "")
+	s.Require().NoError(os.MkdirAll(filepath.Join(s.home, ".ssh"), 0o700))
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	block, err := gossh.MarshalPrivateKey(private, "")
+	s.Require().NoError(err)
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "id_ed25519"), pem.EncodeToMemory(block), 0o600))
+
+	signer, err := gossh.NewSignerFromKey(private)
+	s.Require().NoError(err)
+	s.server, err = newTestSSHServer(signer.PublicKey())
+	s.Require().NoError(err)
+	s.T().Cleanup(func() {
+		_ = s.server.listener.Close()
+	})
+}
+
+// trustServer adds the server's host key to known_hosts.
+func (s *NativeConnectorTestSuite) trustServer(key gossh.PublicKey) {
+	address := knownhosts.Normalize(net.JoinHostPort("127.0.0.1", strconv.Itoa(s.server.port())))
+	line := knownhosts.Line([]string{address}, key) + "\n"
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "known_hosts"), []byte(line), 0o600))
+}
+
+func (s *NativeConnectorTestSuite) connector() *Connector {
+	c := New("127.0.0.1", s.server.port(), "tester")
+	c.config.Backend = BackendNative
+	c.pool = NewPool(time.Minute)
+	s.T().Cleanup(func() {
+		_ = c.Close()
+		c.pool.CloseAll(context.Background())
+	})
+	return c
+}
+
+func (s *NativeConnectorTestSuite) TestExecuteCommand() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+
+	output, err := c.ExecuteCommand(ctx, "echo out; echo err >&2")
+	s.Require().NoError(err)
+	s.Equal("out\n\nSTDERR:\nerr\n", output)
+
+	output, exitCode, err := c.ExecuteCommandWithExitCode(ctx, "echo partial; exit 42")
+	s.Require().NoError(err)
+	s.Equal(42, exitCode)
+	s.Equal("partial\n", output)
+
+	_, err = c.ExecuteCommand(ctx, "exit 3")
+	s.Error(err)
+
+	s.NoError(c.TestConnection(ctx))
+	// Every operation of the connector shares one connection
+	s.Equal(int32(1), s.server.connections.Load())
+}
+
+func (s *NativeConnectorTestSuite) TestExecuteCommandStream() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := c.ExecuteCommandStream(ctx, "echo out; echo err >&2; exit 7", nil, &stdout, &stderr)
+	s.Require().NoError(err)
+	s.Equal(7, exitCode)
+	s.Equal("out\n", stdout.String())
+	s.Equal("err\n", stderr.String())
+
+	cancelled, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	exitCode, err = c.ExecuteCommandStream(cancelled, "sleep 5", nil, &stdout, &stderr)
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Equal(-1, exitCode)
+
+	stdout.Reset()
+	exitCode, err = c.ExecuteCommandStream(ctx, "tr a-z A-Z", strings.NewReader("from stdin\nsecond line\n"), &stdout, &stderr)
+	s.Require().NoError(err)
+	s.Zero(exitCode)
+	s.Equal("FROM STDIN\nSECOND LINE\n", stdout.String())
+}
+
+func (s *NativeConnectorTestSuite) TestFileOperations() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+	dir := s.T().TempDir()
+
+	local := filepath.Join(dir, "local.sh")
+	s.Require().NoError(os.WriteFile(local, []byte("#!/bin/sh\necho hi\n"), 0o640))
+	remote := filepath.Join(dir, "remote's file.sh")
+
+	s.Require().NoError(c.CopyFile(ctx, local, remote))
+	content, err := os.ReadFile(remote)
+	s.Require().NoError(err)
+	s.Equal("#!/bin/sh\necho hi\n", string(content))
+	info, err := os.Stat(remote)
+	s.Require().NoError(err)
+	s.Equal(os.FileMode(0o640), info.Mode().Perm())
+
+	exists, err := c.FileExists(ctx, remote)
+	s.Require().NoError(err)
+	s.True(exists)
+
+	s.Require().NoError(c.MakeExecutable(ctx, remote))
+	output, err := c.ExecuteCommand(ctx, EscapeArg(remote))
+	s.Require().NoError(err)
+	s.Equal("hi\n", output)
+
+	downloaded := filepath.Join(dir, "downloaded")
+	s.Require().NoError(c.CopyFileFromRemote(ctx, remote, downloaded))
+	content, err = os.ReadFile(downloaded)
+	s.Require().NoError(err)
+	s.Equal("#!/bin/sh\necho hi\n", string(content))
+
+	err = c.CopyFileFromRemote(ctx, filepath.Join(dir, "missing"), filepath.Join(dir, "partial"))
+	s.Error(err)
+	s.NoFileExists(filepath.Join(dir, "partial"))
+
+	s.Require().NoError(c.RemoveFile(ctx, remote))
+	exists, err = c.FileExists(ctx, remote)
+	s.Require().NoError(err)
+	s.False(exists)
+}
+
+func (s *NativeConnectorTestSuite) TestStrictHostKeyChecking() {
+	ctx := context.Background()
+
+	// No known_hosts at all
+	_, err := s.connector().ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "no known_hosts")
+
+	// Host missing from known_hosts
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "known_hosts"), []byte{}, 0o600))
+	_, err = s.connector().ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not in known_hosts")
+
+	// Host key changed
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	s.Require().NoError(err)
+	otherSigner, err := gossh.NewSignerFromKey(other)
+	s.Require().NoError(err)
+	s.trustServer(otherSigner.PublicKey())
+	_, err = s.connector().ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "does not match known_hosts")
+
+	s.Equal(int32(0), s.server.connections.Load())
+}
+
+func (s *NativeConnectorTestSuite) TestContextCancellation() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	_, err := c.ExecuteCommand(ctx, "sleep 5")
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.Less(time.Since(started), 2*time.Second)
+}
+
+func (s *NativeConnectorTestSuite) TestSetDefaultBackend() {
+	defer func() {
+		defaultBackend = BackendNative
+	}()
+
+	s.Require().NoError(SetDefaultBackend("openssh"))
+	s.Equal(BackendOpenSSH, New("example.com", 22, "user").config.Backend)
+	s.Require().NoError(SetDefaultBackend("native"))
+	s.Equal(BackendNative, New("example.com", 22, "user").config.Backend)
+	s.Error(SetDefaultBackend("telnet"))
+}
+
+// writeConfig writes ~/.ssh/config; PORT is replaced with the test server's port.
+func (s *NativeConnectorTestSuite) writeConfig(config string) {
+	config = strings.ReplaceAll(config, "PORT", strconv.Itoa(s.server.port()))
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "config"), []byte(config), 0o600))
+}
+
+// aliasConnector returns a native connector to an ssh_config alias, with its own pool.
+func (s *NativeConnectorTestSuite) aliasConnector(alias string) *Connector {
+	c := New(alias, 0, "")
+	s.Require().NoError(c.err)
+	c.config.Backend = BackendNative
+	c.pool = NewPool(time.Minute)
+	s.T().Cleanup(func() {
+		c.pool.CloseAll(context.Background())
+	})
+	return c
+}
+
+func (s *NativeConnectorTestSuite) TestProxyJump() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	s.writeConfig(`
+Host bastion
+  HostName 127.0.0.1
+  Port PORT
+  User tester
+
+Host app
+  HostName localhost
+  Port PORT
+  User tester
+  ProxyJump bastion
+
+Host chain
+  HostName localhost
+  Port PORT
+  User tester
+  ProxyJump bastion,tester@127.0.0.1:PORT
+
+Host loop
+  ProxyJump loop
+`)
+	ctx := context.Background()
+
+	// app's HostName is resolved by the bastion and checked in known_hosts under that name
+	address := knownhosts.Normalize(net.JoinHostPort("localhost", strconv.Itoa(s.server.port())))
+	f, err := os.OpenFile(filepath.Join(s.home, ".ssh", "known_hosts"), os.O_APPEND|os.O_WRONLY, 0o600)
+	s.Require().NoError(err)
+	_, err = f.WriteString(knownhosts.Line([]string{address}, s.server.hostKey.PublicKey()) + "\n")
+	s.Require().NoError(err)
+	s.Require().NoError(f.Close())
+
+	// One connection to the bastion and one tunnelled through it to app
+	output, err := s.aliasConnector("app").ExecuteCommand(ctx, "echo through")
+	s.Require().NoError(err)
+	s.Equal("through\n", output)
+	s.Equal(int32(2), s.server.connections.Load())
+
+	output, err = s.aliasConnector("chain").ExecuteCommand(ctx, "echo chained")
+	s.Require().NoError(err)
+	s.Equal("chained\n", output)
+	s.Equal(int32(5), s.server.connections.Load())
+
+	_, err = s.aliasConnector("loop").ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "too many ProxyJump hops")
+}
+
+func (s *NativeConnectorTestSuite) TestHostKeyAliasAndIdentityFile() {
+	// Move the key away from the default identity files
+	key := filepath.Join(s.home, ".ssh", "id_ed25519")
+	custom := filepath.Join(s.home, ".ssh", "deploy_key")
+	s.Require().NoError(os.Rename(key, custom))
+
+	// Only the alias is known, not the address
+	line := knownhosts.Line([]string{"app.internal"}, s.server.hostKey.PublicKey()) + "\n"
+	s.Require().NoError(os.WriteFile(filepath.Join(s.home, ".ssh", "known_hosts"), []byte(line), 0o600))
+	ctx := context.Background()
+
+	s.writeConfig(`
+Host app
+  HostName 127.0.0.1
+  Port PORT
+  User tester
+  HostKeyAlias app.internal
+  IdentityFile ~/.ssh/deploy_key
+  IdentitiesOnly yes
+
+Host app-nokey
+  HostName 127.0.0.1
+  Port PORT
+  User tester
+  HostKeyAlias app.internal
+
+Host app-noalias
+  HostName 127.0.0.1
+  Port PORT
+  User tester
+  IdentityFile ~/.ssh/deploy_key
+`)
+
+	output, err := s.aliasConnector("app").ExecuteCommand(ctx, "echo aliased")
+	s.Require().NoError(err)
+	s.Equal("aliased\n", output)
+
+	_, err = s.aliasConnector("app-nokey").ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "no SSH keys available")
+
+	_, err = s.aliasConnector("app-noalias").ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not in known_hosts")
+}
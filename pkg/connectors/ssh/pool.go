@@ -0,0 +1,304 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultIdleTimeout = 5 * time.Minute // Idle time after which pooled connections are closed
+	controlExitTimeout = 5 * time.Second // Time allowed for `ssh -O exit` per master on CloseAll
+)
+
+// defaultPool is shared by every connector created by New.
+var defaultPool = NewPool(defaultIdleTimeout)
+
+// DefaultPool returns the pool shared by all connectors.
+func DefaultPool() *Pool {
+	return defaultPool
+}
+
+// PooledConnection describes a pooled connection.
+type PooledConnection struct {
+	Key      string    `json:"key"` // user@host:port
+	Backend  Backend   `json:"backend"`
+	InUse    int       `json:"in_use"` // Commands currently running over the connection
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// PoolStats reports the state of a pool.
+type PoolStats struct {
+	IdleTimeout string             `json:"idle_timeout"`
+	Open        int                `json:"open"`
+	Dials       uint64             `json:"dials"`     // New connections
+	Reuses      uint64             `json:"reuses"`    // Operations served by an existing connection
+	Failures    uint64             `json:"failures"`  // Failed connection attempts
+	Evictions   uint64             `json:"evictions"` // Connections closed because they were idle or broken
+	Connections []PooledConnection `json:"connections"`
+}
+
+type poolEntry struct {
+	config   Config
+	client   *gossh.Client // Native backend only; OpenSSH masters are owned by ssh itself
+	inUse    int
+	created  time.Time
+	lastUsed time.Time
+}
+
+// Pool shares SSH connections between tool calls, keyed by user@host:port. Native connections are
+// shared clients; for the OpenSSH backend the pool hands out ControlMaster sockets kept alive by
+// ControlPersist. Connections idle for longer than the idle timeout are closed.
+type Pool struct {
+	mu          sync.Mutex
+	idleTimeout time.Duration
+	controlDir  string
+	entries     map[string]*poolEntry
+	dialing     map[string]chan struct{} // Closed when the pending dial for a key finishes
+	janitor     chan struct{}            // Closed to stop the idle janitor; nil when it is not running
+
+	dials     uint64
+	reuses    uint64
+	failures  uint64
+	evictions uint64
+}
+
+// NewPool creates a pool closing connections idle for longer than idleTimeout.
+func NewPool(idleTimeout time.Duration) *Pool {
+	return &Pool{
+		idleTimeout: idleTimeout,
+		controlDir:  filepath.Join(os.TempDir(), "remote-debugger-mcp", "ssh-"+strconv.Itoa(os.Getuid())),
+		entries:     make(map[string]*poolEntry),
+		dialing:     make(map[string]chan struct{}),
+	}
+}
+
+// SetIdleTimeout changes the idle timeout of the pool.
+func (p *Pool) SetIdleTimeout(idleTimeout time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.idleTimeout = idleTimeout
+}
+
+// poolKey identifies the connections that can be shared.
+func poolKey(config Config) string {
+	return fmt.Sprintf("%s@%s:%d", config.User, config.Host, config.Port)
+}
+
+// acquire returns a native client for config, dialing when there is none, and a function releasing it.
+func (p *Pool) acquire(ctx context.Context, config Config) (*gossh.Client, func(), error) {
+	key := poolKey(config)
+
+	p.mu.Lock()
+	for {
+		if entry, ok := p.entries[key]; ok && entry.client != nil {
+			entry.inUse++
+			entry.lastUsed = time.Now()
+			p.reuses++
+			p.mu.Unlock()
+			return entry.client, p.releaser(key, entry.client), nil
+		}
+		pending, ok := p.dialing[key]
+		if !ok {
+			break
+		}
+		// Another call is connecting to the same host; wait for it instead of dialing again
+		p.mu.Unlock()
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		p.mu.Lock()
+	}
+	done := make(chan struct{})
+	p.dialing[key] = done
+	p.mu.Unlock()
+
+	client, err := dialNative(ctx, config)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.dialing, key)
+	close(done)
+	if err != nil {
+		p.failures++
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	p.entries[key] = &poolEntry{config: config, client: client, inUse: 1, created: now, lastUsed: now}
+	p.dials++
+	p.startJanitor()
+	return client, p.releaser(key, client), nil
+}
+
+// releaser returns a function marking one use of a client as finished.
+func (p *Pool) releaser(key string, client *gossh.Client) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			if entry, ok := p.entries[key]; ok && entry.client == client {
+				entry.inUse--
+				entry.lastUsed = time.Now()
+			}
+		})
+	}
+}
+
+// discard removes a broken client from the pool so that the next acquire reconnects.
+func (p *Pool) discard(config Config, client *gossh.Client) {
+	key := poolKey(config)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if entry, ok := p.entries[key]; ok && entry.client == client {
+		delete(p.entries, key)
+		p.evictions++
+	}
+	_ = client.Close()
+}
+
+// controlPath returns the ControlPath for OpenSSH multiplexing and records the use of the master,
+// or "" when the control directory cannot be created.
+func (p *Pool) controlPath(config Config) string {
+	if err := os.MkdirAll(p.controlDir, 0o700); err != nil {
+		return ""
+	}
+
+	key := poolKey(config)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if entry, ok := p.entries[key]; ok {
+		entry.lastUsed = now
+		p.reuses++
+	} else {
+		p.entries[key] = &poolEntry{config: config, created: now, lastUsed: now}
+		p.dials++
+		p.startJanitor()
+	}
+	// %C is a hash of the connection parameters, short enough for a unix socket path
+	return filepath.Join(p.controlDir, "%C")
+}
+
+// controlPersist returns the ControlPersist value matching the idle timeout.
+func (p *Pool) controlPersist() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return strconv.Itoa(max(int(p.idleTimeout.Seconds()), 1)) + "s"
+}
+
+// startJanitor starts the goroutine closing idle connections. Callers hold p.mu.
+func (p *Pool) startJanitor() {
+	if p.janitor != nil {
+		return
+	}
+	stop := make(chan struct{})
+	p.janitor = stop
+	interval := max(p.idleTimeout/2, 10*time.Millisecond)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.closeIdle()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// closeIdle closes the connections that have not been used for the idle timeout.
+func (p *Pool) closeIdle() {
+	p.mu.Lock()
+	var expired []*poolEntry
+	for key, entry := range p.entries {
+		if entry.inUse > 0 || time.Since(entry.lastUsed) < p.idleTimeout {
+			continue
+		}
+		delete(p.entries, key)
+		p.evictions++
+		expired = append(expired, entry)
+	}
+	p.mu.Unlock()
+
+	// OpenSSH masters expire by themselves through ControlPersist
+	for _, entry := range expired {
+		if entry.client != nil {
+			_ = entry.client.Close()
+		}
+	}
+}
+
+// Stats returns the pool counters and its open connections.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := PoolStats{
+		IdleTimeout: p.idleTimeout.String(),
+		Open:        len(p.entries),
+		Dials:       p.dials,
+		Reuses:      p.reuses,
+		Failures:    p.failures,
+		Evictions:   p.evictions,
+		Connections: make([]PooledConnection, 0, len(p.entries)),
+	}
+	for key, entry := range p.entries {
+		backend := BackendNative
+		if entry.client == nil {
+			backend = BackendOpenSSH
+		}
+		stats.Connections = append(stats.Connections, PooledConnection{
+			Key:      key,
+			Backend:  backend,
+			InUse:    entry.inUse,
+			Created:  entry.created,
+			LastUsed: entry.lastUsed,
+		})
+	}
+	sort.Slice(stats.Connections, func(i, j int) bool { return stats.Connections[i].Key < stats.Connections[j].Key })
+	return stats
+}
+
+// CloseAll closes every pooled connection and stops the idle janitor, e.g. on server shutdown.
+// OpenSSH masters are asked to exit. The pool can be used again afterwards.
+func (p *Pool) CloseAll(ctx context.Context) {
+	p.mu.Lock()
+	entries := p.entries
+	p.entries = make(map[string]*poolEntry)
+	if p.janitor != nil {
+		close(p.janitor)
+		p.janitor = nil
+	}
+	p.mu.Unlock()
+
+	for _, entry := range entries {
+		if entry.client != nil {
+			_ = entry.client.Close()
+			continue
+		}
+		exitCtx, cancel := context.WithTimeout(ctx, controlExitTimeout)
+		conn := &Connector{config: entry.config}
+		args := append(configArgs(), "-o", "ControlPath="+filepath.Join(p.controlDir, "%C"), "-O", "exit")
+		args = append(args, conn.BuildSSHArgs()...)
+		_ = exec.CommandContext(exitCtx, "ssh", args...).Run()
+		cancel()
+	}
+}
@@ -0,0 +1,152 @@
+package ssh
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pooledConnector returns a native connector to the test server sharing pool.
+func (s *NativeConnectorTestSuite) pooledConnector(pool *Pool, user string) *Connector {
+	c := New("127.0.0.1", s.server.port(), user)
+	c.config.Backend = BackendNative
+	c.pool = pool
+	return c
+}
+
+func (s *NativeConnectorTestSuite) TestPoolSharesConnections() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	pool := NewPool(time.Minute)
+	defer pool.CloseAll(context.Background())
+	ctx := context.Background()
+
+	// Concurrent calls of several connectors to the same user@host:port share one connection
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := s.pooledConnector(pool, "tester").ExecuteCommand(ctx, "echo ok")
+			s.NoError(err)
+			s.Equal("ok\n", output)
+		}()
+	}
+	wg.Wait()
+	_, err := s.pooledConnector(pool, "tester").ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+	s.Equal(int32(1), s.server.connections.Load())
+
+	// A different user gets its own connection
+	_, err = s.pooledConnector(pool, "other").ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+
+	stats := pool.Stats()
+	s.Equal(2, stats.Open)
+	s.Equal(uint64(2), stats.Dials)
+	s.Equal(uint64(5), stats.Reuses)
+	s.Require().Len(stats.Connections, 2)
+	s.True(strings.HasPrefix(stats.Connections[0].Key, "other@127.0.0.1:"))
+	s.Equal(BackendNative, stats.Connections[1].Backend)
+	s.Equal(0, stats.Connections[1].InUse)
+
+	pool.CloseAll(ctx)
+	s.Equal(0, pool.Stats().Open)
+
+	// The pool reconnects after CloseAll
+	_, err = s.pooledConnector(pool, "tester").ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+	s.Equal(int32(3), s.server.connections.Load())
+}
+
+func (s *NativeConnectorTestSuite) TestPoolIdleExpiry() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	pool := NewPool(100 * time.Millisecond)
+	defer pool.CloseAll(context.Background())
+	ctx := context.Background()
+
+	// Connections in use are not closed even when they exceed the idle timeout
+	_, err := s.pooledConnector(pool, "tester").ExecuteCommand(ctx, "sleep 0.3")
+	s.Require().NoError(err)
+	s.Equal(1, pool.Stats().Open)
+
+	s.Eventually(func() bool {
+		return pool.Stats().Open == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	s.Equal(uint64(1), pool.Stats().Evictions)
+
+	_, err = s.pooledConnector(pool, "tester").ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+	s.Equal(int32(2), s.server.connections.Load())
+}
+
+func (s *NativeConnectorTestSuite) TestPoolReconnectsBrokenConnection() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	pool := NewPool(time.Minute)
+	defer pool.CloseAll(context.Background())
+	ctx := context.Background()
+	c := s.pooledConnector(pool, "tester")
+
+	_, err := c.ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+
+	// Break the pooled connection behind the pool's back
+	pool.mu.Lock()
+	for _, entry := range pool.entries {
+		_ = entry.client.Close()
+	}
+	pool.mu.Unlock()
+
+	output, err := c.ExecuteCommand(ctx, "echo again")
+	s.Require().NoError(err)
+	s.Equal("again\n", output)
+	s.Equal(uint64(1), pool.Stats().Evictions)
+	s.Equal(int32(2), s.server.connections.Load())
+}
+
+func (s *NativeConnectorTestSuite) TestControlArgs() {
+	pool := NewPool(90 * time.Second)
+	pool.controlDir = s.T().TempDir()
+	c := New("example.com", 2222, "user")
+	c.config.Backend = BackendOpenSSH
+	c.pool = pool
+
+	args := c.controlArgs()
+	s.Equal([]string{
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + pool.controlDir + "/%C",
+		"-o", "ControlPersist=90s",
+	}, args)
+
+	c.controlArgs()
+	stats := pool.Stats()
+	s.Equal(1, stats.Open)
+	s.Equal(uint64(1), stats.Reuses)
+	s.Equal(BackendOpenSSH, stats.Connections[0].Backend)
+	s.Equal("user@example.com:2222", stats.Connections[0].Key)
+}
+
+func (s *NativeConnectorTestSuite) TestPoolKeepsConnectionOnRefusedSession() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	pool := NewPool(time.Minute)
+	defer pool.CloseAll(context.Background())
+	ctx := context.Background()
+	c := s.pooledConnector(pool, "tester")
+
+	_, err := c.ExecuteCommand(ctx, "true")
+	s.Require().NoError(err)
+
+	// A refused session is reported without dropping the connection
+	s.server.refuse.Store(true)
+	_, err = c.ExecuteCommand(ctx, "true")
+	s.Require().Error(err)
+	s.Contains(err.Error(), "no more sessions")
+	s.Equal(1, pool.Stats().Open)
+	s.Equal(uint64(0), pool.Stats().Evictions)
+
+	s.server.refuse.Store(false)
+	output, err := c.ExecuteCommand(ctx, "echo again")
+	s.Require().NoError(err)
+	s.Equal("again\n", output)
+	s.Equal(int32(1), s.server.connections.Load())
+}
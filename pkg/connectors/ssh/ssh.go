@@ -3,46 +3,116 @@ package ssh
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Backend selects how a Connector talks to the remote host.
+type Backend string
+
+const (
+	// BackendNative uses golang.org/x/crypto/ssh with one connection per Connector.
+	BackendNative Backend = "native"
+	// BackendOpenSSH runs the ssh and scp binaries for every operation.
+	BackendOpenSSH Backend = "openssh"
 )
 
+// defaultBackend is used by New. It is set once at startup.
+var defaultBackend = BackendNative
+
+// SetDefaultBackend selects the backend of connectors created by New.
+func SetDefaultBackend(backend string) error {
+	switch Backend(backend) {
+	case BackendNative, BackendOpenSSH:
+		defaultBackend = Backend(backend)
+		return nil
+	default:
+		return fmt.Errorf("unknown ssh backend %q, expected %q or %q", backend, BackendNative, BackendOpenSSH)
+	}
+}
+
 // Config holds SSH connection configuration.
 type Config struct {
-	Host string
-	Port int
-	User string
+	Host    string // Host as given by the caller, possibly an ssh_config alias
+	Port    int
+	User    string
+	Backend Backend
+
+	// Options from ssh_config, used by the native backend; ssh reads them itself
+	HostName        string        // Address to connect to (default: Host)
+	IdentityFiles   []string      // Keys to offer instead of the default identity files
+	IdentitiesOnly  bool          // Only offer IdentityFiles, even if the agent holds other keys
+	IdentityAgent   string        // Agent socket; "none" disables the agent (default: SSH_AUTH_SOCK)
+	ProxyJump       []string      // Jump hosts in connection order, each [user@]host[:port]
+	ProxyCommand    string        // Not supported by the native backend
+	HostKeyAlias    string        // Name the host key is looked up under in known_hosts
+	KnownHostsFiles []string      // Replace ~/.ssh/known_hosts
+	ConnectTimeout  time.Duration // Replaces the default 10s
 }
 
 // Connector provides SSH connectivity functionality.
 type Connector struct {
 	config Config
+	err    error // Invalid ssh config, returned by every operation
+	pool   *Pool // Connections shared with other connectors
 }
 
-// New creates a new SSH connector.
+// New creates a new SSH connector. host may be an alias from ~/.ssh/config (or the file set by
+// SetConfigFile); a port of 0 or an empty user are taken from the config, then default to 22 and $USER.
 func New(host string, port int, user string) *Connector {
-	if port == 0 {
-		port = 22
-	}
-	if user == "" {
-		user = os.Getenv("USER")
-		if user == "" {
-			// Fallback to root. For the brave.
-			user = "root"
-		}
-	}
+	config, err := resolveConfig(host, port, user)
 	return &Connector{
-		config: Config{
-			Host: host,
-			Port: port,
-			User: user,
-		},
+		config: config,
+		err:    err,
+		pool:   defaultPool,
+	}
+}
+
+// isNative reports whether the connector uses the native backend.
+func (c *Connector) isNative() bool {
+	return c.config.Backend == BackendNative
+}
+
+// connectionPool returns the pool the connector shares connections through.
+func (c *Connector) connectionPool() *Pool {
+	if c.pool == nil {
+		return defaultPool
+	}
+	return c.pool
+}
+
+// controlArgs returns the OpenSSH options multiplexing commands over a pooled master connection.
+func (c *Connector) controlArgs() []string {
+	pool := c.connectionPool()
+	controlPath := pool.controlPath(c.config)
+	if controlPath == "" {
+		return nil
+	}
+	return []string{
+		"-o", "ControlMaster=auto",
+		"-o", "ControlPath=" + controlPath,
+		"-o", "ControlPersist=" + pool.controlPersist(),
 	}
 }
 
+// openSSHArgs returns the options the OpenSSH backend passes before BuildSSHArgs or BuildSCPArgs.
+func (c *Connector) openSSHArgs() []string {
+	return append(configArgs(), c.controlArgs()...)
+}
+
+// Close releases the connector. Pooled connections stay open for other tool calls until they
+// are idle for the pool's idle timeout or the pool is closed.
+func (c *Connector) Close() error {
+	return nil
+}
+
 // GetTarget returns the SSH target string.
 func (c *Connector) GetTarget() string {
 	return fmt.Sprintf("%s@%s", c.config.User, c.config.Host)
@@ -79,7 +149,15 @@ func (c *Connector) BuildSCPArgs() []string {
 
 // ExecuteCommand executes a command on the remote host.
 func (c *Connector) ExecuteCommand(ctx context.Context, command string) (string, error) {
-	args := c.BuildSSHArgs()
+	if c.err != nil {
+		return "", c.err
+	}
+	if c.isNative() {
+		stdout, stderr, _, err := c.runNative(ctx, command, nil, nil, nil)
+		return combineOutput(stdout, stderr), err
+	}
+
+	args := append(c.openSSHArgs(), c.BuildSSHArgs()...)
 	args = append(args, command)
 
 	cmd := exec.CommandContext(ctx, "ssh", args...)
@@ -88,20 +166,24 @@ func (c *Connector) ExecuteCommand(ctx context.Context, command string) (string,
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n"
-		}
-		output += "STDERR:\n" + stderr.String()
-	}
-
-	return output, err
+	return combineOutput(stdout.String(), stderr.String()), err
 }
 
 // ExecuteCommandWithExitCode executes a command and returns output with exit code.
 func (c *Connector) ExecuteCommandWithExitCode(ctx context.Context, command string) (string, int, error) {
-	args := c.BuildSSHArgs()
+	if c.err != nil {
+		return "", -1, c.err
+	}
+	if c.isNative() {
+		stdout, stderr, exitCode, err := c.runNative(ctx, command, nil, nil, nil)
+		var exitErr *gossh.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return "", -1, err
+		}
+		return combineOutput(stdout, stderr), exitCode, nil
+	}
+
+	args := append(c.openSSHArgs(), c.BuildSSHArgs()...)
 	args = append(args, command)
 
 	cmd := exec.CommandContext(ctx, "ssh", args...)
@@ -122,20 +204,55 @@ func (c *Connector) ExecuteCommandWithExitCode(ctx context.Context, command stri
 		}
 	}
 
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		if output != "" {
-			output += "\n"
+	return combineOutput(stdout.String(), stderr.String()), exitCode, nil
+}
+
+// ExecuteCommandStream executes a command, writing its stdout and stderr as they arrive. stdin may be nil.
+// Like ExecuteCommandWithExitCode, a non-zero exit code is not an error.
+func (c *Connector) ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	if c.err != nil {
+		return -1, c.err
+	}
+	if c.isNative() {
+		_, _, exitCode, err := c.runNative(ctx, command, stdin, stdout, stderr)
+		var exitErr *gossh.ExitError
+		if err != nil && !errors.As(err, &exitErr) {
+			return -1, err
 		}
-		output += "STDERR:\n" + stderr.String()
+		return exitCode, nil
 	}
 
-	return output, exitCode, nil
+	args := append(c.openSSHArgs(), c.BuildSSHArgs()...)
+	args = append(args, command)
+
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			return exitErr.ExitCode(), nil
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return -1, err
+	}
+	return 0, nil
 }
 
-// CopyFile copies a local file to the remote host.
+// CopyFile copies a local file to the remote host. The native backend uses UploadFile.
 func (c *Connector) CopyFile(ctx context.Context, localPath, remotePath string) error {
-	args := c.BuildSCPArgs()
+	if c.err != nil {
+		return c.err
+	}
+	if c.isNative() {
+		_, err := c.UploadFile(ctx, localPath, remotePath)
+		return err
+	}
+
+	args := append(c.openSSHArgs(), c.BuildSCPArgs()...)
 	args = append(args, localPath, fmt.Sprintf("%s:%s", c.GetTarget(), remotePath))
 
 	cmd := exec.CommandContext(ctx, "scp", args...)
@@ -148,9 +265,17 @@ func (c *Connector) CopyFile(ctx context.Context, localPath, remotePath string)
 	return nil
 }
 
-// CopyFileFromRemote copies a file from the remote host to local.
+// CopyFileFromRemote copies a file from the remote host to local. The native backend uses DownloadFile.
 func (c *Connector) CopyFileFromRemote(ctx context.Context, remotePath, localPath string) error {
-	args := c.BuildSCPArgs()
+	if c.err != nil {
+		return c.err
+	}
+	if c.isNative() {
+		_, err := c.DownloadFile(ctx, remotePath, localPath)
+		return err
+	}
+
+	args := append(c.openSSHArgs(), c.BuildSCPArgs()...)
 	args = append(args, fmt.Sprintf("%s:%s", c.GetTarget(), remotePath), localPath)
 
 	cmd := exec.CommandContext(ctx, "scp", args...)
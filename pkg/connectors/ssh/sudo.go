@@ -0,0 +1,178 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// SudoMarker starts the output of elevated commands.
+const SudoMarker = "[sudo] elevated command, ran as root:"
+
+var (
+	// ErrSudoNotAllowed is returned for commands missing from the server's sudo allowlist.
+	ErrSudoNotAllowed = errors.New("command is not in the sudo allowlist of the server")
+	// ErrSudoPasswordRequired is returned when sudo on the remote host would prompt for a password.
+	ErrSudoPasswordRequired = errors.New("sudo requires a password on the remote host")
+	// ErrSudoDenied is returned when the remote sudoers policy does not allow the command.
+	ErrSudoDenied = errors.New("sudo is not allowed for this command on the remote host")
+)
+
+// sudoPolicy is the server-side policy for elevated commands. It is set once at startup.
+var sudoPolicy struct {
+	mu    sync.RWMutex
+	allow []string
+	audit zerolog.Logger
+}
+
+func init() {
+	sudoPolicy.audit = zerolog.Nop()
+}
+
+// SetSudoAllowlist sets the programs that may run through sudo. An entry is a bare program name,
+// which only matches the same bare name (resolved by sudo's secure_path), or an absolute path that
+// may contain path.Match wildcards, e.g. "/usr/bin/perf" or "/opt/diag/*". An empty list disables sudo.
+func SetSudoAllowlist(entries []string) error {
+	var allow []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.ContainsAny(entry, " \t\n'\"`$;&|<>") {
+			return fmt.Errorf("invalid sudo allowlist entry %q: expected a program name or absolute path", entry)
+		}
+		if strings.Contains(entry, "/") && !path.IsAbs(entry) {
+			return fmt.Errorf("invalid sudo allowlist entry %q: paths must be absolute", entry)
+		}
+		if _, err := path.Match(entry, ""); err != nil {
+			return fmt.Errorf("invalid sudo allowlist entry %q: %w", entry, err)
+		}
+		allow = append(allow, entry)
+	}
+
+	sudoPolicy.mu.Lock()
+	defer sudoPolicy.mu.Unlock()
+	sudoPolicy.allow = allow
+	return nil
+}
+
+// SudoAllowlist returns the programs that may run through sudo.
+func SudoAllowlist() []string {
+	sudoPolicy.mu.RLock()
+	defer sudoPolicy.mu.RUnlock()
+	return append([]string(nil), sudoPolicy.allow...)
+}
+
+// SetSudoAuditLogger sets the logger every elevated command is recorded with.
+func SetSudoAuditLogger(logger zerolog.Logger) {
+	sudoPolicy.mu.Lock()
+	defer sudoPolicy.mu.Unlock()
+	sudoPolicy.audit = logger.With().Str("component", "sudo").Logger()
+}
+
+// CheckSudoAllowed reports whether program may run through sudo.
+func CheckSudoAllowed(program string) error {
+	sudoPolicy.mu.RLock()
+	defer sudoPolicy.mu.RUnlock()
+
+	if len(sudoPolicy.allow) == 0 {
+		return fmt.Errorf("%w: sudo is disabled (start the server with -sudo-allow)", ErrSudoNotAllowed)
+	}
+	for _, entry := range sudoPolicy.allow {
+		if !strings.Contains(entry, "/") {
+			if program == entry {
+				return nil
+			}
+			continue
+		}
+		if matched, _ := path.Match(entry, path.Clean(program)); matched && path.IsAbs(program) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s (allowed: %s)", ErrSudoNotAllowed, program, strings.Join(sudoPolicy.allow, ", "))
+}
+
+// SudoArgs returns the escaped `sudo -n` invocation of argv. The program must be allowed by the allowlist.
+func SudoArgs(argv []string) (string, error) {
+	if len(argv) == 0 {
+		return "", errors.New("no command to elevate")
+	}
+	if err := CheckSudoAllowed(argv[0]); err != nil {
+		return "", err
+	}
+	return "sudo -n -- " + strings.Join(EscapeArgs(argv), " "), nil
+}
+
+// sudoError classifies the output of a failed `sudo -n` invocation.
+func sudoError(output string) error {
+	lower := strings.ToLower(output)
+	switch {
+	case strings.Contains(lower, "password is required"), strings.Contains(lower, "terminal is required"):
+		return fmt.Errorf("%w: add a NOPASSWD rule to sudoers for this command or connect as root", ErrSudoPasswordRequired)
+	case strings.Contains(lower, "command not found"), strings.Contains(lower, "sudo: not found"):
+		return errors.New("sudo is not installed on the remote host")
+	default:
+		detail := strings.TrimSpace(output)
+		if detail == "" {
+			return ErrSudoDenied
+		}
+		return fmt.Errorf("%w: %s", ErrSudoDenied, detail)
+	}
+}
+
+// CheckSudo verifies with `sudo -n -l` that argv may run without a password, before running it.
+func (c *Connector) CheckSudo(ctx context.Context, argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("no command to elevate")
+	}
+	if err := CheckSudoAllowed(argv[0]); err != nil {
+		return err
+	}
+	command := "sudo -n -l -- " + strings.Join(EscapeArgs(argv), " ")
+	output, exitCode, err := c.ExecuteCommandWithExitCode(ctx, command)
+	if err != nil {
+		return fmt.Errorf("failed to check sudo: %w", err)
+	}
+	if exitCode != 0 {
+		return sudoError(output)
+	}
+	return nil
+}
+
+// AuditSudo records an elevated command with the caller that requested it.
+func (c *Connector) AuditSudo(caller, command string) {
+	sudoPolicy.mu.RLock()
+	audit := sudoPolicy.audit
+	sudoPolicy.mu.RUnlock()
+
+	audit.Warn().
+		Str("caller", caller).
+		Str("target", c.GetTarget()).
+		Str("command", command).
+		Msg("running elevated command")
+}
+
+// ExecuteElevated runs argv with `sudo -n` after checking the allowlist and the remote sudoers
+// policy. The output starts with SudoMarker; a non-zero exit code of the command is not an error.
+func (c *Connector) ExecuteElevated(ctx context.Context, caller string, argv []string) (string, int, error) {
+	command, err := SudoArgs(argv)
+	if err != nil {
+		return "", -1, err
+	}
+	if err := c.CheckSudo(ctx, argv); err != nil {
+		return "", -1, err
+	}
+	c.AuditSudo(caller, command)
+
+	output, exitCode, err := c.ExecuteCommandWithExitCode(ctx, command)
+	if err != nil {
+		return "", -1, err
+	}
+	return fmt.Sprintf("%s %s\n%s", SudoMarker, strings.Join(argv, " "), output), exitCode, nil
+}
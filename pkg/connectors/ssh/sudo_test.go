@@ -0,0 +1,118 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fakeSudo is installed as sudo on the PATH of the test server. Listing allows kill, asks for a
+// password for /usr/sbin/locked and denies anything else; running runs the command as is.
+const fakeSudo = `#!/bin/sh
+[ "$1" = -n ] || exit 2
+shift
+if [ "$1" = -l ]; then
+	case "$3" in
+	kill) echo "$3"; exit 0 ;;
+	/usr/sbin/locked) echo "sudo: a password is required" >&2; exit 1 ;;
+	*) echo "Sorry, user tester is not allowed to execute '$3' as root." >&2; exit 1 ;;
+	esac
+fi
+[ "$1" = -- ] && shift
+exec "$@"
+`
+
+func (s *SSHConnectorTestSuite) setSudoAllowlist(entries ...string) {
+	previous := SudoAllowlist()
+	s.Require().NoError(SetSudoAllowlist(entries))
+	s.T().Cleanup(func() {
+		_ = SetSudoAllowlist(previous)
+	})
+}
+
+func (s *SSHConnectorTestSuite) TestSetSudoAllowlist() {
+	s.setSudoAllowlist(" perf ", "", "/opt/diag/*")
+	s.Equal([]string{"perf", "/opt/diag/*"}, SudoAllowlist())
+
+	for _, entry := range []string{"bin/perf", "perf;reboot", "sh -c", "/opt/[diag"} {
+		s.Error(SetSudoAllowlist([]string{entry}), entry)
+	}
+	// A rejected list leaves the previous one in place
+	s.Equal([]string{"perf", "/opt/diag/*"}, SudoAllowlist())
+}
+
+func (s *SSHConnectorTestSuite) TestCheckSudoAllowed() {
+	s.setSudoAllowlist()
+	s.ErrorIs(CheckSudoAllowed("kill"), ErrSudoNotAllowed)
+	s.ErrorContains(CheckSudoAllowed("kill"), "sudo is disabled")
+
+	s.setSudoAllowlist("kill", "/usr/bin/perf", "/opt/diag/*")
+	for _, program := range []string{"kill", "/usr/bin/perf", "/opt/diag/collect", "/opt/diag/../diag/collect"} {
+		s.NoError(CheckSudoAllowed(program), program)
+	}
+	for _, program := range []string{
+		"/tmp/kill",              // Bare names only match bare names
+		"perf",                   // Paths only match paths
+		"/opt/diag/sub/collect",  // Wildcards do not cross directories
+		"/opt/diag/../../tmp/sh", // Paths are cleaned before matching
+		"pkill",
+	} {
+		s.ErrorIs(CheckSudoAllowed(program), ErrSudoNotAllowed, program)
+	}
+}
+
+func (s *SSHConnectorTestSuite) TestSudoArgs() {
+	s.setSudoAllowlist("/opt/app")
+
+	command, err := SudoArgs([]string{"/opt/app", "-v", "it's"})
+	s.Require().NoError(err)
+	s.Equal(`sudo -n -- '/opt/app' '-v' 'it'\''s'`, command)
+
+	_, err = SudoArgs([]string{"/bin/sh", "-c", "id"})
+	s.ErrorIs(err, ErrSudoNotAllowed)
+	_, err = SudoArgs(nil)
+	s.Error(err)
+}
+
+func (s *SSHConnectorTestSuite) TestSudoError() {
+	s.ErrorIs(sudoError("sudo: a password is required\n"), ErrSudoPasswordRequired)
+	s.ErrorIs(sudoError("sudo: a terminal is required to read the password; either use the -S option"), ErrSudoPasswordRequired)
+	s.ErrorContains(sudoError("sh: 1: sudo: not found"), "sudo is not installed")
+
+	err := sudoError("Sorry, user deploy is not allowed to execute '/usr/bin/perf' as root on host.\n")
+	s.ErrorIs(err, ErrSudoDenied)
+	s.ErrorContains(err, "not allowed to execute '/usr/bin/perf'")
+	s.Equal(ErrSudoDenied, sudoError(""))
+}
+
+func (s *NativeConnectorTestSuite) TestExecuteElevated() {
+	bin := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(filepath.Join(bin, "sudo"), []byte(fakeSudo), 0o755))
+	s.T().Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	previous := SudoAllowlist()
+	s.Require().NoError(SetSudoAllowlist([]string{"kill", "/usr/sbin/locked", "/usr/sbin/other"}))
+	s.T().Cleanup(func() {
+		_ = SetSudoAllowlist(previous)
+	})
+
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+
+	output, exitCode, err := c.ExecuteElevated(ctx, "test", []string{"kill", "-0", "1"})
+	s.Require().NoError(err)
+	s.True(strings.HasPrefix(output, SudoMarker+" kill -0 1\n"), output)
+	s.Contains([]int{0, 1}, exitCode)
+
+	_, _, err = c.ExecuteElevated(ctx, "test", []string{"/usr/sbin/locked"})
+	s.ErrorIs(err, ErrSudoPasswordRequired)
+	s.ErrorContains(err, "NOPASSWD")
+
+	_, _, err = c.ExecuteElevated(ctx, "test", []string{"/usr/sbin/other"})
+	s.ErrorIs(err, ErrSudoDenied)
+
+	// The allowlist of the server is checked before anything runs on the host
+	_, _, err = c.ExecuteElevated(ctx, "test", []string{"sh", "-c", "id"})
+	s.ErrorIs(err, ErrSudoNotAllowed)
+}
@@ -0,0 +1,420 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// partSuffix marks partial transfers. They are kept on failure so that the next transfer resumes them.
+const partSuffix = ".part"
+
+// TransferResult reports what UploadFile or DownloadFile did.
+type TransferResult struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	Size        int64  `json:"size"`
+	Transferred int64  `json:"transferred"`            // Bytes sent over the connection
+	ResumedFrom int64  `json:"resumed_from,omitempty"` // Offset a partial transfer was resumed at
+	Skipped     bool   `json:"skipped,omitempty"`      // The destination already had the same content
+	SHA256      string `json:"sha256"`
+	Verified    bool   `json:"verified"` // The checksums of both sides were compared
+}
+
+// String summarises the transfer for tool output.
+func (r *TransferResult) String() string {
+	switch {
+	case r.Skipped:
+		return fmt.Sprintf("%s unchanged (sha256 %s), transfer skipped", r.Destination, r.SHA256)
+	case r.ResumedFrom > 0:
+		return fmt.Sprintf("%s: transferred %d of %d bytes (resumed at %d)", r.Destination, r.Transferred, r.Size, r.ResumedFrom)
+	default:
+		return fmt.Sprintf("%s: transferred %d bytes", r.Destination, r.Transferred)
+	}
+}
+
+// transferReader counts the bytes read and stops when the context is done.
+type transferReader struct {
+	ctx    context.Context
+	reader io.Reader
+	count  int64
+}
+
+func (r *transferReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// sftpClient starts an SFTP session: the sftp subsystem on the pooled connection for the native
+// backend, `ssh -s sftp` for the OpenSSH backend. The returned function ends it.
+func (c *Connector) sftpClient(ctx context.Context) (*sftp.Client, func(), error) {
+	if c.isNative() {
+		session, release, err := c.newNativeSession(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		closeSession := func() {
+			_ = session.Close()
+			release()
+		}
+		stdin, err := session.StdinPipe()
+		if err != nil {
+			closeSession()
+			return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+		}
+		stdout, err := session.StdoutPipe()
+		if err != nil {
+			closeSession()
+			return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+		}
+		if err := session.RequestSubsystem("sftp"); err != nil {
+			closeSession()
+			return nil, nil, fmt.Errorf("failed to start sftp (is the sftp subsystem enabled on %s?): %w", c.config.Host, err)
+		}
+		client, err := sftp.NewClientPipe(stdout, stdin)
+		if err != nil {
+			closeSession()
+			return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+		}
+		return client, func() {
+			_ = client.Close()
+			closeSession()
+		}, nil
+	}
+
+	args := append(c.openSSHArgs(), "-s")
+	args = append(args, c.BuildSSHArgs()...)
+	args = append(args, "sftp")
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start sftp: %w", err)
+	}
+	client, err := sftp.NewClientPipe(stdout, stdin)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return nil, nil, fmt.Errorf("failed to start sftp: %v - %s", err, stderr.String())
+	}
+	return client, func() {
+		_ = client.Close()
+		_ = cmd.Wait()
+	}, nil
+}
+
+// fileSHA256 hashes the first limit bytes of a local file, or all of it when limit is negative.
+func fileSHA256(path string, limit int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var reader io.Reader = file
+	if limit >= 0 {
+		reader = io.LimitReader(file, limit)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// remoteSHA256 hashes the first limit bytes of a remote file, or all of it when limit is negative,
+// with sha256sum or shasum on the remote host.
+func (c *Connector) remoteSHA256(ctx context.Context, path string, limit int64) (string, error) {
+	input := "< " + EscapeArg(path)
+	if limit >= 0 {
+		input = fmt.Sprintf("head -c %d < %s |", limit, EscapeArg(path))
+	}
+	command := input + " { sha256sum 2>/dev/null || shasum -a 256; }"
+	if limit < 0 {
+		command = "{ sha256sum 2>/dev/null || shasum -a 256; } " + input
+	}
+	output, err := c.ExecuteCommand(ctx, command)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("failed to hash %s: no output", path)
+	}
+	if sum, err := hex.DecodeString(fields[0]); err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("failed to hash %s: unexpected output %q", path, strings.TrimSpace(output))
+	}
+	return fields[0], nil
+}
+
+// UploadFile copies a local file to the remote host over SFTP. The upload is skipped when the
+// remote file has the same SHA-256, resumes a partial upload left by a previous attempt, and
+// replaces the remote file atomically with the local file's mode once the content is verified.
+func (c *Connector) UploadFile(ctx context.Context, localPath, remotePath string) (*TransferResult, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+	localSum, err := fileSHA256(localPath, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+	result := &TransferResult{Source: localPath, Destination: remotePath, Size: info.Size(), SHA256: localSum}
+	mode := info.Mode().Perm()
+
+	client, closeClient, err := c.sftpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClient()
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
+	if remote, err := client.Stat(remotePath); err == nil && remote.Mode().IsRegular() && remote.Size() == info.Size() {
+		if sum, err := c.remoteSHA256(ctx, remotePath, -1); err == nil && sum == localSum {
+			if remote.Mode().Perm() != mode {
+				if err := client.Chmod(remotePath, mode); err != nil {
+					return nil, fmt.Errorf("failed to upload file: %w", err)
+				}
+			}
+			result.Skipped = true
+			result.Verified = true
+			return result, nil
+		}
+	}
+
+	// Resume a partial upload if its content is a prefix of the local file
+	partPath := remotePath + partSuffix
+	if part, err := client.Stat(partPath); err == nil && part.Size() > 0 && part.Size() <= info.Size() {
+		localPrefix, localErr := fileSHA256(localPath, part.Size())
+		remotePrefix, remoteErr := c.remoteSHA256(ctx, partPath, part.Size())
+		if localErr == nil && remoteErr == nil && localPrefix == remotePrefix {
+			result.ResumedFrom = part.Size()
+		}
+	}
+
+	if err := c.uploadPart(ctx, client, localPath, partPath, result); err != nil {
+		if ctx.Err() != nil {
+			// Closing the client on cancellation fails the transfer with a less useful error
+			err = ctx.Err()
+		}
+		return result, fmt.Errorf("failed to upload file (partial upload kept in %s): %w", partPath, err)
+	}
+
+	if sum, err := c.remoteSHA256(ctx, partPath, -1); err == nil {
+		if sum != localSum {
+			_ = client.Remove(partPath)
+			return result, fmt.Errorf("failed to upload file: sha256 of %s is %s, expected %s", remotePath, sum, localSum)
+		}
+		result.Verified = true
+	} else if part, err := client.Stat(partPath); err != nil || part.Size() != info.Size() {
+		return result, fmt.Errorf("failed to upload file: size of %s does not match %s", partPath, localPath)
+	}
+
+	if err := client.Chmod(partPath, mode); err != nil {
+		return result, fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := client.PosixRename(partPath, remotePath); err != nil {
+		// Servers without the posix-rename extension cannot replace an existing file
+		_ = client.Remove(remotePath)
+		if err := client.Rename(partPath, remotePath); err != nil {
+			return result, fmt.Errorf("failed to upload file: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// uploadPart writes the local file to partPath, starting at result.ResumedFrom.
+func (c *Connector) uploadPart(ctx context.Context, client *sftp.Client, localPath, partPath string, result *TransferResult) error {
+	local, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = local.Close()
+	}()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if result.ResumedFrom == 0 {
+		flags |= os.O_TRUNC
+	}
+	remote, err := client.OpenFile(partPath, flags)
+	if err != nil {
+		return err
+	}
+	if _, err := remote.Seek(result.ResumedFrom, io.SeekStart); err != nil {
+		_ = remote.Close()
+		return err
+	}
+	if _, err := local.Seek(result.ResumedFrom, io.SeekStart); err != nil {
+		_ = remote.Close()
+		return err
+	}
+
+	reader := &transferReader{ctx: ctx, reader: local}
+	_, err = io.Copy(remote, reader)
+	result.Transferred = reader.count
+	if closeErr := remote.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// DownloadFile copies a remote file to the local host over SFTP. The download is skipped when the
+// local file has the same SHA-256, resumes a partial download, and replaces the local file
+// atomically with the remote file's mode once the content is verified.
+func (c *Connector) DownloadFile(ctx context.Context, remotePath, localPath string) (*TransferResult, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	client, closeClient, err := c.sftpClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeClient()
+	stop := context.AfterFunc(ctx, func() {
+		_ = client.Close()
+	})
+	defer stop()
+
+	info, err := client.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("failed to download file: %s is not a regular file", remotePath)
+	}
+	result := &TransferResult{Source: remotePath, Destination: localPath, Size: info.Size()}
+	mode := info.Mode().Perm()
+	// Without sha256sum on the remote host, downloads are only checked by size
+	remoteSum, sumErr := c.remoteSHA256(ctx, remotePath, -1)
+	result.SHA256 = remoteSum
+
+	if sumErr == nil {
+		if local, err := os.Stat(localPath); err == nil && local.Mode().IsRegular() && local.Size() == info.Size() {
+			if sum, err := fileSHA256(localPath, -1); err == nil && sum == remoteSum {
+				if local.Mode().Perm() != mode {
+					if err := os.Chmod(localPath, mode); err != nil {
+						return nil, fmt.Errorf("failed to download file: %w", err)
+					}
+				}
+				result.Skipped = true
+				result.Verified = true
+				return result, nil
+			}
+		}
+	}
+
+	partPath := localPath + partSuffix
+	if part, err := os.Stat(partPath); err == nil && part.Size() > 0 && part.Size() <= info.Size() {
+		localPrefix, localErr := fileSHA256(partPath, -1)
+		remotePrefix, remoteErr := c.remoteSHA256(ctx, remotePath, part.Size())
+		if localErr == nil && remoteErr == nil && localPrefix == remotePrefix {
+			result.ResumedFrom = part.Size()
+		}
+	}
+
+	if err := c.downloadPart(ctx, client, remotePath, partPath, result); err != nil {
+		if ctx.Err() != nil {
+			// Closing the client on cancellation fails the transfer with a less useful error
+			err = ctx.Err()
+		}
+		return result, fmt.Errorf("failed to download file (partial download kept in %s): %w", partPath, err)
+	}
+
+	sum, err := fileSHA256(partPath, -1)
+	if err != nil {
+		return result, fmt.Errorf("failed to download file: %w", err)
+	}
+	if sumErr == nil {
+		if sum != remoteSum {
+			_ = os.Remove(partPath)
+			return result, fmt.Errorf("failed to download file: sha256 of %s is %s, expected %s", localPath, sum, remoteSum)
+		}
+		result.Verified = true
+	} else if part, err := os.Stat(partPath); err != nil || part.Size() != info.Size() {
+		return result, fmt.Errorf("failed to download file: size of %s does not match %s", partPath, remotePath)
+	}
+	result.SHA256 = sum
+
+	if err := os.Chmod(partPath, mode); err != nil {
+		return result, fmt.Errorf("failed to download file: %w", err)
+	}
+	if err := os.Rename(partPath, localPath); err != nil {
+		return result, fmt.Errorf("failed to download file: %w", err)
+	}
+	return result, nil
+}
+
+// downloadPart writes the remote file to partPath, starting at result.ResumedFrom.
+func (c *Connector) downloadPart(ctx context.Context, client *sftp.Client, remotePath, partPath string, result *TransferResult) error {
+	remote, err := client.Open(remotePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = remote.Close()
+	}()
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if result.ResumedFrom == 0 {
+		flags |= os.O_TRUNC
+	}
+	local, err := os.OpenFile(partPath, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := remote.Seek(result.ResumedFrom, io.SeekStart); err != nil {
+		_ = local.Close()
+		return err
+	}
+	if _, err := local.Seek(result.ResumedFrom, io.SeekStart); err != nil {
+		_ = local.Close()
+		return err
+	}
+
+	reader := &transferReader{ctx: ctx, reader: remote}
+	_, err = io.Copy(local, reader)
+	result.Transferred = reader.count
+	if closeErr := local.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return err
+}
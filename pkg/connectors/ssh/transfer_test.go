@@ -0,0 +1,139 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// transferContent is large enough for several SFTP packets.
+var transferContent = bytes.Repeat([]byte("0123456789abcdef"), 16*1024)
+
+func transferSHA256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *NativeConnectorTestSuite) TestUploadFile() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+	dir := s.T().TempDir()
+	local := filepath.Join(dir, "debug-binary")
+	remote := filepath.Join(dir, "remote", "debug-binary")
+	s.Require().NoError(os.MkdirAll(filepath.Dir(remote), 0o755))
+	s.Require().NoError(os.WriteFile(local, transferContent, 0o750))
+	size := int64(len(transferContent))
+
+	result, err := c.UploadFile(ctx, local, remote)
+	s.Require().NoError(err)
+	s.Equal(&TransferResult{
+		Source:      local,
+		Destination: remote,
+		Size:        size,
+		Transferred: size,
+		SHA256:      transferSHA256(transferContent),
+		Verified:    true,
+	}, result)
+	content, err := os.ReadFile(remote)
+	s.Require().NoError(err)
+	s.Equal(transferContent, content)
+	info, err := os.Stat(remote)
+	s.Require().NoError(err)
+	s.Equal(os.FileMode(0o750), info.Mode().Perm())
+	s.NoFileExists(remote + partSuffix)
+
+	// Identical content is not sent again, but the mode still follows the local file
+	s.Require().NoError(os.Chmod(local, 0o700))
+	result, err = c.UploadFile(ctx, local, remote)
+	s.Require().NoError(err)
+	s.True(result.Skipped)
+	s.Zero(result.Transferred)
+	s.Contains(result.String(), "transfer skipped")
+	info, err = os.Stat(remote)
+	s.Require().NoError(err)
+	s.Equal(os.FileMode(0o700), info.Mode().Perm())
+
+	// A partial upload matching the local file is resumed
+	changed := append([]byte("v2:"), transferContent...)
+	s.Require().NoError(os.WriteFile(local, changed, 0o700))
+	s.Require().NoError(os.WriteFile(remote+partSuffix, changed[:1000], 0o600))
+	result, err = c.UploadFile(ctx, local, remote)
+	s.Require().NoError(err)
+	s.False(result.Skipped)
+	s.Equal(int64(1000), result.ResumedFrom)
+	s.Equal(int64(len(changed)-1000), result.Transferred)
+	s.Contains(result.String(), "resumed at 1000")
+	content, err = os.ReadFile(remote)
+	s.Require().NoError(err)
+	s.Equal(changed, content)
+
+	// A partial upload of other content is started over
+	s.Require().NoError(os.WriteFile(remote+partSuffix, []byte("garbage"), 0o600))
+	s.Require().NoError(os.WriteFile(local, transferContent, 0o700))
+	result, err = c.UploadFile(ctx, local, remote)
+	s.Require().NoError(err)
+	s.Zero(result.ResumedFrom)
+	s.Equal(size, result.Transferred)
+	content, err = os.ReadFile(remote)
+	s.Require().NoError(err)
+	s.Equal(transferContent, content)
+
+	_, err = c.UploadFile(ctx, filepath.Join(dir, "missing"), remote)
+	s.Error(err)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = c.UploadFile(cancelled, local, filepath.Join(dir, "remote", "other"))
+	s.ErrorIs(err, context.Canceled)
+	s.NoFileExists(filepath.Join(dir, "remote", "other"))
+}
+
+func (s *NativeConnectorTestSuite) TestDownloadFile() {
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+	dir := s.T().TempDir()
+	remote := filepath.Join(dir, "core.dump")
+	local := filepath.Join(dir, "local", "core.dump")
+	s.Require().NoError(os.MkdirAll(filepath.Dir(local), 0o755))
+	s.Require().NoError(os.WriteFile(remote, transferContent, 0o640))
+	size := int64(len(transferContent))
+
+	result, err := c.DownloadFile(ctx, remote, local)
+	s.Require().NoError(err)
+	s.Equal(size, result.Transferred)
+	s.True(result.Verified)
+	s.Equal(transferSHA256(transferContent), result.SHA256)
+	content, err := os.ReadFile(local)
+	s.Require().NoError(err)
+	s.Equal(transferContent, content)
+	info, err := os.Stat(local)
+	s.Require().NoError(err)
+	s.Equal(os.FileMode(0o640), info.Mode().Perm())
+
+	result, err = c.DownloadFile(ctx, remote, local)
+	s.Require().NoError(err)
+	s.True(result.Skipped)
+	s.Zero(result.Transferred)
+
+	// Resume from a partial download
+	changed := append(transferContent, "tail"...)
+	s.Require().NoError(os.WriteFile(remote, changed, 0o640))
+	s.Require().NoError(os.WriteFile(local+partSuffix, changed[:4096], 0o600))
+	result, err = c.DownloadFile(ctx, remote, local)
+	s.Require().NoError(err)
+	s.Equal(int64(4096), result.ResumedFrom)
+	s.Equal(int64(len(changed)-4096), result.Transferred)
+	content, err = os.ReadFile(local)
+	s.Require().NoError(err)
+	s.Equal(changed, content)
+	s.NoFileExists(local + partSuffix)
+
+	_, err = c.DownloadFile(ctx, dir, local)
+	s.Require().Error(err)
+	s.Contains(err.Error(), "not a regular file")
+}
@@ -0,0 +1,525 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/netutil"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	maxTunnelPortOffset = 100              // Ports tried after the requested one when it is taken
+	tunnelStartTimeout  = 10 * time.Second // Time allowed for an ssh -L process to open its port
+	maxRestartDelay     = time.Minute      // Upper bound of the delay between restarts
+)
+
+// tunnelHealthInterval is how often tunnels are checked and restarted when broken.
+var tunnelHealthInterval = 15 * time.Second
+
+// Tunnel states.
+const (
+	TunnelUp         = "up"
+	TunnelRestarting = "restarting"
+	TunnelClosed     = "closed"
+)
+
+// defaultTunnels holds the tunnels created by the tools.
+var defaultTunnels = NewTunnelManager()
+
+// DefaultTunnels returns the tunnel manager shared by all tools.
+func DefaultTunnels() *TunnelManager {
+	return defaultTunnels
+}
+
+// TunnelInfo describes a local port forward.
+type TunnelInfo struct {
+	ID          string    `json:"id"`
+	Target      string    `json:"target"`      // user@host of the SSH connection
+	LocalAddr   string    `json:"local_addr"`  // Address other tools connect to
+	RemoteAddr  string    `json:"remote_addr"` // Address as seen from the remote host
+	Backend     Backend   `json:"backend"`
+	State       string    `json:"state"`
+	Created     time.Time `json:"created"`
+	Restarts    int       `json:"restarts"`
+	Connections int       `json:"connections"` // Forwarded connections currently open (native backend)
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// tunnel forwards a local port to an address reached through an SSH host.
+type tunnel struct {
+	id         string
+	config     Config
+	target     string
+	localAddr  string
+	remoteAddr string
+	created    time.Time
+	ctx        context.Context // Cancelled by close
+	cancel     context.CancelFunc
+	done       chan struct{} // Closed when the tunnel's goroutines are gone
+
+	// Native backend: the tunnel owns a listener and an SSH client outside the pool
+	listener net.Listener
+	conns    sync.WaitGroup
+
+	mu          sync.Mutex
+	client      *gossh.Client
+	cmd         *exec.Cmd // OpenSSH backend
+	state       string
+	restarts    int
+	connections int
+	lastErr     error
+}
+
+func (t *tunnel) info() TunnelInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info := TunnelInfo{
+		ID:          t.id,
+		Target:      t.target,
+		LocalAddr:   t.localAddr,
+		RemoteAddr:  t.remoteAddr,
+		Backend:     t.config.Backend,
+		State:       t.state,
+		Created:     t.created,
+		Restarts:    t.restarts,
+		Connections: t.connections,
+	}
+	if t.lastErr != nil {
+		info.LastError = t.lastErr.Error()
+	}
+	return info
+}
+
+func (t *tunnel) setState(state string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.state = state
+	if err != nil {
+		t.lastErr = err
+	}
+}
+
+// TunnelManager keeps SSH local port forwards open, checking them every tunnelHealthInterval and
+// restarting them when the connection is lost. The local port stays the same across restarts.
+type TunnelManager struct {
+	mu      sync.Mutex
+	tunnels map[string]*tunnel
+}
+
+// NewTunnelManager creates an empty tunnel manager.
+func NewTunnelManager() *TunnelManager {
+	return &TunnelManager{
+		tunnels: make(map[string]*tunnel),
+	}
+}
+
+// Create forwards a local port to remoteAddr, as seen from the host of the connector. The first
+// free port from localPort on is used. It returns once the forward accepts connections.
+func (m *TunnelManager) Create(ctx context.Context, c *Connector, localPort int, remoteAddr string) (TunnelInfo, error) {
+	if c.err != nil {
+		return TunnelInfo{}, c.err
+	}
+	if _, _, err := net.SplitHostPort(remoteAddr); err != nil {
+		return TunnelInfo{}, fmt.Errorf("invalid remote address %q: %w", remoteAddr, err)
+	}
+	port := netutil.FindAvailablePort(ctx, localPort, maxTunnelPortOffset)
+	if port == 0 {
+		return TunnelInfo{}, fmt.Errorf("no available local port in range %d-%d", localPort, localPort+maxTunnelPortOffset-1)
+	}
+
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+	tunnelCtx, cancel := context.WithCancel(context.Background())
+	t := &tunnel{
+		id:         hex.EncodeToString(id),
+		config:     c.config,
+		target:     c.GetTarget(),
+		localAddr:  net.JoinHostPort("127.0.0.1", strconv.Itoa(port)),
+		remoteAddr: remoteAddr,
+		created:    time.Now(),
+		ctx:        tunnelCtx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+		state:      TunnelUp,
+	}
+
+	var err error
+	if c.isNative() {
+		err = t.startNative(ctx)
+	} else {
+		err = t.startOpenSSH(ctx)
+	}
+	if err != nil {
+		cancel()
+		return TunnelInfo{}, err
+	}
+
+	m.mu.Lock()
+	m.tunnels[t.id] = t
+	m.mu.Unlock()
+	return t.info(), nil
+}
+
+// List returns the open tunnels, oldest first.
+func (m *TunnelManager) List() []TunnelInfo {
+	m.mu.Lock()
+	tunnels := make([]*tunnel, 0, len(m.tunnels))
+	for _, t := range m.tunnels {
+		tunnels = append(tunnels, t)
+	}
+	m.mu.Unlock()
+
+	infos := make([]TunnelInfo, 0, len(tunnels))
+	for _, t := range tunnels {
+		infos = append(infos, t.info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Created.Before(infos[j].Created) })
+	return infos
+}
+
+// Get returns a tunnel by id.
+func (m *TunnelManager) Get(id string) (TunnelInfo, error) {
+	m.mu.Lock()
+	t, ok := m.tunnels[id]
+	m.mu.Unlock()
+	if !ok {
+		return TunnelInfo{}, fmt.Errorf("tunnel %s not found", id)
+	}
+	return t.info(), nil
+}
+
+// Close closes a tunnel and the connections forwarded through it.
+func (m *TunnelManager) Close(id string) (TunnelInfo, error) {
+	m.mu.Lock()
+	t, ok := m.tunnels[id]
+	delete(m.tunnels, id)
+	m.mu.Unlock()
+	if !ok {
+		return TunnelInfo{}, fmt.Errorf("tunnel %s not found", id)
+	}
+	t.close()
+	return t.info(), nil
+}
+
+// CloseAll closes every tunnel.
+func (m *TunnelManager) CloseAll() {
+	m.mu.Lock()
+	tunnels := m.tunnels
+	m.tunnels = make(map[string]*tunnel)
+	m.mu.Unlock()
+
+	for _, t := range tunnels {
+		t.close()
+	}
+}
+
+func (t *tunnel) close() {
+	// Cancelling kills the ssh process of the OpenSSH backend
+	t.cancel()
+	if t.listener != nil {
+		_ = t.listener.Close()
+	}
+	t.mu.Lock()
+	if t.client != nil {
+		_ = t.client.Close()
+	}
+	t.mu.Unlock()
+	<-t.done
+	t.setState(TunnelClosed, nil)
+}
+
+// startNative listens on the local port and forwards every connection over a dedicated client.
+func (t *tunnel) startNative(ctx context.Context) error {
+	client, err := dialNative(ctx, t.config)
+	if err != nil {
+		return err
+	}
+	lc := net.ListenConfig{}
+	listener, err := lc.Listen(ctx, "tcp", t.localAddr)
+	if err != nil {
+		_ = client.Close()
+		return fmt.Errorf("failed to listen on %s: %w", t.localAddr, err)
+	}
+	t.client = client
+	t.listener = listener
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.acceptNative()
+	}()
+	go func() {
+		defer wg.Done()
+		t.superviseNative()
+	}()
+	go func() {
+		wg.Wait()
+		t.conns.Wait()
+		close(t.done)
+	}()
+	return nil
+}
+
+func (t *tunnel) acceptNative() {
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+		t.conns.Add(1)
+		go func() {
+			defer t.conns.Done()
+			t.forwardNative(conn)
+		}()
+	}
+}
+
+// forwardNative copies a local connection to the remote address until either side closes.
+func (t *tunnel) forwardNative(local net.Conn) {
+	defer func() {
+		_ = local.Close()
+	}()
+
+	t.mu.Lock()
+	client := t.client
+	t.mu.Unlock()
+	if client == nil {
+		return
+	}
+	remote, err := client.Dial("tcp", t.remoteAddr)
+	if err != nil {
+		t.mu.Lock()
+		t.lastErr = fmt.Errorf("failed to connect to %s: %w", t.remoteAddr, err)
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Lock()
+	t.connections++
+	t.mu.Unlock()
+
+	// Closing one side ends the copy in the other direction
+	stop := context.AfterFunc(t.ctx, func() {
+		_ = local.Close()
+		_ = remote.Close()
+	})
+	defer stop()
+	go func() {
+		_, _ = io.Copy(remote, local)
+		_ = remote.Close()
+	}()
+	_, _ = io.Copy(local, remote)
+	_ = remote.Close()
+
+	t.mu.Lock()
+	t.connections--
+	t.mu.Unlock()
+}
+
+// superviseNative sends keepalives every tunnelHealthInterval and reconnects when they fail.
+func (t *tunnel) superviseNative() {
+	ticker := time.NewTicker(tunnelHealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		t.mu.Lock()
+		client := t.client
+		t.mu.Unlock()
+		if client != nil {
+			if err := keepalive(client, tunnelHealthInterval); err == nil {
+				continue
+			}
+			_ = client.Close()
+			t.mu.Lock()
+			t.client = nil
+			t.mu.Unlock()
+			t.setState(TunnelRestarting, errors.New("ssh connection lost"))
+		}
+
+		ctx, cancel := context.WithTimeout(t.ctx, tunnelHealthInterval)
+		client, err := dialNative(ctx, t.config)
+		cancel()
+		if err != nil {
+			t.setState(TunnelRestarting, err)
+			continue
+		}
+		t.mu.Lock()
+		if t.ctx.Err() != nil {
+			t.mu.Unlock()
+			_ = client.Close()
+			return
+		}
+		t.client = client
+		t.restarts++
+		t.state = TunnelUp
+		t.mu.Unlock()
+	}
+}
+
+// keepalive sends a keepalive request and waits up to timeout for the reply, as a server that stopped
+// responding would block SendRequest until the TCP connection times out.
+func keepalive(client *gossh.Client, timeout time.Duration) error {
+	reply := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		reply <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-reply:
+		return err
+	case <-timer.C:
+		// Closing the client ends the pending request
+		return fmt.Errorf("no keepalive reply within %s", timeout)
+	}
+}
+
+// buildTunnelArgs returns the ssh arguments forwarding localAddr to remoteAddr. The forward gets a
+// connection of its own: a ControlMaster would keep the port open after the forward is closed.
+func (c *Connector) buildTunnelArgs(localAddr, remoteAddr string) []string {
+	args := append(configArgs(),
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval="+strconv.Itoa(int(tunnelHealthInterval.Seconds())),
+		"-o", "ServerAliveCountMax=3",
+		"-L", localAddr+":"+remoteAddr,
+	)
+	return append(args, c.BuildSSHArgs()...)
+}
+
+// startOpenSSH runs ssh -N -L and waits until the local port accepts connections.
+func (t *tunnel) startOpenSSH(ctx context.Context) error {
+	c := &Connector{config: t.config}
+	args := c.buildTunnelArgs(t.localAddr, t.remoteAddr)
+
+	exited, err := t.runOpenSSH(args)
+	if err != nil {
+		return err
+	}
+	if err := waitForPort(ctx, t.localAddr, exited); err != nil {
+		t.kill()
+		<-exited
+		return err
+	}
+	go t.superviseOpenSSH(args, exited)
+	return nil
+}
+
+// runOpenSSH starts ssh; the returned channel receives its exit error, with stderr appended.
+func (t *tunnel) runOpenSSH(args []string) (chan error, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(t.ctx, "ssh", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	t.mu.Lock()
+	t.cmd = cmd
+	t.mu.Unlock()
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = errors.New("ssh exited")
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		exited <- err
+	}()
+	return exited, nil
+}
+
+// kill stops the current ssh process, which is restarted by the supervisor.
+func (t *tunnel) kill() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cmd != nil && t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+}
+
+// waitForPort waits until addr accepts connections, the process exits or tunnelStartTimeout passes.
+func waitForPort(ctx context.Context, addr string, exited chan error) error {
+	ctx, cancel := context.WithTimeout(ctx, tunnelStartTimeout)
+	defer cancel()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-exited:
+			exited <- err
+			return fmt.Errorf("ssh port forward failed: %w", err)
+		case <-ctx.Done():
+			return fmt.Errorf("ssh port forward did not open %s: %w", addr, ctx.Err())
+		case <-ticker.C:
+			dialer := net.Dialer{Timeout: time.Second}
+			if conn, err := dialer.DialContext(ctx, "tcp", addr); err == nil {
+				_ = conn.Close()
+				return nil
+			}
+		}
+	}
+}
+
+// superviseOpenSSH restarts ssh when it exits, backing off up to maxRestartDelay.
+func (t *tunnel) superviseOpenSSH(args []string, exited chan error) {
+	defer close(t.done)
+
+	delay := time.Second
+	for {
+		err := <-exited
+		if t.ctx.Err() != nil {
+			return
+		}
+		t.setState(TunnelRestarting, err)
+
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRestartDelay)
+
+		exited, err = t.runOpenSSH(args)
+		if err != nil {
+			exited = make(chan error, 1)
+			exited <- err
+			continue
+		}
+		if err := waitForPort(t.ctx, t.localAddr, exited); err != nil {
+			// Restarted by the next iteration once the process is gone
+			t.kill()
+			continue
+		}
+		t.mu.Lock()
+		t.restarts++
+		t.state = TunnelUp
+		t.mu.Unlock()
+		delay = time.Second
+	}
+}
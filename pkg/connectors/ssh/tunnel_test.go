@@ -0,0 +1,186 @@
+package ssh
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/netutil"
+)
+
+// echoServer answers every line with the same line.
+func (s *NativeConnectorTestSuite) echoServer() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	s.T().Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, _ = io.Copy(conn, conn)
+				_ = conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (s *NativeConnectorTestSuite) echo(conn net.Conn, line string) string {
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err := conn.Write([]byte(line + "\n"))
+	s.Require().NoError(err)
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	s.Require().NoError(err)
+	return reply
+}
+
+func (s *NativeConnectorTestSuite) TestTunnel() {
+	previous := tunnelHealthInterval
+	tunnelHealthInterval = 50 * time.Millisecond
+	s.T().Cleanup(func() {
+		tunnelHealthInterval = previous
+	})
+	s.trustServer(s.server.hostKey.PublicKey())
+	c := s.connector()
+	ctx := context.Background()
+	manager := NewTunnelManager()
+	defer manager.CloseAll()
+	remote := s.echoServer()
+
+	// The requested port is taken, so the next free one is used
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	defer func() {
+		_ = taken.Close()
+	}()
+	port := taken.Addr().(*net.TCPAddr).Port
+	info, err := manager.Create(ctx, c, port, remote)
+	s.Require().NoError(err)
+	s.Greater(s.portOf(info.LocalAddr), port)
+	s.Equal(remote, info.RemoteAddr)
+	s.Equal("tester@127.0.0.1", info.Target)
+	s.Equal(TunnelUp, info.State)
+
+	conn, err := net.Dial("tcp", info.LocalAddr)
+	s.Require().NoError(err)
+	s.Equal("ping\n", s.echo(conn, "ping"))
+	s.Eventually(func() bool {
+		list := manager.List()
+		return len(list) == 1 && list[0].Connections == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	_ = conn.Close()
+
+	// A lost connection is detected by the health check and reconnected on the same port
+	manager.mu.Lock()
+	t := manager.tunnels[info.ID]
+	manager.mu.Unlock()
+	t.mu.Lock()
+	_ = t.client.Close()
+	t.mu.Unlock()
+	s.Eventually(func() bool {
+		current, err := manager.Get(info.ID)
+		return err == nil && current.Restarts == 1 && current.State == TunnelUp
+	}, 5*time.Second, 10*time.Millisecond)
+	current, err := manager.Get(info.ID)
+	s.Require().NoError(err)
+	s.Equal("ssh connection lost", current.LastError)
+
+	conn, err = net.Dial("tcp", info.LocalAddr)
+	s.Require().NoError(err)
+	s.Equal("pong\n", s.echo(conn, "pong"))
+
+	// Closing the tunnel closes the forwarded connections and frees the port
+	closed, err := manager.Close(info.ID)
+	s.Require().NoError(err)
+	s.Equal(TunnelClosed, closed.State)
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = conn.Read(make([]byte, 1))
+	s.Error(err)
+	_ = conn.Close()
+	s.True(netutil.IsPortAvailable(ctx, s.portOf(info.LocalAddr)))
+	s.Empty(manager.List())
+
+	_, err = manager.Close(info.ID)
+	s.ErrorContains(err, "not found")
+}
+
+func (s *NativeConnectorTestSuite) TestTunnelKeepaliveTimeout() {
+	previous := tunnelHealthInterval
+	tunnelHealthInterval = 50 * time.Millisecond
+	s.T().Cleanup(func() {
+		tunnelHealthInterval = previous
+	})
+	s.trustServer(s.server.hostKey.PublicKey())
+	manager := NewTunnelManager()
+	defer manager.CloseAll()
+
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	s.Require().NoError(err)
+	port := s.portOf(free.Addr().String())
+	s.Require().NoError(free.Close())
+	info, err := manager.Create(context.Background(), s.connector(), port, s.echoServer())
+	s.Require().NoError(err)
+
+	// A server that stops answering keepalives is treated as lost, not waited on
+	s.server.stall.Store(true)
+	s.Eventually(func() bool {
+		current, err := manager.Get(info.ID)
+		return err == nil && current.Restarts >= 1
+	}, 5*time.Second, 10*time.Millisecond)
+	current, err := manager.Get(info.ID)
+	s.Require().NoError(err)
+	s.Equal("ssh connection lost", current.LastError)
+
+	s.server.stall.Store(false)
+	conn, err := net.Dial("tcp", info.LocalAddr)
+	s.Require().NoError(err)
+	defer func() {
+		_ = conn.Close()
+	}()
+	s.Equal("ping\n", s.echo(conn, "ping"))
+}
+
+func (s *NativeConnectorTestSuite) portOf(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	s.Require().NoError(err)
+	n, err := strconv.Atoi(port)
+	s.Require().NoError(err)
+	return n
+}
+
+func (s *NativeConnectorTestSuite) TestTunnelErrors() {
+	c := s.connector()
+	manager := NewTunnelManager()
+
+	_, err := manager.Create(context.Background(), c, 16060, "localhost")
+	s.ErrorContains(err, "invalid remote address")
+
+	// The host key is not trusted
+	_, err = manager.Create(context.Background(), c, 16060, "127.0.0.1:6060")
+	s.Require().Error(err)
+	s.Empty(manager.List())
+}
+
+func (s *NativeConnectorTestSuite) TestBuildTunnelArgs() {
+	c := New("192.168.1.10", 2222, "deploy")
+	s.Equal([]string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=15",
+		"-o", "ServerAliveCountMax=3",
+		"-L", "127.0.0.1:6061:127.0.0.1:6060",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "BatchMode=yes",
+		"-o", "ConnectTimeout=10",
+		"-p", "2222",
+		"deploy@192.168.1.10",
+	}, c.buildTunnelArgs("127.0.0.1:6061", "127.0.0.1:6060"))
+}
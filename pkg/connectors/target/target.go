@@ -0,0 +1,148 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/kube"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/local"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+// Options selects a target: an SSH host, a pod, or the local host when both are empty.
+type Options struct {
+	Host       string
+	Port       int
+	User       string
+	Pod        string
+	Namespace  string
+	Container  string
+	KubeConfig string
+}
+
+// Validate checks that the options name a single target.
+func (o Options) Validate() error {
+	if o.Host != "" && o.Pod != "" {
+		return errors.New("cannot specify both host and pod - choose one")
+	}
+	if o.Pod == "" && (o.Namespace != "" || o.Container != "" || o.KubeConfig != "") {
+		return errors.New("namespace, container and kubeconfig require pod")
+	}
+	return nil
+}
+
+// Target runs shell commands and reads files on an SSH host, in a pod or on the local host.
+type Target struct {
+	name  string
+	ssh   *ssh.Connector
+	kube  *kube.Connector
+	local *local.Connector
+}
+
+// New creates the connector for the target of options.
+func New(options Options) *Target {
+	switch {
+	case options.Host != "":
+		conn := ssh.New(options.Host, options.Port, options.User)
+		return &Target{name: conn.GetTarget(), ssh: conn}
+	case options.Pod != "":
+		conn := kube.New(options.Namespace, options.Pod, options.Container, options.KubeConfig)
+		return &Target{name: "pod " + conn.GetPodIdentifier(), kube: conn}
+	default:
+		conn := local.New()
+		return &Target{name: conn.GetTarget(), local: conn}
+	}
+}
+
+// Name returns the target for tool output, e.g. "user@host:22", "pod ns/name" or "localhost".
+func (t *Target) Name() string {
+	return t.name
+}
+
+// IsLocal reports whether the target is the local host.
+func (t *Target) IsLocal() bool {
+	return t.local != nil
+}
+
+// SSH returns the SSH connector, or nil when the target is not an SSH host.
+func (t *Target) SSH() *ssh.Connector {
+	return t.ssh
+}
+
+// Kube returns the kube connector, or nil when the target is not a pod.
+func (t *Target) Kube() *kube.Connector {
+	return t.kube
+}
+
+// ExecuteCommand executes a shell command. Stderr is appended to the output and a non-zero exit code is an error.
+func (t *Target) ExecuteCommand(ctx context.Context, command string) (string, error) {
+	switch {
+	case t.ssh != nil:
+		return t.ssh.ExecuteCommand(ctx, command)
+	case t.kube != nil:
+		return t.kube.ExecuteCommand(ctx, command)
+	default:
+		return t.local.ExecuteCommand(ctx, command)
+	}
+}
+
+// ExecuteCommandWithExitCode executes a shell command. A non-zero exit code is not an error.
+func (t *Target) ExecuteCommandWithExitCode(ctx context.Context, command string) (string, int, error) {
+	switch {
+	case t.ssh != nil:
+		return t.ssh.ExecuteCommandWithExitCode(ctx, command)
+	case t.kube != nil:
+		return t.kube.ExecuteCommandWithExitCode(ctx, command)
+	default:
+		return t.local.ExecuteCommandWithExitCode(ctx, command)
+	}
+}
+
+// ExecuteCommandStream executes a shell command, writing its stdout and stderr as they arrive. stdin may
+// be nil. A non-zero exit code is not an error.
+func (t *Target) ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	switch {
+	case t.ssh != nil:
+		return t.ssh.ExecuteCommandStream(ctx, command, stdin, stdout, stderr)
+	case t.kube != nil:
+		return t.kube.ExecuteCommandStream(ctx, command, stdin, stdout, stderr)
+	default:
+		return t.local.ExecuteCommandStream(ctx, command, stdin, stdout, stderr)
+	}
+}
+
+// FetchFile makes the file at path available locally and returns the local path. Remote files are
+// copied into dir; local files are used in place.
+func (t *Target) FetchFile(ctx context.Context, path, dir string) (string, error) {
+	if t.local != nil {
+		if _, err := os.Stat(path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+	localPath, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return "", err
+	}
+	_ = localPath.Close()
+	if t.ssh != nil {
+		err = t.ssh.CopyFileFromRemote(ctx, path, localPath.Name())
+	} else {
+		err = t.kube.CopyFileFromPod(ctx, path, localPath.Name())
+	}
+	if err != nil {
+		return "", err
+	}
+	return localPath.Name(), nil
+}
+
+// Close closes the connection to the target.
+func (t *Target) Close() error {
+	if t.ssh != nil {
+		return t.ssh.Close()
+	}
+	return nil
+}
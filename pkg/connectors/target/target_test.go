@@ -0,0 +1,65 @@
+package target
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TargetTestSuite struct {
+	suite.Suite
+}
+
+func TestTargetTestSuite(t *testing.T) {
+	suite.Run(t, new(TargetTestSuite))
+}
+
+func (s *TargetTestSuite) TestValidate() {
+	s.NoError(Options{}.Validate())
+	s.NoError(Options{Host: "app-1", Port: 2222}.Validate())
+	s.NoError(Options{Pod: "api-0", Namespace: "prod", Container: "api"}.Validate())
+	s.ErrorContains(Options{Host: "app-1", Pod: "api-0"}.Validate(), "cannot specify both host and pod")
+	s.ErrorContains(Options{Host: "app-1", Namespace: "prod"}.Validate(), "namespace, container and kubeconfig require pod")
+}
+
+func (s *TargetTestSuite) TestNew() {
+	t := New(Options{Host: "192.168.1.10", Port: 2222, User: "deploy"})
+	s.Equal("deploy@192.168.1.10", t.Name())
+	s.NotNil(t.SSH())
+	s.Nil(t.Kube())
+	s.False(t.IsLocal())
+	s.NoError(t.Close())
+
+	t = New(Options{Pod: "pod/api-0", Namespace: "prod"})
+	s.Equal("pod prod/pod/api-0", t.Name())
+	s.NotNil(t.Kube())
+	s.Nil(t.SSH())
+
+	t = New(Options{})
+	s.Equal("localhost", t.Name())
+	s.True(t.IsLocal())
+}
+
+func (s *TargetTestSuite) TestLocal() {
+	t := New(Options{})
+	ctx := context.Background()
+
+	output, err := t.ExecuteCommand(ctx, "echo hello")
+	s.Require().NoError(err)
+	s.Equal("hello\n", output)
+	_, exitCode, err := t.ExecuteCommandWithExitCode(ctx, "exit 5")
+	s.Require().NoError(err)
+	s.Equal(5, exitCode)
+
+	// Local files are used in place
+	path := filepath.Join(s.T().TempDir(), "app")
+	s.Require().NoError(os.WriteFile(path, []byte("binary"), 0o600))
+	fetched, err := t.FetchFile(ctx, path, s.T().TempDir())
+	s.Require().NoError(err)
+	s.Equal(path, fetched)
+	_, err = t.FetchFile(ctx, path+".missing", s.T().TempDir())
+	s.Error(err)
+}
@@ -0,0 +1,30 @@
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// IsPortAvailable checks if a local port is available for binding on the loopback interface,
+// where port forwards listen.
+func IsPortAvailable(ctx context.Context, port int) bool {
+	lc := net.ListenConfig{}
+	listener, err := lc.Listen(ctx, "tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	_ = listener.Close()
+	return true
+}
+
+// FindAvailablePort finds an available local port among count ports starting from startPort.
+// It returns 0 when none is available.
+func FindAvailablePort(ctx context.Context, startPort, count int) int {
+	for port := startPort; port < startPort+count && port <= 65535; port++ {
+		if IsPortAvailable(ctx, port) {
+			return port
+		}
+	}
+	return 0
+}
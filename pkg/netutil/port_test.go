@@ -0,0 +1,36 @@
+package netutil
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type NetUtilTestSuite struct {
+	suite.Suite
+}
+
+func TestNetUtilTestSuite(t *testing.T) {
+	suite.Run(t, new(NetUtilTestSuite))
+}
+
+func (suite *NetUtilTestSuite) TestFindAvailablePort() {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() {
+		_ = listener.Close()
+	}()
+	taken := listener.Addr().(*net.TCPAddr).Port
+
+	suite.False(IsPortAvailable(ctx, taken))
+	port := FindAvailablePort(ctx, taken, 100)
+	suite.Greater(port, taken, "the taken port is skipped")
+	suite.Less(port, taken+100)
+	suite.True(IsPortAvailable(ctx, port))
+
+	suite.Zero(FindAvailablePort(ctx, taken, 1), "only the taken port is in range")
+	suite.Zero(FindAvailablePort(ctx, 65536, 10))
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	progressTokenKey = "progressToken"
+	sendTimeout      = 5 * time.Second
+	batchInterval    = 500 * time.Millisecond // Background lines are sent to the client in batches
+)
+
+// Notifier sends progress and log notifications to an MCP client session.
+// A nil Notifier, or one without a session, silently drops all notifications.
+type Notifier struct {
+	session  *mcp.ServerSession
+	token    any
+	logger   string
+	mu       sync.Mutex
+	progress float64
+}
+
+// New creates a notifier for the given session.
+// Progress notifications are only sent when the originating request carried a progress token.
+func New(session *mcp.ServerSession, meta mcp.Meta, logger string) *Notifier {
+	var token any
+	if meta != nil {
+		token = meta[progressTokenKey]
+	}
+	return &Notifier{
+		session: session,
+		token:   token,
+		logger:  logger,
+	}
+}
+
+// Progress sends a progress notification with the given message.
+func (n *Notifier) Progress(ctx context.Context, message string) {
+	if n == nil || n.session == nil || n.token == nil {
+		return
+	}
+	n.mu.Lock()
+	n.progress++
+	progress := n.progress
+	n.mu.Unlock()
+
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	_ = n.session.NotifyProgress(sendCtx, &mcp.ProgressNotificationParams{
+		ProgressToken: n.token,
+		Progress:      progress,
+		Message:       message,
+	})
+}
+
+// Log sends a log notification. The client decides which levels it receives.
+func (n *Notifier) Log(ctx context.Context, level mcp.LoggingLevel, data any) {
+	if n == nil || n.session == nil {
+		return
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	_ = n.session.Log(sendCtx, &mcp.LoggingMessageParams{
+		Level:  level,
+		Logger: n.logger,
+		Data:   data,
+	})
+}
+
+// Batches describes lines produced in the background of the server, such as the output of a command.
+type Batches struct {
+	Key    string                 // Log data key of ID, e.g. "run_id"
+	ID     string                 // ID the client uses to tell the batches apart
+	Take   func() ([]string, int) // Lines produced since the last call and the total line count
+	Status func() string          // Final status, logged once the producer is done
+
+	// Progress formats a progress notification from the total line count; nil sends none.
+	// The progress token belongs to the request that carried it, so progress notifications
+	// stop once Request is closed. A nil Request keeps them going.
+	Progress func(total int) string
+	Request  <-chan struct{}
+}
+
+// SendBatches sends the new lines of b as a log notification every batchInterval until done is
+// closed, then the remaining lines and the final status.
+func (n *Notifier) SendBatches(b Batches, done <-chan struct{}) {
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	send := func() {
+		lines, total := b.Take()
+		if len(lines) == 0 {
+			return
+		}
+		n.Log(context.Background(), "info", map[string]any{
+			b.Key:   b.ID,
+			"lines": lines,
+		})
+		if b.Progress == nil {
+			return
+		}
+		select {
+		case <-b.Request:
+		default:
+			n.Progress(context.Background(), b.Progress(total))
+		}
+	}
+	for {
+		select {
+		case <-ticker.C:
+			send()
+		case <-done:
+			send()
+			n.Log(context.Background(), "info", map[string]any{
+				b.Key:    b.ID,
+				"status": b.Status(),
+			})
+			return
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package procfs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// tcpStates names the hex states of the st column of /proc/net/tcp.
+var tcpStates = map[string]string{
+	"01": "ESTABLISHED",
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSE",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": "LISTEN",
+	"0B": "CLOSING",
+}
+
+// TCPState returns the name of a hex state of /proc/net/tcp, e.g. "0A" is LISTEN.
+func TCPState(code string) (string, bool) {
+	state, ok := tcpStates[code]
+	return state, ok
+}
+
+// Sections splits script output into the lines following each "== name" marker.
+// Output from stderr, appended by the connectors after "STDERR:", is dropped.
+func Sections(output string) map[string][]string {
+	result := make(map[string][]string)
+	name := ""
+	for _, line := range strings.Split(output, "\n") {
+		if line == "STDERR:" {
+			break
+		}
+		if strings.HasPrefix(line, "== ") {
+			name = strings.TrimPrefix(line, "== ")
+			result[name] = []string{}
+			continue
+		}
+		if name != "" && line != "" {
+			result[name] = append(result[name], line)
+		}
+	}
+	return result
+}
+
+// ParseHexAddr parses an address of /proc/net/tcp into its IP and port, e.g. "0100007F:1F90" is
+// 127.0.0.1 port 8080. Addresses are stored as 32-bit words in host byte order, little-endian on
+// supported platforms.
+func ParseHexAddr(addr string) (string, int, error) {
+	host, portHex, ok := strings.Cut(addr, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid address: %q", addr)
+	}
+	port, err := strconv.ParseInt(portHex, 16, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid address: %q", addr)
+	}
+	raw, err := hex.DecodeString(host)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid address: %q", addr)
+	}
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip.String(), int(port), nil
+}
@@ -0,0 +1,50 @@
+package procfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ProcfsTestSuite struct {
+	suite.Suite
+}
+
+func TestProcfsTestSuite(t *testing.T) {
+	suite.Run(t, new(ProcfsTestSuite))
+}
+
+func (suite *ProcfsTestSuite) TestSections() {
+	result := Sections("== meta\n100.5 250 16384\n== empty\n\n== two\na\nb\nSTDERR:\n== ignored\n")
+	suite.Equal(map[string][]string{
+		"meta":  {"100.5 250 16384"},
+		"empty": {},
+		"two":   {"a", "b"},
+	}, result)
+	suite.Empty(Sections("no markers\n"))
+}
+
+func (suite *ProcfsTestSuite) TestParseHexAddr() {
+	ip, port, err := ParseHexAddr("0100007F:1F90")
+	suite.Require().NoError(err)
+	suite.Equal("127.0.0.1", ip)
+	suite.Equal(8080, port)
+
+	ip, port, err = ParseHexAddr("00000000000000000000000001000000:0929")
+	suite.Require().NoError(err)
+	suite.Equal("::1", ip)
+	suite.Equal(2345, port)
+
+	for _, addr := range []string{"0100007F", "0100007F:XYZ", "01007F:1F90", "zz00007F:1F90"} {
+		_, _, err = ParseHexAddr(addr)
+		suite.Error(err, addr)
+	}
+}
+
+func (suite *ProcfsTestSuite) TestTCPState() {
+	state, ok := TCPState("0A")
+	suite.True(ok)
+	suite.Equal("LISTEN", state)
+	_, ok = TCPState("FF")
+	suite.False(ok)
+}
@@ -0,0 +1,224 @@
+package delve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+// executionCommands resume the target and only return once it stops again.
+var executionCommands = map[string]bool{
+	"continue": true,
+	"c":        true,
+	"next":     true,
+	"n":        true,
+	"step":     true,
+	"s":        true,
+	"stepout":  true,
+	"so":       true,
+	"call":     true,
+	"restart":  true,
+	"r":        true,
+	"rewind":   true,
+	"rw":       true,
+}
+
+// isExecutionCommand reports whether the command resumes the target.
+func isExecutionCommand(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	return executionCommands[fields[0]]
+}
+
+// isRunning reports whether the target is currently executing.
+func (s *DelveSession) isRunning() bool {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.running
+}
+
+// handleStop records that the running target stopped and notifies the client that resumed it.
+func (d *Tool) handleStop(sessionID string, session *DelveSession, output string) {
+	session.stateMu.Lock()
+	wasRunning := session.running
+	session.running = false
+	session.lastStop = output
+	if wasRunning {
+		close(session.stopped)
+	}
+	notifier := session.notifier
+	session.stateMu.Unlock()
+
+	d.recordEntry(session, TranscriptEntry{SessionID: sessionID, Action: "stop", Output: output})
+	if !wasRunning {
+		return
+	}
+
+	d.logger.Info().Msgf("Target in session %s stopped", sessionID)
+	event := map[string]string{
+		"session_id": sessionID,
+		"event":      "stopped",
+		"output":     strings.TrimSpace(output),
+	}
+	notifier.Log(context.Background(), "info", event)
+	session.notifyObservers(context.Background(), event)
+}
+
+// startExecution sends an execution command without waiting for the target to stop.
+func (d *Tool) startExecution(session *DelveSession, command string, notifier *notify.Notifier) (<-chan struct{}, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.lastUsed = time.Now()
+
+	session.stateMu.Lock()
+	if session.running {
+		session.stateMu.Unlock()
+		return nil, errors.New("target is already running. Use 'wait_for_stop' or 'halt'")
+	}
+	session.running = true
+	session.stopped = make(chan struct{})
+	session.lastStop = ""
+	session.notifier = notifier
+	stopped := session.stopped
+	session.stateMu.Unlock()
+
+	if err := session.backend.resume(command); err != nil {
+		session.stateMu.Lock()
+		session.running = false
+		close(stopped)
+		session.stateMu.Unlock()
+		return nil, err
+	}
+
+	return stopped, nil
+}
+
+// stopState returns the channel closed on the next stop and the output of the last stop.
+func (s *DelveSession) stopState() (<-chan struct{}, string, bool) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.stopped, s.lastStop, s.running
+}
+
+// handleExecution resumes the target and returns once it stops or the grace period elapses.
+func (d *Tool) handleExecution(input Input, session *DelveSession, command string, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	started := time.Now()
+	stopped, err := d.startExecution(session, command, notifier)
+	if err != nil {
+		d.recordCommand(session, input, command, "", err, started)
+		return nil, fmt.Errorf("failed to execute command: %w", err)
+	}
+
+	header := fmt.Sprintf("Session %s - Command: %s\n", input.SessionID, command)
+	select {
+	case <-stopped:
+		_, output, _ := session.stopState()
+		d.recordCommand(session, input, command, output, nil, started)
+		return paginateResult(header, output, input), nil
+	case <-time.After(stopGracePeriod):
+	}
+	// The output is recorded by the stop entry once the target stops
+	d.recordCommand(session, input, command, "", nil, started)
+
+	resultText := header + "\nTarget is running. Use 'wait_for_stop' to wait for it to stop or 'halt' to interrupt it."
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText,
+			},
+		},
+	}, nil
+}
+
+// handleWaitForStop waits until the running target stops or the timeout elapses.
+func (d *Tool) handleWaitForStop(ctx context.Context, input Input, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultWaitTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+
+	stopped, output, running := session.stopState()
+	header := fmt.Sprintf("Session %s - wait_for_stop\n", input.SessionID)
+	if !running {
+		if stopped == nil && output == "" {
+			return nil, fmt.Errorf("target in session %s has not been resumed. Use 'command' with continue, next, step or stepout", input.SessionID)
+		}
+		return paginateResult(header, output, input), nil
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	started := time.Now()
+	for {
+		select {
+		case <-stopped:
+			_, output, _ = session.stopState()
+			notifier.Progress(ctx, "target stopped")
+			return paginateResult(header, output, input), nil
+		case <-ticker.C:
+			notifier.Progress(ctx, fmt.Sprintf("target still running after %s", time.Since(started).Round(time.Second)))
+		case <-deadline.C:
+			resultText := header + fmt.Sprintf("\nTarget is still running after %s. Call 'wait_for_stop' again or use 'halt' to interrupt it.", timeout)
+			return &mcp.CallToolResultFor[Output]{
+				Content: []mcp.Content{
+					&mcp.TextContent{
+						Text: resultText,
+					},
+				},
+			}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// handleHalt interrupts a running target.
+func (d *Tool) handleHalt(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopped, _, running := session.stopState()
+	if !running {
+		return nil, fmt.Errorf("target in session %s is not running", input.SessionID)
+	}
+
+	d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: input.client, Action: "halt"})
+	if err := haltAndWait(session, stopped); err != nil {
+		return nil, fmt.Errorf("failed to halt target in session %s: %w", input.SessionID, err)
+	}
+
+	header := fmt.Sprintf("Session %s - halt\n", input.SessionID)
+	_, output, _ := session.stopState()
+	return paginateResult(header, output, input), nil
+}
+
+// haltAndWait interrupts the running target and waits until stopped is closed.
+func haltAndWait(session *DelveSession, stopped <-chan struct{}) error {
+	if err := session.backend.halt(); err != nil {
+		return err
+	}
+	select {
+	case <-stopped:
+		return nil
+	case <-time.After(commandTimeout):
+		return errors.New("target did not stop after halt request")
+	}
+}
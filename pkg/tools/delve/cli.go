@@ -0,0 +1,283 @@
+package delve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	readBufferSize     = 4096
+	responseBufferSize = 8
+	dlvPrompt          = "(dlv) "
+)
+
+// cliBackend drives a `dlv connect` terminal client over its stdin and stdout.
+type cliBackend struct {
+	logger    zerolog.Logger
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    io.ReadCloser // dlv stdout and stderr share a single pipe
+	responses chan string   // Prompt-terminated output of synchronous commands
+	done      chan struct{} // Closed when the dlv process output ends
+	ctxCancel context.CancelFunc
+	onStop    func(output string)
+	onLine    func(line string) // Called for every line printed while the target runs
+
+	mu           sync.Mutex
+	awaitingStop bool // The next response belongs to an execution command
+}
+
+// newCLIBackend starts `dlv connect` against a headless Delve server.
+func newCLIBackend(logger zerolog.Logger, addr string, onStop, onLine func(string)) (*cliBackend, error) {
+	// Create a background context that won't be cancelled when the calling context ends
+	// Using context.WithoutCancel would be better but requires Go 1.21+
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+
+	// Create command with the background context
+	cmd := exec.CommandContext(backgroundCtx, "dlv", "connect", addr) //nolint:contextcheck // intentionally using a detached context for background process
+
+	// Set process group ID so we can kill the entire group
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid: true,
+		Pgid:    0,
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	// Share one pipe between stdout and stderr so that error messages keep their
+	// position relative to the prompt
+	outputReader, outputWriter, err := os.Pipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create output pipe: %w", err)
+	}
+	cmd.Stdout = outputWriter
+	cmd.Stderr = outputWriter
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		_ = outputReader.Close()
+		_ = outputWriter.Close()
+		return nil, fmt.Errorf("failed to start dlv command: %w", err)
+	}
+	// The child process holds its own copy of the write end
+	_ = outputWriter.Close()
+
+	backend := newPipeBackend(logger, stdin, outputReader, onStop, onLine)
+	backend.cmd = cmd
+	backend.ctxCancel = cancel
+
+	// Start a goroutine to reap the process when it exits (prevents zombies)
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	// Wait for the initial prompt
+	select {
+	case <-backend.responses:
+	case <-backend.done:
+		backend.close()
+		return nil, fmt.Errorf("dlv exited before connecting to %s", addr)
+	case <-time.After(sessionStartupTimeout):
+		backend.close()
+		return nil, fmt.Errorf("timed out connecting to Delve debugger at %s", addr)
+	}
+
+	return backend, nil
+}
+
+// newPipeBackend wraps the terminal client's input and output and starts reading its output.
+func newPipeBackend(logger zerolog.Logger, stdin io.WriteCloser, stdout io.ReadCloser, onStop, onLine func(string)) *cliBackend {
+	backend := &cliBackend{
+		logger:    logger,
+		stdin:     stdin,
+		stdout:    stdout,
+		responses: make(chan string, responseBufferSize),
+		done:      make(chan struct{}),
+		onStop:    onStop,
+		onLine:    onLine,
+	}
+	go backend.readOutput()
+	return backend
+}
+
+// readOutput consumes dlv output for the lifetime of the session and splits it into prompt-terminated responses.
+func (b *cliBackend) readOutput() {
+	defer close(b.done)
+
+	buf := make([]byte, readBufferSize)
+	var pending, line strings.Builder
+	for {
+		n, err := b.stdout.Read(buf)
+		if n > 0 {
+			pending.Write(buf[:n])
+			b.emitLines(&line, buf[:n])
+			for {
+				text := pending.String()
+				idx := strings.Index(text, dlvPrompt)
+				if idx < 0 {
+					break
+				}
+				pending.Reset()
+				pending.WriteString(text[idx+len(dlvPrompt):])
+				b.dispatchResponse(text[:idx])
+			}
+		}
+		if err != nil {
+			// The debugger went away; release anyone waiting for the target to stop
+			b.dispatchResponse(pending.String() + "\ndebugger connection closed")
+			return
+		}
+	}
+}
+
+// emitLines passes the complete lines of output printed while the target runs to onLine,
+// so that events such as tracepoint hits are seen before the target stops.
+func (b *cliBackend) emitLines(line *strings.Builder, chunk []byte) {
+	if b.onLine == nil {
+		return
+	}
+
+	b.mu.Lock()
+	running := b.awaitingStop
+	b.mu.Unlock()
+
+	for _, c := range chunk {
+		if c != '\n' {
+			line.WriteByte(c)
+			continue
+		}
+		text := strings.TrimPrefix(line.String(), dlvPrompt)
+		line.Reset()
+		if running {
+			b.onLine(text)
+		}
+	}
+}
+
+// dispatchResponse routes a response either to the pending execution command or to a synchronous command.
+func (b *cliBackend) dispatchResponse(output string) {
+	b.mu.Lock()
+	awaitingStop := b.awaitingStop
+	b.awaitingStop = false
+	b.mu.Unlock()
+
+	if awaitingStop {
+		b.onStop(output)
+		return
+	}
+
+	select {
+	case b.responses <- output:
+	default:
+		b.logger.Warn().Msg("Dropping unsolicited dlv output")
+	}
+}
+
+// drainResponses discards responses left over from commands that timed out.
+func (b *cliBackend) drainResponses() {
+	for {
+		select {
+		case <-b.responses:
+		default:
+			return
+		}
+	}
+}
+
+// execute sends a command and reads the response.
+func (b *cliBackend) execute(command string) (string, error) {
+	b.drainResponses()
+
+	// Send command
+	if _, err := fmt.Fprintf(b.stdin, "%s\n", command); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+
+	// Wait for response with timeout
+	select {
+	case output := <-b.responses:
+		return output, nil
+	case <-b.done:
+		return "", errors.New("debugger connection closed")
+	case <-time.After(commandTimeout):
+		return "", errors.New("command timed out")
+	}
+}
+
+// resume sends an execution command; its output is reported through onStop.
+func (b *cliBackend) resume(command string) error {
+	b.drainResponses()
+
+	b.mu.Lock()
+	b.awaitingStop = true
+	b.mu.Unlock()
+
+	if _, err := fmt.Fprintf(b.stdin, "%s\n", command); err != nil {
+		b.mu.Lock()
+		b.awaitingStop = false
+		b.mu.Unlock()
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+	return nil
+}
+
+// halt interrupts a running target, the same way Ctrl-C does in an interactive dlv session.
+func (b *cliBackend) halt() error {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return errors.New("no dlv process")
+	}
+	if err := syscall.Kill(b.cmd.Process.Pid, syscall.SIGINT); err != nil {
+		return fmt.Errorf("failed to interrupt dlv: %w", err)
+	}
+	return nil
+}
+
+// close terminates the dlv client and prevents zombie processes.
+func (b *cliBackend) close() {
+	// Try to send exit command first for graceful shutdown
+	if b.stdin != nil {
+		_, _ = fmt.Fprintf(b.stdin, "exit\n")
+		time.Sleep(disconnectDelay)
+		_ = b.stdin.Close()
+	}
+
+	// Close the output pipe
+	if b.stdout != nil {
+		_ = b.stdout.Close()
+	}
+
+	// Kill the process group to ensure all child processes are terminated
+	if b.cmd != nil && b.cmd.Process != nil {
+		pgid := b.cmd.Process.Pid
+		// Try SIGTERM first for graceful shutdown
+		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+			// If SIGTERM fails, try SIGKILL
+			if killErr := syscall.Kill(-pgid, syscall.SIGKILL); killErr != nil {
+				// Log the error but don't fail if the process is already gone
+				if !strings.Contains(killErr.Error(), "no such process") {
+					b.logger.Error().Err(killErr).Msg("Failed to kill Delve process group")
+				}
+			}
+		}
+	}
+
+	// Cancel the background context
+	if b.ctxCancel != nil {
+		b.ctxCancel()
+	}
+}
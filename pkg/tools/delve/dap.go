@@ -0,0 +1,1061 @@
+package delve
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	dapLaunchTimeout  = 2 * time.Minute // Launching in debug mode builds the program first
+	dapDefaultDepth   = 50
+	maxProgramOutput  = 64 * 1024
+	dapClientID       = "remote-debugger-mcp"
+	dapDefaultMode    = "debug"
+	dapLocalsScope    = "Locals"
+	dapArgumentsScope = "Arguments"
+
+	disassembleBefore    = 16   // Instructions shown before the current one
+	disassembleAfter     = 48   // Instructions shown from the current one on
+	maxDisassembleLength = 4096 // Maximum number of instructions of a PC range
+)
+
+// fileLineLocation matches "file.go:123" location specs.
+var fileLineLocation = regexp.MustCompile(`^(.+):(\d+)$`)
+
+// dapCommands maps execution commands onto DAP requests.
+var dapCommands = map[string]string{
+	"continue": "continue",
+	"c":        "continue",
+	"next":     "next",
+	"n":        "next",
+	"step":     "stepIn",
+	"s":        "stepIn",
+	"stepout":  "stepOut",
+	"so":       "stepOut",
+	"rewind":   "reverseContinue",
+	"rw":       "reverseContinue",
+}
+
+type dapSource struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type dapStackFrame struct {
+	ID                          int       `json:"id"`
+	Name                        string    `json:"name"`
+	Source                      dapSource `json:"source"`
+	Line                        int       `json:"line"`
+	InstructionPointerReference string    `json:"instructionPointerReference,omitempty"`
+}
+
+type dapBreakpoint struct {
+	ID       int       `json:"id"`
+	Verified bool      `json:"verified"`
+	Message  string    `json:"message,omitempty"`
+	Source   dapSource `json:"source"`
+	Line     int       `json:"line,omitempty"`
+}
+
+type dapInstruction struct {
+	Address     string    `json:"address"`
+	Instruction string    `json:"instruction"`
+	Symbol      string    `json:"symbol,omitempty"`
+	Location    dapSource `json:"location"`
+	Line        int       `json:"line,omitempty"`
+}
+
+type dapVariable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+type dapThread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type dapStoppedEvent struct {
+	Reason            string `json:"reason"`
+	Description       string `json:"description,omitempty"`
+	ThreadID          int    `json:"threadId,omitempty"`
+	Text              string `json:"text,omitempty"`
+	HitBreakpointIDs  []int  `json:"hitBreakpointIds,omitempty"`
+	AllThreadsStopped bool   `json:"allThreadsStopped,omitempty"`
+}
+
+// breakpointLocation remembers where a DAP breakpoint was requested so that it can be cleared later.
+type breakpointLocation struct {
+	file     string
+	line     int
+	function string
+}
+
+// dapBackend drives a Delve DAP server (`dlv dap`, or a headless server with --accept-multiclient).
+type dapBackend struct {
+	logger      zerolog.Logger
+	client      *dapClient
+	onStop      func(output string)
+	initialized chan struct{}
+	initOnce    sync.Once
+	entry       chan struct{} // Closed once the first stop has been reported
+	entryOnce   sync.Once
+
+	mu                  sync.Mutex
+	resumedAt           uint64 // Index of the last resume response; earlier stops are stale
+	threadID            int
+	frame               int
+	lastFile            string
+	sourceBreakpoints   map[string][]int
+	functionBreakpoints []string
+	breakpoints         map[int]breakpointLocation
+	programOutput       strings.Builder
+}
+
+// newDAPBackend connects to a DAP server and attaches to (or launches) the target.
+func newDAPBackend(ctx context.Context, logger zerolog.Logger, addr string, input Input, onStop func(string)) (*dapBackend, error) {
+	// A server started with --client-addr has no target yet; only headless servers can be attached to
+	if input.Reverse && input.Program == "" && input.ProcessID == 0 {
+		return nil, errors.New("reverse DAP connections need program or process_id: dlv dap --client-addr starts without a target")
+	}
+	conn, err := dialDAP(ctx, addr, input)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &dapBackend{
+		logger:            logger,
+		onStop:            onStop,
+		initialized:       make(chan struct{}),
+		entry:             make(chan struct{}),
+		sourceBreakpoints: make(map[string][]int),
+		breakpoints:       make(map[int]breakpointLocation),
+	}
+	backend.client = newDAPClient(conn, backend.handleEvent)
+
+	if err := backend.start(input); err != nil {
+		backend.close()
+		return nil, err
+	}
+	return backend, nil
+}
+
+// dialDAP connects to a DAP server, or waits for one to dial in when reverse is set.
+func dialDAP(ctx context.Context, addr string, input Input) (net.Conn, error) {
+	if !input.Reverse {
+		dialer := net.Dialer{Timeout: sessionStartupTimeout}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to DAP server at %s: %w", addr, err)
+		}
+		return conn, nil
+	}
+
+	timeout := defaultWaitTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+
+	lc := net.ListenConfig{}
+	listener, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	if tcpListener, ok := listener.(*net.TCPListener); ok {
+		_ = tcpListener.SetDeadline(time.Now().Add(timeout))
+	}
+
+	conn, err := listener.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("no DAP server connected to %s within %s (start it with dlv dap --client-addr=%s): %w", addr, timeout, addr, err)
+	}
+	return conn, nil
+}
+
+// start runs the initialize, launch/attach and configurationDone handshake.
+func (b *dapBackend) start(input Input) error {
+	err := b.client.call("initialize", map[string]any{
+		"clientID":        dapClientID,
+		"adapterID":       "go",
+		"linesStartAt1":   true,
+		"columnsStartAt1": true,
+		"pathFormat":      "path",
+	}, nil, commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	// Keep the target stopped after the handshake, as `dlv connect` does
+	switch {
+	case input.Program != "":
+		mode := dapDefaultMode
+		if input.Mode != "" {
+			mode = input.Mode
+		}
+		err = b.client.call("launch", map[string]any{
+			"request":     "launch",
+			"mode":        mode,
+			"program":     input.Program,
+			"stopOnEntry": true,
+		}, nil, dapLaunchTimeout)
+	case input.ProcessID > 0:
+		err = b.client.call("attach", map[string]any{
+			"request":     "attach",
+			"mode":        "local",
+			"processId":   input.ProcessID,
+			"stopOnEntry": true,
+		}, nil, dapLaunchTimeout)
+	default:
+		err = b.client.call("attach", map[string]any{
+			"request":     "attach",
+			"mode":        "remote",
+			"stopOnEntry": true,
+		}, nil, commandTimeout)
+		if err != nil {
+			return fmt.Errorf("%w (attaching without program or process_id needs a server started with dlv --headless --accept-multiclient; for dlv dap set program or process_id)", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-b.initialized:
+	case <-b.client.done:
+		return errors.New("debugger connection closed during initialization")
+	case <-time.After(commandTimeout):
+		return errors.New("timed out waiting for the initialized event")
+	}
+
+	if err := b.client.call("configurationDone", map[string]any{}, nil, commandTimeout); err != nil {
+		return err
+	}
+
+	// Report the entry stop before accepting commands so that it cannot be
+	// mistaken for the stop of a later execution command
+	select {
+	case <-b.entry:
+	case <-b.client.done:
+		return errors.New("debugger connection closed during initialization")
+	case <-time.After(commandTimeout):
+		b.logger.Debug().Msg("No entry stop reported by DAP server")
+	}
+	return nil
+}
+
+// handleEvent processes events from the DAP server.
+func (b *dapBackend) handleEvent(event *dapMessage) {
+	switch event.Event {
+	case "initialized":
+		b.initOnce.Do(func() {
+			close(b.initialized)
+		})
+	case "output":
+		var body struct {
+			Category string `json:"category"`
+			Output   string `json:"output"`
+		}
+		if json.Unmarshal(event.Body, &body) == nil && body.Category != "telemetry" {
+			b.appendOutput(body.Output)
+		}
+	case "stopped":
+		var body dapStoppedEvent
+		if err := json.Unmarshal(event.Body, &body); err != nil {
+			b.logger.Warn().Err(err).Msg("Invalid DAP stopped event")
+			return
+		}
+		b.mu.Lock()
+		stale := event.index < b.resumedAt
+		b.mu.Unlock()
+		if stale {
+			// The target was resumed after this stop was sent
+			return
+		}
+		b.onStop(b.describeStop(body))
+		b.entryOnce.Do(func() {
+			close(b.entry)
+		})
+	case "exited":
+		var body struct {
+			ExitCode int `json:"exitCode"`
+		}
+		_ = json.Unmarshal(event.Body, &body)
+		b.appendOutput(fmt.Sprintf("Process exited with status %d\n", body.ExitCode))
+	case "terminated":
+		b.onStop(b.takeOutput() + "Debug session terminated")
+	}
+}
+
+// appendOutput buffers program output until the next stop, keeping only the most recent part.
+func (b *dapBackend) appendOutput(output string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.programOutput.WriteString(output)
+	if b.programOutput.Len() > maxProgramOutput {
+		recent := b.programOutput.String()[b.programOutput.Len()-maxProgramOutput:]
+		b.programOutput.Reset()
+		b.programOutput.WriteString(recent)
+	}
+}
+
+// takeOutput returns and clears the buffered program output.
+func (b *dapBackend) takeOutput() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	output := b.programOutput.String()
+	b.programOutput.Reset()
+	return output
+}
+
+// describeStop renders a stopped event the way dlv prints a stop location.
+func (b *dapBackend) describeStop(event dapStoppedEvent) string {
+	b.mu.Lock()
+	if event.ThreadID != 0 {
+		b.threadID = event.ThreadID
+	}
+	b.frame = 0
+	threadID := b.threadID
+	b.mu.Unlock()
+
+	var text strings.Builder
+	if output := b.takeOutput(); output != "" {
+		text.WriteString(output)
+		if !strings.HasSuffix(output, "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	text.WriteString(fmt.Sprintf("> goroutine(%d) stopped: %s", threadID, event.Reason))
+	if event.Description != "" && event.Description != event.Reason {
+		text.WriteString(" - " + event.Description)
+	}
+	if event.Text != "" {
+		text.WriteString(" - " + event.Text)
+	}
+	text.WriteString("\n")
+	for _, id := range event.HitBreakpointIDs {
+		text.WriteString(fmt.Sprintf("Breakpoint %d hit\n", id))
+	}
+
+	frames, err := b.stackTrace(threadID, 0, 1)
+	if err == nil && len(frames) > 0 {
+		b.mu.Lock()
+		b.lastFile = frames[0].Source.Path
+		b.mu.Unlock()
+		text.WriteString(formatFrame(0, frames[0]))
+	}
+	return text.String()
+}
+
+// execute maps a dlv command onto DAP requests.
+func (b *dapBackend) execute(command string) (string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return "", errors.New("empty command")
+	}
+	args := fields[1:]
+
+	switch fields[0] {
+	case "break", "b":
+		return b.setBreakpoint(args)
+	case "clear":
+		return b.clearBreakpoint(args)
+	case "clearall":
+		return b.clearAllBreakpoints()
+	case "breakpoints", "bp":
+		return b.listBreakpoints(), nil
+	case "stack", "bt":
+		return b.stack(args)
+	case "frame":
+		return b.selectFrame(args)
+	case "goroutines", "grs":
+		return b.goroutines(args)
+	case "goroutine", "gr":
+		return b.selectGoroutine(args)
+	case "locals":
+		return b.scopeVariables(dapLocalsScope)
+	case "args":
+		return b.scopeVariables(dapArgumentsScope)
+	case "disassemble", "disass":
+		return b.disassemble(args)
+	case "print", "p":
+		return b.evaluate(strings.TrimSpace(strings.TrimPrefix(command, fields[0])))
+	default:
+		// Delve's DAP server runs terminal commands prefixed with "dlv" in the REPL context
+		return b.evaluate("dlv " + command)
+	}
+}
+
+// resume maps an execution command onto a DAP request.
+func (b *dapBackend) resume(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return errors.New("empty command")
+	}
+	switch fields[0] {
+	case "call":
+		// The evaluate request only returns once the injected call finished
+		go func() {
+			output, err := b.evaluate(command)
+			if err != nil {
+				output = fmt.Sprintf("call failed: %v\n", err)
+			}
+			b.onStop(output)
+		}()
+		return nil
+	case "restart", "r":
+		return errors.New("restart is not supported by the dap backend: disconnect and connect again")
+	}
+	request, ok := dapCommands[fields[0]]
+	if !ok {
+		return fmt.Errorf("unsupported execution command: %s", fields[0])
+	}
+	threadID, err := b.currentThread()
+	if err != nil {
+		return err
+	}
+	response, err := b.client.request(request, map[string]any{"threadId": threadID}, commandTimeout)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.resumedAt = response.index
+	b.mu.Unlock()
+	return nil
+}
+
+// halt pauses the running target.
+func (b *dapBackend) halt() error {
+	threadID, err := b.currentThread()
+	if err != nil {
+		return err
+	}
+	return b.client.call("pause", map[string]any{"threadId": threadID}, nil, commandTimeout)
+}
+
+// close disconnects without terminating the debuggee.
+func (b *dapBackend) close() {
+	_ = b.client.call("disconnect", map[string]any{"terminateDebuggee": false}, nil, disconnectDelay)
+	b.client.close()
+}
+
+// currentThread returns the selected goroutine, defaulting to the first one reported by the server.
+func (b *dapBackend) currentThread() (int, error) {
+	b.mu.Lock()
+	threadID := b.threadID
+	b.mu.Unlock()
+	if threadID != 0 {
+		return threadID, nil
+	}
+
+	threads, err := b.threads()
+	if err != nil {
+		return 0, err
+	}
+	if len(threads) == 0 {
+		return 0, errors.New("target has no goroutines")
+	}
+
+	b.mu.Lock()
+	b.threadID = threads[0].ID
+	b.mu.Unlock()
+	return threads[0].ID, nil
+}
+
+func (b *dapBackend) threads() ([]dapThread, error) {
+	var body struct {
+		Threads []dapThread `json:"threads"`
+	}
+	if err := b.client.call("threads", nil, &body, commandTimeout); err != nil {
+		return nil, err
+	}
+	return body.Threads, nil
+}
+
+func (b *dapBackend) stackTrace(threadID, startFrame, levels int) ([]dapStackFrame, error) {
+	var body struct {
+		StackFrames []dapStackFrame `json:"stackFrames"`
+	}
+	err := b.client.call("stackTrace", map[string]any{
+		"threadId":   threadID,
+		"startFrame": startFrame,
+		"levels":     levels,
+	}, &body, commandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return body.StackFrames, nil
+}
+
+// currentFrame returns the selected frame.
+func (b *dapBackend) currentFrame() (dapStackFrame, error) {
+	threadID, err := b.currentThread()
+	if err != nil {
+		return dapStackFrame{}, err
+	}
+	b.mu.Lock()
+	frame := b.frame
+	b.mu.Unlock()
+
+	frames, err := b.stackTrace(threadID, frame, 1)
+	if err != nil {
+		return dapStackFrame{}, err
+	}
+	if len(frames) == 0 {
+		return dapStackFrame{}, fmt.Errorf("frame %d not found", frame)
+	}
+	return frames[0], nil
+}
+
+// currentFrameID returns the DAP frame ID of the selected frame.
+func (b *dapBackend) currentFrameID() (int, error) {
+	frame, err := b.currentFrame()
+	if err != nil {
+		return 0, err
+	}
+	return frame.ID, nil
+}
+
+// formatFrame renders a stack frame like `dlv stack`.
+func formatFrame(index int, frame dapStackFrame) string {
+	pc := frame.InstructionPointerReference
+	if pc == "" {
+		pc = "?"
+	}
+	return fmt.Sprintf("%d  %s in %s\n   at %s:%d\n", index, pc, frame.Name, frame.Source.Path, frame.Line)
+}
+
+func (b *dapBackend) stack(args []string) (string, error) {
+	depth := dapDefaultDepth
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed <= 0 {
+			return "", fmt.Errorf("invalid stack depth: %s", args[0])
+		}
+		depth = parsed
+	}
+
+	threadID, err := b.currentThread()
+	if err != nil {
+		return "", err
+	}
+	frames, err := b.stackTrace(threadID, 0, depth)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for i, frame := range frames {
+		output.WriteString(formatFrame(i, frame))
+	}
+	return output.String(), nil
+}
+
+// selectFrame handles `frame <index>` and runs `frame <index> <command>` in that frame.
+func (b *dapBackend) selectFrame(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("usage: frame <index> [command]")
+	}
+	index, err := strconv.Atoi(args[0])
+	if err != nil || index < 0 {
+		return "", fmt.Errorf("invalid frame index: %s", args[0])
+	}
+
+	if len(args) > 1 {
+		b.mu.Lock()
+		previous := b.frame
+		b.frame = index
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.frame = previous
+			b.mu.Unlock()
+		}()
+		return b.execute(strings.Join(args[1:], " "))
+	}
+
+	threadID, err := b.currentThread()
+	if err != nil {
+		return "", err
+	}
+	frames, err := b.stackTrace(threadID, index, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(frames) == 0 {
+		return "", fmt.Errorf("frame %d not found", index)
+	}
+
+	b.mu.Lock()
+	b.frame = index
+	b.mu.Unlock()
+	return formatFrame(index, frames[0]), nil
+}
+
+// goroutines handles `goroutines [-t [depth]]`, printing stacks in the same format as dlv.
+func (b *dapBackend) goroutines(args []string) (string, error) {
+	depth := 0
+	if len(args) > 0 && args[0] == "-t" {
+		depth = dapDefaultDepth
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return "", fmt.Errorf("invalid stack depth: %s", args[1])
+			}
+			depth = parsed
+		}
+	}
+
+	threads, err := b.threads()
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	current := b.threadID
+	b.mu.Unlock()
+
+	var output strings.Builder
+	for _, thread := range threads {
+		marker := " "
+		if thread.ID == current {
+			marker = "*"
+		}
+		output.WriteString(fmt.Sprintf("%s Goroutine %d - %s\n", marker, thread.ID, thread.Name))
+		if depth == 0 {
+			continue
+		}
+		frames, err := b.stackTrace(thread.ID, 0, depth)
+		if err != nil {
+			return "", err
+		}
+		for i, frame := range frames {
+			output.WriteString("\t" + strings.ReplaceAll(formatFrame(i, frame), "\n   at", "\n\t   at"))
+		}
+	}
+	output.WriteString(fmt.Sprintf("[%d goroutines]\n", len(threads)))
+	return output.String(), nil
+}
+
+// selectGoroutine handles `goroutine <id>` and runs `goroutine <id> <command>` in that goroutine.
+func (b *dapBackend) selectGoroutine(args []string) (string, error) {
+	if len(args) == 0 {
+		threadID, err := b.currentThread()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Thread %d selected\n", threadID), nil
+	}
+
+	threadID, err := strconv.Atoi(args[0])
+	if err != nil || threadID <= 0 {
+		return "", fmt.Errorf("invalid goroutine id: %s", args[0])
+	}
+
+	if len(args) > 1 {
+		b.mu.Lock()
+		previousThread, previousFrame := b.threadID, b.frame
+		b.threadID, b.frame = threadID, 0
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.threadID, b.frame = previousThread, previousFrame
+			b.mu.Unlock()
+		}()
+		return b.execute(strings.Join(args[1:], " "))
+	}
+	b.mu.Lock()
+	b.threadID = threadID
+	b.frame = 0
+	b.mu.Unlock()
+	return fmt.Sprintf("Switched to goroutine %d\n", threadID), nil
+}
+
+// scopeVariables lists the variables of a scope in the selected frame.
+// Delve reports arguments together with locals, so "Arguments" falls back to "Locals".
+func (b *dapBackend) scopeVariables(scopeName string) (string, error) {
+	frameID, err := b.currentFrameID()
+	if err != nil {
+		return "", err
+	}
+
+	var scopes struct {
+		Scopes []struct {
+			Name               string `json:"name"`
+			VariablesReference int    `json:"variablesReference"`
+		} `json:"scopes"`
+	}
+	if err := b.client.call("scopes", map[string]any{"frameId": frameID}, &scopes, commandTimeout); err != nil {
+		return "", err
+	}
+
+	reference := 0
+	for _, scope := range scopes.Scopes {
+		if scope.Name == scopeName {
+			reference = scope.VariablesReference
+			break
+		}
+		if scope.Name == dapLocalsScope && reference == 0 {
+			reference = scope.VariablesReference
+		}
+	}
+	if reference == 0 {
+		return "(no variables)\n", nil
+	}
+
+	var variables struct {
+		Variables []dapVariable `json:"variables"`
+	}
+	if err := b.client.call("variables", map[string]any{"variablesReference": reference}, &variables, commandTimeout); err != nil {
+		return "", err
+	}
+
+	if len(variables.Variables) == 0 {
+		return "(no variables)\n", nil
+	}
+	var output strings.Builder
+	for _, variable := range variables.Variables {
+		output.WriteString(fmt.Sprintf("%s = %s\n", variable.Name, variable.Value))
+	}
+	return output.String(), nil
+}
+
+// evaluate evaluates an expression (or a "dlv" prefixed command) in the selected frame.
+func (b *dapBackend) evaluate(expression string) (string, error) {
+	if expression == "" {
+		return "", errors.New("missing expression")
+	}
+
+	arguments := map[string]any{
+		"expression": expression,
+		"context":    "repl",
+	}
+	if frameID, err := b.currentFrameID(); err == nil {
+		arguments["frameId"] = frameID
+	}
+
+	var body struct {
+		Result string `json:"result"`
+	}
+	if err := b.client.call("evaluate", arguments, &body, commandTimeout); err != nil {
+		return "", err
+	}
+	return body.Result + "\n", nil
+}
+
+// setBreakpoint handles `break [name] <file:line|line|function>`.
+func (b *dapBackend) setBreakpoint(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errors.New("usage: break [name] <location>")
+	}
+	location := args[len(args)-1]
+
+	if match := fileLineLocation.FindStringSubmatch(location); match != nil {
+		line, _ := strconv.Atoi(match[2])
+		return b.addSourceBreakpoint(match[1], line)
+	}
+	if line, err := strconv.Atoi(location); err == nil {
+		b.mu.Lock()
+		file := b.lastFile
+		b.mu.Unlock()
+		if file == "" {
+			return "", errors.New("no current file; use file:line")
+		}
+		return b.addSourceBreakpoint(file, line)
+	}
+	return b.addFunctionBreakpoint(location)
+}
+
+func (b *dapBackend) addSourceBreakpoint(file string, line int) (string, error) {
+	b.mu.Lock()
+	lines := append([]int{}, b.sourceBreakpoints[file]...)
+	b.mu.Unlock()
+	for _, existing := range lines {
+		if existing == line {
+			return "", fmt.Errorf("breakpoint already exists at %s:%d", file, line)
+		}
+	}
+	lines = append(lines, line)
+
+	breakpoints, err := b.syncSourceBreakpoints(file, lines)
+	if err != nil {
+		return "", err
+	}
+	return describeBreakpoint(breakpoints[len(breakpoints)-1], fmt.Sprintf("%s:%d", file, line)), nil
+}
+
+func (b *dapBackend) addFunctionBreakpoint(function string) (string, error) {
+	b.mu.Lock()
+	functions := append([]string{}, b.functionBreakpoints...)
+	b.mu.Unlock()
+	for _, existing := range functions {
+		if existing == function {
+			return "", fmt.Errorf("breakpoint already exists at %s", function)
+		}
+	}
+	functions = append(functions, function)
+
+	breakpoints, err := b.syncFunctionBreakpoints(functions)
+	if err != nil {
+		return "", err
+	}
+	return describeBreakpoint(breakpoints[len(breakpoints)-1], function), nil
+}
+
+// syncSourceBreakpoints replaces all breakpoints of a file, as setBreakpoints requires.
+func (b *dapBackend) syncSourceBreakpoints(file string, lines []int) ([]dapBreakpoint, error) {
+	requested := make([]map[string]int, 0, len(lines))
+	for _, line := range lines {
+		requested = append(requested, map[string]int{"line": line})
+	}
+
+	var body struct {
+		Breakpoints []dapBreakpoint `json:"breakpoints"`
+	}
+	err := b.client.call("setBreakpoints", map[string]any{
+		"source":      dapSource{Path: file},
+		"breakpoints": requested,
+	}, &body, commandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(body.Breakpoints) != len(lines) {
+		return nil, fmt.Errorf("setBreakpoints returned %d breakpoints for %d lines", len(body.Breakpoints), len(lines))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, location := range b.breakpoints {
+		if location.file == file {
+			delete(b.breakpoints, id)
+		}
+	}
+	if len(lines) == 0 {
+		delete(b.sourceBreakpoints, file)
+	} else {
+		b.sourceBreakpoints[file] = lines
+	}
+	for i, breakpoint := range body.Breakpoints {
+		b.breakpoints[breakpoint.ID] = breakpointLocation{file: file, line: lines[i]}
+	}
+	return body.Breakpoints, nil
+}
+
+// syncFunctionBreakpoints replaces all function breakpoints, as setFunctionBreakpoints requires.
+func (b *dapBackend) syncFunctionBreakpoints(functions []string) ([]dapBreakpoint, error) {
+	requested := make([]map[string]string, 0, len(functions))
+	for _, function := range functions {
+		requested = append(requested, map[string]string{"name": function})
+	}
+
+	var body struct {
+		Breakpoints []dapBreakpoint `json:"breakpoints"`
+	}
+	err := b.client.call("setFunctionBreakpoints", map[string]any{
+		"breakpoints": requested,
+	}, &body, commandTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if len(body.Breakpoints) != len(functions) {
+		return nil, fmt.Errorf("setFunctionBreakpoints returned %d breakpoints for %d functions", len(body.Breakpoints), len(functions))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, location := range b.breakpoints {
+		if location.function != "" {
+			delete(b.breakpoints, id)
+		}
+	}
+	b.functionBreakpoints = functions
+	for i, breakpoint := range body.Breakpoints {
+		b.breakpoints[breakpoint.ID] = breakpointLocation{function: functions[i]}
+	}
+	return body.Breakpoints, nil
+}
+
+func describeBreakpoint(breakpoint dapBreakpoint, requested string) string {
+	if !breakpoint.Verified {
+		return fmt.Sprintf("Breakpoint %d pending at %s: %s\n", breakpoint.ID, requested, breakpoint.Message)
+	}
+	location := requested
+	if breakpoint.Source.Path != "" && breakpoint.Line > 0 {
+		location = fmt.Sprintf("%s:%d", breakpoint.Source.Path, breakpoint.Line)
+	}
+	return fmt.Sprintf("Breakpoint %d set at %s\n", breakpoint.ID, location)
+}
+
+func (b *dapBackend) clearBreakpoint(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: clear <breakpoint id>")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", fmt.Errorf("invalid breakpoint id: %s", args[0])
+	}
+
+	b.mu.Lock()
+	location, ok := b.breakpoints[id]
+	var lines []int
+	var functions []string
+	if ok && location.function != "" {
+		for _, function := range b.functionBreakpoints {
+			if function != location.function {
+				functions = append(functions, function)
+			}
+		}
+	} else if ok {
+		for _, line := range b.sourceBreakpoints[location.file] {
+			if line != location.line {
+				lines = append(lines, line)
+			}
+		}
+	}
+	b.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("breakpoint %d not found", id)
+	}
+
+	if location.function != "" {
+		_, err = b.syncFunctionBreakpoints(functions)
+	} else {
+		_, err = b.syncSourceBreakpoints(location.file, lines)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Breakpoint %d cleared\n", id), nil
+}
+
+func (b *dapBackend) clearAllBreakpoints() (string, error) {
+	b.mu.Lock()
+	files := make([]string, 0, len(b.sourceBreakpoints))
+	for file := range b.sourceBreakpoints {
+		files = append(files, file)
+	}
+	b.mu.Unlock()
+
+	for _, file := range files {
+		if _, err := b.syncSourceBreakpoints(file, nil); err != nil {
+			return "", err
+		}
+	}
+	if _, err := b.syncFunctionBreakpoints(nil); err != nil {
+		return "", err
+	}
+	return "Breakpoints cleared\n", nil
+}
+
+func (b *dapBackend) listBreakpoints() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.breakpoints) == 0 {
+		return "No breakpoints set\n"
+	}
+	ids := make([]int, 0, len(b.breakpoints))
+	for id := range b.breakpoints {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	var output strings.Builder
+	for _, id := range ids {
+		location := b.breakpoints[id]
+		if location.function != "" {
+			output.WriteString(fmt.Sprintf("Breakpoint %d at %s\n", id, location.function))
+		} else {
+			output.WriteString(fmt.Sprintf("Breakpoint %d at %s:%d\n", id, location.file, location.line))
+		}
+	}
+	return output.String()
+}
+
+// disassemble handles `disassemble [-a <start> <end>]` with the disassemble request,
+// marking the current instruction like dlv does.
+func (b *dapBackend) disassemble(args []string) (string, error) {
+	frame, err := b.currentFrame()
+	if err != nil {
+		return "", err
+	}
+	pc, _ := strconv.ParseUint(frame.InstructionPointerReference, 0, 64)
+
+	var start, end uint64
+	var offset, count int
+	switch {
+	case len(args) == 0:
+		if pc == 0 {
+			return "", errors.New("current frame has no instruction pointer")
+		}
+		start, offset, count = pc, -disassembleBefore, disassembleBefore+disassembleAfter
+	case len(args) == 3 && args[0] == "-a":
+		start, err = strconv.ParseUint(args[1], 0, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid start address: %s", args[1])
+		}
+		end, err = strconv.ParseUint(args[2], 0, 64)
+		if err != nil || end <= start {
+			return "", fmt.Errorf("invalid end address: %s", args[2])
+		}
+		// Every instruction takes at least one byte
+		count = int(min(end-start, maxDisassembleLength))
+	default:
+		return "", errors.New("the dap backend supports 'disassemble' and 'disassemble -a <start> <end>'")
+	}
+
+	var body struct {
+		Instructions []dapInstruction `json:"instructions"`
+	}
+	err = b.client.call("disassemble", map[string]any{
+		"memoryReference":   fmt.Sprintf("%#x", start),
+		"instructionOffset": offset,
+		"instructionCount":  count,
+		"resolveSymbols":    true,
+	}, &body, commandTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	symbol := ""
+	for _, instruction := range body.Instructions {
+		address, err := strconv.ParseUint(instruction.Address, 0, 64)
+		if err != nil {
+			continue
+		}
+		if end != 0 && address >= end {
+			break
+		}
+		if instruction.Symbol != "" && instruction.Symbol != symbol {
+			symbol = instruction.Symbol
+			output.WriteString("TEXT " + symbol + "\n")
+		}
+		marker := "  "
+		if address == pc {
+			marker = "=>"
+		}
+		output.WriteString(fmt.Sprintf("%s\t%s:%d\t%#x\t%s\n", marker, filepath.Base(instruction.Location.Path), instruction.Line, address, instruction.Instruction))
+	}
+	if output.Len() == 0 {
+		return "", errors.New("no instructions in range")
+	}
+	return output.String(), nil
+}
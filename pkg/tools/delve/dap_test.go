@@ -0,0 +1,453 @@
+package delve
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeDAPServer answers the subset of DAP requests used by the DAP backend.
+type fakeDAPServer struct {
+	conn    net.Conn
+	mu      sync.Mutex
+	seq     int
+	record  []string
+	chatter int  // Output events sent right after each stop, ahead of later responses
+	plain   bool // Behave like dlv dap, which cannot attach in remote mode
+}
+
+func (f *fakeDAPServer) send(message map[string]any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	message["seq"] = f.seq
+	_ = writeDAPMessage(f.conn, message)
+}
+
+func (f *fakeDAPServer) event(name string, body any) {
+	f.send(map[string]any{"type": "event", "event": name, "body": body})
+}
+
+// stop sends a stopped event followed by the configured burst of program output.
+func (f *fakeDAPServer) stop(body any) {
+	f.event("stopped", body)
+	for i := 0; i < f.chatter; i++ {
+		f.event("output", map[string]any{"category": "stdout", "output": "tick\n"})
+	}
+}
+
+func (f *fakeDAPServer) commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.record...)
+}
+
+func (f *fakeDAPServer) serve() {
+	reader := bufio.NewReader(f.conn)
+	for {
+		payload, err := readDAPMessage(reader)
+		if err != nil {
+			return
+		}
+		var request struct {
+			Seq       int             `json:"seq"`
+			Command   string          `json:"command"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(payload, &request); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.record = append(f.record, request.Command)
+		f.mu.Unlock()
+
+		respond := func(success bool, body any) {
+			f.send(map[string]any{
+				"type":        "response",
+				"request_seq": request.Seq,
+				"command":     request.Command,
+				"success":     success,
+				"body":        body,
+			})
+		}
+
+		switch request.Command {
+		case "initialize":
+			respond(true, map[string]any{"supportsConfigurationDoneRequest": true})
+		case "attach", "launch":
+			var args struct {
+				Mode string `json:"mode"`
+			}
+			_ = json.Unmarshal(request.Arguments, &args)
+			if f.plain && args.Mode == "remote" {
+				f.send(map[string]any{
+					"type":        "response",
+					"request_seq": request.Seq,
+					"command":     request.Command,
+					"success":     false,
+					"body":        map[string]any{"error": map[string]any{"format": "invalid debug configuration - unsupported 'mode' attribute \"remote\""}},
+				})
+				continue
+			}
+			respond(true, nil)
+			f.event("initialized", nil)
+		case "configurationDone":
+			respond(true, nil)
+			f.stop(map[string]any{"reason": "entry", "threadId": 1})
+		case "threads":
+			respond(true, map[string]any{"threads": []map[string]any{{"id": 1, "name": "main.main"}, {"id": 2, "name": "runtime.gopark"}}})
+		case "stackTrace":
+			respond(true, map[string]any{"stackFrames": []map[string]any{
+				{"id": 1000, "name": "main.main", "source": map[string]any{"path": "/src/main.go"}, "line": 10, "instructionPointerReference": "0x1004"},
+				{"id": 1001, "name": "runtime.main", "source": map[string]any{"path": "/go/src/runtime/proc.go"}, "line": 283},
+			}})
+		case "setBreakpoints":
+			var args struct {
+				Source      dapSource `json:"source"`
+				Breakpoints []struct {
+					Line int `json:"line"`
+				} `json:"breakpoints"`
+			}
+			_ = json.Unmarshal(request.Arguments, &args)
+			breakpoints := make([]map[string]any, 0, len(args.Breakpoints))
+			for i, bp := range args.Breakpoints {
+				breakpoints = append(breakpoints, map[string]any{"id": i + 1, "verified": true, "source": args.Source, "line": bp.Line})
+			}
+			respond(true, map[string]any{"breakpoints": breakpoints})
+		case "continue":
+			respond(true, map[string]any{"allThreadsContinued": true})
+			f.event("output", map[string]any{"category": "stdout", "output": "hello\n"})
+			f.stop(map[string]any{"reason": "breakpoint", "threadId": 1, "hitBreakpointIds": []int{1}})
+		case "disassemble":
+			respond(true, map[string]any{"instructions": []map[string]any{
+				{"address": "0x1000", "instruction": "mov rax, rbx", "symbol": "main.main", "location": map[string]any{"path": "/src/main.go"}, "line": 9},
+				{"address": "0x1004", "instruction": "call main.f", "symbol": "main.main", "location": map[string]any{"path": "/src/main.go"}, "line": 10},
+				{"address": "0x1009", "instruction": "ret", "symbol": "main.main", "location": map[string]any{"path": "/src/main.go"}, "line": 11},
+			}})
+		case "scopes":
+			respond(true, map[string]any{"scopes": []map[string]any{{"name": "Locals", "variablesReference": 7}}})
+		case "variables":
+			respond(true, map[string]any{"variables": []map[string]any{{"name": "x", "value": "42", "type": "int"}}})
+		case "evaluate":
+			var args struct {
+				Expression string `json:"expression"`
+			}
+			_ = json.Unmarshal(request.Arguments, &args)
+			if args.Expression == "y" {
+				f.send(map[string]any{
+					"type":        "response",
+					"request_seq": request.Seq,
+					"command":     request.Command,
+					"success":     false,
+					"body":        map[string]any{"error": map[string]any{"format": "could not find symbol value for y"}},
+				})
+				continue
+			}
+			respond(true, map[string]any{"result": "42"})
+		case "disconnect":
+			respond(true, nil)
+			return
+		default:
+			respond(false, nil)
+		}
+	}
+}
+
+type DAPTestSuite struct {
+	suite.Suite
+	tool *Tool
+}
+
+func (suite *DAPTestSuite) SetupTest() {
+	suite.tool = &Tool{
+		logger:    zerolog.Nop(),
+		validator: validator.New(),
+		sessions:  make(map[string]*DelveSession),
+	}
+}
+
+func (suite *DAPTestSuite) call(input Input) (string, error) {
+	result, err := suite.tool.DelveHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{Arguments: input})
+	if err != nil {
+		return "", err
+	}
+	suite.Require().Len(result.Content, 1)
+	content, ok := result.Content[0].(*mcp.TextContent)
+	suite.Require().True(ok)
+	return content.Text, nil
+}
+
+// exercise runs a typical debugging session against a connected fake server.
+func (suite *DAPTestSuite) exercise(sessionID string, server *fakeDAPServer) {
+	text, err := suite.call(Input{SessionID: sessionID, Action: "command", Command: "break main.go:10"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Breakpoint 1 set at main.go:10")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "breakpoints"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Breakpoint 1 at main.go:10")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "continue"})
+	suite.Require().NoError(err)
+	if !suite.Contains(text, "stopped: breakpoint") {
+		text, err = suite.call(Input{SessionID: sessionID, Action: "wait_for_stop", Timeout: 5})
+		suite.Require().NoError(err)
+		suite.Contains(text, "stopped: breakpoint")
+	}
+	suite.Contains(text, "hello")
+	suite.Contains(text, "/src/main.go:10")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "stack"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "0  0x1004 in main.main")
+	suite.Contains(text, "1  ? in runtime.main")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "disassemble"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "TEXT main.main")
+	suite.Contains(text, "=>\tmain.go:10\t0x1004\tcall main.f")
+	suite.Contains(text, "main.go:11\t0x1009\tret")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "disassemble", StartPC: "0x1000", EndPC: "0x1005"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "0x1004")
+	suite.NotContains(text, "0x1009")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "locals"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "x = 42")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "print x"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "42")
+
+	_, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "print y"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "could not find symbol")
+
+	// An injected call returns like a stop once the function finished
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "call f()"})
+	suite.Require().NoError(err)
+	if !suite.Contains(text, "42") {
+		text, err = suite.call(Input{SessionID: sessionID, Action: "wait_for_stop", Timeout: 5})
+		suite.Require().NoError(err)
+		suite.Contains(text, "42")
+	}
+
+	_, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "restart"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "not supported by the dap backend")
+
+	_, err = suite.call(Input{SessionID: sessionID, Action: "trace", Function: "main"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "cli backend")
+
+	text, err = suite.call(Input{SessionID: sessionID, Action: "command", Command: "goroutines"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "* Goroutine 1 - main.main")
+	suite.Contains(text, "[2 goroutines]")
+
+	_, err = suite.call(Input{SessionID: sessionID, Action: "disconnect"})
+	suite.Require().NoError(err)
+
+	commands := server.commands()
+	suite.Contains(commands, "configurationDone")
+	suite.Contains(commands, "setBreakpoints")
+	suite.Contains(commands, "scopes")
+	suite.Contains(commands, "variables")
+}
+
+func (suite *DAPTestSuite) TestConnect() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	server := &fakeDAPServer{}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.conn = conn
+		server.serve()
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	text, err := suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "dap", Action: "connect", Backend: "dap"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "dap backend")
+
+	suite.exercise("dap", server)
+}
+
+func (suite *DAPTestSuite) TestChattyTargetStop() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	// More output than any event buffer arrives between a stop and the stack trace of its location
+	server := &fakeDAPServer{chatter: 1000}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.conn = conn
+		server.serve()
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	started := time.Now()
+	text, err := suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "chatty", Action: "connect", Backend: "dap"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "stopped: entry")
+	suite.Contains(text, "/src/main.go:10", "the stop location is not lost")
+
+	text, err = suite.call(Input{SessionID: "chatty", Action: "command", Command: "continue", MaxLines: 2000})
+	suite.Require().NoError(err)
+	if !suite.Contains(text, "stopped: breakpoint") {
+		text, err = suite.call(Input{SessionID: "chatty", Action: "wait_for_stop", Timeout: 5, MaxLines: 2000})
+		suite.Require().NoError(err)
+	}
+	suite.Contains(text, "tick")
+	suite.Contains(text, "/src/main.go:10")
+	suite.Less(time.Since(started), commandTimeout, "stops do not wait for a request timeout")
+
+	_, err = suite.call(Input{SessionID: "chatty", Action: "disconnect"})
+	suite.Require().NoError(err)
+}
+
+func (suite *DAPTestSuite) TestReverseConnect() {
+	// Reserve a free port for the tool to listen on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	suite.Require().NoError(listener.Close())
+
+	server := &fakeDAPServer{}
+	go func() {
+		// Keep dialing until the tool starts listening, like dlv dap --client-addr
+		for i := 0; i < 50; i++ {
+			conn, err := net.Dial("tcp", listener.Addr().String())
+			if err == nil {
+				server.conn = conn
+				server.serve()
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}()
+
+	text, err := suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "reverse", Action: "connect", Backend: "dap", Reverse: true, Program: "./cmd/app", Timeout: 10})
+	suite.Require().NoError(err)
+	suite.Contains(text, "dap backend")
+
+	suite.exercise("reverse", server)
+}
+
+func (suite *DAPTestSuite) TestReverseConnectTimeout() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	suite.Require().NoError(listener.Close())
+
+	_, err = suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "late", Action: "connect", Backend: "dap", Reverse: true, ProcessID: 42, Timeout: 1})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "--client-addr")
+}
+
+func (suite *DAPTestSuite) TestConnectDoesNotBlockSessions() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	suite.Require().NoError(listener.Close())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "slow", Action: "connect", Backend: "dap", Reverse: true, ProcessID: 42, Timeout: 2})
+		done <- err
+	}()
+
+	suite.Eventually(func() bool {
+		suite.tool.sessionMu.RLock()
+		defer suite.tool.sessionMu.RUnlock()
+		return suite.tool.connecting["slow"]
+	}, time.Second, 10*time.Millisecond)
+
+	// The ID is taken while it connects, and other actions do not wait for it
+	_, err = suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "slow", Action: "connect", Backend: "dap", Reverse: true, ProcessID: 42, Timeout: 1})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "already exists")
+	text, err := suite.call(Input{Action: "list_sessions"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Delve sessions (0)")
+	suite.Len(done, 0)
+
+	// A failed connect releases the ID
+	suite.Require().Error(<-done)
+	suite.Empty(suite.tool.connecting)
+	suite.Empty(suite.tool.sessions)
+}
+
+func (suite *DAPTestSuite) TestAttachWithoutTarget() {
+	_, err := suite.call(Input{Host: "127.0.0.1", Port: 4567, SessionID: "reverse", Action: "connect", Backend: "dap", Reverse: true})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "need program or process_id")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer func() {
+		_ = listener.Close()
+	}()
+	server := &fakeDAPServer{plain: true}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.conn = conn
+		server.serve()
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	_, err = suite.call(Input{Host: "127.0.0.1", Port: port, SessionID: "plain", Action: "connect", Backend: "dap"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "unsupported 'mode'")
+	suite.Contains(err.Error(), "--accept-multiclient")
+}
+
+func (suite *DAPTestSuite) TestDAPMessageFraming() {
+	client, server := net.Pipe()
+	defer func() {
+		_ = client.Close()
+		_ = server.Close()
+	}()
+
+	go func() {
+		_ = writeDAPMessage(client, map[string]any{"seq": 1, "type": "event", "event": "initialized"})
+	}()
+
+	payload, err := readDAPMessage(bufio.NewReader(server))
+	suite.Require().NoError(err)
+	var message dapMessage
+	suite.Require().NoError(json.Unmarshal(payload, &message))
+	suite.Equal("event", message.Type)
+	suite.Equal("initialized", message.Event)
+}
+
+func TestDAPTestSuite(t *testing.T) {
+	suite.Run(t, new(DAPTestSuite))
+}
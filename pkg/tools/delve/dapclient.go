@@ -0,0 +1,254 @@
+package delve
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const contentLengthKey = "Content-Length"
+
+// dapRequest is an outgoing Debug Adapter Protocol request.
+type dapRequest struct {
+	Seq       int    `json:"seq"`
+	Type      string `json:"type"`
+	Command   string `json:"command"`
+	Arguments any    `json:"arguments,omitempty"`
+}
+
+// dapMessage is an incoming Debug Adapter Protocol response or event.
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    bool            `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+
+	index uint64 // Position in the stream of received messages
+}
+
+// dapClient is a minimal DAP client: it sends requests, matches responses by sequence number
+// and hands events to a callback in the order they were received.
+type dapClient struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	writeMu sync.Mutex
+	onEvent func(*dapMessage)
+	done    chan struct{}
+
+	received uint64 // Only accessed by readLoop
+
+	// Events waiting for the callback. The queue is unbounded so that the read loop never blocks:
+	// event handlers issue requests whose responses may arrive behind a burst of events.
+	eventMu     sync.Mutex
+	events      []*dapMessage
+	eventsReady chan struct{}
+
+	mu      sync.Mutex
+	seq     int
+	pending map[int]chan *dapMessage
+}
+
+// newDAPClient starts reading messages from an established DAP connection.
+func newDAPClient(conn net.Conn, onEvent func(*dapMessage)) *dapClient {
+	client := &dapClient{
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		onEvent:     onEvent,
+		done:        make(chan struct{}),
+		eventsReady: make(chan struct{}, 1),
+		pending:     make(map[int]chan *dapMessage),
+	}
+	go client.readLoop()
+	go client.eventLoop()
+	return client
+}
+
+// readDAPMessage reads one Content-Length framed message.
+func readDAPMessage(reader *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get(contentLengthKey))
+	if err != nil || length <= 0 {
+		return nil, fmt.Errorf("invalid %s header: %q", contentLengthKey, headers.Get(contentLengthKey))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// writeDAPMessage writes one Content-Length framed message.
+func writeDAPMessage(writer io.Writer, message any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(writer, "%s: %d\r\n\r\n", contentLengthKey, len(payload)); err != nil {
+		return err
+	}
+	_, err = writer.Write(payload)
+	return err
+}
+
+// readLoop dispatches responses to waiting requests and queues events.
+func (c *dapClient) readLoop() {
+	defer close(c.done)
+
+	for {
+		payload, err := readDAPMessage(c.reader)
+		if err != nil {
+			return
+		}
+		var message dapMessage
+		if err := json.Unmarshal(payload, &message); err != nil {
+			continue
+		}
+		c.received++
+		message.index = c.received
+
+		switch message.Type {
+		case "response":
+			c.mu.Lock()
+			waiter, ok := c.pending[message.RequestSeq]
+			delete(c.pending, message.RequestSeq)
+			c.mu.Unlock()
+			if ok {
+				waiter <- &message
+			}
+		case "event":
+			c.queueEvent(&message)
+		}
+	}
+}
+
+// queueEvent adds an event to the queue without blocking.
+func (c *dapClient) queueEvent(event *dapMessage) {
+	c.eventMu.Lock()
+	c.events = append(c.events, event)
+	c.eventMu.Unlock()
+
+	select {
+	case c.eventsReady <- struct{}{}:
+	default:
+	}
+}
+
+// takeEvents returns and clears the queued events.
+func (c *dapClient) takeEvents() []*dapMessage {
+	c.eventMu.Lock()
+	defer c.eventMu.Unlock()
+	events := c.events
+	c.events = nil
+	return events
+}
+
+// eventLoop delivers events outside the read loop so that handlers can issue requests.
+// Events received before the connection closed are still delivered.
+func (c *dapClient) eventLoop() {
+	for {
+		select {
+		case <-c.eventsReady:
+		case <-c.done:
+			for _, event := range c.takeEvents() {
+				c.onEvent(event)
+			}
+			return
+		}
+		for _, event := range c.takeEvents() {
+			c.onEvent(event)
+		}
+	}
+}
+
+// call sends a request and decodes the response body into result, if result is not nil.
+func (c *dapClient) call(command string, arguments, result any, timeout time.Duration) error {
+	response, err := c.request(command, arguments, timeout)
+	if err != nil {
+		return err
+	}
+	if result != nil && len(response.Body) > 0 {
+		if err := json.Unmarshal(response.Body, result); err != nil {
+			return fmt.Errorf("failed to decode %s response: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// request sends a request and returns its successful response.
+func (c *dapClient) request(command string, arguments any, timeout time.Duration) (*dapMessage, error) {
+	c.mu.Lock()
+	c.seq++
+	seq := c.seq
+	waiter := make(chan *dapMessage, 1)
+	c.pending[seq] = waiter
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	err := writeDAPMessage(c.conn, &dapRequest{
+		Seq:       seq,
+		Type:      "request",
+		Command:   command,
+		Arguments: arguments,
+	})
+	c.writeMu.Unlock()
+	if err != nil {
+		c.forget(seq)
+		return nil, fmt.Errorf("failed to send %s request: %w", command, err)
+	}
+
+	select {
+	case response := <-waiter:
+		if !response.Success {
+			return nil, fmt.Errorf("%s failed: %s", command, dapErrorMessage(response))
+		}
+		return response, nil
+	case <-c.done:
+		return nil, errors.New("debugger connection closed")
+	case <-time.After(timeout):
+		c.forget(seq)
+		return nil, fmt.Errorf("%s request timed out", command)
+	}
+}
+
+// forget drops a request that will never be answered.
+func (c *dapClient) forget(seq int) {
+	c.mu.Lock()
+	delete(c.pending, seq)
+	c.mu.Unlock()
+}
+
+// close closes the connection, which also stops the read loop.
+func (c *dapClient) close() {
+	_ = c.conn.Close()
+}
+
+// dapErrorMessage extracts the most descriptive error text from a failed response.
+func dapErrorMessage(response *dapMessage) string {
+	var body struct {
+		Error struct {
+			Format string `json:"format"`
+		} `json:"error"`
+	}
+	if len(response.Body) > 0 && json.Unmarshal(response.Body, &body) == nil && body.Error.Format != "" {
+		return body.Error.Format
+	}
+	if response.Message != "" {
+		return response.Message
+	}
+	return strings.TrimSpace(string(response.Body))
+}
@@ -0,0 +1,536 @@
+package delve
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+// blockingCall describes a runtime or sync function a blocked goroutine is parked in.
+type blockingCall struct {
+	kind      string // chan, select, mutex, rwmutex, waitgroup or cond
+	operation string
+	receiver  string // Argument holding the address of the primitive
+}
+
+// blockingFunctions maps the frames of blocked goroutines to the primitive they wait on.
+var blockingFunctions = map[string]blockingCall{
+	"runtime.chanrecv":                {kind: "chan", operation: "receive", receiver: "c"},
+	"runtime.chansend":                {kind: "chan", operation: "send", receiver: "c"},
+	"runtime.selectgo":                {kind: "select", operation: "select"},
+	"runtime.block":                   {kind: "select", operation: "select with no cases"},
+	"sync.(*Mutex).Lock":              {kind: "mutex", operation: "lock", receiver: "m"},
+	"sync.(*Mutex).lockSlow":          {kind: "mutex", operation: "lock", receiver: "m"},
+	"internal/sync.(*Mutex).Lock":     {kind: "mutex", operation: "lock", receiver: "m"},
+	"internal/sync.(*Mutex).lockSlow": {kind: "mutex", operation: "lock", receiver: "m"},
+	"sync.(*RWMutex).Lock":            {kind: "rwmutex", operation: "lock", receiver: "rw"},
+	"sync.(*RWMutex).RLock":           {kind: "rwmutex", operation: "rlock", receiver: "rw"},
+	"sync.(*WaitGroup).Wait":          {kind: "waitgroup", operation: "wait", receiver: "wg"},
+	"sync.(*Cond).Wait":               {kind: "cond", operation: "wait", receiver: "c"},
+}
+
+const maxReferenceFrames = 4 // User frames of each goroutine searched for channel variables
+
+// channelVariable matches a channel-typed line of `args` or `locals` output, e.g. "done = chan bool 0/0".
+var channelVariable = regexp.MustCompile(`^(\w+) = (?:<-chan|chan<-|chan) `)
+
+// oppositeQueue is the queue of a channel holding the goroutines that complete a blocked operation.
+var oppositeQueue = map[string]string{
+	"send":    "recvq",
+	"receive": "sendq",
+}
+
+// BlockedGoroutine is a goroutine parked on a synchronization primitive.
+type BlockedGoroutine struct {
+	ID         int    `json:"id"`
+	WaitReason string `json:"wait_reason,omitempty"`
+	Kind       string `json:"kind"`
+	Operation  string `json:"operation"`
+	Resource   string `json:"resource,omitempty"` // Kind and address of the primitive, e.g. "mutex 0xc000012000"
+	CallSite   string `json:"call_site,omitempty"`
+	WaitingFor []int  `json:"waiting_for,omitempty"` // Goroutines likely holding the resource, or that could complete the channel operation
+}
+
+// WaitResource is a synchronization primitive with the goroutines waiting on it.
+type WaitResource struct {
+	ID            string `json:"id"`
+	Kind          string `json:"kind"`
+	Address       string `json:"address"`
+	Waiters       []int  `json:"waiters"`
+	LikelyHolders []int  `json:"likely_holders,omitempty"` // Mutexes: goroutines likely holding it
+	Counterparts  []int  `json:"counterparts,omitempty"`   // Channels: goroutines that could complete a blocked send or receive
+}
+
+// DeadlockReport is the result of the deadlock action.
+type DeadlockReport struct {
+	Goroutines     int                `json:"goroutines"`
+	Blocked        []BlockedGoroutine `json:"blocked"`
+	Resources      []WaitResource     `json:"resources"`
+	Cycles         [][]int            `json:"cycles,omitempty"`
+	GlobalDeadlock bool               `json:"global_deadlock"` // Every user goroutine is blocked
+	Findings       []string           `json:"findings"`
+	Halted         bool               `json:"halted"`  // The target was halted for the report
+	Resumed        bool               `json:"resumed"` // The target was resumed after the report
+}
+
+// isSystemGoroutine reports whether a goroutine only runs runtime code, like the GC workers.
+func isSystemGoroutine(g goroutineInfo) bool {
+	for _, frame := range g.Frames {
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return false
+		}
+	}
+	return len(g.Frames) > 0
+}
+
+// isLibraryFrame reports whether a function belongs to the runtime or sync packages.
+func isLibraryFrame(function string) bool {
+	return strings.HasPrefix(function, "runtime.") || strings.HasPrefix(function, "sync.") || strings.HasPrefix(function, "internal/sync.")
+}
+
+// callSite returns the first frame outside the runtime and sync packages, above frame.
+func callSite(g goroutineInfo, frame int) string {
+	for i := frame + 1; i < len(g.Frames); i++ {
+		function := g.Frames[i].Function
+		if isLibraryFrame(function) {
+			continue
+		}
+		if g.Frames[i].File == "" {
+			return function
+		}
+		return fmt.Sprintf("%s at %s:%d", function, g.Frames[i].File, g.Frames[i].Line)
+	}
+	return ""
+}
+
+// classifyGoroutine finds the primitive a goroutine is blocked on and its address.
+func (d *Tool) classifyGoroutine(session *DelveSession, g goroutineInfo) (BlockedGoroutine, bool) {
+	frame := g.frameIndex(func(function string) bool {
+		_, ok := blockingFunctions[function]
+		return ok
+	})
+	if frame < 0 {
+		return BlockedGoroutine{}, false
+	}
+	call := blockingFunctions[g.Frames[frame].Function]
+
+	// A writer blocked in RWMutex.Lock may be parked in the embedded Mutex; report the RWMutex
+	if call.kind == "mutex" {
+		for i := frame + 1; i < len(g.Frames); i++ {
+			if rwCall, ok := blockingFunctions[g.Frames[i].Function]; ok && rwCall.kind == "rwmutex" {
+				frame, call = i, rwCall
+				break
+			}
+		}
+	}
+
+	blocked := BlockedGoroutine{
+		ID:         g.ID,
+		WaitReason: g.WaitReason,
+		Kind:       call.kind,
+		Operation:  call.operation,
+		CallSite:   callSite(g, frame),
+	}
+	if call.receiver != "" {
+		address, err := d.evaluateAddress(session, fmt.Sprintf("goroutine %d frame %d", g.ID, frame), call.receiver)
+		if err != nil {
+			d.logger.Debug().Err(err).Msgf("Failed to resolve %s of goroutine %d", call.kind, g.ID)
+		} else {
+			blocked.Resource = fmt.Sprintf("%s %#x", call.kind, address)
+		}
+	}
+	return blocked, true
+}
+
+// channelVariables returns the names of the channel-typed arguments and locals of a frame.
+func (d *Tool) channelVariables(session *DelveSession, scope string) []string {
+	var names []string
+	for _, command := range []string{"args", "locals"} {
+		output, err := d.executeCommand(session, scope+" "+command)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if match := channelVariable.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+				names = append(names, match[1])
+			}
+		}
+	}
+	return names
+}
+
+// channelReferences maps the channels in resources to the goroutines whose topmost user frames hold
+// them in an argument or local variable. Channels only reachable through globals or struct fields
+// are not found.
+func (d *Tool) channelReferences(session *DelveSession, goroutines []goroutineInfo, resources map[string]*WaitResource) map[string][]int {
+	references := make(map[string][]int)
+	for _, g := range goroutines {
+		if isSystemGoroutine(g) {
+			continue
+		}
+		seen := make(map[string]bool)
+		searched := 0
+		for i, frame := range g.Frames {
+			if isLibraryFrame(frame.Function) {
+				continue
+			}
+			if searched == maxReferenceFrames {
+				break
+			}
+			searched++
+			scope := fmt.Sprintf("goroutine %d frame %d", g.ID, i)
+			for _, name := range d.channelVariables(session, scope) {
+				// A channel is a pointer to its runtime.hchan
+				address, err := d.evaluateAddress(session, scope, "*(*uintptr)(uintptr(&"+name+"))")
+				if err != nil {
+					continue
+				}
+				id := fmt.Sprintf("chan %#x", address)
+				if _, ok := resources[id]; ok && !seen[id] {
+					seen[id] = true
+					references[id] = append(references[id], g.ID)
+				}
+			}
+		}
+	}
+	return references
+}
+
+// channelCounterparts returns the goroutines that could complete a blocked channel operation: those
+// queued for the opposite operation and those referencing the channel, except the goroutines
+// blocked on the same operation.
+func (d *Tool) channelCounterparts(session *DelveSession, blocked BlockedGoroutine, resource *WaitResource, references []int, operations map[int]string) []int {
+	candidates := append(d.channelWaiters(session, fmt.Sprintf("(*runtime.hchan)(%s).%s", resource.Address, oppositeQueue[blocked.Operation])), references...)
+	seen := make(map[int]bool)
+	var counterparts []int
+	for _, id := range candidates {
+		if id == blocked.ID || seen[id] || operations[id] == blocked.Operation {
+			continue
+		}
+		seen[id] = true
+		counterparts = append(counterparts, id)
+	}
+	sort.Ints(counterparts)
+	return counterparts
+}
+
+// waitCycles returns the strongly connected components of the wait-for graph with more than one goroutine.
+func waitCycles(edges map[int][]int) [][]int {
+	nodes := make([]int, 0, len(edges))
+	for node := range edges {
+		nodes = append(nodes, node)
+	}
+	sort.Ints(nodes)
+
+	// Tarjan's algorithm
+	index := make(map[int]int)
+	lowlink := make(map[int]int)
+	onStack := make(map[int]bool)
+	var stack []int
+	var cycles [][]int
+	next := 0
+
+	var connect func(node int)
+	connect = func(node int) {
+		index[node] = next
+		lowlink[node] = next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, target := range edges[node] {
+			if _, visited := index[target]; !visited {
+				connect(target)
+				lowlink[node] = min(lowlink[node], lowlink[target])
+			} else if onStack[target] {
+				lowlink[node] = min(lowlink[node], index[target])
+			}
+		}
+
+		if lowlink[node] != index[node] {
+			return
+		}
+		var component []int
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Ints(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, node := range nodes {
+		if _, visited := index[node]; !visited {
+			connect(node)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// analyzeDeadlock builds the wait-for graph of the goroutines and reports cycles and contention.
+func (d *Tool) analyzeDeadlock(session *DelveSession, goroutines []goroutineInfo) *DeadlockReport {
+	report := &DeadlockReport{Goroutines: len(goroutines)}
+	resources := make(map[string]*WaitResource)
+	mutexWaitersByResource := make(map[string][]mutexWaiter)
+	userGoroutines, blockedUserGoroutines := 0, 0
+
+	for _, g := range goroutines {
+		system := isSystemGoroutine(g)
+		if !system {
+			userGoroutines++
+		}
+		blocked, ok := d.classifyGoroutine(session, g)
+		if !ok {
+			continue
+		}
+		if !system {
+			blockedUserGoroutines++
+		}
+		report.Blocked = append(report.Blocked, blocked)
+		if blocked.Resource == "" {
+			continue
+		}
+
+		resource, exists := resources[blocked.Resource]
+		if !exists {
+			resource = &WaitResource{
+				ID:      blocked.Resource,
+				Kind:    blocked.Kind,
+				Address: strings.TrimPrefix(blocked.Resource, blocked.Kind+" "),
+			}
+			resources[blocked.Resource] = resource
+		}
+		resource.Waiters = append(resource.Waiters, g.ID)
+
+		if blocked.Kind == "mutex" || blocked.Kind == "rwmutex" {
+			frame := g.frameIndex(func(function string) bool { return mutexLockFunctions[function] })
+			mutexWaitersByResource[blocked.Resource] = append(mutexWaitersByResource[blocked.Resource],
+				mutexWaiter{Goroutine: g.ID, Function: g.Frames[frame].Function, Caller: lockCaller(g, frame)})
+		}
+	}
+
+	// Edges go from each mutex waiter to the goroutines likely holding the mutex, and from each
+	// blocked channel operation to the goroutines that could complete it
+	edges := make(map[int][]int)
+	for id, waiters := range mutexWaitersByResource {
+		resource := resources[id]
+		resource.LikelyHolders = likelyHolders(goroutines, waiters)
+	}
+	var references map[string][]int
+	channelOperations := make(map[string]map[int]string)
+	for _, blocked := range report.Blocked {
+		if blocked.Kind == "chan" && blocked.Resource != "" && blocked.Resource != "chan 0x0" {
+			if channelOperations[blocked.Resource] == nil {
+				channelOperations[blocked.Resource] = make(map[int]string)
+			}
+			channelOperations[blocked.Resource][blocked.ID] = blocked.Operation
+		}
+	}
+	if len(channelOperations) > 0 {
+		references = d.channelReferences(session, goroutines, resources)
+	}
+	for i := range report.Blocked {
+		blocked := &report.Blocked[i]
+		resource, ok := resources[blocked.Resource]
+		if !ok {
+			continue
+		}
+		if operations, isChannel := channelOperations[blocked.Resource]; isChannel {
+			blocked.WaitingFor = d.channelCounterparts(session, *blocked, resource, references[blocked.Resource], operations)
+			resource.Counterparts = mergeGoroutineIDs(resource.Counterparts, blocked.WaitingFor)
+		} else {
+			blocked.WaitingFor = resource.LikelyHolders
+		}
+		edges[blocked.ID] = append(edges[blocked.ID], blocked.WaitingFor...)
+	}
+	report.Cycles = waitCycles(edges)
+
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	// Most contended first
+	sort.Slice(ids, func(i, j int) bool {
+		if len(resources[ids[i]].Waiters) != len(resources[ids[j]].Waiters) {
+			return len(resources[ids[i]].Waiters) > len(resources[ids[j]].Waiters)
+		}
+		return ids[i] < ids[j]
+	})
+	for _, id := range ids {
+		report.Resources = append(report.Resources, *resources[id])
+	}
+
+	report.GlobalDeadlock = userGoroutines > 0 && blockedUserGoroutines == userGoroutines
+	report.Findings = deadlockFindings(report)
+	return report
+}
+
+// mergeGoroutineIDs returns the sorted union of two lists of goroutine IDs.
+func mergeGoroutineIDs(a, b []int) []int {
+	seen := make(map[int]bool)
+	var merged []int
+	for _, id := range append(append([]int{}, a...), b...) {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	sort.Ints(merged)
+	return merged
+}
+
+// deadlockFindings summarizes a report in sentences.
+func deadlockFindings(report *DeadlockReport) []string {
+	kinds := make(map[int]string)
+	for _, blocked := range report.Blocked {
+		kinds[blocked.ID] = blocked.Kind
+	}
+
+	var findings []string
+	for _, cycle := range report.Cycles {
+		channels := false
+		for _, id := range cycle {
+			channels = channels || kinds[id] == "chan"
+		}
+		if channels {
+			findings = append(findings, fmt.Sprintf("Wait cycle: goroutines %s each wait on a channel or mutex that only another goroutine of the cycle can complete or release", formatGoroutineIDs(cycle)))
+		} else {
+			findings = append(findings, fmt.Sprintf("Lock cycle: goroutines %s each wait on a mutex likely held by another goroutine of the cycle", formatGoroutineIDs(cycle)))
+		}
+	}
+	if report.GlobalDeadlock {
+		findings = append(findings, "Every user goroutine is blocked: the process cannot make progress (fatal deadlock unless a timer, I/O or a new goroutine wakes it)")
+	}
+	for _, resource := range report.Resources {
+		if len(resource.Waiters) < 2 {
+			continue
+		}
+		finding := fmt.Sprintf("%d goroutines are waiting on %s", len(resource.Waiters), resource.ID)
+		if len(resource.Waiters) == len(report.Blocked) {
+			finding = fmt.Sprintf("Every blocked goroutine (%d) is waiting on %s", len(resource.Waiters), resource.ID)
+		}
+		if len(resource.LikelyHolders) > 0 {
+			finding += fmt.Sprintf(", likely held by goroutine %s", formatGoroutineIDs(resource.LikelyHolders))
+		}
+		if len(resource.Counterparts) > 0 {
+			finding += fmt.Sprintf(", which goroutine %s could complete", formatGoroutineIDs(resource.Counterparts))
+		}
+		findings = append(findings, finding)
+	}
+	for _, resource := range report.Resources {
+		if (resource.Kind == "mutex" || resource.Kind == "rwmutex") && len(resource.LikelyHolders) == 0 {
+			findings = append(findings, fmt.Sprintf("No goroutine appears to hold %s: look for a missing Unlock, e.g. on an early return", resource.ID))
+		}
+	}
+	for _, blocked := range report.Blocked {
+		if blocked.Kind == "chan" && blocked.Resource == "chan 0x0" {
+			findings = append(findings, fmt.Sprintf("Goroutine %d blocks forever on a %s of a nil channel", blocked.ID, blocked.Operation))
+		}
+	}
+	if len(findings) == 0 {
+		findings = append(findings, fmt.Sprintf("No deadlock pattern found among %d blocked goroutines", len(report.Blocked)))
+	}
+	return findings
+}
+
+// formatDeadlockReport renders a report as text.
+func formatDeadlockReport(report *DeadlockReport) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Goroutines: %d, blocked: %d\n\nFindings:\n", report.Goroutines, len(report.Blocked)))
+	for _, finding := range report.Findings {
+		output.WriteString("- " + finding + "\n")
+	}
+
+	output.WriteString("\nBlocked goroutines:\n")
+	for _, blocked := range report.Blocked {
+		resource := blocked.Resource
+		if resource == "" {
+			resource = blocked.Kind
+		}
+		output.WriteString(fmt.Sprintf("  goroutine %d: %s on %s", blocked.ID, blocked.Operation, resource))
+		if blocked.CallSite != "" {
+			output.WriteString(" in " + blocked.CallSite)
+		}
+		if len(blocked.WaitingFor) > 0 {
+			output.WriteString(" (waiting for goroutine " + formatGoroutineIDs(blocked.WaitingFor) + ")")
+		}
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+// handleDeadlock halts the target if it is running, analyzes what every goroutine is blocked on and
+// optionally resumes the target. Mutex holders are inferred from stacks, as the runtime does not record them.
+func (d *Tool) handleDeadlock(input Input, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+
+	stopped, _, running := session.stopState()
+	if running {
+		d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: input.client, Action: "halt"})
+		if err := haltAndWait(session, stopped); err != nil {
+			return nil, fmt.Errorf("failed to halt target in session %s: %w", input.SessionID, err)
+		}
+	}
+
+	goroutines, err := d.goroutineStacks(session)
+	if err != nil {
+		return nil, err
+	}
+	report := d.analyzeDeadlock(session, goroutines)
+	report.Halted = running
+
+	if input.Resume {
+		if _, err := d.startExecution(session, "continue", notifier); err != nil {
+			return nil, fmt.Errorf("failed to resume target: %w", err)
+		}
+		report.Resumed = true
+	}
+
+	text := formatDeadlockReport(report)
+	d.recordCommand(session, input, "deadlock", text, nil, started)
+	structured, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode deadlock report: %w", err)
+	}
+
+	state, status := "Target is stopped.", "stopped"
+	if report.Resumed {
+		state, status = "Target was resumed.", "running"
+	}
+	header := fmt.Sprintf("Session %s - Deadlock report\n%s\n\n", input.SessionID, state)
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: header + text,
+			},
+			&mcp.TextContent{
+				Text: string(structured),
+			},
+		},
+		StructuredContent: Output{
+			SessionID: input.SessionID,
+			Action:    "deadlock",
+			Output:    text,
+			Status:    status,
+			Deadlock:  report,
+		},
+	}, nil
+}
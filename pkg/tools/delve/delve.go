@@ -1,41 +1,68 @@
 package delve
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
-	"io"
-	"os/exec"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
 )
 
 const (
-	sessionStartupDelay = 500 * time.Millisecond
-	commandTimeout      = 5 * time.Second
-	disconnectDelay     = 100 * time.Millisecond
-	cleanupInterval     = 5 * time.Minute
-	sessionMaxIdleTime  = 30 * time.Minute
+	sessionStartupTimeout = 10 * time.Second
+	commandTimeout        = 5 * time.Second
+	disconnectDelay       = 100 * time.Millisecond
+	cleanupInterval       = 5 * time.Minute
+	sessionMaxIdleTime    = 30 * time.Minute
+	stopGracePeriod       = 1 * time.Second  // How long execution commands wait before reporting a running target
+	defaultWaitTimeout    = 30 * time.Second // Default timeout for wait_for_stop
+	progressInterval      = 5 * time.Second  // Interval between progress notifications while waiting
 )
 
 type Input struct {
-	Host      string `json:"host,omitempty" validate:"omitempty,hostname|ip"`
-	Port      int    `json:"port,omitempty" validate:"min=0,max=65535"`
-	Command   string `json:"command,omitempty" validate:"max=4096"`
-	SessionID string `json:"session_id,omitempty" validate:"omitempty,max=64"`                       // Session ID for persistent connections
-	Action    string `json:"action,omitempty" validate:"omitempty,oneof=connect disconnect command"` // Action: connect, disconnect, or command (default: command)
-	MaxLines  int    `json:"max_lines,omitempty" validate:"min=0,max=100000"`                        // Maximum lines to return (default: 1000)
-	Offset    int    `json:"offset,omitempty" validate:"min=0"`                                      // Line offset for pagination
+	Host         string `json:"host,omitempty" validate:"omitempty,hostname|ip"`
+	Port         int    `json:"port,omitempty" validate:"min=0,max=65535"`
+	Command      string `json:"command,omitempty" validate:"max=4096"`
+	SessionID    string `json:"session_id,omitempty" validate:"omitempty,max=64"`                                                                                                                                                                                  // Session ID for persistent connections
+	Action       string `json:"action,omitempty" validate:"omitempty,oneof=connect disconnect command wait_for_stop halt export_transcript replay source disassemble trace trace_events watch join leave handoff list_sessions channel mutex collection deadlock"` // Action: connect, disconnect, command, wait_for_stop, halt, export_transcript, replay, source, disassemble, trace, trace_events, watch, join, leave, handoff, list_sessions, channel, mutex, collection or deadlock (default: command)
+	Timeout      int    `json:"timeout,omitempty" validate:"min=0,max=3600"`                                                                                                                                                                                       // Seconds to wait in wait_for_stop or for a reverse DAP connection (default: 30)
+	Backend      string `json:"backend,omitempty" validate:"omitempty,oneof=cli dap"`                                                                                                                                                                              // Protocol used on connect: cli (dlv connect) or dap (default: cli)
+	Reverse      bool   `json:"reverse,omitempty"`                                                                                                                                                                                                                 // DAP only: listen on host:port for a server started with --client-addr
+	Program      string `json:"program,omitempty" validate:"omitempty,max=4096"`                                                                                                                                                                                   // DAP only: program to launch instead of attaching
+	Mode         string `json:"mode,omitempty" validate:"omitempty,oneof=debug exec test"`                                                                                                                                                                         // DAP only: launch mode for program (default: debug)
+	ProcessID    int    `json:"process_id,omitempty" validate:"min=0,max=2147483647"`                                                                                                                                                                              // DAP only: local process to attach to
+	Transcript   string `json:"transcript,omitempty" validate:"required_if=Action replay,max=4096"`                                                                                                                                                                // replay only: ID of a recorded session, or path of a file in the transcript directory
+	Frame        int    `json:"frame,omitempty" validate:"min=0,max=100000"`                                                                                                                                                                                       // source and disassemble: stack frame (default: 0, the current frame)
+	Location     string `json:"location,omitempty" validate:"omitempty,max=4096"`                                                                                                                                                                                  // source only: file:line to show instead of a frame
+	ContextLines int    `json:"context_lines,omitempty" validate:"min=0,max=1000"`                                                                                                                                                                                 // source only: lines before and after the current line (default: 10)
+	Function     string `json:"function,omitempty" validate:"omitempty,printascii,max=1024"`                                                                                                                                                                       // disassemble: function to disassemble; trace: regular expression of functions to trace
+	StartPC      string `json:"start_pc,omitempty" validate:"omitempty,hexadecimal"`                                                                                                                                                                               // disassemble only: start of the PC range
+	EndPC        string `json:"end_pc,omitempty" validate:"omitempty,hexadecimal"`                                                                                                                                                                                 // disassemble only: end of the PC range
+	BufferSize   int    `json:"buffer_size,omitempty" validate:"min=0,max=100000"`                                                                                                                                                                                 // trace only: number of hits kept (default: 1000)
+	Expression   string `json:"expression,omitempty" validate:"omitempty,printascii,max=4096"`                                                                                                                                                                     // watch: expression to watch; channel, mutex and collection: expression to inspect
+	WatchMode    string `json:"watch_mode,omitempty" validate:"omitempty,oneof=w r rw"`                                                                                                                                                                            // watch only: w (write), r (read) or rw (default: w)
+	SSHHost      string `json:"ssh_host,omitempty" validate:"omitempty,hostname|ip"`                                                                                                                                                                               // source only: read files from this host instead of locally
+	SSHPort      int    `json:"ssh_port,omitempty" validate:"min=0,max=65535"`                                                                                                                                                                                     // source only: SSH port (default: 22)
+	SSHUser      string `json:"ssh_user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"`                                                                                                                                                     // source only: SSH user
+	MaxLines     int    `json:"max_lines,omitempty" validate:"min=0,max=100000"`                                                                                                                                                                                   // Maximum lines to return (default: 1000); collection: elements per page (default: 100)
+	ToClient     string `json:"to_client,omitempty" validate:"required_if=Action handoff,max=256"`                                                                                                                                                                 // handoff only: client ID of the observer that takes control
+	Offset       int    `json:"offset,omitempty" validate:"min=0"`                                                                                                                                                                                                 // Line offset for pagination; collection: first element
+	Resume       bool   `json:"resume,omitempty"`                                                                                                                                                                                                                  // deadlock only: resume the target after the report
+
+	client string // MCP client that sent the request, set by DelveHandler
 }
 
 type Output struct {
@@ -49,28 +76,56 @@ type Output struct {
 	Offset     int    `json:"offset"`
 	MaxLines   int    `json:"max_lines"`
 	Truncated  bool   `json:"truncated"`
-	Status     string `json:"status"` // Session status: connected, disconnected, command_executed
+	Status     string `json:"status"` // Session status: connected, disconnected, command_executed, running, stopped
+
+	Deadlock *DeadlockReport `json:"deadlock,omitempty"` // Set by the deadlock action
+}
+
+// backend executes debugger commands for a session.
+type backend interface {
+	// execute runs a command that does not resume the target and returns its output.
+	execute(command string) (string, error)
+	// resume starts an execution command. The next stop is reported through the stop callback.
+	resume(command string) error
+	// halt interrupts the running target.
+	halt() error
+	// close terminates the connection to the debugger.
+	close()
 }
 
 // DelveSession represents a persistent Delve debugger connection.
 type DelveSession struct {
-	cmd       *exec.Cmd
-	stdin     io.WriteCloser
-	stdout    io.ReadCloser
-	stderr    io.ReadCloser
-	scanner   *bufio.Scanner
-	host      string
-	port      int
-	mu        sync.Mutex
-	lastUsed  time.Time
-	ctxCancel context.CancelFunc // To cancel the background context
+	backend    backend
+	host       string
+	port       int
+	mu         sync.Mutex
+	lastUsed   time.Time
+	transcript *transcript // Records commands and outputs, nil when disabled
+
+	// Execution state, guarded by stateMu
+	stateMu  sync.Mutex
+	running  bool
+	stopped  chan struct{} // Closed when the running target stops
+	lastStop string
+	notifier *notify.Notifier
+	trace    *traceBuffer // Set by the trace action
+
+	// Sharing, guarded by stateMu
+	owner     string                      // Client that drives execution
+	observers map[string]*notify.Notifier // Clients that may only inspect, with their stop notifiers
 }
 
 type Tool struct {
-	logger    zerolog.Logger
-	validator *validator.Validate
-	sessions  map[string]*DelveSession
-	sessionMu sync.RWMutex
+	logger     zerolog.Logger
+	validator  *validator.Validate
+	sessions   map[string]*DelveSession
+	connecting map[string]bool // Session IDs reserved while their connect is in progress
+	sessionMu  sync.RWMutex
+
+	transcriptDir   string // Directory for session transcripts, empty to disable them
+	transcriptMu    sync.Mutex
+	transcripts     map[string]string // Session ID to transcript path, kept after disconnect
+	transcriptOrder []string          // Session IDs of transcripts, oldest first
 }
 
 func (d *Tool) DelveHandler(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
@@ -100,9 +155,16 @@ func (d *Tool) DelveHandler(ctx context.Context, session *mcp.ServerSession, par
 	if input.SessionID == "" {
 		input.SessionID = session.ID()
 	}
+	input.client = clientID(session)
+
+	d.logger.Info().Str("client", input.client).Str("session_id", input.SessionID).Str("action", action).Str("command", input.Command).Msg("Delve request")
+	if err := d.authorize(input, action, input.client); err != nil {
+		return nil, err
+	}
 
 	// Non-session mode (backward compatibility)
-	return d.handleSessionOperation(ctx, input, host, port, action)
+	notifier := notify.New(session, params.Meta, "delve")
+	return d.handleSessionOperation(ctx, input, host, port, action, notifier)
 }
 
 func (d *Tool) Register(srv *server.Server) {
@@ -139,65 +201,36 @@ func (d *Tool) cleanupStaleSessions() {
 }
 
 // connectSession creates a new Delve session.
-func (d *Tool) connectSession(_ context.Context, sessionID, host string, port int) (*DelveSession, error) {
-	addr := fmt.Sprintf("%s:%d", host, port)
+func (d *Tool) connectSession(ctx context.Context, sessionID, host string, port int, input Input) (*DelveSession, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 	d.logger.Info().Msgf("Creating new Delve session %s at %s", sessionID, addr)
 
-	// Create a background context that won't be cancelled when the calling context ends
-	// Using context.WithoutCancel would be better but requires Go 1.21+
-	backgroundCtx, cancel := context.WithCancel(context.Background())
-	
-	// Create command with the background context
-	cmd := exec.CommandContext(backgroundCtx, "dlv", "connect", addr) //nolint:contextcheck // intentionally using a detached context for background process
-
-	// Set process group ID so we can kill the entire group
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Setpgid: true,
-		Pgid:    0,
-	}
-
-	stdin, err := cmd.StdinPipe()
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
-	}
-
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
-	}
-
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to start dlv command: %w", err)
-	}
-
 	session := &DelveSession{
-		cmd:       cmd,
-		stdin:     stdin,
-		stdout:    stdout,
-		stderr:    stderr,
-		scanner:   bufio.NewScanner(stdout),
 		host:      host,
 		port:      port,
 		lastUsed:  time.Now(),
-		ctxCancel: cancel,
+		owner:     input.client,
+		observers: make(map[string]*notify.Notifier),
 	}
+	onStop := func(output string) {
+		d.handleStop(sessionID, session, output)
+	}
+	// The backend may report the entry stop before it returns
+	d.startTranscript(sessionID, session)
 
-	// Start a goroutine to reap the process when it exits (prevents zombies)
-	go func() {
-		_ = cmd.Wait()
-	}()
-
-	// Read initial prompt
-	time.Sleep(sessionStartupDelay)
+	var err error
+	switch input.Backend {
+	case "dap":
+		session.backend, err = newDAPBackend(ctx, d.logger, addr, input, onStop)
+	default:
+		session.backend, err = newCLIBackend(d.logger, addr, onStop, func(line string) {
+			d.handleOutputLine(session, line)
+		})
+	}
+	if err != nil {
+		d.discardTranscript(session)
+		return nil, err
+	}
 
 	return session, nil
 }
@@ -208,47 +241,7 @@ func (d *Tool) executeCommand(session *DelveSession, command string) (string, er
 	defer session.mu.Unlock()
 
 	session.lastUsed = time.Now()
-
-	// Send command
-	if _, err := fmt.Fprintf(session.stdin, "%s\n", command); err != nil {
-		return "", fmt.Errorf("failed to send command: %w", err)
-	}
-
-	// Read response with timeout
-	var output strings.Builder
-	done := make(chan bool)
-	var readErr error
-
-	go func() {
-		scanner := bufio.NewScanner(session.stdout)
-		for scanner.Scan() {
-			line := scanner.Text()
-			output.WriteString(line)
-			output.WriteString("\n")
-
-			// Check for prompt indicating command completion
-			if strings.Contains(line, "(dlv)") || strings.Contains(line, ">") {
-				done <- true
-				return
-			}
-		}
-		if err := scanner.Err(); err != nil {
-			readErr = err
-		}
-		done <- false
-	}()
-
-	// Wait for response with timeout
-	select {
-	case <-done:
-		if readErr != nil {
-			return output.String(), readErr
-		}
-	case <-time.After(commandTimeout):
-		return output.String(), errors.New("command timed out")
-	}
-
-	return output.String(), nil
+	return session.backend.execute(command)
 }
 
 // disconnectSession closes a Delve session.
@@ -269,80 +262,119 @@ func (d *Tool) disconnectSession(sessionID string) error {
 	return nil
 }
 
-// cleanupSession properly terminates a Delve session and prevents zombie processes.
+// cleanupSession properly terminates a Delve session.
 func (d *Tool) cleanupSession(session *DelveSession) {
 	if session == nil {
 		return
 	}
-
-	// Try to send exit command first for graceful shutdown
-	if session.stdin != nil {
-		_, _ = fmt.Fprintf(session.stdin, "exit\n")
-		time.Sleep(disconnectDelay)
-		_ = session.stdin.Close()
-	}
-
-	// Close stdout and stderr pipes
-	if session.stdout != nil {
-		_ = session.stdout.Close()
-	}
-	if session.stderr != nil {
-		_ = session.stderr.Close()
-	}
-
-	// Kill the process group to ensure all child processes are terminated
-	if session.cmd != nil && session.cmd.Process != nil {
-		pgid := session.cmd.Process.Pid
-		// Try SIGTERM first for graceful shutdown
-		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
-			// If SIGTERM fails, try SIGKILL
-			if killErr := syscall.Kill(-pgid, syscall.SIGKILL); killErr != nil {
-				// Log the error but don't fail if the process is already gone
-				if !strings.Contains(killErr.Error(), "no such process") {
-					d.logger.Error().Err(killErr).Msg("Failed to kill Delve process group")
-				}
-			}
-		}
-	}
-
-	// Cancel the background context
-	if session.ctxCancel != nil {
-		session.ctxCancel()
+	if session.backend != nil {
+		session.backend.close()
 	}
+	session.transcript.close()
 }
 
 // handleSessionOperation handles session-based operations.
-func (d *Tool) handleSessionOperation(ctx context.Context, input Input, host string, port int, action string) (*mcp.CallToolResultFor[Output], error) {
+func (d *Tool) handleSessionOperation(ctx context.Context, input Input, host string, port int, action string, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
 	switch action {
 	case "connect":
 		return d.handleConnect(ctx, input, host, port)
 	case "disconnect":
 		return d.handleDisconnect(input)
 	case "command":
-		return d.handleCommand(input)
+		return d.handleCommand(input, notifier)
+	case "wait_for_stop":
+		return d.handleWaitForStop(ctx, input, notifier)
+	case "halt":
+		return d.handleHalt(input)
+	case "export_transcript":
+		return d.handleExportTranscript(input)
+	case "replay":
+		return d.handleReplay(input)
+	case "source":
+		return d.handleSource(ctx, input)
+	case "disassemble":
+		return d.handleDisassemble(input)
+	case "trace":
+		return d.handleTrace(input, notifier)
+	case "trace_events":
+		return d.handleTraceEvents(input)
+	case "watch":
+		return d.handleWatch(input)
+	case "join":
+		return d.handleJoin(input, input.client, notifier)
+	case "leave":
+		return d.handleLeave(input, input.client)
+	case "handoff":
+		return d.handleHandoff(input, input.client, notifier)
+	case "list_sessions":
+		return d.handleListSessions(input, input.client)
+	case "channel":
+		return d.handleChannel(input)
+	case "mutex":
+		return d.handleMutex(input)
+	case "collection":
+		return d.handleCollection(input)
+	case "deadlock":
+		return d.handleDeadlock(input, notifier)
 	default:
-		return nil, fmt.Errorf("unsupported action: %s. Use 'connect', 'disconnect', or 'command'", action)
+		return nil, fmt.Errorf("unsupported action: %s. Use 'connect', 'disconnect', 'command', 'wait_for_stop', 'halt', 'export_transcript', 'replay', 'source', 'disassemble', 'trace', 'trace_events', 'watch', 'join', 'leave', 'handoff', 'list_sessions', 'channel', 'mutex', 'collection' or 'deadlock'", action)
+	}
+}
+
+// getSession returns an existing session or an error if it is not connected.
+func (d *Tool) getSession(sessionID string) (*DelveSession, error) {
+	d.sessionMu.RLock()
+	session, exists := d.sessions[sessionID]
+	d.sessionMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("session %s not found. Use 'connect' action first", sessionID)
 	}
+	return session, nil
 }
 
 // handleConnect creates a new Delve session.
 func (d *Tool) handleConnect(ctx context.Context, input Input, host string, port int) (*mcp.CallToolResultFor[Output], error) {
+	// Reserve the ID, so other sessions stay usable while this one connects
 	d.sessionMu.Lock()
-	if _, exists := d.sessions[input.SessionID]; exists {
+	if _, exists := d.sessions[input.SessionID]; exists || d.connecting[input.SessionID] {
 		d.sessionMu.Unlock()
 		return nil, fmt.Errorf("session %s already exists", input.SessionID)
 	}
+	if d.connecting == nil {
+		d.connecting = make(map[string]bool)
+	}
+	d.connecting[input.SessionID] = true
+	d.sessionMu.Unlock()
+
+	session, err := d.connectSession(ctx, input.SessionID, host, port, input)
 
-	session, err := d.connectSession(ctx, input.SessionID, host, port)
+	d.sessionMu.Lock()
+	delete(d.connecting, input.SessionID)
+	if err == nil {
+		d.sessions[input.SessionID] = session
+	}
+	d.sessionMu.Unlock()
 	if err != nil {
-		d.sessionMu.Unlock()
 		return nil, err
 	}
+	d.rememberTranscript(input.SessionID, session)
 
-	d.sessions[input.SessionID] = session
-	d.sessionMu.Unlock()
-
-	resultText := fmt.Sprintf("Connected to Delve debugger at %s:%d\nSession ID: %s\nSession established. Use 'command' action to send debugging commands.", host, port, input.SessionID)
+	backendName := input.Backend
+	if backendName == "" {
+		backendName = "cli"
+	}
+	resultText := fmt.Sprintf("Connected to Delve debugger at %s:%d (%s backend)\nSession ID: %s\nOwner: %s\nSession established. Use 'command' action to send debugging commands. Other clients can 'join' it as observers.", host, port, backendName, input.SessionID, input.client)
+	if _, lastStop, _ := session.stopState(); lastStop != "" {
+		resultText += "\n\n" + lastStop
+	}
+	d.recordEntry(session, TranscriptEntry{
+		SessionID: input.SessionID,
+		Client:    input.client,
+		Action:    "connect",
+		Command:   fmt.Sprintf("%s %s", backendName, net.JoinHostPort(host, strconv.Itoa(port))),
+		Output:    resultText,
+	})
 
 	result := &mcp.CallToolResultFor[Output]{
 		Content: []mcp.Content{
@@ -357,6 +389,9 @@ func (d *Tool) handleConnect(ctx context.Context, input Input, host string, port
 
 // handleDisconnect disconnects a Delve session.
 func (d *Tool) handleDisconnect(input Input) (*mcp.CallToolResultFor[Output], error) {
+	if session, err := d.getSession(input.SessionID); err == nil {
+		d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: input.client, Action: "disconnect"})
+	}
 	if err := d.disconnectSession(input.SessionID); err != nil {
 		return nil, err
 	}
@@ -375,26 +410,41 @@ func (d *Tool) handleDisconnect(input Input) (*mcp.CallToolResultFor[Output], er
 }
 
 // handleCommand executes a command in an existing session.
-func (d *Tool) handleCommand(input Input) (*mcp.CallToolResultFor[Output], error) {
-	d.sessionMu.RLock()
-	session, exists := d.sessions[input.SessionID]
-	d.sessionMu.RUnlock()
-
-	if !exists {
-		return nil, fmt.Errorf("session %s not found. Use 'connect' action first", input.SessionID)
+func (d *Tool) handleCommand(input Input, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
 	}
 
 	command := "help"
 	if input.Command != "" {
 		command = input.Command
 	}
+	if strings.ContainsAny(command, "\r\n") {
+		return nil, errors.New("command must be a single line: send one command per request")
+	}
 
+	if isExecutionCommand(command) {
+		return d.handleExecution(input, session, command, notifier)
+	}
+
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' before sending %q", input.SessionID, command)
+	}
+
+	started := time.Now()
 	output, err := d.executeCommand(session, command)
+	d.recordCommand(session, input, command, output, err, started)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute command: %w", err)
 	}
 
-	// Apply pagination
+	header := fmt.Sprintf("Session %s - Command: %s\n", input.SessionID, command)
+	return paginateResult(header, output, input), nil
+}
+
+// paginateResult applies max_lines/offset pagination to command output and wraps it in a tool result.
+func paginateResult(header, output string, input Input) *mcp.CallToolResultFor[Output] {
 	maxLines := types.MaxDefaultLines
 	if input.MaxLines > 0 {
 		maxLines = input.MaxLines
@@ -424,21 +474,19 @@ func (d *Tool) handleCommand(input Input) (*mcp.CallToolResultFor[Output], error
 
 	paginatedOutput := strings.Join(lines, "\n")
 
-	resultText := fmt.Sprintf("Session %s - Command: %s\n", input.SessionID, command)
+	resultText := header
 	if truncated {
 		resultText += fmt.Sprintf("[Showing lines %d-%d of %d total lines. Use offset parameter to view more.]\n", offset+1, offset+len(lines), totalLines)
 	}
 	resultText += "\n" + strings.TrimSpace(paginatedOutput)
 
-	result := &mcp.CallToolResultFor[Output]{
+	return &mcp.CallToolResultFor[Output]{
 		Content: []mcp.Content{
 			&mcp.TextContent{
 				Text: resultText,
 			},
 		},
 	}
-
-	return result, nil
 }
 
 func New(logger zerolog.Logger) tools.Tool {
@@ -448,5 +496,8 @@ func New(logger zerolog.Logger) tools.Tool {
 		logger:    logger.With().Str("tool", "delve").Logger(),
 		validator: validate,
 		sessions:  make(map[string]*DelveSession),
+
+		transcriptDir: filepath.Join(os.TempDir(), "remote-debugger-mcp", "transcripts"),
+		transcripts:   make(map[string]string),
 	}
 }
@@ -1,13 +1,22 @@
 package delve
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/suite"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
 )
 
 type DelveTestSuite struct {
@@ -23,6 +32,8 @@ func (suite *DelveTestSuite) SetupTest() {
 		logger:    logger,
 		validator: suite.validator,
 		sessions:  make(map[string]*DelveSession),
+
+		transcripts: make(map[string]string),
 	}
 }
 
@@ -258,7 +269,7 @@ func (suite *DelveTestSuite) TestDelveHandlerValidation() {
 			params := &mcp.CallToolParamsFor[Input]{
 				Arguments: tc.input,
 			}
-			
+
 			_, err := suite.tool.DelveHandler(ctx, session, params)
 			if tc.shouldError {
 				suite.Error(err)
@@ -275,7 +286,7 @@ func (suite *DelveTestSuite) TestDelveHandlerValidation() {
 func (suite *DelveTestSuite) TestNewCreatesValidTool() {
 	logger := zerolog.Nop()
 	tool := New(logger)
-	
+
 	suite.NotNil(tool)
 	delveTool, ok := tool.(*Tool)
 	suite.True(ok)
@@ -295,7 +306,7 @@ func (suite *DelveTestSuite) TestSessionManagement() {
 			Action:    "disconnect",
 		},
 	}
-	
+
 	_, err := suite.tool.DelveHandler(ctx, session, params)
 	suite.Error(err)
 	suite.Contains(err.Error(), "not found")
@@ -308,12 +319,827 @@ func (suite *DelveTestSuite) TestSessionManagement() {
 			Command:   "help",
 		},
 	}
-	
+
 	_, err = suite.tool.DelveHandler(ctx, session, params)
 	suite.Error(err)
 	suite.Contains(err.Error(), "not found")
 }
 
+// newFakeSession wires a session to pipes instead of a dlv process.
+// Commands written by the tool are delivered on the returned channel and
+// dlv output is simulated by writing to the returned file.
+func (suite *DelveTestSuite) newFakeSession(sessionID string) (*os.File, <-chan string) {
+	stdinReader, stdinWriter, err := os.Pipe()
+	suite.Require().NoError(err)
+	outputReader, outputWriter, err := os.Pipe()
+	suite.Require().NoError(err)
+
+	tool := suite.tool
+	session := &DelveSession{
+		owner:     localClient,
+		observers: make(map[string]*notify.Notifier),
+	}
+	session.backend = newPipeBackend(zerolog.Nop(), stdinWriter, outputReader, func(output string) {
+		tool.handleStop(sessionID, session, output)
+	}, func(line string) {
+		tool.handleOutputLine(session, line)
+	})
+	suite.tool.sessions[sessionID] = session
+
+	commands := make(chan string, responseBufferSize)
+	go func() {
+		scanner := bufio.NewScanner(stdinReader)
+		for scanner.Scan() {
+			commands <- scanner.Text()
+		}
+	}()
+
+	suite.T().Cleanup(func() {
+		_ = stdinWriter.Close()
+		_ = outputWriter.Close()
+		_ = stdinReader.Close()
+	})
+	return outputWriter, commands
+}
+
+func (suite *DelveTestSuite) TestIsExecutionCommand() {
+	suite.True(isExecutionCommand("continue"))
+	suite.True(isExecutionCommand("c"))
+	suite.True(isExecutionCommand("next"))
+	suite.True(isExecutionCommand("  stepout"))
+	suite.True(isExecutionCommand("call f()"))
+	suite.True(isExecutionCommand("restart"))
+	suite.True(isExecutionCommand("rewind"))
+	suite.False(isExecutionCommand("print x"))
+	suite.False(isExecutionCommand("break main.main"))
+	suite.False(isExecutionCommand(""))
+}
+
+func (suite *DelveTestSuite) TestAsyncExecution() {
+	ctx := context.Background()
+	session := &mcp.ServerSession{}
+	output, commands := suite.newFakeSession("async")
+
+	call := func(input Input) (*mcp.CallToolResultFor[Output], error) {
+		input.SessionID = "async"
+		return suite.tool.DelveHandler(ctx, session, &mcp.CallToolParamsFor[Input]{Arguments: input})
+	}
+	text := func(result *mcp.CallToolResultFor[Output]) string {
+		suite.Require().Len(result.Content, 1)
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text
+	}
+
+	// Synchronous commands return the prompt-terminated response
+	go func() {
+		suite.Equal("print x", <-commands)
+		_, _ = output.WriteString("42\n(dlv) ")
+	}()
+	result, err := call(Input{Action: "command", Command: "print x"})
+	suite.Require().NoError(err)
+	suite.Contains(text(result), "42")
+
+	// Halting a stopped target is an error
+	_, err = call(Input{Action: "halt"})
+	suite.Error(err)
+	suite.Contains(err.Error(), "not running")
+
+	// Execution commands return while the target keeps running
+	result, err = call(Input{Action: "command", Command: "continue"})
+	suite.Require().NoError(err)
+	suite.Contains(text(result), "Target is running")
+	suite.Equal("continue", <-commands)
+
+	// Inspection commands are rejected while running
+	_, err = call(Input{Action: "command", Command: "locals"})
+	suite.Error(err)
+	suite.Contains(err.Error(), "is running")
+
+	result, err = call(Input{Action: "wait_for_stop", Timeout: 1})
+	suite.Require().NoError(err)
+	suite.Contains(text(result), "still running")
+
+	// Hitting a breakpoint completes the pending execution command
+	_, _ = output.WriteString("> main.main() ./main.go:10 (hits goroutine(1):1 total:1)\n(dlv) ")
+	result, err = call(Input{Action: "wait_for_stop", Timeout: 5})
+	suite.Require().NoError(err)
+	suite.Contains(text(result), "main.go:10")
+
+	// The last stop is returned again without waiting
+	result, err = call(Input{Action: "wait_for_stop"})
+	suite.Require().NoError(err)
+	suite.Contains(text(result), "main.go:10")
+}
+
+func (suite *DelveTestSuite) TestTranscriptAndReplay() {
+	ctx := context.Background()
+	session := &mcp.ServerSession{}
+	suite.tool.transcriptDir = suite.T().TempDir()
+
+	call := func(input Input) (*mcp.CallToolResultFor[Output], error) {
+		return suite.tool.DelveHandler(ctx, session, &mcp.CallToolParamsFor[Input]{Arguments: input})
+	}
+	text := func(result *mcp.CallToolResultFor[Output]) string {
+		suite.Require().Len(result.Content, 1)
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text
+	}
+
+	// Record an investigation
+	output, commands := suite.newFakeSession("original")
+	suite.tool.startTranscript("original", suite.tool.sessions["original"])
+	suite.tool.rememberTranscript("original", suite.tool.sessions["original"])
+	go func() {
+		suite.Equal("break main.go:10", <-commands)
+		_, _ = output.WriteString("Breakpoint 1 set at 0x49c3a8 for main.main() ./main.go:10\n(dlv) ")
+		suite.Equal("continue", <-commands)
+		_, _ = output.WriteString("> main.main() ./main.go:10 (hits goroutine(1):1 total:1)\n(dlv) ")
+		suite.Equal("print x", <-commands)
+		_, _ = output.WriteString("42\n(dlv) ")
+	}()
+	_, err := call(Input{SessionID: "original", Action: "command", Command: "break main.go:10"})
+	suite.Require().NoError(err)
+	_, err = call(Input{SessionID: "original", Action: "command", Command: "continue"})
+	suite.Require().NoError(err)
+	_, err = call(Input{SessionID: "original", Action: "wait_for_stop", Timeout: 5})
+	suite.Require().NoError(err)
+	_, err = call(Input{SessionID: "original", Action: "command", Command: "print x"})
+	suite.Require().NoError(err)
+	_, err = call(Input{SessionID: "original", Action: "disconnect"})
+	suite.Require().NoError(err)
+
+	// The transcript stays available after disconnecting
+	result, err := call(Input{SessionID: "original", Action: "export_transcript"})
+	suite.Require().NoError(err)
+	exported := text(result)
+	suite.Contains(exported, `"command":"break main.go:10"`)
+	suite.Contains(exported, `"action":"stop"`)
+	suite.Contains(exported, `"output":"42\n"`)
+	suite.Contains(exported, `"action":"disconnect"`)
+
+	path, err := suite.tool.transcriptPath("original")
+	suite.Require().NoError(err)
+	entries, err := readTranscript(path)
+	suite.Require().NoError(err)
+	suite.Len(entries, 5)
+
+	// Replay it against a new session
+	output, commands = suite.newFakeSession("replayed")
+	go func() {
+		suite.Equal("break main.go:10", <-commands)
+		_, _ = output.WriteString("Breakpoint 1 set at 0x49c3a8 for main.main() ./main.go:10\n(dlv) ")
+		suite.Equal("continue", <-commands)
+		_, _ = output.WriteString("> main.main() ./main.go:10 (hits goroutine(1):1 total:1)\n(dlv) ")
+		suite.Equal("print x", <-commands)
+		_, _ = output.WriteString("43\n(dlv) ")
+	}()
+	result, err = call(Input{SessionID: "replayed", Action: "replay", Transcript: "original", Timeout: 5})
+	suite.Require().NoError(err)
+	replay := text(result)
+	suite.Contains(replay, "3 commands")
+	suite.Contains(replay, "[2/3] continue")
+	suite.Contains(replay, "main.go:10")
+	suite.Contains(replay, "(matches transcript)")
+	suite.Contains(replay, "43\n(differs from transcript)")
+
+	// Replay requires a transcript
+	_, err = call(Input{SessionID: "replayed", Action: "replay"})
+	suite.Error(err)
+	suite.Contains(err.Error(), "validation error")
+
+	// Files are only read from the transcript directory
+	resolved, err := suite.tool.resolveTranscript(path)
+	suite.Require().NoError(err)
+	suite.Equal(filepath.Base(path), filepath.Base(resolved))
+	_, err = suite.tool.resolveTranscript(filepath.Base(path))
+	suite.NoError(err)
+	outside := filepath.Join(suite.T().TempDir(), "secret.jsonl")
+	suite.Require().NoError(os.WriteFile(outside, []byte(`{"action":"command","command":"print x"}`+"\n"), 0o600))
+	for _, name := range []string{outside, "../" + filepath.Base(outside), "/etc/passwd"} {
+		_, err = call(Input{SessionID: "replayed", Action: "replay", Transcript: name})
+		suite.Require().Error(err, name)
+		suite.Contains(err.Error(), "neither a recorded session nor a file in")
+	}
+}
+
+func (suite *DelveTestSuite) TestTranscriptLimit() {
+	dir := suite.T().TempDir()
+	remember := func(id, name string) string {
+		path := filepath.Join(dir, name)
+		suite.Require().NoError(os.WriteFile(path, nil, 0o600))
+		suite.tool.rememberTranscript(id, &DelveSession{transcript: &transcript{path: path}})
+		return path
+	}
+	for i := 0; i <= maxTranscripts; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		remember(id, id+".jsonl")
+	}
+	// Reconnecting a session makes it the most recent one
+	again := remember("session-1", "again.jsonl")
+	// A connected session keeps its file even when its ID is evicted
+	suite.tool.sessions["live"] = &DelveSession{transcript: &transcript{path: filepath.Join(dir, "session-2.jsonl")}}
+	remember("session-extra", "extra.jsonl")
+
+	suite.Len(suite.tool.transcripts, maxTranscripts)
+	suite.Len(suite.tool.transcriptOrder, maxTranscripts)
+	_, err := suite.tool.transcriptPath("session-0")
+	suite.Error(err, "the oldest transcript is forgotten")
+	suite.NoFileExists(filepath.Join(dir, "session-0.jsonl"), "and its file deleted")
+	suite.NoFileExists(filepath.Join(dir, "session-1.jsonl"), "the replaced file is deleted")
+	_, err = suite.tool.transcriptPath("session-2")
+	suite.Error(err)
+	suite.FileExists(filepath.Join(dir, "session-2.jsonl"))
+	suite.FileExists(filepath.Join(dir, "session-3.jsonl"))
+	path, err := suite.tool.transcriptPath("session-1")
+	suite.Require().NoError(err)
+	suite.Equal(again, path)
+}
+
+func (suite *DelveTestSuite) TestSourceAndDisassemble() {
+	ctx := context.Background()
+	session := &mcp.ServerSession{}
+
+	// A source file that only the "target" knows about
+	dir := suite.T().TempDir()
+	path := filepath.Join(dir, "main.go")
+	var source strings.Builder
+	for i := 1; i <= 30; i++ {
+		source.WriteString(fmt.Sprintf("line %d\n", i))
+	}
+	suite.Require().NoError(os.WriteFile(path, []byte(source.String()), 0o600))
+
+	output, commands := suite.newFakeSession("source")
+	call := func(input Input) (string, error) {
+		input.SessionID = "source"
+		result, err := suite.tool.DelveHandler(ctx, session, &mcp.CallToolParamsFor[Input]{Arguments: input})
+		if err != nil {
+			return "", err
+		}
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text, nil
+	}
+
+	// Source window around the current frame
+	sources := "sources ^" + regexp.QuoteMeta(path) + "$"
+	go func() {
+		suite.Equal("stack 1", <-commands)
+		_, _ = output.WriteString(fmt.Sprintf("0  0x000000000049c3a8 in main.main\n    at %s:12\n(dlv) ", path))
+		suite.Equal(sources, <-commands)
+		_, _ = output.WriteString(path + "\n(dlv) ")
+	}()
+	text, err := call(Input{Action: "source", ContextLines: 2})
+	suite.Require().NoError(err)
+	suite.Contains(text, "=> 12:\tline 12")
+	suite.Contains(text, "10:\tline 10")
+	suite.Contains(text, "14:\tline 14")
+	suite.NotContains(text, "line 15")
+
+	// Relative paths are resolved with the target's list of sources
+	go func() {
+		suite.Equal("sources main\\.go$", <-commands)
+		_, _ = output.WriteString(path + "\n(dlv) ")
+	}()
+	text, err = call(Input{Action: "source", Location: "./main.go:30", ContextLines: 1})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Source: "+path+":30")
+	suite.Contains(text, "=> 30:\tline 30")
+
+	// Pagination applies to the window
+	go func() {
+		suite.Equal("stack 2", <-commands)
+		_, _ = output.WriteString(fmt.Sprintf("0  0x1 in main.f\n    at %s:3\n1  0x2 in main.main\n    at %s:20\n(dlv) ", path, path))
+		suite.Equal(sources, <-commands)
+		_, _ = output.WriteString(path + "\n(dlv) ")
+	}()
+	text, err = call(Input{Action: "source", Frame: 1, MaxLines: 5})
+	suite.Require().NoError(err)
+	suite.Contains(text, "[Showing lines 1-5 of")
+	suite.Contains(text, "line 10")
+	suite.NotContains(text, "line 20")
+
+	// Other files on the server are not read
+	go func() {
+		suite.Equal("sources ^/etc/passwd$", <-commands)
+		_, _ = output.WriteString("(dlv) ")
+	}()
+	_, err = call(Input{Action: "source", Location: "/etc/passwd:1"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "not a source file of the debugged binary")
+
+	// Disassembly of a PC range
+	go func() {
+		suite.Equal("disassemble -a 0x49c3a0 0x49c3b0", <-commands)
+		_, _ = output.WriteString("TEXT main.main(SB) ./main.go\n=>\tmain.go:12\t0x49c3a8\tc3\tret\n(dlv) ")
+	}()
+	text, err = call(Input{Action: "disassemble", StartPC: "0x49c3a0", EndPC: "0x49c3b0"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "=>\tmain.go:12")
+
+	_, err = call(Input{Action: "disassemble", StartPC: "0x49c3a0"})
+	suite.Error(err)
+}
+
+func (suite *DelveTestSuite) TestDisassembleCommand() {
+	testCases := []struct {
+		input    Input
+		expected string
+	}{
+		{Input{}, "disassemble"},
+		{Input{Frame: 2}, "frame 2 disassemble"},
+		{Input{Function: "main.main"}, "disassemble -l main.main"},
+		{Input{StartPC: "0x10", EndPC: "0x20", Function: "main.main"}, "disassemble -a 0x10 0x20"},
+	}
+	for _, tc := range testCases {
+		command, err := disassembleCommand(tc.input)
+		suite.NoError(err)
+		suite.Equal(tc.expected, command)
+	}
+}
+
+func (suite *DelveTestSuite) TestParseTraceLine() {
+	event, ok := parseTraceLine("> goroutine(1): main.fib(n = 10)")
+	suite.True(ok)
+	suite.Equal(TraceEvent{Goroutine: 1, Function: "main.fib", Kind: "call", Args: "n = 10"}, event)
+
+	event, ok = parseTraceLine("> [tp1] goroutine(7): main.(*Server).handle(s = (*main.Server)(0xc000010000), r = \"GET\")")
+	suite.True(ok)
+	suite.Equal("main.(*Server).handle", event.Function)
+	suite.Equal(7, event.Goroutine)
+
+	event, ok = parseTraceLine(">> goroutine(1): main.fib => (55)")
+	suite.True(ok)
+	suite.Equal(TraceEvent{Goroutine: 1, Function: "main.fib", Kind: "return", Return: "55"}, event)
+
+	_, ok = parseTraceLine("> main.main() ./main.go:10 (hits goroutine(1):1 total:1)")
+	suite.False(ok)
+}
+
+func (suite *DelveTestSuite) TestTraceBuffer() {
+	buffer := newTraceBuffer("main", 3)
+	for i := 1; i <= 5; i++ {
+		buffer.add(TraceEvent{Goroutine: i})
+	}
+	events, total := buffer.snapshot()
+	suite.Equal(5, total)
+	suite.Require().Len(events, 3)
+	suite.Equal(3, events[0].Seq)
+	suite.Equal(5, events[2].Seq)
+}
+
+func (suite *DelveTestSuite) TestTraceAndWatch() {
+	ctx := context.Background()
+	session := &mcp.ServerSession{}
+	output, commands := suite.newFakeSession("trace")
+	call := func(input Input) (string, error) {
+		input.SessionID = "trace"
+		result, err := suite.tool.DelveHandler(ctx, session, &mcp.CallToolParamsFor[Input]{Arguments: input})
+		if err != nil {
+			return "", err
+		}
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text, nil
+	}
+
+	_, err := call(Input{Action: "trace_events"})
+	suite.Error(err)
+	suite.Contains(err.Error(), "not tracing")
+
+	_, err = call(Input{Action: "trace", Function: "github.com/x.F"})
+	suite.Error(err)
+
+	go func() {
+		suite.Equal("watch -rw counter", <-commands)
+		_, _ = output.WriteString("Watchpoint counter set at 0xc000012345\n(dlv) ")
+	}()
+	text, err := call(Input{Action: "watch", Expression: "counter", WatchMode: "rw"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Watchpoint counter set")
+
+	go func() {
+		suite.Equal("trace /main\\.fib/", <-commands)
+		_, _ = output.WriteString("Tracepoint 2 set at 0x49c3a8 for main.fib() ./main.go:5\n(dlv) ")
+	}()
+	text, err = call(Input{Action: "trace", Function: "main\\.fib", BufferSize: 2})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Target is running")
+	suite.Equal("continue", <-commands)
+
+	// Hits are collected while the target keeps running
+	_, _ = output.WriteString("> goroutine(1): main.fib(n = 2)\n> goroutine(1): main.fib(n = 1)\n>> goroutine(1): main.fib => (1)\n")
+	suite.Eventually(func() bool {
+		text, err = call(Input{Action: "trace_events"})
+		return err == nil && strings.Contains(text, "3 total")
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.Contains(text, "2 events buffered, 3 total, 1 dropped")
+	suite.Contains(text, "goroutine(1) main.fib(n = 1)")
+	suite.Contains(text, "goroutine(1) main.fib => (1)")
+	suite.NotContains(text, "n = 2")
+	suite.True(suite.tool.sessions["trace"].isRunning())
+
+	// The watchpoint stops the target
+	_, _ = output.WriteString("> main.main() ./main.go:12 (hits goroutine(1):1 total:1)\n(dlv) ")
+	text, err = call(Input{Action: "wait_for_stop", Timeout: 5})
+	suite.Require().NoError(err)
+	suite.Contains(text, "main.go:12")
+}
+
+func (suite *DelveTestSuite) TestSharedSessions() {
+	ctx := context.Background()
+	output, commands := suite.newFakeSession("shared")
+	suite.tool.sessions["shared"].owner = "alice"
+	suite.tool.sessions["shared"].host = "localhost"
+	suite.tool.sessions["shared"].port = 2345
+
+	// callAs performs an action the way DelveHandler does for the given client
+	callAs := func(client string, input Input) (string, error) {
+		input.SessionID = "shared"
+		input.client = client
+		if input.Action == "" {
+			input.Action = "command"
+		}
+		if err := suite.tool.authorize(input, input.Action, client); err != nil {
+			return "", err
+		}
+		result, err := suite.tool.handleSessionOperation(ctx, input, "localhost", 2345, input.Action, nil)
+		if err != nil {
+			return "", err
+		}
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text, nil
+	}
+
+	// Clients that did not join cannot use the session
+	_, err := callAs("bob", Input{Command: "print x"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "join")
+
+	text, err := callAs("bob", Input{Action: "join"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Owner: alice")
+
+	// Observers can inspect
+	go func() {
+		suite.Equal("print x", <-commands)
+		_, _ = output.WriteString("42\n(dlv) ")
+	}()
+	text, err = callAs("bob", Input{Command: "print x"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "42")
+
+	// ...but not drive execution or change the session
+	for _, input := range []Input{
+		{Command: "continue"},
+		{Command: "break main.go:10"},
+		{Command: "print x\ncontinue"},
+		{Command: "print x\r\ncontinue"},
+		{Command: "goroutines -exec continue"},
+		{Command: "grs -t -exec print x"},
+		{Command: "frame 1 continue"},
+		{Command: "goroutine 2 continue"},
+		{Command: "frame 1 print x"},
+		{Command: "print call f()"},
+		{Command: "p %x call f()"},
+		{Action: "channel", Expression: "call f()"},
+		{Action: "collection", Expression: " call f()"},
+		{Action: "halt"},
+		{Action: "disconnect"},
+		{Action: "handoff", ToClient: "carol"},
+	} {
+		_, err = callAs("bob", input)
+		suite.Require().Error(err)
+		suite.Contains(err.Error(), "observers may only inspect")
+	}
+
+	// Nobody can send several commands at once
+	_, err = callAs("alice", Input{Command: "print x\ncontinue"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "single line")
+
+	text, err = callAs("bob", Input{Action: "list_sessions"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "shared\tlocalhost")
+	suite.Contains(text, "owner=alice\tobservers=bob")
+
+	// Handoff requires the new owner to have joined
+	_, err = callAs("alice", Input{Action: "handoff", ToClient: "carol"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "not an observer")
+
+	text, err = callAs("alice", Input{Action: "handoff", ToClient: "bob"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "handed off to bob")
+	suite.Equal("owner", suite.tool.sessions["shared"].role("bob"))
+	suite.Equal("observer", suite.tool.sessions["shared"].role("alice"))
+
+	// The new owner drives execution
+	text, err = callAs("bob", Input{Command: "continue"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Target is running")
+	suite.Equal("continue", <-commands)
+
+	_, err = callAs("alice", Input{Command: "next"})
+	suite.Require().Error(err)
+
+	text, err = callAs("alice", Input{Action: "leave"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "left")
+	suite.Equal("", suite.tool.sessions["shared"].role("alice"))
+}
+
+// answer replies to the commands of a fake session from a table until the test ends.
+// Commands missing from the table fail like an unknown symbol does in dlv.
+func (suite *DelveTestSuite) answer(output *os.File, commands <-chan string, responses map[string]string) {
+	done := make(chan struct{})
+	suite.T().Cleanup(func() {
+		close(done)
+	})
+	go func() {
+		for {
+			select {
+			case command := <-commands:
+				response, ok := responses[command]
+				if !ok {
+					response = "Command failed: could not find symbol value for " + command
+				}
+				_, _ = output.WriteString(response + "\n(dlv) ")
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+func (suite *DelveTestSuite) TestParseGoroutines() {
+	goroutines := parseGoroutines(`  Goroutine 1 - User: ./main.go:20 main.main (0x49c3a8) [chan receive]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x000000000049c3a8 in main.main
+	    at ./main.go:20
+* Goroutine 2 - Runtime: /usr/local/go/src/runtime/proc.go:398 runtime.gopark (0x43b0f6)
+[2 goroutines]
+`)
+	suite.Require().Len(goroutines, 2)
+	suite.Equal(1, goroutines[0].ID)
+	suite.Equal("chan receive", goroutines[0].WaitReason)
+	suite.Require().Len(goroutines[0].Frames, 2)
+	suite.Equal(stackFrame{Function: "main.main", File: "./main.go", Line: 20}, goroutines[0].Frames[1])
+	suite.True(goroutines[1].Current)
+	suite.Empty(goroutines[1].WaitReason)
+	suite.Equal(1, goroutines[0].frameIndex(func(function string) bool { return function == "main.main" }))
+}
+
+func (suite *DelveTestSuite) TestCollectionElements() {
+	suite.Equal([]string{"1", "2", "3"}, collectionElements("[]int len: 3, cap: 3, [1,2,3]"))
+	suite.Equal([]string{`"a, b": 1`, `"c]": 2`}, collectionElements(`map[string]int ["a, b": 1, "c]": 2, ]`))
+	suite.Equal([]string{"{A: 1, B: [1,2]}", "{A: 2, B: []}"}, collectionElements("[]main.T len: 2, cap: 2, [{A: 1, B: [1,2]},{A: 2, B: []}]"))
+	suite.Empty(collectionElements("[]int len: 0, cap: 0, []"))
+}
+
+func (suite *DelveTestSuite) TestGoInspection() {
+	ctx := context.Background()
+	session := &mcp.ServerSession{}
+	output, commands := suite.newFakeSession("inspect")
+	call := func(input Input) (string, error) {
+		input.SessionID = "inspect"
+		result, err := suite.tool.DelveHandler(ctx, session, &mcp.CallToolParamsFor[Input]{Arguments: input})
+		if err != nil {
+			return "", err
+		}
+		content, ok := result.Content[0].(*mcp.TextContent)
+		suite.Require().True(ok)
+		return content.Text, nil
+	}
+
+	suite.answer(output, commands, map[string]string{
+		// Channel with a wrapped buffer and one blocked sender
+		"print ch":                    "chan int 2/3",
+		"print ch.qcount":             "2",
+		"print ch.dataqsiz":           "3",
+		"print ch.closed":             "0",
+		"print ch.recvx":              "2",
+		"print ch.buf[2]":             "7",
+		"print ch.buf[0]":             "8",
+		"print ch.sendq.first.g.goid": "9",
+		// Mutex held by a worker, with a waiter locking it from the same function
+		"print mu":                             "sync.Mutex {state: 9, sema: 0}",
+		"print uintptr(&mu)":                   "824633794560",
+		"print mu.state":                       "9",
+		"goroutine 5 frame 1 print uintptr(m)": "824633794560",
+		"goroutines -t 50": `  Goroutine 4 - User: ./main.go:30 main.worker (0x49c3a8) [sleep]
+	0  0x000000000043b0f6 in time.Sleep
+	    at /usr/local/go/src/runtime/time.go:195
+	1  0x000000000049c3a8 in main.worker
+	    at ./main.go:30
+  Goroutine 5 - User: /usr/local/go/src/runtime/sema.go:71 sync.runtime_SemacquireMutex (0x46a245) [sync.Mutex.Lock]
+	0  0x000000000046a245 in sync.runtime_SemacquireMutex
+	    at /usr/local/go/src/runtime/sema.go:71
+	1  0x0000000000476fe9 in sync.(*Mutex).lockSlow
+	    at /usr/local/go/src/sync/mutex.go:171
+	2  0x0000000000476c8d in sync.(*Mutex).Lock
+	    at /usr/local/go/src/sync/mutex.go:90
+	3  0x000000000049c3a8 in main.worker
+	    at ./main.go:28
+[2 goroutines]`,
+		// Map paged two elements at a time
+		"print m":      `map[string]int ["a": 1, "b": 2, "c": 3, ]`,
+		"print len(m)": "3",
+		"print m[0:2]": `map[string]int ["a": 1, "b": 2, ]`,
+		"print m[2:3]": `map[string]int ["c": 3, ]`,
+		"print x":      "42",
+	})
+
+	text, err := call(Input{Action: "channel", Expression: "ch"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Type: chan int")
+	suite.Contains(text, "Closed: false")
+	suite.Contains(text, "Buffer: 2/3")
+	suite.Contains(text, "[0] 7\n  [1] 8")
+	suite.Contains(text, "Receivers waiting: none")
+	suite.Contains(text, "Senders waiting: 9")
+
+	text, err = call(Input{Action: "mutex", Expression: "mu"})
+	suite.Require().NoError(err)
+	suite.Contains(text, "Type: sync.Mutex at 0xc000012000")
+	suite.Contains(text, "Locked: true")
+	suite.Contains(text, "Waiter count: 1")
+	suite.Contains(text, "goroutine 5 in sync.(*Mutex).lockSlow called from main.worker")
+	suite.Contains(text, "Likely holders: 4")
+
+	text, err = call(Input{Action: "collection", Expression: "m", MaxLines: 2})
+	suite.Require().NoError(err)
+	suite.Contains(text, "m: map[string]int, 3 elements")
+	suite.Contains(text, "\"a\": 1\n\"b\": 2\n")
+	suite.Contains(text, "Showing elements 0-2 of 3")
+
+	text, err = call(Input{Action: "collection", Expression: "m", Offset: 2, MaxLines: 2})
+	suite.Require().NoError(err)
+	suite.Contains(text, "\"c\": 3")
+	suite.NotContains(text, "Showing elements")
+
+	for _, input := range []Input{
+		{Action: "channel", Expression: "x"},
+		{Action: "mutex", Expression: "x"},
+		{Action: "collection", Expression: "x"},
+		{Action: "collection", Expression: "missing"},
+		{Action: "mutex"},
+	} {
+		_, err = call(input)
+		suite.Error(err, input.Action+" "+input.Expression)
+	}
+}
+
+func (suite *DelveTestSuite) TestWaitCycles() {
+	suite.Equal([][]int{{1, 2}, {4, 5, 6}}, waitCycles(map[int][]int{
+		1: {2},
+		2: {1},
+		3: {1},
+		4: {5},
+		5: {6},
+		6: {4, 3},
+	}))
+	suite.Empty(waitCycles(map[int][]int{1: {2}, 2: {3}}))
+}
+
+func (suite *DelveTestSuite) TestDeadlock() {
+	output, commands := suite.newFakeSession("deadlock")
+	suite.answer(output, commands, map[string]string{
+		"goroutines -t 50": `  Goroutine 1 - User: ./bank.go:12 main.transfer (0x49c3a8) [sync.Mutex.Lock]
+	0  0x000000000046a245 in sync.runtime_SemacquireMutex
+	    at /usr/local/go/src/runtime/sema.go:71
+	1  0x0000000000476fe9 in sync.(*Mutex).lockSlow
+	    at /usr/local/go/src/sync/mutex.go:171
+	2  0x0000000000476c8d in sync.(*Mutex).Lock
+	    at /usr/local/go/src/sync/mutex.go:90
+	3  0x000000000049c3a8 in main.transfer
+	    at ./bank.go:12
+  Goroutine 2 - User: ./bank.go:12 main.transfer (0x49c3a8) [sync.Mutex.Lock]
+	0  0x000000000046a245 in sync.runtime_SemacquireMutex
+	    at /usr/local/go/src/runtime/sema.go:71
+	1  0x0000000000476fe9 in sync.(*Mutex).lockSlow
+	    at /usr/local/go/src/sync/mutex.go:171
+	2  0x0000000000476c8d in sync.(*Mutex).Lock
+	    at /usr/local/go/src/sync/mutex.go:90
+	3  0x000000000049c3a8 in main.transfer
+	    at ./bank.go:12
+  Goroutine 3 - User: ./main.go:30 main.collect (0x49c500) [chan receive]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x0000000000405a1d in runtime.chanrecv
+	    at /usr/local/go/src/runtime/chan.go:583
+	2  0x00000000004055f2 in runtime.chanrecv1
+	    at /usr/local/go/src/runtime/chan.go:442
+	3  0x000000000049c500 in main.collect
+	    at ./main.go:30
+  Goroutine 4 - Runtime: /usr/local/go/src/runtime/mgc.go:1293 runtime.gcBgMarkWorker (0x41b8e5) [GC worker (idle)]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x000000000041b8e5 in runtime.gcBgMarkWorker
+	    at /usr/local/go/src/runtime/mgc.go:1293
+[4 goroutines]`,
+		"goroutine 1 frame 1 print uintptr(m)": "824633794568",
+		"goroutine 2 frame 1 print uintptr(m)": "824633794560",
+		"goroutine 3 frame 1 print uintptr(c)": "824634302464",
+	})
+
+	result, err := suite.tool.DelveHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{
+		Arguments: Input{SessionID: "deadlock", Action: "deadlock", Resume: true},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(result.Content, 2)
+	text, ok := result.Content[0].(*mcp.TextContent)
+	suite.Require().True(ok)
+	suite.Contains(text.Text, "Target was resumed")
+	suite.Contains(text.Text, "Lock cycle: goroutines 1, 2")
+	suite.Contains(text.Text, "Every user goroutine is blocked")
+	suite.Contains(text.Text, "goroutine 1: lock on mutex 0xc000012008 in main.transfer at ./bank.go:12 (waiting for goroutine 2)")
+	suite.Contains(text.Text, "goroutine 3: receive on chan 0xc00008e000 in main.collect at ./main.go:30")
+
+	report := result.StructuredContent.Deadlock
+	suite.Require().NotNil(report)
+	suite.Equal(4, report.Goroutines)
+	suite.Len(report.Blocked, 3)
+	suite.Equal([][]int{{1, 2}}, report.Cycles)
+	suite.True(report.GlobalDeadlock)
+	suite.False(report.Halted)
+	suite.True(report.Resumed)
+
+	structured, ok := result.Content[1].(*mcp.TextContent)
+	suite.Require().True(ok)
+	var decoded DeadlockReport
+	suite.Require().NoError(json.Unmarshal([]byte(structured.Text), &decoded))
+	suite.Equal(report.Findings, decoded.Findings)
+	suite.Eventually(func() bool {
+		return !suite.tool.sessions["deadlock"].isRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func (suite *DelveTestSuite) TestChannelDeadlock() {
+	// ping receives on a and then sends on b, pong receives on b and then sends on a
+	output, commands := suite.newFakeSession("channels")
+	suite.answer(output, commands, map[string]string{
+		"goroutines -t 50": `  Goroutine 6 - User: ./main.go:12 main.ping (0x49c3a8) [chan receive]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x0000000000405a1d in runtime.chanrecv
+	    at /usr/local/go/src/runtime/chan.go:583
+	2  0x00000000004055f2 in runtime.chanrecv1
+	    at /usr/local/go/src/runtime/chan.go:442
+	3  0x000000000049c3a8 in main.ping
+	    at ./main.go:12
+  Goroutine 7 - User: ./main.go:20 main.pong (0x49c500) [chan receive]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x0000000000405a1d in runtime.chanrecv
+	    at /usr/local/go/src/runtime/chan.go:583
+	2  0x00000000004055f2 in runtime.chanrecv1
+	    at /usr/local/go/src/runtime/chan.go:442
+	3  0x000000000049c500 in main.pong
+	    at ./main.go:20
+  Goroutine 8 - User: ./main.go:30 main.listen (0x49c600) [chan receive]
+	0  0x000000000043b0f6 in runtime.gopark
+	    at /usr/local/go/src/runtime/proc.go:398
+	1  0x0000000000405a1d in runtime.chanrecv
+	    at /usr/local/go/src/runtime/chan.go:583
+	2  0x00000000004055f2 in runtime.chanrecv1
+	    at /usr/local/go/src/runtime/chan.go:442
+	3  0x000000000049c600 in main.listen
+	    at ./main.go:30
+[3 goroutines]`,
+		"goroutine 6 frame 1 print uintptr(c)":                        "824633786368", // a: 0xc000010000
+		"goroutine 7 frame 1 print uintptr(c)":                        "824633790464", // b: 0xc000011000
+		"goroutine 8 frame 1 print uintptr(c)":                        "824633786368",
+		"goroutine 6 frame 3 args":                                    "a = chan int 0/0\nb = chan int 0/0",
+		"goroutine 6 frame 3 locals":                                  "(no locals)",
+		"goroutine 7 frame 3 args":                                    "a = chan int 0/0\nb = chan int 0/0",
+		"goroutine 7 frame 3 locals":                                  "n = 3",
+		"goroutine 8 frame 3 args":                                    "a = <-chan int 0/0",
+		"goroutine 6 frame 3 print uintptr(*(*uintptr)(uintptr(&a)))": "824633786368",
+		"goroutine 6 frame 3 print uintptr(*(*uintptr)(uintptr(&b)))": "824633790464",
+		"goroutine 7 frame 3 print uintptr(*(*uintptr)(uintptr(&a)))": "824633786368",
+		"goroutine 7 frame 3 print uintptr(*(*uintptr)(uintptr(&b)))": "824633790464",
+		"goroutine 8 frame 3 print uintptr(*(*uintptr)(uintptr(&a)))": "824633786368",
+	})
+
+	result, err := suite.tool.DelveHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{
+		Arguments: Input{SessionID: "channels", Action: "deadlock"},
+	})
+	suite.Require().NoError(err)
+	report := result.StructuredContent.Deadlock
+	suite.Require().NotNil(report)
+	suite.Equal([][]int{{6, 7}}, report.Cycles, "another receiver of a cannot complete the receive of ping")
+	suite.Require().Len(report.Blocked, 3)
+	suite.Equal([]int{7}, report.Blocked[0].WaitingFor)
+	suite.Equal([]int{6}, report.Blocked[1].WaitingFor)
+	suite.Equal([]int{7}, report.Blocked[2].WaitingFor)
+	suite.Contains(report.Findings[0], "Wait cycle: goroutines 6, 7")
+	suite.Contains(report.Findings, "2 goroutines are waiting on chan 0xc000010000, which goroutine 7 could complete")
+}
+
 func TestDelveTestSuite(t *testing.T) {
 	suite.Run(t, new(DelveTestSuite))
-}
\ No newline at end of file
+}
@@ -0,0 +1,480 @@
+package delve
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxChannelWaiters     = 32 // Waiters read from each of a channel's queues
+	maxChannelBuffer      = 64 // Buffered elements shown for a channel
+	collectionChunkSize   = 64 // Elements loaded per print, dlv's default max-array-values
+	defaultCollectionPage = 100
+	mutexLocked           = 1 // sync.Mutex state bits
+	mutexWoken            = 2
+	mutexStarving         = 4
+	mutexWaiterShift      = 3
+)
+
+// mutexLockFunctions are the frames a goroutine blocked on a mutex is parked in.
+var mutexLockFunctions = map[string]bool{
+	"sync.(*Mutex).Lock":              true,
+	"sync.(*Mutex).lockSlow":          true,
+	"internal/sync.(*Mutex).Lock":     true,
+	"internal/sync.(*Mutex).lockSlow": true,
+	"sync.(*RWMutex).Lock":            true,
+	"sync.(*RWMutex).RLock":           true,
+}
+
+// valueType matches the type printed before a value, e.g. "*sync.Mutex {" or "[]int len: 3".
+var valueType = regexp.MustCompile(`^(\S+)`)
+
+// evaluate prints an expression and returns its value, turning dlv error output into an error.
+func (d *Tool) evaluate(session *DelveSession, expression string) (string, error) {
+	return d.evaluateIn(session, "", expression)
+}
+
+// evaluateIn prints an expression in a scope such as "goroutine 5 frame 2".
+func (d *Tool) evaluateIn(session *DelveSession, scope, expression string) (string, error) {
+	command := "print " + expression
+	if scope != "" {
+		command = scope + " " + command
+	}
+	output, err := d.executeCommand(session, command)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSpace(output)
+	if strings.HasPrefix(value, "Command failed:") {
+		return "", errors.New(strings.TrimSpace(strings.TrimPrefix(value, "Command failed:")))
+	}
+	return value, nil
+}
+
+// evaluateInt prints an integer expression.
+func (d *Tool) evaluateInt(session *DelveSession, expression string) (int64, error) {
+	value, err := d.evaluate(session, expression)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not an integer: %s", expression, value)
+	}
+	return number, nil
+}
+
+// evaluateAddress returns the address a pointer expression points to, evaluated in scope.
+func (d *Tool) evaluateAddress(session *DelveSession, scope, expression string) (uint64, error) {
+	value, err := d.evaluateIn(session, scope, "uintptr("+expression+")")
+	if err != nil {
+		return 0, err
+	}
+	address, err := strconv.ParseUint(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a pointer: %s", expression, value)
+	}
+	return address, nil
+}
+
+// evaluateIntField prints the first of several alternative integer fields that exists,
+// covering runtime layouts that differ between Go versions.
+func (d *Tool) evaluateIntField(session *DelveSession, expressions ...string) (int64, error) {
+	var lastErr error
+	for _, expression := range expressions {
+		value, err := d.evaluateInt(session, expression)
+		if err == nil {
+			return value, nil
+		}
+		lastErr = err
+	}
+	return 0, lastErr
+}
+
+// inspectionSession returns a stopped session for an inspection action.
+func (d *Tool) inspectionSession(input Input, action string) (*DelveSession, error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if input.Expression == "" {
+		return nil, fmt.Errorf("expression is required for %s", action)
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' first", input.SessionID)
+	}
+	return session, nil
+}
+
+// channelWaiters walks a runtime.waitq and returns the IDs of the goroutines queued on it.
+func (d *Tool) channelWaiters(session *DelveSession, queue string) []int {
+	var waiters []int
+	sudog := queue + ".first"
+	for len(waiters) < maxChannelWaiters {
+		goid, err := d.evaluateInt(session, sudog+".g.goid")
+		if err != nil {
+			break // nil sudog ends the queue
+		}
+		waiters = append(waiters, int(goid))
+		sudog += ".next"
+	}
+	return waiters
+}
+
+// handleChannel shows a channel's buffered elements, the goroutines waiting to send and receive and whether it is closed.
+func (d *Tool) handleChannel(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.inspectionSession(input, "channel")
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	expr := input.Expression
+
+	value, err := d.evaluate(session, expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", expr, err)
+	}
+	if !strings.HasPrefix(value, "chan ") && !strings.HasPrefix(value, "<-chan ") && !strings.HasPrefix(value, "chan<- ") {
+		return nil, fmt.Errorf("%s is not a channel: %s", expr, value)
+	}
+
+	// dlv prints channels as "chan int 2/10", or "chan int nil"
+	fields := strings.Fields(value)
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Type: %s\n", strings.Join(fields[:min(len(fields), 2)], " ")))
+	if strings.HasSuffix(value, " nil") {
+		output.WriteString("Channel is nil: sends and receives block forever\n")
+		return d.inspectionResult(session, input, "channel", output.String(), started), nil
+	}
+
+	count, err := d.evaluateInt(session, expr+".qcount")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel length: %w", err)
+	}
+	size, err := d.evaluateInt(session, expr+".dataqsiz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel capacity: %w", err)
+	}
+	closed, err := d.evaluateInt(session, expr+".closed")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channel state: %w", err)
+	}
+
+	output.WriteString(fmt.Sprintf("Closed: %t\n", closed != 0))
+	output.WriteString(fmt.Sprintf("Buffer: %d/%d\n", count, size))
+
+	if count > 0 {
+		recvx, err := d.evaluateInt(session, expr+".recvx")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read channel receive index: %w", err)
+		}
+		// Elements are listed in the order they will be received
+		for i := int64(0); i < min(count, maxChannelBuffer); i++ {
+			index := (recvx + i) % size
+			element, err := d.evaluate(session, fmt.Sprintf("%s.buf[%d]", expr, index))
+			if err != nil {
+				element = "<" + err.Error() + ">"
+			}
+			output.WriteString(fmt.Sprintf("  [%d] %s\n", i, element))
+		}
+		if count > maxChannelBuffer {
+			output.WriteString(fmt.Sprintf("  ... %d more\n", count-maxChannelBuffer))
+		}
+	}
+
+	receivers := d.channelWaiters(session, expr+".recvq")
+	senders := d.channelWaiters(session, expr+".sendq")
+	output.WriteString(fmt.Sprintf("Receivers waiting: %s\n", formatGoroutineIDs(receivers)))
+	output.WriteString(fmt.Sprintf("Senders waiting: %s\n", formatGoroutineIDs(senders)))
+
+	return d.inspectionResult(session, input, "channel", output.String(), started), nil
+}
+
+// lockCaller returns the function that called into sync from a goroutine's lock frame.
+func lockCaller(g goroutineInfo, lockFrame int) string {
+	for i := lockFrame + 1; i < len(g.Frames); i++ {
+		function := g.Frames[i].Function
+		if !strings.HasPrefix(function, "sync.") && !strings.HasPrefix(function, "internal/sync.") {
+			return function
+		}
+	}
+	return ""
+}
+
+// mutexWaiter is a goroutine blocked on a mutex.
+type mutexWaiter struct {
+	Goroutine int    `json:"goroutine"`
+	Function  string `json:"function"` // Lock or RLock frame
+	Caller    string `json:"caller,omitempty"`
+}
+
+// mutexWaiters maps the goroutines parked in a Lock frame to the mutex at address.
+func (d *Tool) mutexWaiters(session *DelveSession, goroutines []goroutineInfo, address uint64) []mutexWaiter {
+	var waiters []mutexWaiter
+	for _, g := range goroutines {
+		frame := g.frameIndex(func(function string) bool { return mutexLockFunctions[function] })
+		if frame < 0 {
+			continue
+		}
+		receiver := "m"
+		if strings.Contains(g.Frames[frame].Function, "RWMutex") {
+			receiver = "rw"
+		}
+		// A mutex embedded at offset 0 (RWMutex.w, Mutex.mu) shares the address of its parent
+		lockAddress, err := d.evaluateAddress(session, fmt.Sprintf("goroutine %d frame %d", g.ID, frame), receiver)
+		if err != nil || lockAddress != address {
+			continue
+		}
+		waiters = append(waiters, mutexWaiter{Goroutine: g.ID, Function: g.Frames[frame].Function, Caller: lockCaller(g, frame)})
+	}
+	return waiters
+}
+
+// likelyHolders returns the goroutines, other than the waiters, that are inside one of the functions
+// the waiters lock the mutex from. The runtime does not record mutex owners, so this is a heuristic.
+func likelyHolders(goroutines []goroutineInfo, waiters []mutexWaiter) []int {
+	callers := make(map[string]bool)
+	waiting := make(map[int]bool)
+	for _, waiter := range waiters {
+		waiting[waiter.Goroutine] = true
+		if waiter.Caller != "" {
+			callers[waiter.Caller] = true
+		}
+	}
+
+	var holders []int
+	for _, g := range goroutines {
+		if waiting[g.ID] {
+			continue
+		}
+		if g.frameIndex(func(function string) bool { return callers[function] }) >= 0 {
+			holders = append(holders, g.ID)
+		}
+	}
+	return holders
+}
+
+// handleMutex decodes a sync.Mutex or sync.RWMutex and maps its waiters, and likely holders, to goroutines.
+func (d *Tool) handleMutex(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.inspectionSession(input, "mutex")
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	expr := input.Expression
+
+	value, err := d.evaluate(session, expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", expr, err)
+	}
+	typeName := valueType.FindString(value)
+	if strings.HasPrefix(typeName, "*") {
+		expr = "(*" + expr + ")"
+		typeName = strings.TrimPrefix(typeName, "*")
+	}
+	isRWMutex := strings.HasSuffix(typeName, "sync.RWMutex")
+	if !isRWMutex && !strings.HasSuffix(typeName, "sync.Mutex") {
+		return nil, fmt.Errorf("%s is not a sync.Mutex or sync.RWMutex: %s", input.Expression, value)
+	}
+
+	address, err := d.evaluateAddress(session, "", "&"+expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address of %s: %w", input.Expression, err)
+	}
+
+	mutex := expr
+	if isRWMutex {
+		mutex = expr + ".w"
+	}
+	// Go 1.24 moved the implementation to internal/sync, wrapped in the mu field
+	state, err := d.evaluateIntField(session, mutex+".state", mutex+".mu.state")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mutex state: %w", err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Type: %s at %#x\n", typeName, address))
+	output.WriteString(fmt.Sprintf("Locked: %t\n", state&mutexLocked != 0))
+	output.WriteString(fmt.Sprintf("Starving: %t\n", state&mutexStarving != 0))
+	output.WriteString(fmt.Sprintf("Woken: %t\n", state&mutexWoken != 0))
+	output.WriteString(fmt.Sprintf("Waiter count: %d\n", state>>mutexWaiterShift))
+
+	if isRWMutex {
+		readerCount, err := d.evaluateIntField(session, expr+".readerCount.v", expr+".readerCount")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reader count: %w", err)
+		}
+		readerWait, err := d.evaluateIntField(session, expr+".readerWait.v", expr+".readerWait")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read reader wait: %w", err)
+		}
+		// A pending writer subtracts rwmutexMaxReaders from readerCount
+		writerPending := readerCount < 0
+		if writerPending {
+			readerCount += 1 << 30
+		}
+		output.WriteString(fmt.Sprintf("Readers: %d\n", readerCount))
+		output.WriteString(fmt.Sprintf("Writer pending: %t (waiting for %d readers)\n", writerPending, readerWait))
+	}
+
+	goroutines, err := d.goroutineStacks(session)
+	if err != nil {
+		return nil, err
+	}
+	waiters := d.mutexWaiters(session, goroutines, address)
+	output.WriteString(fmt.Sprintf("\nWaiters (%d):\n", len(waiters)))
+	for _, waiter := range waiters {
+		output.WriteString(fmt.Sprintf("  goroutine %d in %s", waiter.Goroutine, waiter.Function))
+		if waiter.Caller != "" {
+			output.WriteString(" called from " + waiter.Caller)
+		}
+		output.WriteString("\n")
+	}
+
+	if state&mutexLocked != 0 || isRWMutex {
+		holders := likelyHolders(goroutines, waiters)
+		output.WriteString(fmt.Sprintf("\nLikely holders: %s\n", formatGoroutineIDs(holders)))
+		output.WriteString("The runtime does not record mutex owners; holders are goroutines inside a function the waiters lock from.\n")
+	}
+
+	return d.inspectionResult(session, input, "mutex", output.String(), started), nil
+}
+
+// collectionElements splits the last bracketed list of a printed value into its elements,
+// e.g. `[]int len: 3, cap: 3, [1,2,3]` or `map[string]int ["a": 1, "b": 2, ]`.
+func collectionElements(value string) []string {
+	var elements []string
+	var current strings.Builder
+	depth := 0
+	inString := false
+	escaped := false
+	for _, r := range value {
+		if inString {
+			current.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch r {
+		case '"':
+			inString = true
+		case '[', '{', '(':
+			depth++
+			if depth == 1 && r == '[' {
+				// A new top-level list replaces the previous one
+				elements = nil
+				current.Reset()
+				continue
+			}
+		case ']', '}', ')':
+			depth--
+			if depth == 0 && r == ']' {
+				if element := strings.TrimSpace(current.String()); element != "" {
+					elements = append(elements, element)
+				}
+				current.Reset()
+				continue
+			}
+		case ',':
+			if depth == 1 {
+				if element := strings.TrimSpace(current.String()); element != "" {
+					elements = append(elements, element)
+				}
+				current.Reset()
+				continue
+			}
+		}
+		if depth > 0 {
+			current.WriteRune(r)
+		}
+	}
+	return elements
+}
+
+// handleCollection prints the elements of a map, slice or array one per line, a page at a time.
+// offset and max_lines select the page of elements.
+func (d *Tool) handleCollection(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.inspectionSession(input, "collection")
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	expr := input.Expression
+
+	value, err := d.evaluate(session, expr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %w", expr, err)
+	}
+	typeName := valueType.FindString(value)
+	isMap := strings.HasPrefix(typeName, "map[")
+	if !isMap && !strings.HasPrefix(typeName, "[") {
+		return nil, fmt.Errorf("%s is not a map, slice or array: %s", expr, value)
+	}
+
+	length, err := d.evaluateInt(session, "len("+expr+")")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get length of %s: %w", expr, err)
+	}
+
+	pageSize := int64(defaultCollectionPage)
+	if input.MaxLines > 0 {
+		pageSize = int64(input.MaxLines)
+	}
+	first := min(int64(input.Offset), length)
+	last := min(first+pageSize, length)
+
+	var output strings.Builder
+	for start := first; start < last; start += collectionChunkSize {
+		end := min(start+collectionChunkSize, last)
+		chunk, err := d.evaluate(session, fmt.Sprintf("%s[%d:%d]", expr, start, end))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read elements %d-%d of %s: %w", start, end, expr, err)
+		}
+		for i, element := range collectionElements(chunk) {
+			if isMap {
+				output.WriteString(element + "\n")
+			} else {
+				output.WriteString(fmt.Sprintf("[%d] %s\n", start+int64(i), element))
+			}
+		}
+	}
+	if last < length {
+		output.WriteString(fmt.Sprintf("\n[Showing elements %d-%d of %d total elements. Use offset parameter to view more.]\n", first, last, length))
+	}
+
+	header := fmt.Sprintf("Session %s - %s: %s, %d elements\n", input.SessionID, expr, typeName, length)
+	d.recordCommand(session, input, "collection "+expr, output.String(), nil, started)
+	return textResult(header + "\n" + output.String()), nil
+}
+
+// inspectionResult records an inspection in the transcript and wraps its report in a result.
+func (d *Tool) inspectionResult(session *DelveSession, input Input, action, report string, started time.Time) *mcp.CallToolResultFor[Output] {
+	d.recordCommand(session, input, action+" "+input.Expression, report, nil, started)
+	header := fmt.Sprintf("Session %s - %s %s\n\n", input.SessionID, strings.ToUpper(action[:1])+action[1:], input.Expression)
+	return textResult(header + report)
+}
+
+// formatGoroutineIDs lists goroutine IDs, or "none".
+func formatGoroutineIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ", ")
+}
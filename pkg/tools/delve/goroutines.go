@@ -0,0 +1,86 @@
+package delve
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const goroutineStackDepth = 50
+
+var (
+	// goroutineHeader matches a goroutine of `goroutines` output, e.g.
+	// "* Goroutine 1 - User: ./main.go:20 main.main (0x49c3a8) [chan receive]".
+	goroutineHeader = regexp.MustCompile(`^\s*(\*)?\s*Goroutine (\d+) - (.*?)(?: \[([^\]]+)\])?\s*$`)
+	// goroutineFrame matches a frame of `goroutines -t` output.
+	goroutineFrame = regexp.MustCompile(`^\s*(\d+)\s+\S+ in (.+?)\s*$`)
+	// goroutineFrameLocation matches the "at file:line" line following a frame.
+	goroutineFrameLocation = regexp.MustCompile(`^\s*at (.+):(\d+)\s*$`)
+)
+
+// stackFrame is a frame of a goroutine stack.
+type stackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// goroutineInfo is a goroutine parsed from `goroutines -t` output.
+type goroutineInfo struct {
+	ID         int          `json:"id"`
+	Current    bool         `json:"current,omitempty"`
+	Location   string       `json:"location"`
+	WaitReason string       `json:"wait_reason,omitempty"`
+	Frames     []stackFrame `json:"frames,omitempty"`
+}
+
+// frameIndex returns the index of the first frame whose function matches, or -1.
+func (g goroutineInfo) frameIndex(match func(function string) bool) int {
+	for i, frame := range g.Frames {
+		if match(frame.Function) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseGoroutines parses `goroutines` or `goroutines -t` output.
+func parseGoroutines(output string) []goroutineInfo {
+	var goroutines []goroutineInfo
+	for _, line := range strings.Split(output, "\n") {
+		if match := goroutineHeader.FindStringSubmatch(line); match != nil {
+			id, _ := strconv.Atoi(match[2])
+			goroutines = append(goroutines, goroutineInfo{
+				ID:         id,
+				Current:    match[1] == "*",
+				Location:   match[3],
+				WaitReason: match[4],
+			})
+			continue
+		}
+		if len(goroutines) == 0 {
+			continue
+		}
+		current := &goroutines[len(goroutines)-1]
+		if match := goroutineFrame.FindStringSubmatch(line); match != nil {
+			current.Frames = append(current.Frames, stackFrame{Function: match[2]})
+			continue
+		}
+		if match := goroutineFrameLocation.FindStringSubmatch(line); match != nil && len(current.Frames) > 0 {
+			frame := &current.Frames[len(current.Frames)-1]
+			frame.File = match[1]
+			frame.Line, _ = strconv.Atoi(match[2])
+		}
+	}
+	return goroutines
+}
+
+// goroutineStacks lists every goroutine of the target with its stack.
+func (d *Tool) goroutineStacks(session *DelveSession) ([]goroutineInfo, error) {
+	output, err := d.executeCommand(session, fmt.Sprintf("goroutines -t %d", goroutineStackDepth))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goroutines: %w", err)
+	}
+	return parseGoroutines(output), nil
+}
@@ -0,0 +1,270 @@
+package delve
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+const localClient = "local" // Client ID used when the transport has no session IDs
+
+// callExpression matches an expression that calls a function in the target, which delve allows in
+// print and evaluate (REPL) requests, e.g. "call f()" or "%x call f()".
+var callExpression = regexp.MustCompile(`^\s*(?:%\S+\s+)?call\s`)
+
+// readOnlyCommands inspect the target without changing its state or the debugger's selection,
+// so observers may run them. frame and goroutine are left out: they prefix any other command.
+var readOnlyCommands = map[string]bool{
+	"print":       true,
+	"p":           true,
+	"locals":      true,
+	"args":        true,
+	"vars":        true,
+	"whatis":      true,
+	"stack":       true,
+	"bt":          true,
+	"goroutines":  true,
+	"grs":         true,
+	"threads":     true,
+	"breakpoints": true,
+	"bp":          true,
+	"list":        true,
+	"ls":          true,
+	"l":           true,
+	"sources":     true,
+	"funcs":       true,
+	"types":       true,
+	"regs":        true,
+	"examinemem":  true,
+	"x":           true,
+	"disassemble": true,
+	"disass":      true,
+	"help":        true,
+	"h":           true,
+}
+
+// openActions may be used by any client.
+var openActions = map[string]bool{
+	"connect":       true,
+	"join":          true,
+	"list_sessions": true,
+}
+
+// observerActions may be used by observers of a shared session. Everything else needs ownership.
+var observerActions = map[string]bool{
+	"wait_for_stop":     true,
+	"source":            true,
+	"disassemble":       true,
+	"trace_events":      true,
+	"export_transcript": true,
+	"leave":             true,
+	"channel":           true,
+	"mutex":             true,
+	"collection":        true,
+}
+
+// isReadOnlyCommand reports whether an observer may run the command.
+func isReadOnlyCommand(command string) bool {
+	if strings.ContainsAny(command, "\r\n") {
+		return false // More than one command
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return true // Defaults to help
+	}
+	if !readOnlyCommands[fields[0]] {
+		return false
+	}
+	if fields[0] == "print" || fields[0] == "p" {
+		// call runs code in the target
+		return !callExpression.MatchString(strings.TrimPrefix(strings.TrimSpace(command), fields[0]))
+	}
+	if fields[0] == "goroutines" || fields[0] == "grs" {
+		// -exec runs a command on every goroutine
+		for _, field := range fields[1:] {
+			if field == "-exec" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// clientID identifies the MCP client that sent a request.
+func clientID(session *mcp.ServerSession) string {
+	if id := session.ID(); id != "" {
+		return id
+	}
+	return localClient
+}
+
+// role returns "owner", "observer" or "" for a client of the session.
+func (s *DelveSession) role(client string) string {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	if s.owner == client {
+		return "owner"
+	}
+	if _, ok := s.observers[client]; ok {
+		return "observer"
+	}
+	return ""
+}
+
+// authorize checks that a client may perform an action on an existing session.
+func (d *Tool) authorize(input Input, action, client string) error {
+	if openActions[action] {
+		return nil
+	}
+
+	d.sessionMu.RLock()
+	session, exists := d.sessions[input.SessionID]
+	d.sessionMu.RUnlock()
+	if !exists {
+		// The action reports the missing session itself
+		return nil
+	}
+
+	switch session.role(client) {
+	case "owner":
+		return nil
+	case "observer":
+		if observerActions[action] && !callExpression.MatchString(input.Expression) {
+			return nil
+		}
+		if action == "command" && isReadOnlyCommand(input.Command) {
+			return nil
+		}
+		return fmt.Errorf("session %s is controlled by another client; observers may only inspect it. Ask the owner to hand off control", input.SessionID)
+	default:
+		return fmt.Errorf("session %s is owned by another client. Use 'join' to observe it", input.SessionID)
+	}
+}
+
+// handleJoin adds the client as an observer of a shared session.
+func (d *Tool) handleJoin(input Input, client string, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.stateMu.Lock()
+	owner := session.owner
+	if owner != client {
+		session.observers[client] = notifier
+	}
+	session.stateMu.Unlock()
+
+	d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: client, Action: "join"})
+
+	resultText := fmt.Sprintf("Joined Delve session %s as observer\nClient ID: %s\nOwner: %s\nObservers can inspect the target; only the owner resumes, halts or changes it.", input.SessionID, client, owner)
+	if owner == client {
+		resultText = fmt.Sprintf("Client %s already owns session %s", client, input.SessionID)
+	}
+	return textResult(resultText), nil
+}
+
+// handleLeave removes an observer from a shared session.
+func (d *Tool) handleLeave(input Input, client string) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.stateMu.Lock()
+	delete(session.observers, client)
+	session.stateMu.Unlock()
+
+	d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: client, Action: "leave"})
+	return textResult(fmt.Sprintf("Client %s left Delve session %s", client, input.SessionID)), nil
+}
+
+// handleHandoff transfers control of a session to one of its observers. The previous owner becomes an observer.
+func (d *Tool) handleHandoff(input Input, client string, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.stateMu.Lock()
+	if _, ok := session.observers[input.ToClient]; !ok {
+		session.stateMu.Unlock()
+		return nil, fmt.Errorf("client %s is not an observer of session %s. It must 'join' first", input.ToClient, input.SessionID)
+	}
+	delete(session.observers, input.ToClient)
+	session.observers[client] = notifier
+	session.owner = input.ToClient
+	session.stateMu.Unlock()
+
+	d.logger.Info().Str("client", client).Str("session_id", input.SessionID).Msgf("Handed off control to %s", input.ToClient)
+	d.recordEntry(session, TranscriptEntry{SessionID: input.SessionID, Client: client, Action: "handoff", Command: input.ToClient})
+	return textResult(fmt.Sprintf("Control of Delve session %s handed off to %s. Client %s is now an observer.", input.SessionID, input.ToClient, client)), nil
+}
+
+// handleListSessions lists the sessions with their owners and observers.
+func (d *Tool) handleListSessions(input Input, client string) (*mcp.CallToolResultFor[Output], error) {
+	d.sessionMu.RLock()
+	ids := make([]string, 0, len(d.sessions))
+	for id := range d.sessions {
+		ids = append(ids, id)
+	}
+	sessions := make(map[string]*DelveSession, len(d.sessions))
+	for id, session := range d.sessions {
+		sessions[id] = session
+	}
+	d.sessionMu.RUnlock()
+	sort.Strings(ids)
+
+	var output strings.Builder
+	for _, id := range ids {
+		session := sessions[id]
+		session.stateMu.Lock()
+		observers := make([]string, 0, len(session.observers))
+		for observer := range session.observers {
+			observers = append(observers, observer)
+		}
+		state := "stopped"
+		if session.running {
+			state = "running"
+		}
+		owner := session.owner
+		session.stateMu.Unlock()
+		sort.Strings(observers)
+
+		output.WriteString(fmt.Sprintf("%s\t%s:%d\t%s\towner=%s\tobservers=%s\n", id, session.host, session.port, state, owner, strings.Join(observers, ",")))
+	}
+
+	header := fmt.Sprintf("Delve sessions (%d) - Client ID: %s\n", len(ids), client)
+	return paginateResult(header, output.String(), input), nil
+}
+
+// notifyObservers sends a log notification to the observers of a session.
+func (s *DelveSession) notifyObservers(ctx context.Context, data any) {
+	s.stateMu.Lock()
+	notifiers := make([]*notify.Notifier, 0, len(s.observers))
+	for _, notifier := range s.observers {
+		notifiers = append(notifiers, notifier)
+	}
+	s.stateMu.Unlock()
+
+	for _, notifier := range notifiers {
+		notifier.Log(ctx, "info", data)
+	}
+}
+
+// textResult wraps a message in a tool result.
+func textResult(text string) *mcp.CallToolResultFor[Output] {
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: text,
+			},
+		},
+	}
+}
@@ -0,0 +1,212 @@
+package delve
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+const defaultSourceContext = 10 // Lines shown before and after the current line
+
+// stackFrameLocation matches a frame of `stack` output and its "at file:line" line.
+var stackFrameLocation = regexp.MustCompile(`(?m)^\s*(\d+)\s+\S+ in .+\n\s+at (.+):(\d+)\s*$`)
+
+// frameLocation returns the source file and line of a stack frame.
+func (d *Tool) frameLocation(session *DelveSession, frame int) (string, int, error) {
+	output, err := d.executeCommand(session, fmt.Sprintf("stack %d", frame+1))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get stack: %w", err)
+	}
+
+	for _, match := range stackFrameLocation.FindAllStringSubmatch(output, -1) {
+		index, _ := strconv.Atoi(match[1])
+		if index != frame {
+			continue
+		}
+		line, _ := strconv.Atoi(match[3])
+		return match[2], line, nil
+	}
+	return "", 0, fmt.Errorf("frame %d not found in stack:\n%s", frame, strings.TrimSpace(output))
+}
+
+// resolveSourcePath looks a path up in the list of sources compiled into the binary, mapping a
+// relative path printed by dlv onto the absolute path known to the target. It reports whether the
+// path is in that list.
+func (d *Tool) resolveSourcePath(session *DelveSession, file string) (string, bool) {
+	pattern := "^" + regexp.QuoteMeta(filepath.ToSlash(file)) + "$"
+	suffix := strings.TrimPrefix(filepath.ToSlash(file), "./")
+	if !filepath.IsAbs(file) {
+		pattern = regexp.QuoteMeta(suffix) + "$"
+	}
+
+	output, err := d.executeCommand(session, "sources "+pattern)
+	if err != nil {
+		return file, false
+	}
+	for _, line := range strings.Split(output, "\n") {
+		candidate := strings.TrimSpace(line)
+		if candidate == file || filepath.IsAbs(candidate) && strings.HasSuffix(filepath.ToSlash(candidate), "/"+suffix) {
+			return candidate, true
+		}
+	}
+	return file, false
+}
+
+// readSourceFile reads a source file locally, or from the debug target's host over SSH.
+// Only stdout is used; a failing read is an error rather than file content.
+func readSourceFile(ctx context.Context, input Input, path string) (string, error) {
+	if input.SSHHost == "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s locally (set ssh_host to read it from the target host): %w", path, err)
+		}
+		return string(content), nil
+	}
+
+	conn := ssh.New(input.SSHHost, input.SSHPort, input.SSHUser)
+	defer func() {
+		_ = conn.Close()
+	}()
+	var stdout, stderr bytes.Buffer
+	exitCode, err := conn.ExecuteCommandStream(ctx, "cat -- "+ssh.EscapeArg(path), nil, &stdout, &stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s on %s: %w", path, conn.GetTarget(), err)
+	}
+	if exitCode != 0 {
+		return "", fmt.Errorf("failed to read %s on %s: exit code %d: %s", path, conn.GetTarget(), exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// formatSourceWindow renders the lines around current with line numbers, marking current like `dlv list`.
+func formatSourceWindow(content string, current, around int) (string, error) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if current < 1 || current > len(lines) {
+		return "", fmt.Errorf("line %d is outside of the file (%d lines)", current, len(lines))
+	}
+
+	first := max(current-around, 1)
+	last := min(current+around, len(lines))
+	width := len(strconv.Itoa(last))
+
+	var window strings.Builder
+	for number := first; number <= last; number++ {
+		marker := "  "
+		if number == current {
+			marker = "=>"
+		}
+		window.WriteString(fmt.Sprintf("%s %*d:\t%s\n", marker, width, number, lines[number-1]))
+	}
+	return window.String(), nil
+}
+
+// parseLocation splits a file:line location.
+func parseLocation(location string) (string, int, error) {
+	match := fileLineLocation.FindStringSubmatch(location)
+	if match == nil {
+		return "", 0, fmt.Errorf("invalid location %q, expected file:line", location)
+	}
+	line, err := strconv.Atoi(match[2])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid line in location %q: %w", location, err)
+	}
+	return match[1], line, nil
+}
+
+// handleSource returns the source window around a frame or an explicit file:line location.
+func (d *Tool) handleSource(ctx context.Context, input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' first", input.SessionID)
+	}
+
+	var file string
+	var line int
+	if input.Location != "" {
+		file, line, err = parseLocation(input.Location)
+	} else {
+		file, line, err = d.frameLocation(session, input.Frame)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Files on this server are only read when the debugged binary was built from them
+	path, known := d.resolveSourcePath(session, file)
+	if !known && input.SSHHost == "" {
+		return nil, fmt.Errorf("%s is not a source file of the debugged binary", path)
+	}
+	content, err := readSourceFile(ctx, input, path)
+	if err != nil {
+		return nil, err
+	}
+
+	around := defaultSourceContext
+	if input.ContextLines > 0 {
+		around = input.ContextLines
+	}
+	window, err := formatSourceWindow(content, line, around)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	header := fmt.Sprintf("Session %s - Source: %s:%d\n", input.SessionID, path, line)
+	return paginateResult(header, window, input), nil
+}
+
+// disassembleCommand builds the dlv disassemble command for the requested range, function or frame.
+func disassembleCommand(input Input) (string, error) {
+	switch {
+	case input.StartPC != "" || input.EndPC != "":
+		if input.StartPC == "" || input.EndPC == "" {
+			return "", errors.New("both start_pc and end_pc are required for a PC range")
+		}
+		return fmt.Sprintf("disassemble -a %s %s", input.StartPC, input.EndPC), nil
+	case input.Function != "":
+		return "disassemble -l " + input.Function, nil
+	case input.Frame > 0:
+		return fmt.Sprintf("frame %d disassemble", input.Frame), nil
+	default:
+		return "disassemble", nil
+	}
+}
+
+// handleDisassemble disassembles a PC range, a function or the function of a frame.
+// The current instruction is marked with "=>".
+func (d *Tool) handleDisassemble(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' first", input.SessionID)
+	}
+
+	command, err := disassembleCommand(input)
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	output, err := d.executeCommand(session, command)
+	d.recordCommand(session, input, command, output, err, started)
+	if err != nil {
+		return nil, fmt.Errorf("failed to disassemble: %w", err)
+	}
+
+	header := fmt.Sprintf("Session %s - Command: %s\n", input.SessionID, command)
+	return paginateResult(header, output, input), nil
+}
@@ -0,0 +1,235 @@
+package delve
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+const defaultTraceBufferSize = 1000
+
+var (
+	// traceCallLine matches the line dlv prints when a tracepoint is hit,
+	// e.g. "> goroutine(1): main.fib(n = 10)" or "> [name] goroutine(1): main.fib(10)".
+	traceCallLine = regexp.MustCompile(`^> (?:\[[^\]]*\] )?goroutine\((\d+)\): ((?:[^\s(]|\([^)\s]*\))+)\((.*)\)\s*$`)
+	// traceReturnLine matches the line dlv prints when a traced function returns,
+	// e.g. ">> goroutine(1): main.fib => (55)".
+	traceReturnLine = regexp.MustCompile(`^>> (?:\[[^\]]*\] )?goroutine\((\d+)\):\s*(\S*)\s*=> \((.*)\)\s*$`)
+)
+
+// TraceEvent is a tracepoint hit.
+type TraceEvent struct {
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Goroutine int       `json:"goroutine"`
+	Function  string    `json:"function,omitempty"`
+	Kind      string    `json:"kind"` // call or return
+	Args      string    `json:"args,omitempty"`
+	Return    string    `json:"return,omitempty"`
+}
+
+// traceBuffer keeps the most recent trace events in a ring buffer.
+type traceBuffer struct {
+	pattern string
+
+	mu     sync.Mutex
+	events []TraceEvent
+	next   int // Index of the slot written next
+	total  int // Events seen since tracing started
+}
+
+func newTraceBuffer(pattern string, size int) *traceBuffer {
+	return &traceBuffer{
+		pattern: pattern,
+		events:  make([]TraceEvent, 0, size),
+	}
+}
+
+// add appends an event, overwriting the oldest one when the buffer is full.
+func (t *traceBuffer) add(event TraceEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total++
+	event.Seq = t.total
+	if len(t.events) < cap(t.events) {
+		t.events = append(t.events, event)
+		return
+	}
+	t.events[t.next] = event
+	t.next = (t.next + 1) % len(t.events)
+}
+
+// snapshot returns the buffered events in order and the number of events seen.
+func (t *traceBuffer) snapshot() ([]TraceEvent, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := make([]TraceEvent, 0, len(t.events))
+	events = append(events, t.events[t.next:]...)
+	events = append(events, t.events[:t.next]...)
+	return events, t.total
+}
+
+// parseTraceLine converts a line of dlv output into a trace event.
+func parseTraceLine(line string) (TraceEvent, bool) {
+	line = strings.TrimSpace(line)
+	if match := traceReturnLine.FindStringSubmatch(line); match != nil {
+		goroutine, _ := strconv.Atoi(match[1])
+		return TraceEvent{Goroutine: goroutine, Function: match[2], Kind: "return", Return: match[3]}, true
+	}
+	if match := traceCallLine.FindStringSubmatch(line); match != nil {
+		goroutine, _ := strconv.Atoi(match[1])
+		return TraceEvent{Goroutine: goroutine, Function: match[2], Kind: "call", Args: match[3]}, true
+	}
+	return TraceEvent{}, false
+}
+
+// handleOutputLine records tracepoint hits printed while the target runs.
+func (d *Tool) handleOutputLine(session *DelveSession, line string) {
+	session.stateMu.Lock()
+	trace := session.trace
+	session.stateMu.Unlock()
+	if trace == nil {
+		return
+	}
+
+	if event, ok := parseTraceLine(line); ok {
+		event.Time = time.Now().UTC()
+		trace.add(event)
+	}
+}
+
+// supportsTerminalCommands rejects actions that rely on dlv terminal commands the DAP backend does not provide.
+func supportsTerminalCommands(session *DelveSession, action string) error {
+	if _, ok := session.backend.(*dapBackend); ok {
+		return fmt.Errorf("%s is only supported by the cli backend", action)
+	}
+	return nil
+}
+
+// handleTrace sets tracepoints on the functions matching a regular expression and resumes the target.
+// Hits are collected while the target runs and are returned by 'trace_events'.
+func (d *Tool) handleTrace(input Input, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := supportsTerminalCommands(session, "trace"); err != nil {
+		return nil, err
+	}
+	if input.Function == "" {
+		return nil, errors.New("function is required: a regular expression of the functions to trace")
+	}
+	if strings.Contains(input.Function, "/") {
+		// dlv ends the /regex/ location at the next slash; match package paths with '.' instead
+		return nil, errors.New("function regular expression must not contain '/'")
+	}
+	if _, err := regexp.Compile(input.Function); err != nil {
+		return nil, fmt.Errorf("invalid function regular expression: %w", err)
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'halt' before changing tracepoints", input.SessionID)
+	}
+
+	command := fmt.Sprintf("trace /%s/", input.Function)
+	started := time.Now()
+	output, err := d.executeCommand(session, command)
+	d.recordCommand(session, input, command, output, err, started)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set tracepoints: %w", err)
+	}
+
+	size := defaultTraceBufferSize
+	if input.BufferSize > 0 {
+		size = input.BufferSize
+	}
+	session.stateMu.Lock()
+	session.trace = newTraceBuffer(input.Function, size)
+	session.stateMu.Unlock()
+
+	if _, err := d.startExecution(session, "continue", notifier); err != nil {
+		return nil, fmt.Errorf("failed to resume target: %w", err)
+	}
+	d.recordCommand(session, input, "continue", "", nil, started)
+
+	resultText := fmt.Sprintf("Session %s - Tracing /%s/\n\n%s\nTarget is running. Use 'trace_events' to read hits (up to %d are kept) and 'halt' to stop.",
+		input.SessionID, input.Function, strings.TrimSpace(output), size)
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText,
+			},
+		},
+	}, nil
+}
+
+// handleTraceEvents returns the buffered trace events, oldest first.
+func (d *Tool) handleTraceEvents(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session.stateMu.Lock()
+	trace := session.trace
+	session.stateMu.Unlock()
+	if trace == nil {
+		return nil, fmt.Errorf("session %s is not tracing. Use the 'trace' action first", input.SessionID)
+	}
+
+	events, total := trace.snapshot()
+	var output strings.Builder
+	for _, event := range events {
+		timestamp := event.Time.Format(time.RFC3339Nano)
+		if event.Kind == "return" {
+			output.WriteString(fmt.Sprintf("#%d %s goroutine(%d) %s => (%s)\n", event.Seq, timestamp, event.Goroutine, event.Function, event.Return))
+		} else {
+			output.WriteString(fmt.Sprintf("#%d %s goroutine(%d) %s(%s)\n", event.Seq, timestamp, event.Goroutine, event.Function, event.Args))
+		}
+	}
+
+	header := fmt.Sprintf("Session %s - Trace /%s/: %d events buffered, %d total, %d dropped\n",
+		input.SessionID, trace.pattern, len(events), total, total-len(events))
+	return paginateResult(header, output.String(), input), nil
+}
+
+// handleWatch sets a hardware watchpoint on an expression.
+func (d *Tool) handleWatch(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := supportsTerminalCommands(session, "watch"); err != nil {
+		return nil, err
+	}
+	if input.Expression == "" {
+		return nil, errors.New("expression is required for watch")
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' first", input.SessionID)
+	}
+
+	mode := "w"
+	if input.WatchMode != "" {
+		mode = input.WatchMode
+	}
+
+	command := fmt.Sprintf("watch -%s %s", mode, input.Expression)
+	started := time.Now()
+	output, err := d.executeCommand(session, command)
+	d.recordCommand(session, input, command, output, err, started)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set watchpoint: %w", err)
+	}
+
+	header := fmt.Sprintf("Session %s - Command: %s\n", input.SessionID, command)
+	return paginateResult(header, output, input), nil
+}
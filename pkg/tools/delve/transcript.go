@@ -0,0 +1,383 @@
+package delve
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	transcriptDirPerm  = 0o700
+	transcriptFilePerm = 0o600
+	transcriptTimeFmt  = "20060102T150405"
+	maxTranscriptLine  = 16 * 1024 * 1024
+	maxTranscripts     = 256 // Recorded sessions whose transcript files are kept
+)
+
+// unsafeFileChars matches characters that are not allowed in transcript file names.
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// replaySkipCommands end the debugging session and are never replayed.
+var replaySkipCommands = map[string]bool{
+	"exit":   true,
+	"quit":   true,
+	"q":      true,
+	"detach": true,
+}
+
+// TranscriptEntry is one line of a session transcript.
+type TranscriptEntry struct {
+	Time       time.Time `json:"time"`
+	SessionID  string    `json:"session_id"`
+	Client     string    `json:"client,omitempty"` // MCP client that performed the action
+	Action     string    `json:"action"`           // connect, command, stop, halt, join, leave, handoff or disconnect
+	Command    string    `json:"command,omitempty"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+}
+
+// transcript appends entries to a per-session JSONL file.
+type transcript struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openTranscript creates a new transcript file for a session in dir.
+func openTranscript(dir, sessionID string) (*transcript, error) {
+	if err := os.MkdirAll(dir, transcriptDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.jsonl", unsafeFileChars.ReplaceAllString(sessionID, "_"), time.Now().UTC().Format(transcriptTimeFmt))
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, transcriptFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transcript: %w", err)
+	}
+	return &transcript{path: path, file: file}, nil
+}
+
+// record appends an entry. It is a no-op on a nil transcript.
+func (t *transcript) record(entry TranscriptEntry) error {
+	if t == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file == nil {
+		return errors.New("transcript is closed")
+	}
+	_, err = t.file.Write(append(line, '\n'))
+	return err
+}
+
+// close closes the transcript file. It is a no-op on a nil transcript.
+func (t *transcript) close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.file != nil {
+		_ = t.file.Close()
+		t.file = nil
+	}
+}
+
+// readTranscript parses a JSONL transcript file.
+func readTranscript(path string) ([]TranscriptEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var entries []TranscriptEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, readBufferSize), maxTranscriptLine)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry TranscriptEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid transcript line %d: %w", lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return entries, nil
+}
+
+// recordEntry writes an entry to the session transcript, logging failures instead of failing the command.
+func (d *Tool) recordEntry(session *DelveSession, entry TranscriptEntry) {
+	if err := session.transcript.record(entry); err != nil {
+		d.logger.Warn().Err(err).Msgf("Failed to record transcript entry for session %s", entry.SessionID)
+	}
+}
+
+// startTranscript opens the transcript of a new session, unless transcripts are disabled. It is
+// called before the session is published or its backend started, which record entries to it.
+func (d *Tool) startTranscript(sessionID string, session *DelveSession) {
+	if d.transcriptDir == "" {
+		return
+	}
+	t, err := openTranscript(d.transcriptDir, sessionID)
+	if err != nil {
+		d.logger.Warn().Err(err).Msgf("Transcript disabled for session %s", sessionID)
+		return
+	}
+	session.transcript = t
+}
+
+// discardTranscript closes and removes the transcript of a session that failed to connect.
+func (d *Tool) discardTranscript(session *DelveSession) {
+	if session.transcript == nil {
+		return
+	}
+	session.transcript.close()
+	_ = os.Remove(session.transcript.path)
+}
+
+// rememberTranscript makes the transcript of a connected session available by session ID.
+// Only the most recent maxTranscripts sessions are kept: the files of older ones, and the
+// previous file of a reconnected session ID, are deleted unless a connected session still writes them.
+func (d *Tool) rememberTranscript(sessionID string, session *DelveSession) {
+	if session.transcript == nil {
+		return
+	}
+	live := d.liveTranscripts()
+	live[session.transcript.path] = true
+
+	var evicted []string
+	d.transcriptMu.Lock()
+	if previous, exists := d.transcripts[sessionID]; exists {
+		for i, id := range d.transcriptOrder {
+			if id == sessionID {
+				d.transcriptOrder = append(d.transcriptOrder[:i], d.transcriptOrder[i+1:]...)
+				break
+			}
+		}
+		evicted = append(evicted, previous)
+	}
+	d.transcripts[sessionID] = session.transcript.path
+	d.transcriptOrder = append(d.transcriptOrder, sessionID)
+	for len(d.transcriptOrder) > maxTranscripts {
+		evicted = append(evicted, d.transcripts[d.transcriptOrder[0]])
+		delete(d.transcripts, d.transcriptOrder[0])
+		d.transcriptOrder = d.transcriptOrder[1:]
+	}
+	d.transcriptMu.Unlock()
+
+	for _, path := range evicted {
+		if live[path] {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			d.logger.Warn().Err(err).Msgf("Failed to remove transcript %s", path)
+		}
+	}
+}
+
+// liveTranscripts returns the transcript paths of the connected sessions.
+func (d *Tool) liveTranscripts() map[string]bool {
+	d.sessionMu.RLock()
+	defer d.sessionMu.RUnlock()
+	live := make(map[string]bool, len(d.sessions))
+	for _, session := range d.sessions {
+		if session.transcript != nil {
+			live[session.transcript.path] = true
+		}
+	}
+	return live
+}
+
+// transcriptPath resolves the transcript of a session, which stays available after disconnecting.
+func (d *Tool) transcriptPath(sessionID string) (string, error) {
+	d.transcriptMu.Lock()
+	path, ok := d.transcripts[sessionID]
+	d.transcriptMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no transcript recorded for session %s", sessionID)
+	}
+	return path, nil
+}
+
+// resolveTranscript resolves a transcript given by the ID of a recorded session, or by the path of
+// a file in the transcript directory. Other files on the server are never read.
+func (d *Tool) resolveTranscript(name string) (string, error) {
+	if path, err := d.transcriptPath(name); err == nil {
+		return path, nil
+	}
+	if d.transcriptDir == "" {
+		return "", fmt.Errorf("no transcript recorded for session %s", name)
+	}
+
+	dir, err := filepath.EvalSymlinks(d.transcriptDir)
+	if err != nil {
+		return "", fmt.Errorf("no transcript recorded for session %s", name)
+	}
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(d.transcriptDir, name)
+	}
+	path, err := filepath.EvalSymlinks(name)
+	if err != nil {
+		return "", fmt.Errorf("transcript %s is neither a recorded session nor a file in %s", name, d.transcriptDir)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("transcript %s is neither a recorded session nor a file in %s", name, d.transcriptDir)
+	}
+	return path, nil
+}
+
+// handleExportTranscript returns the JSONL transcript of a session.
+func (d *Tool) handleExportTranscript(input Input) (*mcp.CallToolResultFor[Output], error) {
+	path, err := d.transcriptPath(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	header := fmt.Sprintf("Session %s - Transcript: %s\n", input.SessionID, path)
+	return paginateResult(header, string(content), input), nil
+}
+
+// handleReplay re-runs the commands of a transcript against a connected session.
+func (d *Tool) handleReplay(input Input) (*mcp.CallToolResultFor[Output], error) {
+	session, err := d.getSession(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.isRunning() {
+		return nil, fmt.Errorf("target in session %s is running. Use 'wait_for_stop' or 'halt' before replaying", input.SessionID)
+	}
+
+	path, err := d.resolveTranscript(input.Transcript)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readTranscript(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []TranscriptEntry
+	for _, entry := range entries {
+		// Execution commands that returned while running are completed by the following stop
+		if entry.Action == "stop" && len(steps) > 0 && isExecutionCommand(steps[len(steps)-1].Command) && steps[len(steps)-1].Output == "" {
+			steps[len(steps)-1].Output = entry.Output
+			continue
+		}
+		fields := strings.Fields(entry.Command)
+		if entry.Action != "command" || entry.Error != "" || len(fields) == 0 || replaySkipCommands[fields[0]] || strings.ContainsAny(entry.Command, "\r\n") {
+			continue
+		}
+		steps = append(steps, entry)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("transcript %s contains no commands to replay", path)
+	}
+
+	timeout := defaultWaitTimeout
+	if input.Timeout > 0 {
+		timeout = time.Duration(input.Timeout) * time.Second
+	}
+
+	var report strings.Builder
+	for i, step := range steps {
+		report.WriteString(fmt.Sprintf("[%d/%d] %s\n", i+1, len(steps), step.Command))
+
+		output, err := d.replayCommand(session, input, step.Command, timeout)
+		if err != nil {
+			report.WriteString(fmt.Sprintf("error: %v\n", err))
+			if session.isRunning() {
+				report.WriteString(fmt.Sprintf("\nReplay stopped at step %d: target is still running.\n", i+1))
+				break
+			}
+			continue
+		}
+
+		report.WriteString(strings.TrimSpace(output) + "\n")
+		if strings.TrimSpace(output) == strings.TrimSpace(step.Output) {
+			report.WriteString("(matches transcript)\n")
+		} else {
+			report.WriteString("(differs from transcript)\n")
+		}
+	}
+
+	header := fmt.Sprintf("Session %s - Replay of %s (%d commands)\n", input.SessionID, path, len(steps))
+	return paginateResult(header, report.String(), input), nil
+}
+
+// replayCommand runs one transcript command, waiting for execution commands to stop.
+func (d *Tool) replayCommand(session *DelveSession, input Input, command string, timeout time.Duration) (string, error) {
+	started := time.Now()
+	if !isExecutionCommand(command) {
+		output, err := d.executeCommand(session, command)
+		d.recordCommand(session, input, command, output, err, started)
+		return output, err
+	}
+
+	stopped, err := d.startExecution(session, command, nil)
+	if err != nil {
+		d.recordCommand(session, input, command, "", err, started)
+		return "", err
+	}
+
+	select {
+	case <-stopped:
+		_, output, _ := session.stopState()
+		d.recordCommand(session, input, command, output, nil, started)
+		return output, nil
+	case <-time.After(timeout):
+		err := fmt.Errorf("target did not stop within %s", timeout)
+		d.recordCommand(session, input, command, "", err, started)
+		return "", err
+	}
+}
+
+// recordCommand records the outcome of a command started at started.
+func (d *Tool) recordCommand(session *DelveSession, input Input, command, output string, err error, started time.Time) {
+	entry := TranscriptEntry{
+		Time:       started.UTC(),
+		SessionID:  input.SessionID,
+		Client:     input.client,
+		Action:     "command",
+		Command:    command,
+		Output:     output,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	d.recordEntry(session, entry)
+}
@@ -0,0 +1,204 @@
+package gobinary
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
+)
+
+type Input struct {
+	Host         string `json:"host,omitempty" validate:"omitempty,hostname|ip"`                           // SSH host or ~/.ssh/config alias (omit for a pod or the local host)
+	Port         int    `json:"port,omitempty" validate:"min=0,max=65535"`                                 // SSH port (default: from ssh config, else 22)
+	User         string `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"` // SSH user (default: from ssh config, else current user)
+	Pod          string `json:"pod,omitempty" validate:"omitempty,max=253"`                                // Pod to read binaries from with kubectl cp, "name" or "pod/name" (instead of host)
+	Namespace    string `json:"namespace,omitempty" validate:"omitempty,alphanum|contains=-,max=63"`       // Namespace of the pod (default: "default")
+	Container    string `json:"container,omitempty" validate:"omitempty,max=253"`                          // Container in the pod (default: kubectl's default container)
+	KubeConfig   string `json:"kubeconfig,omitempty" validate:"omitempty,filepath"`                        // Path to kubeconfig file
+	Path         string `json:"path,omitempty" validate:"omitempty,max=4096"`                              // Binary on the target, or a local binary without host and pod
+	PID          int    `json:"pid,omitempty" validate:"min=0,max=2147483647"`                             // Read the executable of this process instead of path
+	ComparePath  string `json:"compare_path,omitempty" validate:"omitempty,max=4096"`                      // Second binary to diff the build information with, on the same target
+	ComparePID   int    `json:"compare_pid,omitempty" validate:"min=0,max=2147483647"`                     // Diff with the executable of this process instead of compare_path
+	CompareLocal bool   `json:"compare_local,omitempty"`                                                   // compare_path is a local file, e.g. a fresh build to diff with the deployed binary
+	Vulns        bool   `json:"vulns,omitempty"`                                                           // Check the Go version and modules against the offline vulnerability database
+	VulnDB       string `json:"vuln_db,omitempty" validate:"omitempty,max=4096"`                           // Directory or zip of OSV JSON entries on the server (default: -vuln-db), implies vulns
+	MaxLines     int    `json:"max_lines,omitempty" validate:"min=0,max=100000"`                           // Maximum lines to return (default: 200)
+	Offset       int    `json:"offset,omitempty" validate:"min=0"`                                         // Line offset for pagination
+}
+
+type Output struct {
+	Binary  *Binary     `json:"binary"`
+	Compare *Binary     `json:"compare,omitempty"`
+	Diff    *Diff       `json:"diff,omitempty"`
+	Vulns   *VulnReport `json:"vulns,omitempty"` // Vulnerabilities of binary
+}
+
+type Tool struct {
+	logger    zerolog.Logger
+	validator *validator.Validate
+}
+
+func (t *Tool) Register(srv *server.Server) {
+	goBinaryTool := &mcp.Tool{
+		Name:        "gobinary",
+		Description: "Report the Go build information of a binary on a remote host, in a pod, of a running process or a local file: Go version, modules, replaces, VCS revision, build settings, symbols and DWARF; diff two binaries; check modules against an offline Go vulnerability database",
+	}
+
+	mcp.AddTool(&srv.Server, goBinaryTool, t.GoBinaryHandler)
+	t.logger.Debug().Msg("gobinary tool registered")
+}
+
+func (t *Tool) GoBinaryHandler(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
+	input := params.Arguments
+
+	// Validate input using validator
+	if err := t.validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	options := target.Options{
+		Host:       input.Host,
+		Port:       input.Port,
+		User:       input.User,
+		Pod:        input.Pod,
+		Namespace:  input.Namespace,
+		Container:  input.Container,
+		KubeConfig: input.KubeConfig,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if (input.Path == "") == (input.PID == 0) {
+		return nil, errors.New("specify either path or pid")
+	}
+	if input.ComparePath != "" && input.ComparePID > 0 {
+		return nil, errors.New("cannot specify both compare_path and compare_pid - choose one")
+	}
+	if input.CompareLocal && input.ComparePath == "" {
+		return nil, errors.New("compare_local requires compare_path")
+	}
+	vulnDB := input.VulnDB
+	if vulnDB == "" && input.Vulns {
+		vulnDB = VulnDB()
+		if vulnDB == "" {
+			return nil, errNoVulnDB
+		}
+	}
+
+	maxLines := types.MaxDefaultLines
+	if input.MaxLines > 0 {
+		maxLines = input.MaxLines
+	}
+
+	conn := target.New(options)
+	defer func() {
+		_ = conn.Close()
+	}()
+	var localPath string
+	dir, err := os.MkdirTemp("", "remote-debugger-mcp-gobinary-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	var output Output
+	output.Binary, localPath, err = t.read(ctx, conn, input.Path, input.PID, dir)
+	if err != nil {
+		return nil, err
+	}
+	text := ""
+	switch {
+	case input.ComparePath != "" || input.ComparePID > 0:
+		compareConn := conn
+		if input.CompareLocal {
+			compareConn = target.New(target.Options{})
+		}
+		output.Compare, _, err = t.read(ctx, compareConn, input.ComparePath, input.ComparePID, dir)
+		if err != nil {
+			return nil, err
+		}
+		output.Diff = diffBinaries(output.Binary, output.Compare)
+		text = formatDiff(output.Diff, output.Binary, output.Compare)
+	default:
+		text = formatBinary(output.Binary)
+	}
+	if vulnDB != "" {
+		output.Vulns, err = t.checkVulns(vulnDB, output.Binary, localPath)
+		if err != nil {
+			return nil, err
+		}
+		text += formatVulns(output.Vulns)
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: types.Paginate(text, maxLines, input.Offset),
+			},
+		},
+		StructuredContent: output,
+	}, nil
+}
+
+// read fetches the binary at path, or the executable of pid, into dir and reads its build information.
+// It returns the local path of the binary too.
+func (t *Tool) read(ctx context.Context, conn *target.Target, path string, pid int, dir string) (*Binary, string, error) {
+	source := conn.Name() + ":" + path
+	if pid > 0 {
+		// The executable link works for deleted binaries; its target is shown when readable
+		path = fmt.Sprintf("/proc/%d/exe", pid)
+		exe, err := conn.ExecuteCommand(ctx, "readlink "+path)
+		exe = strings.TrimSpace(exe)
+		if err != nil || exe == "" {
+			return nil, "", fmt.Errorf("failed to read the executable of process %d on %s: %s", pid, conn.Name(), strings.TrimSpace(exe))
+		}
+		source = fmt.Sprintf("%s:%s (PID %d)", conn.Name(), exe, pid)
+		if conn.Kube() != nil {
+			// kubectl cp copies the link itself, not the file it points to
+			path = strings.TrimSuffix(exe, " (deleted)")
+		}
+	}
+
+	t.logger.Debug().Str("source", source).Msg("reading go binary")
+	localPath, err := conn.FetchFile(ctx, path, dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %w", source, err)
+	}
+	binary, err := readBinary(localPath, source)
+	return binary, localPath, err
+}
+
+// checkVulns matches the binary at localPath against the vulnerability database at path.
+func (t *Tool) checkVulns(path string, binary *Binary, localPath string) (*VulnReport, error) {
+	db, err := openVulnDB(path)
+	if err != nil {
+		return nil, err
+	}
+	t.logger.Debug().Str("database", path).Int("entries", db.entries).Int("skipped", db.skipped).Msg("loaded vulnerability database")
+
+	symbols, err := readSymbols(localPath)
+	if err != nil {
+		t.logger.Debug().Err(err).Str("source", binary.Source).Msg("failed to read symbols")
+		symbols = nil
+	}
+	return db.check(binary, symbols), nil
+}
+
+func New(logger zerolog.Logger) tools.Tool {
+	validate := validator.New()
+
+	return &Tool{
+		logger:    logger.With().Str("tool", "gobinary").Logger(),
+		validator: validate,
+	}
+}
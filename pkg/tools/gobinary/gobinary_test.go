@@ -0,0 +1,119 @@
+package gobinary
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+)
+
+type GoBinaryTestSuite struct {
+	suite.Suite
+	tool *Tool
+}
+
+func TestGoBinaryTestSuite(t *testing.T) {
+	suite.Run(t, new(GoBinaryTestSuite))
+}
+
+func (suite *GoBinaryTestSuite) SetupTest() {
+	suite.tool = &Tool{
+		logger:    zerolog.Nop(),
+		validator: validator.New(),
+	}
+}
+
+func (suite *GoBinaryTestSuite) call(input Input) (*mcp.CallToolResultFor[Output], error) {
+	return suite.tool.GoBinaryHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{Arguments: input})
+}
+
+func (suite *GoBinaryTestSuite) TestInputValidation() {
+	testCases := []struct {
+		name     string
+		input    Input
+		errorMsg string
+	}{
+		{
+			name:     "no path or pid",
+			input:    Input{Host: "192.168.1.100"},
+			errorMsg: "specify either path or pid",
+		},
+		{
+			name:     "path and pid",
+			input:    Input{Path: "/usr/local/bin/app", PID: 1},
+			errorMsg: "specify either path or pid",
+		},
+		{
+			name:     "compare path and pid",
+			input:    Input{Path: "/usr/local/bin/app", ComparePath: "/tmp/app", ComparePID: 1},
+			errorMsg: "cannot specify both compare_path and compare_pid",
+		},
+		{
+			name:     "compare local without path",
+			input:    Input{Path: "/usr/local/bin/app", CompareLocal: true},
+			errorMsg: "compare_local requires compare_path",
+		},
+		{
+			name:     "host and pod",
+			input:    Input{Host: "192.168.1.100", Pod: "app-0", Path: "/app"},
+			errorMsg: "cannot specify both host and pod",
+		},
+		{
+			name:     "container without pod",
+			input:    Input{Container: "app", Path: "/app"},
+			errorMsg: "namespace, container and kubeconfig require pod",
+		},
+		{
+			name:     "invalid host",
+			input:    Input{Host: "bad host", Path: "/app"},
+			errorMsg: "validation error",
+		},
+		{
+			name:     "negative pid",
+			input:    Input{PID: -1},
+			errorMsg: "validation error",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			result, err := suite.call(tc.input)
+			suite.ErrorContains(err, tc.errorMsg)
+			suite.Nil(result)
+		})
+	}
+}
+
+func (suite *GoBinaryTestSuite) TestLocal() {
+	executable, err := os.Executable()
+	suite.Require().NoError(err)
+
+	// The test binary is a Go binary with build information
+	result, err := suite.call(Input{Path: executable})
+	suite.Require().NoError(err)
+	binary := result.StructuredContent.Binary
+	suite.Require().NotNil(binary)
+	suite.Equal("localhost:"+executable, binary.Source)
+	suite.Equal("github.com/tb0hdan/remote-debugger-mcp", binary.Main.Path)
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Go binary localhost:"+executable)
+	suite.Contains(text, "Dependencies (")
+
+	// The executable of the running test process is the same file
+	result, err = suite.call(Input{Path: executable, ComparePID: os.Getpid()})
+	suite.Require().NoError(err)
+	suite.Require().NotNil(result.StructuredContent.Diff)
+	suite.True(result.StructuredContent.Diff.Identical)
+	suite.Contains(result.StructuredContent.Compare.Source, fmt.Sprintf("(PID %d)", os.Getpid()))
+	suite.Contains(result.Content[0].(*mcp.TextContent).Text, "Binaries are identical")
+
+	_, err = suite.call(Input{PID: 2147483647})
+	suite.ErrorContains(err, "failed to read the executable of process 2147483647")
+	_, err = suite.call(Input{Path: executable + ".missing"})
+	suite.ErrorContains(err, "failed to fetch")
+}
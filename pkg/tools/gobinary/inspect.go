@@ -0,0 +1,308 @@
+package gobinary
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Binary is the build information of a Go executable.
+type Binary struct {
+	Source     string    `json:"source"` // Target and path the binary was read from
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	Format     string    `json:"format"` // elf, macho or pe
+	GoVersion  string    `json:"go_version"`
+	Path       string    `json:"path"` // Package path of the main package
+	Main       Module    `json:"main"`
+	Deps       []Module  `json:"deps"`
+	Settings   []Setting `json:"settings"`
+	VCS        string    `json:"vcs,omitempty"`
+	Revision   string    `json:"revision,omitempty"`
+	CommitTime string    `json:"commit_time,omitempty"`
+	Modified   bool      `json:"modified"` // The working tree had uncommitted changes
+	Stripped   bool      `json:"stripped"` // No symbol table
+	HasDWARF   bool      `json:"has_dwarf"`
+}
+
+// Module is a module of the build list, with its replacement if any.
+type Module struct {
+	Path    string  `json:"path"`
+	Version string  `json:"version"`
+	Sum     string  `json:"sum,omitempty"`
+	Replace *Module `json:"replace,omitempty"`
+}
+
+// Setting is a build setting, e.g. GOOS, -ldflags or vcs.revision.
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Change is a value that differs between two binaries; empty sides were missing.
+type Change struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Diff lists the build information differences of two binaries.
+type Diff struct {
+	Identical bool     `json:"identical"` // Same SHA-256
+	GoVersion *Change  `json:"go_version,omitempty"`
+	Main      *Change  `json:"main,omitempty"`
+	Settings  []Change `json:"settings,omitempty"`
+	Deps      []Change `json:"deps,omitempty"`
+	Stripped  *Change  `json:"stripped,omitempty"`
+	HasDWARF  *Change  `json:"has_dwarf,omitempty"`
+}
+
+// readBinary reads the build information, symbol table and DWARF presence of a local file.
+func readBinary(path, source string) (*Binary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read binary: %w", err)
+	}
+
+	info, err := buildinfo.Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a Go binary or has no build information: %w", source, err)
+	}
+
+	binary := &Binary{
+		Source:    source,
+		Size:      size,
+		SHA256:    hex.EncodeToString(hash.Sum(nil)),
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Main:      newModule(&info.Main),
+	}
+	for _, dep := range info.Deps {
+		binary.Deps = append(binary.Deps, newModule(dep))
+	}
+	for _, setting := range info.Settings {
+		binary.Settings = append(binary.Settings, Setting{Key: setting.Key, Value: setting.Value})
+		switch setting.Key {
+		case "vcs":
+			binary.VCS = setting.Value
+		case "vcs.revision":
+			binary.Revision = setting.Value
+		case "vcs.time":
+			binary.CommitTime = setting.Value
+		case "vcs.modified":
+			binary.Modified = setting.Value == "true"
+		}
+	}
+
+	binary.Format, binary.Stripped, binary.HasDWARF = objectInfo(file)
+	return binary, nil
+}
+
+func newModule(module *debug.Module) Module {
+	result := Module{Path: module.Path, Version: module.Version, Sum: module.Sum}
+	if module.Replace != nil {
+		replace := newModule(module.Replace)
+		result.Replace = &replace
+	}
+	return result
+}
+
+// String formats the module version with its replacement, e.g. "v1.2.0 => ../fork".
+func (m Module) String() string {
+	version := m.Version
+	if m.Replace != nil {
+		replace := m.Replace.Path
+		if m.Replace.Version != "" {
+			replace += " " + m.Replace.Version
+		}
+		version += " => " + replace
+	}
+	return version
+}
+
+// objectInfo returns the object format and whether the symbol table and DWARF data are present.
+func objectInfo(file io.ReaderAt) (string, bool, bool) {
+	if f, err := elf.NewFile(file); err == nil {
+		stripped := f.Section(".symtab") == nil
+		dwarf := f.Section(".debug_info") != nil || f.Section(".zdebug_info") != nil
+		return "elf", stripped, dwarf
+	}
+	if f, err := macho.NewFile(file); err == nil {
+		stripped := f.Symtab == nil || len(f.Symtab.Syms) == 0
+		dwarf := f.Section("__debug_info") != nil || f.Section("__zdebug_info") != nil
+		return "macho", stripped, dwarf
+	}
+	if f, err := pe.NewFile(file); err == nil {
+		stripped := len(f.Symbols) == 0
+		dwarf := f.Section(".debug_info") != nil || f.Section(".zdebug_info") != nil
+		return "pe", stripped, dwarf
+	}
+	return "unknown", false, false
+}
+
+// diffBinaries compares the build information of two binaries.
+func diffBinaries(before, after *Binary) *Diff {
+	diff := &Diff{Identical: before.SHA256 == after.SHA256}
+	if before.GoVersion != after.GoVersion {
+		diff.GoVersion = &Change{Name: "go", Old: before.GoVersion, New: after.GoVersion}
+	}
+	beforeMain := before.Main.Path + " " + before.Main.String()
+	afterMain := after.Main.Path + " " + after.Main.String()
+	if before.Path != after.Path || beforeMain != afterMain {
+		diff.Main = &Change{Name: "main", Old: before.Path + " (" + beforeMain + ")", New: after.Path + " (" + afterMain + ")"}
+	}
+	if before.Stripped != after.Stripped {
+		diff.Stripped = &Change{Name: "stripped", Old: fmt.Sprint(before.Stripped), New: fmt.Sprint(after.Stripped)}
+	}
+	if before.HasDWARF != after.HasDWARF {
+		diff.HasDWARF = &Change{Name: "dwarf", Old: fmt.Sprint(before.HasDWARF), New: fmt.Sprint(after.HasDWARF)}
+	}
+
+	beforeSettings := make(map[string]string)
+	for _, setting := range before.Settings {
+		beforeSettings[setting.Key] = setting.Value
+	}
+	afterSettings := make(map[string]string)
+	for _, setting := range after.Settings {
+		afterSettings[setting.Key] = setting.Value
+	}
+	diff.Settings = diffMaps(beforeSettings, afterSettings)
+
+	beforeDeps := make(map[string]string)
+	for _, dep := range before.Deps {
+		beforeDeps[dep.Path] = dep.String()
+	}
+	afterDeps := make(map[string]string)
+	for _, dep := range after.Deps {
+		afterDeps[dep.Path] = dep.String()
+	}
+	diff.Deps = diffMaps(beforeDeps, afterDeps)
+	return diff
+}
+
+// diffMaps returns the keys whose values differ, sorted by key.
+func diffMaps(before, after map[string]string) []Change {
+	var changes []Change
+	for key, value := range before {
+		if afterValue, ok := after[key]; !ok || afterValue != value {
+			changes = append(changes, Change{Name: key, Old: value, New: afterValue})
+		}
+	}
+	for key, value := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, Change{Name: key, New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// empty reports whether the build information of the two binaries is the same.
+func (d *Diff) empty() bool {
+	return d.GoVersion == nil && d.Main == nil && d.Stripped == nil && d.HasDWARF == nil &&
+		len(d.Settings) == 0 && len(d.Deps) == 0
+}
+
+func formatBinary(b *Binary) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Go binary %s\n", b.Source))
+	output.WriteString(fmt.Sprintf("  Size: %d bytes, SHA-256: %s\n", b.Size, b.SHA256))
+	output.WriteString(fmt.Sprintf("  Format: %s, symbol table: %s, DWARF: %s\n", b.Format, presence(!b.Stripped), presence(b.HasDWARF)))
+	output.WriteString(fmt.Sprintf("  Go version: %s\n", b.GoVersion))
+	output.WriteString(fmt.Sprintf("  Package: %s\n", b.Path))
+	output.WriteString(fmt.Sprintf("  Module: %s %s\n", b.Main.Path, b.Main.String()))
+	if b.VCS != "" {
+		dirty := ""
+		if b.Modified {
+			dirty = " (dirty: uncommitted changes)"
+		}
+		output.WriteString(fmt.Sprintf("  VCS: %s revision %s at %s%s\n", b.VCS, b.Revision, b.CommitTime, dirty))
+	} else {
+		output.WriteString("  VCS: no revision recorded\n")
+	}
+
+	output.WriteString(fmt.Sprintf("\nBuild settings (%d):\n", len(b.Settings)))
+	for _, setting := range b.Settings {
+		output.WriteString(fmt.Sprintf("  %s=%s\n", setting.Key, setting.Value))
+	}
+
+	replaced := 0
+	for _, dep := range b.Deps {
+		if dep.Replace != nil {
+			replaced++
+		}
+	}
+	output.WriteString(fmt.Sprintf("\nDependencies (%d, %d replaced):\n", len(b.Deps), replaced))
+	for _, dep := range b.Deps {
+		output.WriteString(fmt.Sprintf("  %s %s\n", dep.Path, dep.String()))
+	}
+	return output.String()
+}
+
+func formatDiff(d *Diff, before, after *Binary) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Build info diff:\n  - %s\n  + %s\n", before.Source, after.Source))
+	if d.Identical {
+		output.WriteString("Binaries are identical (same SHA-256).\n")
+		return output.String()
+	}
+	if d.empty() {
+		output.WriteString("Binaries differ, but their build information is the same.\n")
+		return output.String()
+	}
+	for _, change := range []*Change{d.GoVersion, d.Main, d.Stripped, d.HasDWARF} {
+		if change != nil {
+			output.WriteString(fmt.Sprintf("  %s: %s -> %s\n", change.Name, change.Old, change.New))
+		}
+	}
+	if len(d.Settings) > 0 {
+		output.WriteString(fmt.Sprintf("\nBuild settings (%d changed):\n", len(d.Settings)))
+		writeChanges(&output, d.Settings)
+	}
+	if len(d.Deps) > 0 {
+		output.WriteString(fmt.Sprintf("\nDependencies (%d changed):\n", len(d.Deps)))
+		writeChanges(&output, d.Deps)
+	}
+	return output.String()
+}
+
+// writeChanges writes changes as "~ name old -> new", "+ name new" or "- name old".
+func writeChanges(output *strings.Builder, changes []Change) {
+	for _, change := range changes {
+		switch {
+		case change.Old == "":
+			output.WriteString(fmt.Sprintf("  + %s %s\n", change.Name, change.New))
+		case change.New == "":
+			output.WriteString(fmt.Sprintf("  - %s %s\n", change.Name, change.Old))
+		default:
+			output.WriteString(fmt.Sprintf("  ~ %s %s -> %s\n", change.Name, change.Old, change.New))
+		}
+	}
+}
+
+func presence(present bool) string {
+	if present {
+		return "present"
+	}
+	return "missing"
+}
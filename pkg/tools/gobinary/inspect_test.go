@@ -0,0 +1,145 @@
+package gobinary
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// helloModule writes a small main module into dir/src.
+func (suite *GoBinaryTestSuite) helloModule(dir string) {
+	src := filepath.Join(dir, "src")
+	suite.Require().NoError(os.MkdirAll(src, 0o755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(src, "go.mod"), []byte("module example.com/hello\n\ngo 1.21\n"), 0o644))
+	suite.Require().NoError(os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n\nfunc main() { println(\"hello\") }\n"), 0o644))
+}
+
+// buildHello builds the module of helloModule into dir with the given ldflags and returns the binary path.
+func (suite *GoBinaryTestSuite) buildHello(dir, name, ldflags string) string {
+	binary := filepath.Join(dir, name)
+	cmd := exec.Command("go", "build", "-o", binary, "-ldflags", ldflags, ".")
+	cmd.Dir = filepath.Join(dir, "src")
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOFLAGS=-buildvcs=false")
+	output, err := cmd.CombinedOutput()
+	suite.Require().NoError(err, string(output))
+	return binary
+}
+
+func (suite *GoBinaryTestSuite) TestReadBinary() {
+	dir := suite.T().TempDir()
+	suite.helloModule(dir)
+
+	full, err := readBinary(suite.buildHello(dir, "full", ""), "localhost:full")
+	suite.Require().NoError(err)
+	suite.Equal("localhost:full", full.Source)
+	suite.Equal("example.com/hello", full.Path)
+	suite.Equal("example.com/hello", full.Main.Path)
+	suite.True(strings.HasPrefix(full.GoVersion, "go"))
+	suite.Len(full.SHA256, 64)
+	suite.Positive(full.Size)
+	suite.NotEqual("unknown", full.Format)
+	suite.False(full.Stripped)
+	suite.Empty(full.VCS)
+
+	stripped, err := readBinary(suite.buildHello(dir, "stripped", "-s -w"), "localhost:stripped")
+	suite.Require().NoError(err)
+	suite.True(stripped.Stripped)
+	suite.False(stripped.HasDWARF)
+
+	diff := diffBinaries(full, stripped)
+	suite.False(diff.Identical)
+	suite.Nil(diff.GoVersion)
+	suite.Nil(diff.Main)
+	suite.Require().NotNil(diff.Stripped)
+	suite.Equal(Change{Name: "stripped", Old: "false", New: "true"}, *diff.Stripped)
+	suite.Contains(diff.Settings, Change{Name: "-ldflags", New: "-s -w"})
+	suite.Empty(diff.Deps)
+
+	text := formatDiff(diff, full, stripped)
+	suite.Contains(text, "stripped: false -> true")
+	suite.Contains(text, "  + -ldflags -s -w")
+
+	_, err = readBinary(filepath.Join(dir, "src", "main.go"), "localhost:main.go")
+	suite.ErrorContains(err, "localhost:main.go is not a Go binary")
+}
+
+func (suite *GoBinaryTestSuite) TestDiffBinaries() {
+	before := &Binary{
+		SHA256:    "aaa",
+		GoVersion: "go1.22.1",
+		Path:      "example.com/app/cmd/app",
+		Main:      Module{Path: "example.com/app", Version: "(devel)"},
+		Deps: []Module{
+			{Path: "github.com/rs/zerolog", Version: "v1.33.0"},
+			{Path: "golang.org/x/net", Version: "v0.20.0"},
+			{Path: "example.com/lib", Version: "v1.0.0"},
+		},
+		Settings: []Setting{{Key: "GOOS", Value: "linux"}, {Key: "vcs.revision", Value: "abc"}},
+	}
+	after := &Binary{
+		SHA256:    "bbb",
+		GoVersion: "go1.22.3",
+		Path:      "example.com/app/cmd/app",
+		Main:      Module{Path: "example.com/app", Version: "(devel)"},
+		Deps: []Module{
+			{Path: "github.com/rs/zerolog", Version: "v1.33.0"},
+			{Path: "golang.org/x/net", Version: "v0.25.0"},
+			{Path: "example.com/lib", Version: "v1.0.0", Replace: &Module{Path: "../lib"}},
+			{Path: "github.com/google/uuid", Version: "v1.6.0"},
+		},
+		Settings: []Setting{{Key: "GOOS", Value: "linux"}, {Key: "vcs.revision", Value: "def"}, {Key: "-race", Value: "true"}},
+	}
+
+	diff := diffBinaries(before, after)
+	suite.False(diff.Identical)
+	suite.False(diff.empty())
+	suite.Equal(&Change{Name: "go", Old: "go1.22.1", New: "go1.22.3"}, diff.GoVersion)
+	suite.Nil(diff.Main)
+	suite.Equal([]Change{
+		{Name: "-race", New: "true"},
+		{Name: "vcs.revision", Old: "abc", New: "def"},
+	}, diff.Settings)
+	suite.Equal([]Change{
+		{Name: "example.com/lib", Old: "v1.0.0", New: "v1.0.0 => ../lib"},
+		{Name: "github.com/google/uuid", New: "v1.6.0"},
+		{Name: "golang.org/x/net", Old: "v0.20.0", New: "v0.25.0"},
+	}, diff.Deps)
+
+	text := formatDiff(diff, before, after)
+	suite.Contains(text, "go: go1.22.1 -> go1.22.3")
+	suite.Contains(text, "  ~ example.com/lib v1.0.0 -> v1.0.0 => ../lib")
+	suite.Contains(text, "  + github.com/google/uuid v1.6.0")
+
+	// Removed dependencies and a rebuilt binary with the same build information
+	diff = diffBinaries(after, &Binary{SHA256: "ccc", GoVersion: "go1.22.3", Path: after.Path, Main: after.Main, Settings: after.Settings})
+	suite.Len(diff.Deps, 4)
+	suite.Contains(formatDiff(diff, after, before), "  - golang.org/x/net v0.25.0")
+	rebuilt := *after
+	rebuilt.SHA256 = "ddd"
+	diff = diffBinaries(after, &rebuilt)
+	suite.True(diff.empty())
+	suite.Contains(formatDiff(diff, after, &rebuilt), "build information is the same")
+	suite.True(diffBinaries(after, after).Identical)
+}
+
+func (suite *GoBinaryTestSuite) TestFormatBinary() {
+	text := formatBinary(&Binary{
+		Source:     "deploy@app-1:22:/usr/local/bin/app",
+		Format:     "elf",
+		GoVersion:  "go1.22.3",
+		Path:       "example.com/app/cmd/app",
+		Main:       Module{Path: "example.com/app", Version: "v1.4.0"},
+		Deps:       []Module{{Path: "example.com/lib", Version: "v1.0.0", Replace: &Module{Path: "example.com/fork", Version: "v1.0.1"}}},
+		VCS:        "git",
+		Revision:   "abc123",
+		CommitTime: "2024-05-01T10:00:00Z",
+		Modified:   true,
+		Stripped:   true,
+	})
+	suite.Contains(text, "Go binary deploy@app-1:22:/usr/local/bin/app")
+	suite.Contains(text, "Format: elf, symbol table: missing, DWARF: missing")
+	suite.Contains(text, "VCS: git revision abc123 at 2024-05-01T10:00:00Z (dirty: uncommitted changes)")
+	suite.Contains(text, "Dependencies (1, 1 replaced):")
+	suite.Contains(text, "  example.com/lib v1.0.0 => example.com/fork v1.0.1")
+}
@@ -0,0 +1,110 @@
+package gobinary
+
+import (
+	"debug/elf"
+	"debug/gosym"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// symbolSet holds the function names of a binary by package path, e.g. "net/http" -> "Client.Do".
+type symbolSet map[string]map[string]bool
+
+func (s symbolSet) add(name string) {
+	pkg, symbol := splitSymbol(name)
+	if pkg == "" || symbol == "" {
+		return
+	}
+	if s[pkg] == nil {
+		s[pkg] = make(map[string]bool)
+	}
+	s[pkg][symbol] = true
+}
+
+// readSymbols reads the function names of a binary. They come from the pclntab, which the linker keeps
+// in binaries stripped with -ldflags="-s -w", or from the symbol table when there is no pclntab section.
+func readSymbols(path string) (symbolSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open binary: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	symbols := make(symbolSet)
+	if f, err := elf.NewFile(file); err == nil {
+		if pclntab, text := f.Section(".gopclntab"), f.Section(".text"); pclntab != nil && text != nil {
+			return symbols, readPclntab(symbols, pclntab.Data, text.Addr)
+		}
+		syms, err := f.Symbols()
+		if err != nil {
+			return nil, fmt.Errorf("no pclntab or symbol table: %w", err)
+		}
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) == elf.STT_FUNC {
+				symbols.add(sym.Name)
+			}
+		}
+		return symbols, nil
+	}
+	if f, err := macho.NewFile(file); err == nil {
+		if pclntab, text := f.Section("__gopclntab"), f.Section("__text"); pclntab != nil && text != nil {
+			return symbols, readPclntab(symbols, pclntab.Data, text.Addr)
+		}
+		if f.Symtab == nil {
+			return nil, errors.New("no pclntab or symbol table")
+		}
+		for _, sym := range f.Symtab.Syms {
+			symbols.add(strings.TrimPrefix(sym.Name, "_"))
+		}
+		return symbols, nil
+	}
+	if f, err := pe.NewFile(file); err == nil {
+		if len(f.Symbols) == 0 {
+			return nil, errors.New("no symbol table")
+		}
+		for _, sym := range f.Symbols {
+			symbols.add(sym.Name)
+		}
+		return symbols, nil
+	}
+	return nil, errors.New("unknown object format")
+}
+
+func readPclntab(symbols symbolSet, data func() ([]byte, error), textAddr uint64) error {
+	pclntab, err := data()
+	if err != nil {
+		return fmt.Errorf("failed to read pclntab: %w", err)
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(pclntab, textAddr))
+	if err != nil {
+		return fmt.Errorf("failed to parse pclntab: %w", err)
+	}
+	for _, fn := range table.Funcs {
+		symbols.add(fn.Name)
+	}
+	return nil
+}
+
+// splitSymbol splits a linker symbol name into its package path and the symbol as the vulnerability
+// database names it: "net/http.(*Client).Do" is "net/http" and "Client.Do". Type parameters are dropped
+// and the dots the linker escapes in the last path element ("yaml%2ev3") restored.
+func splitSymbol(name string) (string, string) {
+	name = strings.ReplaceAll(name, "[...]", "")
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", ""
+	}
+	pkg := strings.ReplaceAll(name[:slash+1+dot], "%2e", ".")
+	symbol := name[slash+2+dot:]
+	if strings.HasPrefix(symbol, "(*") {
+		symbol = strings.Replace(strings.TrimPrefix(symbol, "(*"), ")", "", 1)
+	}
+	return pkg, symbol
+}
@@ -0,0 +1,574 @@
+package gobinary
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Levels of a finding, from the most to the least certain.
+const (
+	LevelSymbol  = "symbol"  // Vulnerable symbols are in the binary
+	LevelPackage = "package" // A vulnerable package is linked, the entry lists no symbols
+	LevelModule  = "module"  // The module version is affected, but its vulnerable code is not linked
+	LevelUnknown = "unknown" // The binary has no symbol information
+)
+
+// maxCachedVulnDBs is the number of parsed databases kept, e.g. the default and a few given with vuln_db.
+const maxCachedVulnDBs = 4
+
+// Modules of the Go vulnerability database that are not in the build list.
+const (
+	stdlibModule    = "stdlib"
+	toolchainModule = "toolchain"
+)
+
+var (
+	vulnDBMu sync.RWMutex
+	vulnDB   string
+
+	errNoVulnDB = errors.New("no vulnerability database: start the server with -vuln-db or set vuln_db")
+
+	// vulnDBCache keeps parsed databases by path until one of their files changes.
+	vulnDBCache = struct {
+		mu      sync.Mutex
+		entries map[string]*cachedVulnDB
+	}{entries: make(map[string]*cachedVulnDB)}
+)
+
+// cachedVulnDB is a parsed database with the modification time it was read at.
+type cachedVulnDB struct {
+	db       *vulnDatabase
+	modified time.Time
+	used     time.Time
+}
+
+// SetVulnDB sets the default vulnerability database: a directory or zip file of OSV JSON entries, such
+// as a mirror of https://vuln.go.dev. An empty path disables the default.
+func SetVulnDB(path string) error {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid vulnerability database: %w", err)
+		}
+	}
+	vulnDBMu.Lock()
+	defer vulnDBMu.Unlock()
+	vulnDB = path
+	return nil
+}
+
+// VulnDB returns the default vulnerability database.
+func VulnDB() string {
+	vulnDBMu.RLock()
+	defer vulnDBMu.RUnlock()
+	return vulnDB
+}
+
+// osvEntry is the part of an OSV entry used for matching Go modules.
+type osvEntry struct {
+	ID        string        `json:"id"`
+	Aliases   []string      `json:"aliases"`
+	Summary   string        `json:"summary"`
+	Withdrawn string        `json:"withdrawn"`
+	Affected  []osvAffected `json:"affected"`
+}
+
+type osvAffected struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Ranges []struct {
+		Type   string `json:"type"`
+		Events []struct {
+			Introduced string `json:"introduced"`
+			Fixed      string `json:"fixed"`
+		} `json:"events"`
+	} `json:"ranges"`
+	EcosystemSpecific struct {
+		Imports []osvImport `json:"imports"`
+	} `json:"ecosystem_specific"`
+}
+
+type osvImport struct {
+	Path    string   `json:"path"`
+	GOOS    []string `json:"goos"`
+	GOARCH  []string `json:"goarch"`
+	Symbols []string `json:"symbols"`
+}
+
+// vulnDatabase holds the entries of a vulnerability database by module path.
+type vulnDatabase struct {
+	path    string
+	entries int
+	skipped int // Files that are not OSV entries, e.g. the index
+	modules map[string][]*osvEntry
+}
+
+// Finding is a vulnerability affecting a module of the binary.
+type Finding struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Summary  string   `json:"summary"`
+	Module   string   `json:"module"`  // Module path, or "stdlib" for the Go standard library
+	Version  string   `json:"version"` // Version in the binary
+	Fixed    string   `json:"fixed"`   // Earliest fixed version, empty when there is no fix
+	Level    string   `json:"level"`   // symbol, package, module or unknown
+	Packages []string `json:"packages,omitempty"`
+	Symbols  []string `json:"symbols,omitempty"` // Vulnerable symbols found in the binary
+}
+
+// VulnReport is the result of checking a binary against a vulnerability database.
+type VulnReport struct {
+	Database string    `json:"database"`
+	Entries  int       `json:"entries"`
+	Symbols  bool      `json:"symbols"` // Whether the binary's symbols could be read
+	Findings []Finding `json:"findings"`
+}
+
+// openVulnDB returns the database at path, parsing it again only when it was modified since it was cached.
+func openVulnDB(path string) (*vulnDatabase, error) {
+	modified, err := vulnDBModified(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database: %w", err)
+	}
+
+	vulnDBCache.mu.Lock()
+	defer vulnDBCache.mu.Unlock()
+	if cached, ok := vulnDBCache.entries[path]; ok && cached.modified.Equal(modified) {
+		cached.used = time.Now()
+		return cached.db, nil
+	}
+	db, err := loadVulnDB(path)
+	if err != nil {
+		delete(vulnDBCache.entries, path)
+		return nil, err
+	}
+	if _, ok := vulnDBCache.entries[path]; !ok && len(vulnDBCache.entries) >= maxCachedVulnDBs {
+		var oldest string
+		for name, cached := range vulnDBCache.entries {
+			if oldest == "" || cached.used.Before(vulnDBCache.entries[oldest].used) {
+				oldest = name
+			}
+		}
+		delete(vulnDBCache.entries, oldest)
+	}
+	vulnDBCache.entries[path] = &cachedVulnDB{db: db, modified: modified, used: time.Now()}
+	return db, nil
+}
+
+// vulnDBModified returns the modification time of a zip file, or the newest one of the directories and
+// entries of a directory tree, since updating a mirror does not touch its root.
+func vulnDBModified(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	modified := info.ModTime()
+	if !info.IsDir() {
+		return modified, nil
+	}
+	err = filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && filepath.Ext(name) != ".json" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modified) {
+			modified = info.ModTime()
+		}
+		return nil
+	})
+	return modified, err
+}
+
+// loadVulnDB reads the OSV entries of a directory tree or zip file.
+func loadVulnDB(path string) (*vulnDatabase, error) {
+	db := &vulnDatabase{path: path, modules: make(map[string][]*osvEntry)}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database: %w", err)
+	}
+
+	if !info.IsDir() {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("vulnerability database must be a directory or zip file: %w", err)
+		}
+		defer func() {
+			_ = archive.Close()
+		}()
+		for _, file := range archive.File {
+			if file.FileInfo().IsDir() || filepath.Ext(file.Name) != ".json" {
+				continue
+			}
+			reader, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+			}
+			db.add(reader)
+			_ = reader.Close()
+		}
+	} else {
+		err = filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || filepath.Ext(name) != ".json" {
+				return nil
+			}
+			file, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			db.add(file)
+			return file.Close()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vulnerability database: %w", err)
+		}
+	}
+
+	if db.entries == 0 {
+		return nil, fmt.Errorf("no OSV entries in vulnerability database %s", path)
+	}
+	return db, nil
+}
+
+func (db *vulnDatabase) add(reader io.Reader) {
+	var entry osvEntry
+	if err := json.NewDecoder(reader).Decode(&entry); err != nil || entry.ID == "" {
+		db.skipped++
+		return
+	}
+	db.entries++
+	if entry.Withdrawn != "" {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, affected := range entry.Affected {
+		module := affected.Package.Name
+		if affected.Package.Ecosystem != "Go" || module == toolchainModule || seen[module] {
+			continue
+		}
+		seen[module] = true
+		db.modules[module] = append(db.modules[module], &entry)
+	}
+}
+
+// check matches the build list and Go version of a binary. symbols is nil when they could not be read.
+func (db *vulnDatabase) check(b *Binary, symbols symbolSet) *VulnReport {
+	report := &VulnReport{Database: db.path, Entries: db.entries, Symbols: symbols != nil}
+	goos, goarch := "", ""
+	for _, setting := range b.Settings {
+		switch setting.Key {
+		case "GOOS":
+			goos = setting.Value
+		case "GOARCH":
+			goarch = setting.Value
+		}
+	}
+
+	modules := []Module{{Path: stdlibModule, Version: goSemver(b.GoVersion)}}
+	for _, dep := range b.Deps {
+		if dep.Replace != nil {
+			if dep.Replace.Version == "" {
+				// Replaced by a local directory: the version of the code is unknown
+				continue
+			}
+			dep = *dep.Replace
+		}
+		modules = append(modules, Module{Path: dep.Path, Version: strings.TrimPrefix(dep.Version, "v")})
+	}
+
+	for _, module := range modules {
+		if module.Version == "" {
+			continue
+		}
+		for _, entry := range db.modules[module.Path] {
+			for _, affected := range entry.Affected {
+				if affected.Package.Name != module.Path {
+					continue
+				}
+				fixed, ok := affects(affected, module.Version)
+				if !ok {
+					continue
+				}
+				finding := Finding{
+					ID:      entry.ID,
+					Aliases: entry.Aliases,
+					Summary: entry.Summary,
+					Module:  module.Path,
+					Version: displayVersion(module.Path, module.Version),
+					Fixed:   displayVersion(module.Path, fixed),
+				}
+				finding.Level, finding.Packages, finding.Symbols = reachable(affected.EcosystemSpecific.Imports, symbols, goos, goarch)
+				report.Findings = append(report.Findings, finding)
+			}
+		}
+	}
+
+	rank := map[string]int{LevelSymbol: 0, LevelPackage: 1, LevelUnknown: 2, LevelModule: 3}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if rank[a.Level] != rank[b.Level] {
+			return rank[a.Level] < rank[b.Level]
+		}
+		return a.ID < b.ID
+	})
+	return report
+}
+
+// affects reports whether version is in the SEMVER ranges of affected, and returns the earliest fixed
+// version after it.
+func affects(affected osvAffected, version string) (string, bool) {
+	fixed := ""
+	found := false
+	for _, r := range affected.Ranges {
+		if r.Type != "SEMVER" {
+			continue
+		}
+		// Events are applied in version order; the last one at or below version decides
+		type event struct {
+			version    string
+			introduced bool
+		}
+		var events []event
+		for _, e := range r.Events {
+			if e.Introduced != "" {
+				events = append(events, event{version: e.Introduced, introduced: true})
+			}
+			if e.Fixed != "" {
+				events = append(events, event{version: e.Fixed})
+			}
+		}
+		sort.SliceStable(events, func(i, j int) bool {
+			return compareVersions(events[i].version, events[j].version) < 0
+		})
+		inRange := false
+		for _, e := range events {
+			if compareVersions(e.version, version) > 0 {
+				if inRange && !e.introduced && (fixed == "" || compareVersions(e.version, fixed) < 0) {
+					fixed = e.version
+				}
+				break
+			}
+			inRange = e.introduced
+		}
+		found = found || inRange
+	}
+	return fixed, found
+}
+
+// reachable returns the level of a finding with the vulnerable packages and the vulnerable symbols
+// present in the binary. Imports for another GOOS or GOARCH are ignored.
+func reachable(imports []osvImport, symbols symbolSet, goos, goarch string) (string, []string, []string) {
+	var packages, found []string
+	linked := false
+	for _, imp := range imports {
+		if !matchPlatform(imp.GOOS, goos) || !matchPlatform(imp.GOARCH, goarch) {
+			continue
+		}
+		packages = append(packages, imp.Path)
+		pkg := symbols[imp.Path]
+		if len(pkg) == 0 {
+			continue
+		}
+		if len(imp.Symbols) == 0 {
+			linked = true
+		}
+		for _, symbol := range imp.Symbols {
+			if pkg[symbol] {
+				found = append(found, imp.Path+"."+symbol)
+			}
+		}
+	}
+	switch {
+	case symbols == nil:
+		return LevelUnknown, packages, nil
+	case len(found) > 0:
+		return LevelSymbol, packages, found
+	case linked:
+		return LevelPackage, packages, nil
+	case len(imports) == 0:
+		// The whole module is vulnerable
+		return LevelPackage, nil, nil
+	default:
+		return LevelModule, packages, nil
+	}
+}
+
+func matchPlatform(values []string, value string) bool {
+	if len(values) == 0 || value == "" {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// displayVersion adds the prefix of module or Go versions to an OSV version.
+func displayVersion(module, version string) string {
+	switch {
+	case version == "":
+		return ""
+	case module == stdlibModule:
+		return "go" + version
+	default:
+		return "v" + version
+	}
+}
+
+// goSemver converts a Go version to semver as used by the database: "go1.22rc1" is "1.22.0-rc.1".
+func goSemver(version string) string {
+	version, _, _ = strings.Cut(version, " ")
+	version, ok := strings.CutPrefix(version, "go")
+	if !ok || version == "" {
+		return ""
+	}
+	prerelease := ""
+	for _, tag := range []string{"rc", "beta"} {
+		if i := strings.Index(version, tag); i > 0 {
+			prerelease = "-" + tag + "." + version[i+len(tag):]
+			version = version[:i]
+			break
+		}
+	}
+	switch strings.Count(version, ".") {
+	case 0:
+		version += ".0.0"
+	case 1:
+		version += ".0"
+	}
+	return version + prerelease
+}
+
+// compareVersions compares semantic versions without the "v" prefix. "0" is the lowest version, below
+// pseudo-versions such as 0.0.0-20230101000000-abcdef123456.
+func compareVersions(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "0":
+		return -1
+	case b == "0":
+		return 1
+	}
+	a, _, _ = strings.Cut(a, "+")
+	b, _, _ = strings.Cut(b, "+")
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := 0; i < 3; i++ {
+		if c := compareIdentifiers(part(aParts, i), part(bParts, i)); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aIDs, bIDs := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := 0; i < len(aIDs) && i < len(bIDs); i++ {
+		if c := compareIdentifiers(aIDs[i], bIDs[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(aIDs), len(bIDs))
+}
+
+func part(parts []string, i int) string {
+	if i < len(parts) {
+		return parts[i]
+	}
+	return "0"
+}
+
+// compareIdentifiers compares numeric identifiers numerically and before alphanumeric ones.
+func compareIdentifiers(a, b string) int {
+	aNum, aErr := strconv.ParseUint(a, 10, 64)
+	bNum, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case aNum < bNum:
+			return -1
+		case aNum > bNum:
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func formatVulns(report *VulnReport) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("\nVulnerabilities (%d found, database %s with %d entries):\n",
+		len(report.Findings), report.Database, report.Entries))
+	if !report.Symbols {
+		output.WriteString("  Symbols could not be read; reachability is unknown\n")
+	}
+	if len(report.Findings) == 0 {
+		output.WriteString("  No known vulnerabilities affect the Go version or modules\n")
+		return output.String()
+	}
+	for _, finding := range report.Findings {
+		id := finding.ID
+		if len(finding.Aliases) > 0 {
+			id += " (" + strings.Join(finding.Aliases, ", ") + ")"
+		}
+		output.WriteString(fmt.Sprintf("  %s [%s]: %s\n", id, finding.Level, finding.Summary))
+		fixed := "no fix available"
+		if finding.Fixed != "" {
+			fixed = "fixed in " + finding.Fixed
+		}
+		output.WriteString(fmt.Sprintf("    Module: %s %s, %s\n", finding.Module, finding.Version, fixed))
+		switch {
+		case len(finding.Symbols) > 0:
+			output.WriteString(fmt.Sprintf("    Symbols in binary: %s\n", strings.Join(finding.Symbols, ", ")))
+		case finding.Level == LevelPackage && len(finding.Packages) > 0:
+			output.WriteString(fmt.Sprintf("    Packages linked: %s\n", strings.Join(finding.Packages, ", ")))
+		case finding.Level == LevelModule:
+			output.WriteString(fmt.Sprintf("    Vulnerable code not in binary: %s\n", strings.Join(finding.Packages, ", ")))
+		}
+	}
+	return output.String()
+}
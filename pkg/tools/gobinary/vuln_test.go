@@ -0,0 +1,303 @@
+package gobinary
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	testifyVuln = `{
+  "schema_version": "1.3.1",
+  "id": "GO-2099-0001",
+  "aliases": ["CVE-2099-0001"],
+  "summary": "Test runner vulnerability in github.com/stretchr/testify",
+  "affected": [{
+    "package": {"name": "github.com/stretchr/testify", "ecosystem": "Go"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "99.0.0"}]}],
+    "ecosystem_specific": {"imports": [
+      {"path": "github.com/stretchr/testify/suite", "symbols": ["Run", "Suite.Run"]},
+      {"path": "github.com/stretchr/testify/mock", "symbols": ["Mock.Called"]}
+    ]}
+  }]
+}`
+	unreachableVuln = `{
+  "id": "GO-2099-0002",
+  "summary": "Unused assertion vulnerability",
+  "affected": [{
+    "package": {"name": "github.com/stretchr/testify", "ecosystem": "Go"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "1.0.0"}]}],
+    "ecosystem_specific": {"imports": [{"path": "github.com/stretchr/testify/assert", "symbols": ["NoSuchAssertion"]}]}
+  }]
+}`
+	fixedVuln = `{
+  "id": "GO-2099-0003",
+  "summary": "Fixed long ago",
+  "affected": [{
+    "package": {"name": "github.com/stretchr/testify", "ecosystem": "Go"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "1.0.0"}]}]
+  }]
+}`
+	stdlibVuln = `{
+  "id": "GO-2099-0004",
+  "summary": "Formatting vulnerability in fmt",
+  "affected": [{
+    "package": {"name": "stdlib", "ecosystem": "Go"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "99.0.0"}]}],
+    "ecosystem_specific": {"imports": [
+      {"path": "fmt", "symbols": ["Sprintf"]},
+      {"path": "os", "goos": ["plan9"], "symbols": ["Getpid"]}
+    ]}
+  }]
+}`
+	withdrawnVuln = `{
+  "id": "GO-2099-0005",
+  "summary": "Withdrawn",
+  "withdrawn": "2099-01-01T00:00:00Z",
+  "affected": [{
+    "package": {"name": "github.com/stretchr/testify", "ecosystem": "Go"},
+    "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+  }]
+}`
+)
+
+// writeVulnDB writes a vulnerability database in the layout of vuln.go.dev.
+func (suite *GoBinaryTestSuite) writeVulnDB(dir string) string {
+	db := filepath.Join(dir, "vulndb")
+	suite.Require().NoError(os.MkdirAll(filepath.Join(db, "ID"), 0o755))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(db, "index"), 0o755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(db, "index", "db.json"), []byte(`{"modified":"2099-01-01T00:00:00Z"}`), 0o644))
+	suite.Require().NoError(os.WriteFile(filepath.Join(db, "index", "modules.json"), []byte(`[{"path":"stdlib"}]`), 0o644))
+	for i, entry := range []string{testifyVuln, unreachableVuln, fixedVuln, stdlibVuln, withdrawnVuln} {
+		name := filepath.Join(db, "ID", fmt.Sprintf("GO-2099-%04d.json", i+1))
+		suite.Require().NoError(os.WriteFile(name, []byte(entry), 0o644))
+	}
+	return db
+}
+
+func (suite *GoBinaryTestSuite) TestVulns() {
+	executable, err := os.Executable()
+	suite.Require().NoError(err)
+	dir := suite.T().TempDir()
+	db := suite.writeVulnDB(dir)
+
+	result, err := suite.call(Input{Path: executable, VulnDB: db})
+	suite.Require().NoError(err)
+	report := result.StructuredContent.Vulns
+	suite.Require().NotNil(report)
+	suite.Equal(5, report.Entries)
+	suite.True(report.Symbols)
+	suite.Require().Len(report.Findings, 3)
+
+	testify := report.Findings[0]
+	suite.Equal("GO-2099-0001", testify.ID)
+	suite.Equal(LevelSymbol, testify.Level)
+	suite.Equal("github.com/stretchr/testify", testify.Module)
+	suite.Equal("v1.11.0", testify.Version)
+	suite.Equal("v99.0.0", testify.Fixed)
+	suite.Contains(testify.Symbols, "github.com/stretchr/testify/suite.Run")
+	suite.Contains(testify.Symbols, "github.com/stretchr/testify/suite.Suite.Run")
+
+	stdlib := report.Findings[1]
+	suite.Equal("GO-2099-0004", stdlib.ID)
+	suite.Equal(LevelSymbol, stdlib.Level)
+	suite.Equal("go99.0.0", stdlib.Fixed)
+	suite.Equal([]string{"fmt.Sprintf"}, stdlib.Symbols)
+	suite.Equal([]string{"fmt"}, stdlib.Packages)
+
+	unreachable := report.Findings[2]
+	suite.Equal("GO-2099-0002", unreachable.ID)
+	suite.Equal(LevelModule, unreachable.Level)
+	suite.Empty(unreachable.Fixed)
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Vulnerabilities (3 found, database "+db+" with 5 entries):")
+	suite.Contains(text, "GO-2099-0001 (CVE-2099-0001) [symbol]: Test runner vulnerability")
+	suite.Contains(text, "Module: github.com/stretchr/testify v1.11.0, fixed in v99.0.0")
+	suite.Contains(text, "Module: github.com/stretchr/testify v1.11.0, no fix available")
+	suite.Contains(text, "Vulnerable code not in binary: github.com/stretchr/testify/assert")
+
+	// The same entries from a zip file, and the server default
+	archive := filepath.Join(dir, "vulndb.zip")
+	file, err := os.Create(archive)
+	suite.Require().NoError(err)
+	writer := zip.NewWriter(file)
+	for id, entry := range map[string]string{"GO-2099-0001": testifyVuln, "GO-2099-0004": stdlibVuln} {
+		w, err := writer.Create("ID/" + id + ".json")
+		suite.Require().NoError(err)
+		_, err = w.Write([]byte(entry))
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(writer.Close())
+	suite.Require().NoError(file.Close())
+
+	suite.Require().NoError(SetVulnDB(archive))
+	defer func() {
+		_ = SetVulnDB("")
+	}()
+	result, err = suite.call(Input{Path: executable, Vulns: true})
+	suite.Require().NoError(err)
+	suite.Equal(2, result.StructuredContent.Vulns.Entries)
+	suite.Len(result.StructuredContent.Vulns.Findings, 2)
+}
+
+func (suite *GoBinaryTestSuite) TestVulnDBCache() {
+	db := suite.writeVulnDB(suite.T().TempDir())
+	first, err := openVulnDB(db)
+	suite.Require().NoError(err)
+	second, err := openVulnDB(db)
+	suite.Require().NoError(err)
+	suite.Same(first, second, "an unchanged database is parsed once")
+
+	// A new entry in a subdirectory is picked up although the root directory is unchanged
+	entry := filepath.Join(db, "ID", "GO-2099-0006.json")
+	suite.Require().NoError(os.WriteFile(entry, []byte(strings.Replace(fixedVuln, "GO-2099-", "GO-2099-1", 1)), 0o644))
+	later := time.Now().Add(time.Minute)
+	suite.Require().NoError(os.Chtimes(entry, later, later))
+	third, err := openVulnDB(db)
+	suite.Require().NoError(err)
+	suite.NotSame(first, third)
+	suite.Equal(first.entries+1, third.entries)
+
+	// Only the most recently used databases are kept
+	for range maxCachedVulnDBs {
+		_, err = openVulnDB(suite.writeVulnDB(suite.T().TempDir()))
+		suite.Require().NoError(err)
+	}
+	vulnDBCache.mu.Lock()
+	suite.Len(vulnDBCache.entries, maxCachedVulnDBs)
+	suite.NotContains(vulnDBCache.entries, db)
+	vulnDBCache.mu.Unlock()
+}
+
+func (suite *GoBinaryTestSuite) TestVulnDBErrors() {
+	executable, err := os.Executable()
+	suite.Require().NoError(err)
+	dir := suite.T().TempDir()
+
+	_, err = suite.call(Input{Path: executable, Vulns: true})
+	suite.ErrorIs(err, errNoVulnDB)
+	suite.ErrorContains(SetVulnDB(filepath.Join(dir, "missing")), "invalid vulnerability database")
+
+	_, err = suite.call(Input{Path: executable, VulnDB: dir})
+	suite.ErrorContains(err, "no OSV entries in vulnerability database")
+	notZip := filepath.Join(dir, "vulndb.json")
+	suite.Require().NoError(os.WriteFile(notZip, []byte(testifyVuln), 0o644))
+	_, err = suite.call(Input{Path: executable, VulnDB: notZip})
+	suite.ErrorContains(err, "must be a directory or zip file")
+}
+
+func (suite *GoBinaryTestSuite) TestReadSymbols() {
+	executable, err := os.Executable()
+	suite.Require().NoError(err)
+	symbols, err := readSymbols(executable)
+	suite.Require().NoError(err)
+	suite.True(symbols["github.com/stretchr/testify/suite"]["Run"])
+	suite.True(symbols["github.com/stretchr/testify/suite"]["Suite.Run"])
+	suite.True(symbols["fmt"]["Sprintf"])
+
+	// Function names are read from the pclntab of stripped binaries
+	dir := suite.T().TempDir()
+	suite.helloModule(dir)
+	symbols, err = readSymbols(suite.buildHello(dir, "stripped", "-s -w"))
+	suite.Require().NoError(err)
+	suite.True(symbols["main"]["main"])
+	suite.True(symbols["runtime"]["main"])
+
+	_, err = readSymbols(filepath.Join(dir, "src", "main.go"))
+	suite.ErrorContains(err, "unknown object format")
+}
+
+func (suite *GoBinaryTestSuite) TestSplitSymbol() {
+	testCases := []struct {
+		name   string
+		pkg    string
+		symbol string
+	}{
+		{name: "net/http.(*Client).Do", pkg: "net/http", symbol: "Client.Do"},
+		{name: "net/http.Header.Get", pkg: "net/http", symbol: "Header.Get"},
+		{name: "fmt.Sprintf", pkg: "fmt", symbol: "Sprintf"},
+		{name: "gopkg.in/yaml%2ev3.Unmarshal", pkg: "gopkg.in/yaml.v3", symbol: "Unmarshal"},
+		{name: "golang.org/x/exp/slices.Sort[...]", pkg: "golang.org/x/exp/slices", symbol: "Sort"},
+		{name: "example.com/list.(*List[...]).Push", pkg: "example.com/list", symbol: "List.Push"},
+		{name: "main.main.func1", pkg: "main", symbol: "main.func1"},
+		{name: "go:buildid"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			pkg, symbol := splitSymbol(tc.name)
+			suite.Equal(tc.pkg, pkg)
+			suite.Equal(tc.symbol, symbol)
+		})
+	}
+}
+
+func (suite *GoBinaryTestSuite) TestAffects() {
+	var affected osvAffected
+	entry := `{"ranges": [
+		{"type": "SEMVER", "events": [{"introduced": "0"}, {"fixed": "0.17.0"}, {"introduced": "0.18.0"}, {"fixed": "0.19.1"}]},
+		{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}
+	]}`
+	suite.Require().NoError(json.Unmarshal([]byte(entry), &affected))
+
+	testCases := []struct {
+		version  string
+		fixed    string
+		affected bool
+	}{
+		{version: "0.7.0", fixed: "0.17.0", affected: true},
+		{version: "0.17.0-rc.1", fixed: "0.17.0", affected: true},
+		{version: "0.17.0"},
+		{version: "0.17.1"},
+		{version: "0.18.0", fixed: "0.19.1", affected: true},
+		{version: "0.19.0", fixed: "0.19.1", affected: true},
+		{version: "0.19.1"},
+		{version: "1.0.0"},
+	}
+	for _, tc := range testCases {
+		suite.Run(tc.version, func() {
+			fixed, ok := affects(affected, tc.version)
+			suite.Equal(tc.affected, ok)
+			suite.Equal(tc.fixed, fixed)
+		})
+	}
+}
+
+func (suite *GoBinaryTestSuite) TestVersions() {
+	suite.Equal("1.21.3", goSemver("go1.21.3"))
+	suite.Equal("1.21.0", goSemver("go1.21"))
+	suite.Equal("1.22.0-rc.1", goSemver("go1.22rc1"))
+	suite.Equal("1.20.0-beta.1", goSemver("go1.20beta1"))
+	suite.Equal("1.24.0", goSemver("go1.24.0 X:boringcrypto"))
+	suite.Empty(goSemver("devel go1.23-abc"))
+
+	ordered := []string{
+		"0",
+		"0.0.0-20230101000000-abcdef123456",
+		"0.0.1",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0+incompatible",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			want := compareInts(i, j)
+			suite.Equal(want, compareVersions(ordered[i], ordered[j]), "%s vs %s", ordered[i], ordered[j])
+		}
+	}
+	suite.Equal(0, compareVersions("1.2.3+meta", "1.2.3"))
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,6 +16,7 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
 	kubeconnector "github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/kube"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/netutil"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
 )
@@ -103,27 +103,6 @@ func New(logger zerolog.Logger) tools.Tool {
 	}
 }
 
-// isPortAvailable checks if a local port is available for binding.
-func (k *Tool) isPortAvailable(ctx context.Context, port int) bool {
-	lc := net.ListenConfig{}
-	listener, err := lc.Listen(ctx, "tcp", fmt.Sprintf(":%d", port))
-	if err != nil {
-		return false
-	}
-	_ = listener.Close()
-	return true
-}
-
-// findAvailablePort finds an available port starting from the given port.
-func (k *Tool) findAvailablePort(ctx context.Context, startPort int) int {
-	for port := startPort; port < startPort+maxPortOffset; port++ {
-		if k.isPortAvailable(ctx, port) {
-			return port
-		}
-	}
-	return 0
-}
-
 func (k *Tool) handlePortForward(ctx context.Context, input *Input) (*mcp.CallToolResultFor[Output], error) {
 	// Validate input
 	if input.Resource == "" {
@@ -169,9 +148,9 @@ func (k *Tool) handlePortForward(ctx context.Context, input *Input) (*mcp.CallTo
 	}
 
 	// Check if port is available
-	if !k.isPortAvailable(ctx, localPort) {
+	if !netutil.IsPortAvailable(ctx, localPort) {
 		// Try to find an available port
-		availablePort := k.findAvailablePort(ctx, localPort)
+		availablePort := netutil.FindAvailablePort(ctx, localPort, maxPortOffset)
 		if availablePort == 0 {
 			return nil, fmt.Errorf("port %d is not available and no alternative ports found in range %d-%d", localPort, localPort, localPort+maxPortRange)
 		}
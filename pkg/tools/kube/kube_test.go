@@ -8,6 +8,7 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/suite"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/netutil"
 )
 
 type KubeTestSuite struct {
@@ -267,11 +268,11 @@ func (suite *KubeTestSuite) TestPortAvailabilityCheck() {
 	ctx := context.Background()
 	
 	// Test with a known available port (0 should always work for binding test)
-	available := suite.tool.isPortAvailable(ctx, 0)
+	available := netutil.IsPortAvailable(ctx, 0)
 	suite.True(available)
 	
 	// Test finding available port
-	port := suite.tool.findAvailablePort(ctx, 50000) // Use high port range
+	port := netutil.FindAvailablePort(ctx, 50000, maxPortOffset) // Use high port range
 	suite.Greater(port, 0)
 	suite.LessOrEqual(port, 50000+maxPortOffset)
 }
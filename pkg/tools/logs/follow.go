@@ -0,0 +1,152 @@
+package logs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+const (
+	maxFollows      = 10    // Follows running at once
+	maxPendingLines = 10000 // Lines kept for a client that is not keeping up
+)
+
+// follow streams the new lines of a log that pass a filter to the client until it is stopped or
+// its duration is over. It is not bound to the request that started it.
+type follow struct {
+	id      string
+	target  string
+	source  string
+	started time.Time
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	filter filter
+	fields []string
+
+	mu      sync.Mutex
+	partial string
+	pending []string
+	matched int
+	err     error
+}
+
+// Write splits the log output into lines, keeping an unterminated line until it is complete.
+func (f *follow) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lines := strings.Split(f.partial+string(p), "\n")
+	f.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+		if journalMarker(line) || !f.filter.matchLine(line) {
+			continue
+		}
+		f.matched++
+		f.pending = append(f.pending, formatEntry(parseLine(line, f.fields), f.fields))
+		if len(f.pending) > maxPendingLines {
+			f.pending = f.pending[1:]
+		}
+	}
+	return len(p), nil
+}
+
+// takePending returns the lines matched since the last call and the number of matched lines.
+func (f *follow) takePending() ([]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending := f.pending
+	f.pending = nil
+	return pending, f.matched
+}
+
+// status returns a one-line description of the follow.
+func (f *follow) status() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	elapsed := time.Since(f.started).Round(time.Second)
+	select {
+	case <-f.done:
+	default:
+		return fmt.Sprintf("following for %s, %d lines matched", elapsed, f.matched)
+	}
+	if f.err != nil && !errors.Is(f.err, context.Canceled) && !errors.Is(f.err, context.DeadlineExceeded) {
+		return fmt.Sprintf("failed after %s, %d lines matched: %v", elapsed, f.matched, f.err)
+	}
+	return fmt.Sprintf("stopped after %s, %d lines matched", elapsed, f.matched)
+}
+
+func newFollowID() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// startFollow follows src for duration, sending new lines to notifier, and closes the target
+// connection when it is over.
+func (t *Tool) startFollow(src source, f filter, fields []string, duration time.Duration, notifier *notify.Notifier) (*follow, error) {
+	t.followsMu.Lock()
+	defer t.followsMu.Unlock()
+	if len(t.follows) >= maxFollows {
+		return nil, fmt.Errorf("too many follows running (%d); stop one with stop_follow", maxFollows)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	fl := &follow{
+		id:      newFollowID(),
+		target:  src.conn.Name(),
+		source:  src.String(),
+		started: time.Now(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		filter:  f,
+		fields:  fields,
+	}
+	t.follows[fl.id] = fl
+
+	// The follow outlives the request that started it, so its progress token is stale: log only
+	go notifier.SendBatches(notify.Batches{
+		Key:    "follow_id",
+		ID:     fl.id,
+		Take:   fl.takePending,
+		Status: fl.status,
+	}, fl.done)
+	go func() {
+		err := src.stream(ctx, 0, time.Time{}, time.Time{}, true, fl)
+		fl.mu.Lock()
+		fl.err = err
+		fl.mu.Unlock()
+		cancel()
+		_ = src.conn.Close()
+		close(fl.done)
+
+		t.followsMu.Lock()
+		delete(t.follows, fl.id)
+		t.followsMu.Unlock()
+		t.logger.Debug().Str("follow_id", fl.id).Str("status", fl.status()).Msg("follow finished")
+	}()
+	return fl, nil
+}
+
+// stopFollow stops a follow and waits for it to finish.
+func (t *Tool) stopFollow(id string) (*follow, error) {
+	t.followsMu.Lock()
+	fl, ok := t.follows[id]
+	t.followsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("follow %s not found (it stopped or its duration is over)", id)
+	}
+	fl.cancel()
+	<-fl.done
+	return fl, nil
+}
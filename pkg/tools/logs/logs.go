@@ -0,0 +1,255 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
+)
+
+const (
+	defaultLines          = 1000            // Lines read from the end of the log
+	defaultFollowDuration = 5 * time.Minute // How long a follow lasts unless stopped
+	localLogDir           = "/var/log"      // Local log files must be in this directory
+)
+
+type Input struct {
+	Host           string   `json:"host,omitempty" validate:"omitempty,hostname|ip"`                           // SSH host or ~/.ssh/config alias (omit for a pod or the local host)
+	Port           int      `json:"port,omitempty" validate:"min=0,max=65535"`                                 // SSH port (default: from ssh config, else 22)
+	User           string   `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"` // SSH user (default: from ssh config, else current user)
+	Pod            string   `json:"pod,omitempty" validate:"omitempty,max=253"`                                // Pod whose container logs to read, "name" or "pod/name" (instead of host)
+	Namespace      string   `json:"namespace,omitempty" validate:"omitempty,alphanum|contains=-,max=63"`       // Namespace of the pod (default: "default")
+	Container      string   `json:"container,omitempty" validate:"omitempty,max=253"`                          // Container in the pod (default: kubectl's default container)
+	KubeConfig     string   `json:"kubeconfig,omitempty" validate:"omitempty,filepath"`                        // Path to kubeconfig file
+	File           string   `json:"file,omitempty" validate:"omitempty,max=4096"`                              // Log file to tail on the host or in the pod's container; local files must be in /var/log
+	Unit           string   `json:"unit,omitempty" validate:"omitempty,max=256"`                               // systemd unit whose journal to read with journalctl -u
+	Previous       bool     `json:"previous,omitempty"`                                                        // Container logs of the previous, terminated instance
+	Lines          int      `json:"lines,omitempty" validate:"min=0,max=100000"`                               // Lines read from the end of the log before filtering (default: 1000)
+	Since          string   `json:"since,omitempty" validate:"omitempty,max=64"`                               // Only lines at or after this time: a duration ago such as 15m, or RFC3339
+	Until          string   `json:"until,omitempty" validate:"omitempty,max=64"`                               // Only lines at or before this time: a duration ago such as 5m, or RFC3339
+	Grep           string   `json:"grep,omitempty" validate:"omitempty,max=1024"`                              // Regular expression lines must match
+	Exclude        string   `json:"exclude,omitempty" validate:"omitempty,max=1024"`                           // Regular expression of lines to drop
+	Fields         []string `json:"fields,omitempty" validate:"omitempty,max=32,dive,min=1,max=128"`           // JSON fields to extract from zerolog/zap lines, "*" for all
+	Follow         bool     `json:"follow,omitempty"`                                                          // Keep sending new matching lines as log notifications
+	FollowDuration int      `json:"follow_duration,omitempty" validate:"min=0,max=3600"`                       // Seconds to follow (default: 300)
+	StopFollow     string   `json:"stop_follow,omitempty" validate:"omitempty,hexadecimal,len=12"`             // Stop the follow with this follow_id
+	MaxLines       int      `json:"max_lines,omitempty" validate:"min=0,max=100000"`                           // Maximum lines to return (default: 200)
+	Offset         int      `json:"offset,omitempty" validate:"min=0"`                                         // Line offset for pagination
+}
+
+type Output struct {
+	Target   string  `json:"target"`
+	Source   string  `json:"source"`
+	Read     int     `json:"read"`    // Lines read from the log
+	Matched  int     `json:"matched"` // Lines that passed the filters
+	Entries  []Entry `json:"entries"` // Matched lines of the page
+	FollowID string  `json:"follow_id,omitempty"`
+}
+
+type Tool struct {
+	logger    zerolog.Logger
+	validator *validator.Validate
+	logDir    string // Directory of the log files readable on the local host
+
+	followsMu sync.Mutex
+	follows   map[string]*follow // Running follows; they outlive the request that started them
+}
+
+func (t *Tool) Register(srv *server.Server) {
+	logsTool := &mcp.Tool{
+		Name:        "logs",
+		Description: "Tail and search logs: files or journalctl units over SSH or locally, and pod container logs; since/until, regex filters, zerolog/zap JSON field extraction, and follow mode streaming new lines as notifications",
+	}
+
+	mcp.AddTool(&srv.Server, logsTool, t.LogsHandler)
+	t.logger.Debug().Msg("logs tool registered")
+}
+
+func (t *Tool) LogsHandler(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
+	input := params.Arguments
+
+	// Validate input using validator
+	if err := t.validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	if input.StopFollow != "" {
+		return t.handleStopFollow(input.StopFollow)
+	}
+
+	options := target.Options{
+		Host:       input.Host,
+		Port:       input.Port,
+		User:       input.User,
+		Pod:        input.Pod,
+		Namespace:  input.Namespace,
+		Container:  input.Container,
+		KubeConfig: input.KubeConfig,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	switch {
+	case input.File != "" && input.Unit != "":
+		return nil, errors.New("cannot specify both file and unit - choose one")
+	case input.Unit != "" && input.Pod != "":
+		return nil, errors.New("unit cannot be combined with pod")
+	case input.File == "" && input.Unit == "" && input.Pod == "":
+		return nil, errors.New("specify file or unit, or pod for container logs")
+	case input.Previous && (input.Pod == "" || input.File != ""):
+		return nil, errors.New("previous requires pod without file")
+	case input.Follow && input.Until != "":
+		return nil, errors.New("until cannot be combined with follow")
+	case input.FollowDuration > 0 && !input.Follow:
+		return nil, errors.New("follow_duration requires follow")
+	}
+
+	var f filter
+	var err error
+	now := time.Now()
+	if input.Since != "" {
+		if f.since, err = parseTimeArg(input.Since, now); err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if input.Until != "" {
+		if f.until, err = parseTimeArg(input.Until, now); err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+		if !f.since.IsZero() && f.until.Before(f.since) {
+			return nil, errors.New("until must not be before since")
+		}
+	}
+	if input.Grep != "" {
+		if f.grep, err = regexp.Compile(input.Grep); err != nil {
+			return nil, fmt.Errorf("invalid grep regular expression: %w", err)
+		}
+	}
+	if input.Exclude != "" {
+		if f.exclude, err = regexp.Compile(input.Exclude); err != nil {
+			return nil, fmt.Errorf("invalid exclude regular expression: %w", err)
+		}
+	}
+
+	lines := defaultLines
+	if input.Lines > 0 {
+		lines = input.Lines
+	}
+	maxLines := types.MaxDefaultLines
+	if input.MaxLines > 0 {
+		maxLines = input.MaxLines
+	}
+
+	if input.File != "" && input.Host == "" && input.Pod == "" {
+		if input.File, err = localLogFile(t.logDir, input.File); err != nil {
+			return nil, err
+		}
+	}
+
+	conn := target.New(options)
+	src := source{conn: conn, file: input.File, unit: input.Unit, previous: input.Previous}
+	output := Output{Target: conn.Name(), Source: src.String()}
+	t.logger.Debug().Str("target", output.Target).Str("source", output.Source).Msg("reading logs")
+
+	var stdout bytes.Buffer
+	if err := src.stream(ctx, lines, f.since, f.until, false, &stdout); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read %s on %s: %w", output.Source, output.Target, err)
+	}
+	var logLines []string
+	for _, line := range strings.Split(strings.TrimRight(stdout.String(), "\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line != "" && !journalMarker(line) {
+			logLines = append(logLines, line)
+		}
+	}
+	output.Read = len(logLines)
+	matched, timed := filterEntries(logLines, f, input.Fields)
+	output.Matched = len(matched)
+
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("Logs of %s on %s: %d of %d lines match\n", output.Source, output.Target, output.Matched, output.Read))
+	if f.hasWindow() && !timed && output.Read > 0 {
+		text.WriteString("No timestamps recognized in the log; since and until were not applied\n")
+	}
+	if output.Read == lines {
+		text.WriteString(fmt.Sprintf("Only the last %d lines were read; raise lines to search further back\n", lines))
+	}
+
+	if input.Follow {
+		duration := defaultFollowDuration
+		if input.FollowDuration > 0 {
+			duration = time.Duration(input.FollowDuration) * time.Second
+		}
+		fl, err := t.startFollow(src, f, input.Fields, duration, notify.New(session, params.Meta, "logs"))
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		output.FollowID = fl.id
+		text.WriteString(fmt.Sprintf("Following for %s: new matching lines are sent as log notifications with follow_id=%s. Stop with stop_follow=%s.\n", duration, fl.id, fl.id))
+	} else {
+		_ = conn.Close()
+	}
+
+	if input.Offset < len(matched) {
+		end := min(input.Offset+maxLines, len(matched))
+		output.Entries = matched[input.Offset:end]
+		if input.Offset > 0 || end < len(matched) {
+			text.WriteString(fmt.Sprintf("[Showing lines %d-%d of %d matching lines. Use offset parameter to view more.]\n", input.Offset+1, end, len(matched)))
+		}
+	} else if len(matched) > 0 {
+		text.WriteString(fmt.Sprintf("[No lines at offset %d of %d matching lines]\n", input.Offset, len(matched)))
+	}
+	text.WriteString("\n")
+	for _, entry := range output.Entries {
+		text.WriteString(formatEntry(entry, input.Fields) + "\n")
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: strings.TrimRight(text.String(), "\n"),
+			},
+		},
+		StructuredContent: output,
+	}, nil
+}
+
+func (t *Tool) handleStopFollow(id string) (*mcp.CallToolResultFor[Output], error) {
+	fl, err := t.stopFollow(id)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Follow %s of %s on %s %s", fl.id, fl.source, fl.target, fl.status()),
+			},
+		},
+		StructuredContent: Output{Target: fl.target, Source: fl.source, FollowID: fl.id},
+	}, nil
+}
+
+func New(logger zerolog.Logger) tools.Tool {
+	validate := validator.New()
+
+	return &Tool{
+		logger:    logger.With().Str("tool", "logs").Logger(),
+		validator: validate,
+		logDir:    localLogDir,
+		follows:   make(map[string]*follow),
+	}
+}
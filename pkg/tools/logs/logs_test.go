@@ -0,0 +1,262 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+)
+
+type LogsTestSuite struct {
+	suite.Suite
+	tool *Tool
+}
+
+func TestLogsTestSuite(t *testing.T) {
+	suite.Run(t, new(LogsTestSuite))
+}
+
+func (suite *LogsTestSuite) SetupTest() {
+	suite.tool = &Tool{
+		logger:    zerolog.Nop(),
+		validator: validator.New(),
+		logDir:    suite.T().TempDir(),
+		follows:   make(map[string]*follow),
+	}
+}
+
+func (suite *LogsTestSuite) call(input Input) (*mcp.CallToolResultFor[Output], error) {
+	return suite.tool.LogsHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{Arguments: input})
+}
+
+func (suite *LogsTestSuite) TestInputValidation() {
+	testCases := []struct {
+		name     string
+		input    Input
+		errorMsg string
+	}{
+		{
+			name:     "no source",
+			input:    Input{Host: "192.168.1.100"},
+			errorMsg: "specify file or unit, or pod for container logs",
+		},
+		{
+			name:     "file and unit",
+			input:    Input{File: "/var/log/app.log", Unit: "app"},
+			errorMsg: "cannot specify both file and unit",
+		},
+		{
+			name:     "unit in pod",
+			input:    Input{Pod: "api-0", Unit: "app"},
+			errorMsg: "unit cannot be combined with pod",
+		},
+		{
+			name:     "previous without pod",
+			input:    Input{File: "/var/log/app.log", Previous: true},
+			errorMsg: "previous requires pod without file",
+		},
+		{
+			name:     "host and pod",
+			input:    Input{Host: "192.168.1.100", Pod: "api-0"},
+			errorMsg: "cannot specify both host and pod",
+		},
+		{
+			name:     "follow with until",
+			input:    Input{Unit: "app", Follow: true, Until: "5m"},
+			errorMsg: "until cannot be combined with follow",
+		},
+		{
+			name:     "follow duration without follow",
+			input:    Input{Unit: "app", FollowDuration: 60},
+			errorMsg: "follow_duration requires follow",
+		},
+		{
+			name:     "invalid since",
+			input:    Input{Unit: "app", Since: "last week"},
+			errorMsg: "invalid since",
+		},
+		{
+			name:     "until before since",
+			input:    Input{Unit: "app", Since: "5m", Until: "1h"},
+			errorMsg: "until must not be before since",
+		},
+		{
+			name:     "invalid grep",
+			input:    Input{Unit: "app", Grep: "error("},
+			errorMsg: "invalid grep regular expression",
+		},
+		{
+			name:     "invalid exclude",
+			input:    Input{Unit: "app", Exclude: "[a-"},
+			errorMsg: "invalid exclude regular expression",
+		},
+		{
+			name:     "invalid stop follow",
+			input:    Input{StopFollow: "nothex"},
+			errorMsg: "validation error",
+		},
+		{
+			name:     "unknown follow",
+			input:    Input{StopFollow: "0a1b2c3d4e5f"},
+			errorMsg: "follow 0a1b2c3d4e5f not found",
+		},
+		{
+			name:     "follow duration too long",
+			input:    Input{Unit: "app", Follow: true, FollowDuration: 7200},
+			errorMsg: "validation error",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			result, err := suite.call(tc.input)
+			suite.ErrorContains(err, tc.errorMsg)
+			suite.Nil(result)
+		})
+	}
+}
+
+func (suite *LogsTestSuite) TestCommand() {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	until := since.Add(time.Hour)
+
+	file := source{file: "/var/log/my app.log"}
+	suite.Equal("file /var/log/my app.log", file.String())
+	suite.Equal("tail -n 500 -- '/var/log/my app.log'", file.command(500, since, until, false))
+	suite.Equal("tail -n 0 -F -- '/var/log/my app.log'", file.command(500, since, time.Time{}, true))
+
+	unit := source{unit: "api.service"}
+	suite.Equal("unit api.service", unit.String())
+	suite.Equal("journalctl -u 'api.service' --no-pager -o short-iso -n 1000 --since '2024-05-01 10:00:00 UTC' --until '2024-05-01 11:00:00 UTC'",
+		unit.command(1000, since, until, false))
+	suite.Equal("journalctl -u 'api.service' --no-pager -o short-iso -n 0 -f", unit.command(1000, since, time.Time{}, true))
+
+	suite.Equal("container logs", source{}.String())
+	suite.Equal("previous container logs", source{previous: true}.String())
+	suite.True(journalMarker("-- No entries --"))
+	suite.False(journalMarker("-- partial"))
+}
+
+func (suite *LogsTestSuite) TestLocalFile() {
+	path := filepath.Join(suite.tool.logDir, "app.log")
+	log := strings.Join([]string{
+		`{"level":"info","time":"2024-05-01T10:00:00Z","message":"started","version":"1.4.0"}`,
+		`{"level":"error","time":"2024-05-01T10:05:00Z","message":"request failed","error":"connection refused","path":"/api"}`,
+		`{"level":"info","time":"2024-05-01T10:06:00Z","message":"request served","path":"/health"}`,
+		`{"level":"error","time":"2024-05-01T10:20:00Z","message":"request failed","error":"timeout","path":"/api"}`,
+	}, "\n") + "\n"
+	suite.Require().NoError(os.WriteFile(path, []byte(log), 0o644))
+
+	result, err := suite.call(Input{File: path, Grep: `"level":"error"`, Until: "2024-05-01T10:10:00Z", Fields: []string{"error", "path"}})
+	suite.Require().NoError(err)
+	output := result.StructuredContent
+	suite.Equal("localhost", output.Target)
+	suite.Equal("file "+path, output.Source)
+	suite.Equal(4, output.Read)
+	suite.Equal(1, output.Matched)
+	suite.Require().Len(output.Entries, 1)
+	suite.Equal("ERROR", output.Entries[0].Level)
+	suite.Equal(map[string]any{"error": "connection refused", "path": "/api"}, output.Entries[0].Fields)
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Logs of file "+path+" on localhost: 1 of 4 lines match")
+	suite.Contains(text, "2024-05-01T10:05:00Z ERROR request failed error=\"connection refused\" path=/api")
+
+	// The last lines, paged
+	result, err = suite.call(Input{File: path, Lines: 3, MaxLines: 1, Offset: 1})
+	suite.Require().NoError(err)
+	suite.Equal(3, result.StructuredContent.Read)
+	suite.Require().Len(result.StructuredContent.Entries, 1)
+	suite.Contains(result.StructuredContent.Entries[0].Line, "request served")
+	text = result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Only the last 3 lines were read")
+	suite.Contains(text, "[Showing lines 2-2 of 3 matching lines. Use offset parameter to view more.]")
+
+	// Relative names are taken from the log directory
+	result, err = suite.call(Input{File: "app.log", Lines: 1})
+	suite.Require().NoError(err)
+	suite.Equal("file "+path, result.StructuredContent.Source)
+
+	_, err = suite.call(Input{File: path + ".missing"})
+	suite.ErrorContains(err, "failed to read file "+path+".missing on localhost")
+
+	// Local files outside the log directory are refused, also through .. and symlinks
+	outside := filepath.Join(suite.T().TempDir(), "secret")
+	suite.Require().NoError(os.WriteFile(outside, []byte("password\n"), 0o600))
+	suite.Require().NoError(os.Symlink(outside, filepath.Join(suite.tool.logDir, "link.log")))
+	for _, file := range []string{"/etc/passwd", outside, "../" + filepath.Base(filepath.Dir(outside)) + "/secret", "link.log"} {
+		_, err = suite.call(Input{File: file})
+		suite.ErrorContains(err, "is outside "+suite.tool.logDir, file)
+	}
+}
+
+func (suite *LogsTestSuite) TestFollow() {
+	path := filepath.Join(suite.tool.logDir, "app.log")
+	suite.Require().NoError(os.WriteFile(path, []byte("old line\n"), 0o644))
+
+	result, err := suite.call(Input{File: path, Grep: "ERROR", Follow: true, FollowDuration: 60})
+	suite.Require().NoError(err)
+	id := result.StructuredContent.FollowID
+	suite.Len(id, 12)
+	suite.Contains(result.Content[0].(*mcp.TextContent).Text, "stop_follow="+id)
+
+	suite.tool.followsMu.Lock()
+	fl := suite.tool.follows[id]
+	suite.tool.followsMu.Unlock()
+	suite.Require().NotNil(fl)
+
+	// Lines are appended until tail -F has started and picked them up
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	suite.Require().NoError(err)
+	defer func() {
+		_ = file.Close()
+	}()
+	appended := 0
+	suite.Eventually(func() bool {
+		appended++
+		_, err := fmt.Fprintf(file, "INFO ok %d\nERROR failed %d\n", appended, appended)
+		suite.Require().NoError(err)
+		return strings.Contains(fl.status(), "following for") && !strings.Contains(fl.status(), " 0 lines matched")
+	}, 10*time.Second, 200*time.Millisecond)
+
+	result, err = suite.call(Input{StopFollow: id})
+	suite.Require().NoError(err)
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Follow "+id+" of file "+path+" on localhost stopped after")
+	suite.NotContains(text, " 0 lines matched")
+
+	_, err = suite.call(Input{StopFollow: id})
+	suite.ErrorContains(err, "not found")
+}
+
+func (suite *LogsTestSuite) TestFollowWrite() {
+	fl := &follow{
+		filter: filter{exclude: regexp.MustCompile("DEBUG")},
+		fields: []string{"path"},
+		done:   make(chan struct{}),
+	}
+	_, _ = fl.Write([]byte("-- No entries --\nDEBUG noise\n{\"level\":\"info\",\"ts\":1714557600,\"msg\":\"served\",\"path\":\"/api\"}\npart"))
+	_, _ = fl.Write([]byte("ial\n"))
+	lines, matched := fl.takePending()
+	suite.Equal(2, matched)
+	suite.Equal([]string{"2024-05-01T10:00:00Z INFO served path=/api", "partial"}, lines)
+	lines, _ = fl.takePending()
+	suite.Empty(lines)
+}
+
+func (suite *LogsTestSuite) TestTooManyFollows() {
+	for i := 0; i < maxFollows; i++ {
+		suite.tool.follows[fmt.Sprint(i)] = &follow{}
+	}
+	_, err := suite.tool.startFollow(source{conn: target.New(target.Options{}), file: "/dev/null"}, filter{}, nil, time.Second, nil)
+	suite.ErrorContains(err, "too many follows running")
+}
@@ -0,0 +1,298 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Keys of the time, level and message fields in zerolog, zap and other JSON loggers, by preference.
+var (
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp"}
+	levelKeys   = []string{"level", "lvl", "severity"}
+	messageKeys = []string{"message", "msg"}
+)
+
+// Layouts of timestamps at the start of a line: RFC3339 (kubectl --timestamps), journalctl -o short-iso,
+// and the Go log package. Timestamps without a zone are taken as UTC.
+var (
+	tokenLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999-0700",
+		"2006-01-02T15:04:05.999999999",
+	}
+	pairLayouts = []string{
+		"2006-01-02 15:04:05.999999999Z07:00",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05,999",
+		"2006/01/02 15:04:05.999999999",
+	}
+	argLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+)
+
+// Entry is a log line. Time, level and message are set for JSON lines and lines with a timestamp.
+type Entry struct {
+	Time    string         `json:"time,omitempty"`
+	Level   string         `json:"level,omitempty"`
+	Message string         `json:"message,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"` // Extracted JSON fields
+	Line    string         `json:"line"`
+
+	at     time.Time
+	isJSON bool
+}
+
+// parseLine parses a log line: a JSON object, possibly after a prefix such as a kubectl or journal
+// timestamp, or text starting with a timestamp. fields names the JSON fields to extract, "*" for all.
+func parseLine(line string, fields []string) Entry {
+	entry := Entry{Line: line}
+	if at, ok := prefixTime(line); ok {
+		entry.at = at
+		entry.Time = at.Format(time.RFC3339Nano)
+	}
+
+	start := strings.IndexByte(line, '{')
+	if start < 0 || !strings.HasSuffix(strings.TrimSpace(line), "}") {
+		return entry
+	}
+	decoder := json.NewDecoder(strings.NewReader(line[start:]))
+	decoder.UseNumber()
+	var object map[string]any
+	if err := decoder.Decode(&object); err != nil {
+		return entry
+	}
+
+	entry.isJSON = true
+	timeKey, timeValue := lookup(object, timeKeys)
+	if at, ok := jsonTime(timeValue); ok {
+		entry.at = at
+		entry.Time = at.Format(time.RFC3339Nano)
+	}
+	levelKey, level := lookup(object, levelKeys)
+	entry.Level = strings.ToUpper(fmt.Sprint(valueOr(level, "")))
+	messageKey, message := lookup(object, messageKeys)
+	entry.Message = fmt.Sprint(valueOr(message, ""))
+
+	for _, field := range fields {
+		if field == "*" {
+			for key, value := range object {
+				if key != timeKey && key != levelKey && key != messageKey {
+					entry.setField(key, value)
+				}
+			}
+			continue
+		}
+		if value, ok := object[field]; ok {
+			entry.setField(field, value)
+		}
+	}
+	return entry
+}
+
+func (e *Entry) setField(key string, value any) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]any)
+	}
+	e.Fields[key] = value
+}
+
+func lookup(object map[string]any, keys []string) (string, any) {
+	for _, key := range keys {
+		if value, ok := object[key]; ok {
+			return key, value
+		}
+	}
+	return "", nil
+}
+
+func valueOr(value, fallback any) any {
+	if value == nil {
+		return fallback
+	}
+	return value
+}
+
+// jsonTime parses a JSON time field: a string, or a Unix time in seconds (zap's float "ts"),
+// milliseconds, microseconds or nanoseconds.
+func jsonTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		return parseTimestamp(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		switch {
+		case f < 1e11:
+			return time.Unix(0, int64(f*1e9)).UTC(), true
+		case f < 1e14:
+			return time.UnixMilli(int64(f)).UTC(), true
+		case f < 1e17:
+			return time.UnixMicro(int64(f)).UTC(), true
+		default:
+			return time.Unix(0, int64(f)).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// prefixTime parses the timestamp a line starts with.
+func prefixTime(line string) (time.Time, bool) {
+	trimmed := strings.TrimLeft(line, "[")
+	if trimmed == "" || !unicode.IsDigit(rune(trimmed[0])) {
+		return time.Time{}, false
+	}
+	tokens := strings.SplitN(line, " ", 3)
+	if at, ok := parseTimestamp(tokens[0]); ok {
+		return at, true
+	}
+	if len(tokens) > 1 {
+		return parseTimestamp(tokens[0] + " " + tokens[1])
+	}
+	return time.Time{}, false
+}
+
+// parseTimestamp parses a timestamp in one of the layouts of tokenLayouts and pairLayouts.
+func parseTimestamp(value string) (time.Time, bool) {
+	value = strings.Trim(value, "[],")
+	layouts := tokenLayouts
+	if strings.Contains(value, " ") {
+		layouts = pairLayouts
+	}
+	for _, layout := range layouts {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseTimeArg parses a since or until argument: a duration before now such as "15m", or a time.
+func parseTimeArg(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	for _, layout := range argLayouts {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use a duration such as 15m or a time such as 2006-01-02T15:04:05Z", value)
+}
+
+// filter selects log lines by regular expressions and a time window.
+type filter struct {
+	grep    *regexp.Regexp
+	exclude *regexp.Regexp
+	since   time.Time
+	until   time.Time
+}
+
+// matchLine reports whether a line passes the regular expressions.
+func (f filter) matchLine(line string) bool {
+	if f.grep != nil && !f.grep.MatchString(line) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(line)
+}
+
+// matchTime reports whether a time is in the window.
+func (f filter) matchTime(at time.Time) bool {
+	if !f.since.IsZero() && at.Before(f.since) {
+		return false
+	}
+	return f.until.IsZero() || !at.After(f.until)
+}
+
+func (f filter) hasWindow() bool {
+	return !f.since.IsZero() || !f.until.IsZero()
+}
+
+// filterEntries parses lines and returns the entries that pass f. Lines without a timestamp, such as
+// stack traces, take the time of the line before. It reports whether any timestamp was found; without
+// one the time window is not applied.
+func filterEntries(lines []string, f filter, fields []string) ([]Entry, bool) {
+	entries := make([]Entry, 0, len(lines))
+	timed := false
+	var last time.Time
+	for _, line := range lines {
+		entry := parseLine(line, fields)
+		if entry.at.IsZero() {
+			entry.at = last
+		} else {
+			timed = true
+			last = entry.at
+		}
+		entries = append(entries, entry)
+	}
+
+	matched := entries[:0]
+	for _, entry := range entries {
+		if !f.matchLine(entry.Line) {
+			continue
+		}
+		if timed && f.hasWindow() && (entry.at.IsZero() || !f.matchTime(entry.at)) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	return matched, timed
+}
+
+// formatEntry formats a JSON line as "time LEVEL message key=value ..." when fields are extracted,
+// and returns other lines as they are.
+func formatEntry(entry Entry, fields []string) string {
+	if !entry.isJSON || len(fields) == 0 {
+		return entry.Line
+	}
+	var output strings.Builder
+	output.WriteString(entry.Time)
+	if entry.Level != "" {
+		output.WriteString(" " + entry.Level)
+	}
+	if entry.Message != "" {
+		output.WriteString(" " + entry.Message)
+	}
+	keys := make([]string, 0, len(entry.Fields))
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		output.WriteString(" " + key + "=" + formatValue(entry.Fields[key]))
+	}
+	return strings.TrimSpace(output.String())
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" || strings.ContainsAny(v, " \t\n\"=") {
+			return strconv.Quote(v)
+		}
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return "null"
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(buf.String())
+}
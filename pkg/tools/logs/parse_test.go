@@ -0,0 +1,169 @@
+package logs
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
+
+func (suite *LogsTestSuite) TestParseLine() {
+	testCases := []struct {
+		name    string
+		line    string
+		fields  []string
+		time    string
+		level   string
+		message string
+		values  map[string]any
+		isJSON  bool
+	}{
+		{
+			name:    "zerolog",
+			line:    `{"level":"error","service":"api","error":"connection refused","time":"2024-05-01T10:00:00Z","message":"request failed"}`,
+			fields:  []string{"error", "missing"},
+			time:    "2024-05-01T10:00:00Z",
+			level:   "ERROR",
+			message: "request failed",
+			values:  map[string]any{"error": "connection refused"},
+			isJSON:  true,
+		},
+		{
+			name:    "zap",
+			line:    `{"level":"info","ts":1714557600.5,"caller":"api/server.go:42","msg":"listening","addr":":8080"}`,
+			fields:  []string{"*"},
+			time:    "2024-05-01T10:00:00.5Z",
+			level:   "INFO",
+			message: "listening",
+			values:  map[string]any{"caller": "api/server.go:42", "addr": ":8080"},
+			isJSON:  true,
+		},
+		{
+			name:    "zerolog unix milliseconds",
+			line:    `{"level":"debug","time":1714557600123,"message":"tick"}`,
+			time:    "2024-05-01T10:00:00.123Z",
+			level:   "DEBUG",
+			message: "tick",
+			isJSON:  true,
+		},
+		{
+			name:    "kubectl timestamps",
+			line:    `2024-05-01T10:00:00.123456789Z {"level":"warn","msg":"slow"}`,
+			time:    "2024-05-01T10:00:00.123456789Z",
+			level:   "WARN",
+			message: "slow",
+			isJSON:  true,
+		},
+		{
+			name: "journal short-iso",
+			line: "2024-05-01T12:00:00+0200 app-1 api[4242]: started",
+			time: "2024-05-01T12:00:00+02:00",
+		},
+		{
+			name: "go log",
+			line: "2024/05/01 10:00:00 listening on :8080",
+			time: "2024-05-01T10:00:00Z",
+		},
+		{
+			name: "plain",
+			line: "goroutine 1 [running]:",
+		},
+		{
+			name: "invalid json",
+			line: `{"level":"info"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			entry := parseLine(tc.line, tc.fields)
+			suite.Equal(tc.line, entry.Line)
+			suite.Equal(tc.time, entry.Time)
+			suite.Equal(tc.level, entry.Level)
+			suite.Equal(tc.message, entry.Message)
+			suite.Equal(tc.isJSON, entry.isJSON)
+			if tc.values == nil {
+				suite.Empty(entry.Fields)
+				return
+			}
+			suite.Equal(tc.values, entry.Fields)
+		})
+	}
+}
+
+func (suite *LogsTestSuite) TestFormatEntry() {
+	entry := parseLine(`{"level":"error","time":"2024-05-01T10:00:00Z","message":"request failed","status":503,"error":"upstream timeout","tags":["a","b"]}`, []string{"*"})
+	suite.Equal(`2024-05-01T10:00:00Z ERROR request failed error="upstream timeout" status=503 tags=["a","b"]`, formatEntry(entry, []string{"*"}))
+	suite.Equal(entry.Line, formatEntry(entry, nil))
+
+	plain := parseLine("2024/05/01 10:00:00 started", []string{"*"})
+	suite.Equal("2024/05/01 10:00:00 started", formatEntry(plain, []string{"*"}))
+
+	// Extracted fields serialize in the structured output
+	data, err := json.Marshal(entry)
+	suite.Require().NoError(err)
+	suite.Contains(string(data), `"fields":{"error":"upstream timeout","status":503,"tags":["a","b"]}`)
+}
+
+func (suite *LogsTestSuite) TestParseTimeArg() {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value    string
+		expected time.Time
+		errorMsg string
+	}{
+		{value: "15m", expected: now.Add(-15 * time.Minute)},
+		{value: "2h30m", expected: now.Add(-150 * time.Minute)},
+		{value: "2024-05-01T10:00:00Z", expected: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T12:00:00+02:00", expected: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01 10:00:00", expected: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "-5m", errorMsg: "invalid time"},
+		{value: "yesterday", errorMsg: "invalid time"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.value, func() {
+			at, err := parseTimeArg(tc.value, now)
+			if tc.errorMsg != "" {
+				suite.ErrorContains(err, tc.errorMsg)
+				return
+			}
+			suite.Require().NoError(err)
+			suite.True(tc.expected.Equal(at), "%s != %s", tc.expected, at)
+		})
+	}
+}
+
+func (suite *LogsTestSuite) TestFilterEntries() {
+	lines := []string{
+		"2024-05-01T10:00:00Z starting",
+		"2024-05-01T10:05:00Z panic: boom",
+		"goroutine 1 [running]:",
+		"main.main()",
+		"2024-05-01T10:10:00Z restarted",
+	}
+	f := filter{
+		since: time.Date(2024, 5, 1, 10, 1, 0, 0, time.UTC),
+		until: time.Date(2024, 5, 1, 10, 9, 0, 0, time.UTC),
+	}
+
+	// The stack trace takes the time of the panic
+	entries, timed := filterEntries(lines, f, nil)
+	suite.True(timed)
+	suite.Require().Len(entries, 3)
+	suite.Equal("2024-05-01T10:05:00Z panic: boom", entries[0].Line)
+	suite.Equal("main.main()", entries[2].Line)
+	suite.Empty(entries[2].Time)
+
+	f.grep = regexp.MustCompile(`(?i)panic|restart`)
+	f.exclude = regexp.MustCompile(`restarted`)
+	f.until = time.Time{}
+	entries, _ = filterEntries(lines, f, nil)
+	suite.Require().Len(entries, 1)
+	suite.Equal("2024-05-01T10:05:00Z panic: boom", entries[0].Line)
+
+	// Without timestamps the time window is not applied
+	entries, timed = filterEntries([]string{"one", "two"}, filter{since: f.since}, nil)
+	suite.False(timed)
+	suite.Len(entries, 2)
+}
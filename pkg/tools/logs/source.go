@@ -0,0 +1,114 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/kube"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+)
+
+// journalTimeLayout is a journalctl --since/--until time; UTC avoids depending on the target's zone.
+const journalTimeLayout = "2006-01-02 15:04:05 UTC"
+
+// source is a log on a target: a file, a systemd unit's journal or the logs of a pod's container.
+type source struct {
+	conn     *target.Target
+	file     string
+	unit     string
+	previous bool
+}
+
+// String describes the source for tool output.
+func (s source) String() string {
+	switch {
+	case s.file != "":
+		return "file " + s.file
+	case s.unit != "":
+		return "unit " + s.unit
+	case s.previous:
+		return "previous container logs"
+	default:
+		return "container logs"
+	}
+}
+
+// command returns the shell command reading the last lines of a file or journal, or following it
+// from the end. The time window narrows journalctl's output; files are filtered after reading.
+func (s source) command(lines int, since, until time.Time, follow bool) string {
+	if s.file != "" {
+		if follow {
+			return "tail -n 0 -F -- " + ssh.EscapeArg(s.file)
+		}
+		return fmt.Sprintf("tail -n %d -- %s", lines, ssh.EscapeArg(s.file))
+	}
+
+	args := []string{"journalctl", "-u", ssh.EscapeArg(s.unit), "--no-pager", "-o", "short-iso"}
+	if follow {
+		return strings.Join(append(args, "-n", "0", "-f"), " ")
+	}
+	args = append(args, "-n", fmt.Sprint(lines))
+	if !since.IsZero() {
+		args = append(args, "--since", ssh.EscapeArg(since.UTC().Format(journalTimeLayout)))
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", ssh.EscapeArg(until.UTC().Format(journalTimeLayout)))
+	}
+	return strings.Join(args, " ")
+}
+
+// stream writes the log to stdout: the last lines, or new lines until ctx is done when following.
+// Container logs are read with timestamps so since and until apply to them.
+func (s source) stream(ctx context.Context, lines int, since, until time.Time, follow bool, stdout io.Writer) error {
+	if s.file == "" && s.unit == "" {
+		return s.conn.Kube().StreamContainerLogs(ctx, kube.LogOptions{
+			Tail:       lines,
+			SinceTime:  since,
+			Timestamps: true,
+			Previous:   s.previous,
+			Follow:     follow,
+		}, stdout)
+	}
+
+	var stderr bytes.Buffer
+	exitCode, err := s.conn.ExecuteCommandStream(ctx, s.command(lines, since, until, follow), nil, stdout, &stderr)
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return fmt.Errorf("failed to read %s (exit code %d): %s", s, exitCode, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// localLogFile resolves a log file on the local host. Relative names are taken from dir, and the file
+// must be inside dir so that the tool cannot be used to read arbitrary files of the server.
+func localLogFile(dir, file string) (string, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("local log directory %s is not available: %w", dir, err)
+	}
+	resolved, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s on localhost: %w", file, err)
+	}
+	rel, err := filepath.Rel(resolvedDir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("local file %s is outside %s; read other logs with host or pod", file, dir)
+	}
+	return resolved, nil
+}
+
+// journalMarker reports whether a line is a journalctl marker such as "-- No entries --".
+func journalMarker(line string) bool {
+	return strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --")
+}
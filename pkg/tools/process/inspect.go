@@ -0,0 +1,316 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+)
+
+const maxProbes = 8 // Listening ports probed for pprof
+
+// detailsScript prints the /proc files of a process. It exits with 3 when the process does not exist.
+const detailsScript = `d=/proc/%[1]d
+[ -r "$d/stat" ] || { echo "process %[1]d not found"; exit 3; }
+` + metaScript + `echo "== stat"; cat "$d/stat"
+echo "== status"; cat "$d/status" 2>/dev/null
+echo "== cmdline"; tr '\0' '\n' < "$d/cmdline" 2>/dev/null
+echo "== environ"; tr '\0' '\n' < "$d/environ" 2>/dev/null || echo "!unreadable"
+echo "== exe"; readlink "$d/exe" 2>/dev/null
+echo "== cwd"; readlink "$d/cwd" 2>/dev/null
+echo "== cgroup"; cat "$d/cgroup" 2>/dev/null
+echo "== fd"; ls -l "$d/fd" 2>/dev/null
+echo "== tasks"; cat "$d"/task/*/stat 2>/dev/null
+echo "== net"; for f in tcp tcp6 udp udp6; do sed "s/^/$f /" "$d/net/$f" 2>/dev/null; done
+echo "== go"
+if [ ! -r "$d/exe" ]; then echo unknown
+elif grep -q 'Go buildinf:' "$d/exe" 2>/dev/null; then echo yes; grep -aoE 'go1\.[0-9]+(\.[0-9]+)?' "$d/exe" 2>/dev/null | head -1
+else echo no; fi
+echo "== tracer"
+t=$(sed -n 's/^TracerPid:[[:space:]]*//p' "$d/status" 2>/dev/null)
+if [ -n "$t" ] && [ "$t" != 0 ]; then echo "$t $(cat /proc/$t/comm 2>/dev/null)"; ls -l /proc/$t/fd 2>/dev/null; fi
+echo "== sample"; sleep 1; cat "$d/stat" 2>/dev/null
+true
+`
+
+// probeScript prints the HTTP status of /debug/pprof/ for each address, or "none" without curl and wget.
+const probeScript = `probe() {
+	if command -v curl >/dev/null 2>&1; then curl -s -o /dev/null -m 2 -w '%%{http_code}' "http://$1/debug/pprof/"
+	elif command -v wget >/dev/null 2>&1; then wget -q -T 2 -O /dev/null "http://$1/debug/pprof/" && echo 200
+	else echo none; fi
+}
+echo "== probes"
+for a in %s; do echo "$a $(probe "$a")"; done
+true
+`
+
+// inspect gathers the details of a process.
+func inspect(ctx context.Context, exec executor, pid int) (*Details, error) {
+	output, err := exec.ExecuteCommand(ctx, fmt.Sprintf(detailsScript, pid))
+	if strings.HasPrefix(output, fmt.Sprintf("process %d not found", pid)) {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+	result := procfs.Sections(output)
+	if err != nil && len(result) == 0 {
+		return nil, fmt.Errorf("failed to read /proc/%d: %v", pid, err)
+	}
+	if len(result["stat"]) == 0 {
+		return nil, fmt.Errorf("process %d not found", pid)
+	}
+
+	stat, err := parseStat(result["stat"][0])
+	if err != nil {
+		return nil, err
+	}
+	meta := parseMeta(result["meta"])
+	details := &Details{
+		Process:     newProcess(stat, meta),
+		Args:        redactArgs(result["cmdline"]),
+		Cgroup:      result["cgroup"],
+		FDs:         parseFDs(result["fd"]),
+		FDCounts:    make(map[string]int),
+		EnvReadable: true,
+		GoBinary:    "unknown",
+	}
+	details.Command = strings.Join(details.Args, " ")
+	if env := result["environ"]; len(env) == 1 && env[0] == "!unreadable" {
+		details.EnvReadable = false
+	} else {
+		details.Env = redactEnv(env)
+	}
+	if lines := result["exe"]; len(lines) > 0 {
+		details.Exe = lines[0]
+	}
+	if lines := result["cwd"]; len(lines) > 0 {
+		details.Cwd = lines[0]
+	}
+
+	status := parseStatus(result["status"])
+	if uid := strings.Fields(status["Uid"]); len(uid) > 0 {
+		details.UID, _ = strconv.Atoi(uid[0])
+	}
+	details.User = userName(parsePasswd(result["passwd"]), details.UID)
+	details.RSSKB = statusKB(status["VmRSS"])
+	details.PeakRSSKB = statusKB(status["VmHWM"])
+	details.VMSizeKB = statusKB(status["VmSize"])
+	details.TracerPID, _ = strconv.Atoi(status["TracerPid"])
+
+	if lines := result["sample"]; len(lines) > 0 {
+		if sample, err := parseStat(lines[0]); err == nil {
+			const percent = 100
+			details.CurrentCPUPercent = float64(sample.UTime+sample.STime-stat.UTime-stat.STime) / float64(meta.ClockTicks) * percent
+		}
+	}
+
+	for _, line := range result["tasks"] {
+		if task, err := parseStat(line); err == nil {
+			details.Tasks = append(details.Tasks, Task{TID: task.PID, Name: task.Comm, State: task.State})
+		}
+	}
+
+	// Sockets of the process, matched by inode in the tables of its network namespace
+	sockets := parseSockets(result["net"])
+	owned := make(map[string]bool)
+	for _, fd := range details.FDs {
+		details.FDCounts[fdKind(fd.Target)]++
+		if inode, ok := socketInode(fd.Target); ok {
+			owned[inode] = true
+		}
+	}
+	for _, socket := range sockets {
+		if owned[socket.Inode] {
+			details.Sockets = append(details.Sockets, socket)
+		}
+	}
+
+	if lines := result["go"]; len(lines) > 0 {
+		details.GoBinary = lines[0]
+		if len(lines) > 1 {
+			details.GoVersion = lines[1]
+		}
+	}
+
+	// A process started or attached by dlv is traced by it; dlv itself listens for clients
+	if tracer := result["tracer"]; len(tracer) > 0 {
+		fields := strings.Fields(tracer[0])
+		if len(fields) > 1 {
+			details.Tracer = fields[1]
+		}
+		if details.Tracer == "dlv" {
+			details.DlvAddrs = listening(sockets, parseFDs(tracer[1:]))
+		}
+	}
+	listeners := listening(details.Sockets, details.FDs)
+	if details.Name == "dlv" {
+		details.DlvAddrs = listeners
+	} else if details.GoBinary != "no" && len(listeners) > 0 {
+		details.PprofURLs = probePprof(ctx, exec, listeners)
+	}
+	return details, nil
+}
+
+// parseStatus parses /proc/<pid>/status into its fields.
+func parseStatus(lines []string) map[string]string {
+	status := make(map[string]string)
+	for _, line := range lines {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+	return status
+}
+
+// statusKB parses a size of /proc/<pid>/status, e.g. "1234 kB".
+func statusKB(value string) int64 {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, _ := strconv.ParseInt(fields[0], 10, 64)
+	return kb
+}
+
+// listening returns the local addresses of the listening TCP sockets among the descriptors.
+func listening(sockets []Socket, fds []FD) []string {
+	owned := make(map[string]bool)
+	for _, fd := range fds {
+		if inode, ok := socketInode(fd.Target); ok {
+			owned[inode] = true
+		}
+	}
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, socket := range sockets {
+		if socket.State == "LISTEN" && owned[socket.Inode] && !seen[socket.Local] {
+			seen[socket.Local] = true
+			addrs = append(addrs, socket.Local)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// probeAddr returns the address to connect to for a listening address; wildcards become loopback.
+func probeAddr(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	switch host {
+	case "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// probePprof requests /debug/pprof/ on the listening addresses from the target and returns the URLs that answer.
+func probePprof(ctx context.Context, exec executor, listeners []string) []string {
+	if len(listeners) > maxProbes {
+		listeners = listeners[:maxProbes]
+	}
+	addrs := make([]string, 0, len(listeners))
+	for _, listen := range listeners {
+		addrs = append(addrs, probeAddr(listen))
+	}
+	result, err := run(ctx, exec, fmt.Sprintf(probeScript, strings.Join(addrs, " ")))
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	for _, line := range result["probes"] {
+		fields := strings.Fields(line)
+		const probeFields = 2
+		if len(fields) == probeFields && fields[1] == "200" {
+			urls = append(urls, "http://"+fields[0]+"/debug/pprof/")
+		}
+	}
+	return urls
+}
+
+func formatDetails(d *Details, target string) string {
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("Process %d on %s: %s\n", d.PID, target, d.Name))
+	output.WriteString(fmt.Sprintf("  Command: %s\n", d.commandLine()))
+	output.WriteString(fmt.Sprintf("  Executable: %s\n", valueOr(d.Exe, "(not readable)")))
+	output.WriteString(fmt.Sprintf("  Working directory: %s\n", valueOr(d.Cwd, "(not readable)")))
+	output.WriteString(fmt.Sprintf("  User: %s (uid %d), parent PID: %d, state: %s\n", d.User, d.UID, d.PPID, d.State))
+	output.WriteString(fmt.Sprintf("  Running for: %s\n", formatElapsed(d.ElapsedSeconds)))
+	output.WriteString(fmt.Sprintf("  Memory: RSS %.1f MB, peak RSS %.1f MB, virtual %.1f MB\n",
+		float64(d.RSSKB)/1024, float64(d.PeakRSSKB)/1024, float64(d.VMSizeKB)/1024))
+	output.WriteString(fmt.Sprintf("  CPU: %.1f%% now, %.1f%% average\n", d.CurrentCPUPercent, d.CPUPercent))
+	output.WriteString(fmt.Sprintf("  Threads: %d\n", d.Threads))
+
+	switch d.GoBinary {
+	case "yes":
+		output.WriteString(fmt.Sprintf("  Go binary: yes (%s)\n", valueOr(d.GoVersion, "version unknown")))
+	case "no":
+		output.WriteString("  Go binary: no\n")
+	default:
+		output.WriteString("  Go binary: unknown (executable not readable)\n")
+	}
+	if len(d.PprofURLs) > 0 {
+		output.WriteString(fmt.Sprintf("  pprof: %s\n", strings.Join(d.PprofURLs, ", ")))
+	}
+	if d.TracerPID > 0 {
+		output.WriteString(fmt.Sprintf("  Traced by: %s (PID %d)\n", valueOr(d.Tracer, "unknown"), d.TracerPID))
+	}
+	if len(d.DlvAddrs) > 0 {
+		output.WriteString(fmt.Sprintf("  dlv listening on: %s\n", strings.Join(d.DlvAddrs, ", ")))
+	}
+
+	if len(d.Cgroup) > 0 {
+		output.WriteString("\nCgroup:\n")
+		for _, line := range d.Cgroup {
+			output.WriteString("  " + line + "\n")
+		}
+	}
+
+	output.WriteString(fmt.Sprintf("\nSockets (%d):\n", len(d.Sockets)))
+	for _, s := range d.Sockets {
+		output.WriteString(fmt.Sprintf("  %-5s %-12s %s -> %s\n", s.Proto, s.State, s.Local, s.Remote))
+	}
+
+	kinds := make([]string, 0, len(d.FDCounts))
+	for kind, count := range d.FDCounts {
+		kinds = append(kinds, fmt.Sprintf("%d %s", count, kind))
+	}
+	sort.Strings(kinds)
+	output.WriteString(fmt.Sprintf("\nFile descriptors (%d", len(d.FDs)))
+	if len(kinds) > 0 {
+		output.WriteString(": " + strings.Join(kinds, ", "))
+	}
+	output.WriteString("):\n")
+	if len(d.FDs) == 0 && d.Exe == "" {
+		output.WriteString("  (not readable: run as the process owner or root)\n")
+	}
+	for _, fd := range d.FDs {
+		output.WriteString(fmt.Sprintf("  %d -> %s\n", fd.FD, fd.Target))
+	}
+
+	output.WriteString(fmt.Sprintf("\nThreads (%d):\n", len(d.Tasks)))
+	for _, task := range d.Tasks {
+		output.WriteString(fmt.Sprintf("  %d %s %s\n", task.TID, task.State, task.Name))
+	}
+
+	output.WriteString("\nEnvironment (secrets redacted):\n")
+	if !d.EnvReadable {
+		output.WriteString("  (not readable: run as the process owner or root)\n")
+	}
+	for _, env := range d.Env {
+		output.WriteString("  " + env + "\n")
+	}
+	return output.String()
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
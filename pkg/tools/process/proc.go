@@ -0,0 +1,323 @@
+package process
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+)
+
+const (
+	defaultClockTicks = 100
+	defaultPageSize   = 4096
+	redacted          = "[REDACTED]"
+)
+
+// metaScript prints the uptime in seconds, USER_HZ and the page size.
+const metaScript = `echo "== meta"; echo "$(cut -d' ' -f1 /proc/uptime) $(getconf CLK_TCK 2>/dev/null || echo 100) $(getconf PAGESIZE 2>/dev/null || echo 4096)"
+echo "== passwd"; cat /etc/passwd 2>/dev/null
+`
+
+// listScript prints one line per process: uid, command line and /proc/<pid>/stat, tab separated.
+const listScript = metaScript + `echo "== processes"
+for d in /proc/[0-9]*; do
+	s=$(cat "$d/stat" 2>/dev/null) || continue
+	u=$(sed -n 's/^Uid:[[:space:]]*\([0-9]*\).*/\1/p' "$d/status" 2>/dev/null)
+	c=$(tr '\0\t\n' '   ' < "$d/cmdline" 2>/dev/null)
+	printf '%s\t%s\t%s\n' "$u" "$c" "$s"
+done
+`
+
+// socketsScript prints the TCP and UDP tables and the socket inodes of every readable process.
+const socketsScript = `echo "== net"
+for f in tcp tcp6 udp udp6; do sed "s/^/$f /" /proc/net/$f 2>/dev/null; done
+echo "== sockets"
+for d in /proc/[0-9]*; do ls -l "$d/fd" 2>/dev/null | sed -n "s|.*socket:\[\([0-9]*\)\]\$|${d#/proc/} \1|p"; done
+true
+`
+
+// envSecretRe matches environment variable and flag names whose values are redacted.
+var envSecretRe = regexp.MustCompile(`(?i)(pass|secret|token|key|credential|auth|cookie|session|private|dsn|cert)`)
+
+// envNameRe matches the name of an environment variable; other lines of environ continue a value.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// urlPasswordRe matches the password of credentials embedded in URLs.
+var urlPasswordRe = regexp.MustCompile(`(://[^/\s:@]*:)[^/\s@]+@`)
+
+// dsnPasswordRe matches the password of a MySQL DSN such as user:pass@tcp(db:3306)/app.
+var dsnPasswordRe = regexp.MustCompile(`^([^/\s:@]+:)[^\s@]+@(tcp|unix)\(`)
+
+// kvPasswordRe matches the password of a key=value DSN such as "host=db password=secret".
+var kvPasswordRe = regexp.MustCompile(`(?i)\b(password|passwd|pwd)=[^\s;&]+`)
+
+// procStat holds the fields of /proc/<pid>/stat used by the tool.
+type procStat struct {
+	PID       int
+	Comm      string
+	State     string
+	PPID      int
+	UTime     int64
+	STime     int64
+	Threads   int
+	StartTime int64
+	RSSPages  int64
+}
+
+// procMeta holds the host values needed to turn /proc counters into times and sizes.
+type procMeta struct {
+	Uptime     float64
+	ClockTicks int64
+	PageSize   int64
+}
+
+// parseStat parses a /proc/<pid>/stat line. The command name may contain spaces and parentheses.
+func parseStat(line string) (procStat, error) {
+	open := strings.IndexByte(line, '(')
+	closing := strings.LastIndexByte(line, ')')
+	if open < 0 || closing < open {
+		return procStat{}, fmt.Errorf("invalid stat line: %q", line)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(line[:open]))
+	if err != nil {
+		return procStat{}, fmt.Errorf("invalid stat line: %q", line)
+	}
+	// Fields after the command name start with field 3 (state) of proc(5)
+	fields := strings.Fields(line[closing+1:])
+	const minStatFields = 22
+	if len(fields) < minStatFields {
+		return procStat{}, fmt.Errorf("invalid stat line: %q", line)
+	}
+	stat := procStat{
+		PID:   pid,
+		Comm:  line[open+1 : closing],
+		State: fields[0],
+	}
+	stat.PPID, _ = strconv.Atoi(fields[1])
+	stat.UTime, _ = strconv.ParseInt(fields[11], 10, 64)
+	stat.STime, _ = strconv.ParseInt(fields[12], 10, 64)
+	stat.Threads, _ = strconv.Atoi(fields[17])
+	stat.StartTime, _ = strconv.ParseInt(fields[19], 10, 64)
+	stat.RSSPages, _ = strconv.ParseInt(fields[21], 10, 64)
+	return stat, nil
+}
+
+// parseMeta parses the line printed by metaScript, falling back to common defaults.
+func parseMeta(lines []string) procMeta {
+	meta := procMeta{ClockTicks: defaultClockTicks, PageSize: defaultPageSize}
+	if len(lines) == 0 {
+		return meta
+	}
+	fields := strings.Fields(lines[0])
+	if len(fields) > 0 {
+		meta.Uptime, _ = strconv.ParseFloat(fields[0], 64)
+	}
+	if len(fields) > 1 {
+		if ticks, err := strconv.ParseInt(fields[1], 10, 64); err == nil && ticks > 0 {
+			meta.ClockTicks = ticks
+		}
+	}
+	if len(fields) > 2 {
+		if size, err := strconv.ParseInt(fields[2], 10, 64); err == nil && size > 0 {
+			meta.PageSize = size
+		}
+	}
+	return meta
+}
+
+// parsePasswd maps uids to user names.
+func parsePasswd(lines []string) map[int]string {
+	users := make(map[int]string)
+	for _, line := range lines {
+		fields := strings.Split(line, ":")
+		const minPasswdFields = 3
+		if len(fields) < minPasswdFields {
+			continue
+		}
+		if uid, err := strconv.Atoi(fields[2]); err == nil {
+			if _, ok := users[uid]; !ok {
+				users[uid] = fields[0]
+			}
+		}
+	}
+	return users
+}
+
+// elapsed returns the seconds since the process started.
+func (m procMeta) elapsed(stat procStat) float64 {
+	elapsed := m.Uptime - float64(stat.StartTime)/float64(m.ClockTicks)
+	if elapsed < 0 {
+		return 0
+	}
+	return elapsed
+}
+
+// cpuPercent returns the average CPU usage of the process over its lifetime.
+func (m procMeta) cpuPercent(stat procStat) float64 {
+	elapsed := m.elapsed(stat)
+	if elapsed <= 0 {
+		return 0
+	}
+	const percent = 100
+	return float64(stat.UTime+stat.STime) / float64(m.ClockTicks) / elapsed * percent
+}
+
+// parseSockets parses /proc/net tables prefixed with their protocol, as printed by socketsScript.
+func parseSockets(lines []string) []Socket {
+	var sockets []Socket
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		const minSocketFields = 11
+		if len(fields) < minSocketFields || fields[1] == "sl" {
+			continue
+		}
+		proto := fields[0]
+		localIP, port, err := procfs.ParseHexAddr(fields[2])
+		if err != nil {
+			continue
+		}
+		remoteIP, remotePort, err := procfs.ParseHexAddr(fields[3])
+		if err != nil {
+			continue
+		}
+		local := net.JoinHostPort(localIP, strconv.Itoa(port))
+		remote := net.JoinHostPort(remoteIP, strconv.Itoa(remotePort))
+		state, _ := procfs.TCPState(fields[4])
+		if strings.HasPrefix(proto, "udp") {
+			state = "UNCONN"
+			if fields[4] == "01" {
+				state = "ESTABLISHED"
+			}
+		}
+		sockets = append(sockets, Socket{
+			Proto:  proto,
+			Local:  local,
+			Remote: remote,
+			Port:   port,
+			State:  state,
+			Inode:  fields[10],
+		})
+	}
+	return sockets
+}
+
+// parseFDs parses `ls -l /proc/<pid>/fd` into descriptor numbers and link targets.
+func parseFDs(lines []string) []FD {
+	var fds []FD
+	for _, line := range lines {
+		left, target, ok := strings.Cut(line, " -> ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(left)
+		if len(fields) == 0 {
+			continue
+		}
+		fd, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			continue
+		}
+		fds = append(fds, FD{FD: fd, Target: target})
+	}
+	return fds
+}
+
+// fdKind classifies a descriptor target: file, socket, pipe, anon_inode or device.
+func fdKind(target string) string {
+	switch {
+	case strings.HasPrefix(target, "socket:"):
+		return "socket"
+	case strings.HasPrefix(target, "pipe:"):
+		return "pipe"
+	case strings.HasPrefix(target, "anon_inode:"):
+		return "anon_inode"
+	case strings.HasPrefix(target, "/dev/"):
+		return "device"
+	default:
+		return "file"
+	}
+}
+
+// socketInode returns the inode of a "socket:[12345]" descriptor target.
+func socketInode(target string) (string, bool) {
+	if !strings.HasPrefix(target, "socket:[") || !strings.HasSuffix(target, "]") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]"), true
+}
+
+// redactCredentials hides passwords embedded in URLs and DSNs.
+func redactCredentials(value string) string {
+	value = urlPasswordRe.ReplaceAllString(value, "${1}"+redacted+"@")
+	value = dsnPasswordRe.ReplaceAllString(value, "${1}"+redacted+"@${2}(")
+	return kvPasswordRe.ReplaceAllString(value, "${1}="+redacted)
+}
+
+// redactEnv hides the values of variables that look like secrets and passwords in URLs and DSNs.
+// environ is split on newlines, so a line only starts a new variable when it begins with a valid
+// name and "="; a secret PEM block continues until its END line, as base64 padding looks like "name=".
+func redactEnv(lines []string) []string {
+	var env []string
+	secret, pem := false, false
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || !envNameRe.MatchString(key) || pem {
+			// Continuation of a value containing a newline
+			if pem && strings.HasPrefix(line, "-----END ") {
+				pem = false
+			}
+			if len(env) > 0 && !secret {
+				env[len(env)-1] += `\n` + redactCredentials(line)
+			}
+			continue
+		}
+		secret = envSecretRe.MatchString(key)
+		pem = strings.HasPrefix(value, "-----BEGIN ")
+		if secret {
+			value = redacted
+		} else {
+			value = redactCredentials(value)
+		}
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+// redactArgs hides the values of secret-looking flags and variables in a command line, given as
+// "--password=x", "--password x" or "PASSWORD=x", and passwords in URLs and DSNs.
+func redactArgs(args []string) []string {
+	redactedArgs := make([]string, len(args))
+	secretNext := false
+	for i, arg := range args {
+		if secretNext {
+			secretNext = false
+			if !strings.HasPrefix(arg, "-") {
+				redactedArgs[i] = redacted
+				continue
+			}
+		}
+		switch {
+		case strings.HasPrefix(arg, "-"):
+			name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			if envSecretRe.MatchString(name) {
+				if hasValue {
+					redactedArgs[i] = arg[:strings.Index(arg, "=")+1] + redacted
+				} else {
+					redactedArgs[i] = arg
+					secretNext = true
+				}
+				continue
+			}
+		default:
+			if name, _, ok := strings.Cut(arg, "="); ok && envNameRe.MatchString(name) && envSecretRe.MatchString(name) {
+				redactedArgs[i] = name + "=" + redacted
+				continue
+			}
+		}
+		redactedArgs[i] = redactCredentials(arg)
+	}
+	return redactedArgs
+}
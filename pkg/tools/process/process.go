@@ -0,0 +1,368 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
+)
+
+type Input struct {
+	Host        string `json:"host,omitempty" validate:"omitempty,hostname|ip"`                           // SSH host or ~/.ssh/config alias (omit for a pod or the local host)
+	Port        int    `json:"port,omitempty" validate:"min=0,max=65535"`                                 // SSH port (default: from ssh config, else 22)
+	User        string `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"` // SSH user (default: from ssh config, else current user)
+	Pod         string `json:"pod,omitempty" validate:"omitempty,max=253"`                                // Pod to inspect with kubectl exec, "name" or "pod/name" (instead of host)
+	Namespace   string `json:"namespace,omitempty" validate:"omitempty,alphanum|contains=-,max=63"`       // Namespace of the pod (default: "default")
+	Container   string `json:"container,omitempty" validate:"omitempty,max=253"`                          // Container in the pod (default: kubectl's default container)
+	KubeConfig  string `json:"kubeconfig,omitempty" validate:"omitempty,filepath"`                        // Path to kubeconfig file
+	Name        string `json:"name,omitempty" validate:"omitempty,max=1024"`                              // Regular expression matched against process names and command lines
+	ProcessUser string `json:"process_user,omitempty" validate:"omitempty,max=32"`                        // Only processes owned by this user name or uid
+	SocketPort  int    `json:"socket_port,omitempty" validate:"min=0,max=65535"`                          // Only processes with a TCP or UDP socket bound to this local port
+	PID         int    `json:"pid,omitempty" validate:"min=0,max=2147483647"`                             // Show details of this process instead of a list
+	MaxLines    int    `json:"max_lines,omitempty" validate:"min=0,max=100000"`                           // Maximum lines to return (default: 200)
+	Offset      int    `json:"offset,omitempty" validate:"min=0"`                                         // Line offset for pagination
+}
+
+type Output struct {
+	Target    string    `json:"target"`
+	Processes []Process `json:"processes,omitempty"`
+	Details   *Details  `json:"details,omitempty"`
+}
+
+// Process is a row of the process list.
+type Process struct {
+	PID            int     `json:"pid"`
+	PPID           int     `json:"ppid"`
+	UID            int     `json:"uid"`
+	User           string  `json:"user"`
+	State          string  `json:"state"`
+	Name           string  `json:"name"`
+	Command        string  `json:"command"`
+	RSSKB          int64   `json:"rss_kb"`
+	CPUPercent     float64 `json:"cpu_percent"` // Average over the lifetime of the process
+	Threads        int     `json:"threads"`
+	ElapsedSeconds int64   `json:"elapsed_seconds"`
+}
+
+// Details describes a single process.
+type Details struct {
+	Process
+	Exe               string         `json:"exe"`
+	Cwd               string         `json:"cwd"`
+	Args              []string       `json:"args"` // Values of secret-looking flags are redacted
+	Env               []string       `json:"env"`  // Values of secret-looking variables are redacted
+	EnvReadable       bool           `json:"env_readable"`
+	Cgroup            []string       `json:"cgroup"`
+	FDs               []FD           `json:"fds"`
+	FDCounts          map[string]int `json:"fd_counts"`
+	Sockets           []Socket       `json:"sockets"`
+	Tasks             []Task         `json:"tasks"`
+	VMSizeKB          int64          `json:"vm_size_kb"`
+	PeakRSSKB         int64          `json:"peak_rss_kb"`
+	CurrentCPUPercent float64        `json:"current_cpu_percent"` // Sampled over one second
+	GoBinary          string         `json:"go_binary"`           // "yes", "no" or "unknown" when the executable is not readable
+	GoVersion         string         `json:"go_version,omitempty"`
+	PprofURLs         []string       `json:"pprof_urls,omitempty"`
+	DlvAddrs          []string       `json:"dlv_addrs,omitempty"`
+	TracerPID         int            `json:"tracer_pid,omitempty"`
+	Tracer            string         `json:"tracer,omitempty"`
+}
+
+// FD is an open file descriptor and its target.
+type FD struct {
+	FD     int    `json:"fd"`
+	Target string `json:"target"`
+}
+
+// Socket is an entry of /proc/net/{tcp,tcp6,udp,udp6}.
+type Socket struct {
+	Proto  string `json:"proto"`
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+	Port   int    `json:"-"`
+	State  string `json:"state"`
+	Inode  string `json:"inode"`
+}
+
+// Task is a thread of the process.
+type Task struct {
+	TID   int    `json:"tid"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// executor runs shell commands on the target: an SSH host, a pod or the local host (see target.Target).
+type executor interface {
+	ExecuteCommand(ctx context.Context, command string) (string, error)
+}
+
+type Tool struct {
+	logger    zerolog.Logger
+	validator *validator.Validate
+}
+
+func (t *Tool) Register(srv *server.Server) {
+	processTool := &mcp.Tool{
+		Name:        "process",
+		Description: "List processes on a remote host via SSH, in a pod or locally, filtered by name, user or socket port, or inspect one PID: command line, redacted environment, fds, sockets, threads, memory, CPU, cgroup and Go pprof/dlv endpoints",
+	}
+
+	mcp.AddTool(&srv.Server, processTool, t.ProcessHandler)
+	t.logger.Debug().Msg("process tool registered")
+}
+
+func (t *Tool) ProcessHandler(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
+	input := params.Arguments
+
+	// Validate input using validator
+	if err := t.validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+	options := target.Options{
+		Host:       input.Host,
+		Port:       input.Port,
+		User:       input.User,
+		Pod:        input.Pod,
+		Namespace:  input.Namespace,
+		Container:  input.Container,
+		KubeConfig: input.KubeConfig,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	if input.PID > 0 && (input.Name != "" || input.ProcessUser != "" || input.SocketPort > 0) {
+		return nil, errors.New("name, process_user and socket_port filter the list and cannot be combined with pid")
+	}
+	var nameRe *regexp.Regexp
+	if input.Name != "" {
+		var err error
+		if nameRe, err = regexp.Compile(input.Name); err != nil {
+			return nil, fmt.Errorf("invalid name regular expression: %w", err)
+		}
+	}
+
+	maxLines := types.MaxDefaultLines
+	if input.MaxLines > 0 {
+		maxLines = input.MaxLines
+	}
+
+	conn := target.New(options)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	var (
+		output Output
+		text   string
+		err    error
+	)
+	output.Target = conn.Name()
+	if input.PID > 0 {
+		t.logger.Debug().Str("target", conn.Name()).Int("pid", input.PID).Msg("inspecting process")
+		output.Details, err = inspect(ctx, conn, input.PID)
+		if err != nil {
+			return nil, err
+		}
+		text = formatDetails(output.Details, conn.Name())
+	} else {
+		t.logger.Debug().Str("target", conn.Name()).Msg("listing processes")
+		var total int
+		output.Processes, total, err = list(ctx, conn, nameRe, input.ProcessUser, input.SocketPort)
+		if err != nil {
+			return nil, err
+		}
+		text = formatList(output.Processes, total, conn.Name(), input)
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: types.Paginate(text, maxLines, input.Offset),
+			},
+		},
+		StructuredContent: output,
+	}, nil
+}
+
+// run executes script, accepting a failed exit status as long as it printed its sections.
+func run(ctx context.Context, exec executor, script string) (map[string][]string, error) {
+	output, err := exec.ExecuteCommand(ctx, script)
+	result := procfs.Sections(output)
+	if err != nil && len(result) == 0 {
+		return nil, fmt.Errorf("failed to read /proc: %v", err)
+	}
+	return result, nil
+}
+
+// list returns the processes matching the filters, sorted by PID, and the number of processes seen.
+func list(ctx context.Context, exec executor, nameRe *regexp.Regexp, user string, port int) ([]Process, int, error) {
+	script := "echo \"== self\"; echo $$\n" + listScript
+	if port > 0 {
+		script += socketsScript
+	}
+	result, err := run(ctx, exec, script)
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, ok := result["processes"]; !ok {
+		return nil, 0, errors.New("failed to list processes: /proc is not readable")
+	}
+
+	self := -1
+	if lines := result["self"]; len(lines) > 0 {
+		self, _ = strconv.Atoi(strings.TrimSpace(lines[0]))
+	}
+	meta := parseMeta(result["meta"])
+	users := parsePasswd(result["passwd"])
+
+	var portPIDs map[int]bool
+	if port > 0 {
+		portPIDs = socketOwners(result["net"], result["sockets"], port)
+	}
+
+	var processes []Process
+	total := 0
+	for _, line := range result["processes"] {
+		fields := strings.SplitN(line, "\t", 3)
+		const processFields = 3
+		if len(fields) < processFields {
+			continue
+		}
+		stat, err := parseStat(fields[2])
+		if err != nil || stat.PID == self || stat.PPID == self {
+			continue
+		}
+		total++
+		process := newProcess(stat, meta)
+		process.UID, _ = strconv.Atoi(fields[0])
+		process.User = userName(users, process.UID)
+		// Redacted before filtering, so that name cannot be used to probe for secrets
+		process.Command = strings.Join(redactArgs(strings.Fields(fields[1])), " ")
+
+		if nameRe != nil && !nameRe.MatchString(process.Name) && !nameRe.MatchString(process.Command) {
+			continue
+		}
+		if user != "" && user != process.User && user != strconv.Itoa(process.UID) {
+			continue
+		}
+		if portPIDs != nil && !portPIDs[process.PID] {
+			continue
+		}
+		processes = append(processes, process)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].PID < processes[j].PID
+	})
+	return processes, total, nil
+}
+
+// socketOwners returns the PIDs with a socket bound to the local port.
+func socketOwners(netLines, socketLines []string, port int) map[int]bool {
+	inodes := make(map[string]bool)
+	for _, socket := range parseSockets(netLines) {
+		if socket.Port == port {
+			inodes[socket.Inode] = true
+		}
+	}
+	owners := make(map[int]bool)
+	for _, line := range socketLines {
+		fields := strings.Fields(line)
+		const socketFields = 2
+		if len(fields) != socketFields || !inodes[fields[1]] {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			owners[pid] = true
+		}
+	}
+	return owners
+}
+
+func newProcess(stat procStat, meta procMeta) Process {
+	const kb = 1024
+	return Process{
+		PID:            stat.PID,
+		PPID:           stat.PPID,
+		State:          stat.State,
+		Name:           stat.Comm,
+		RSSKB:          stat.RSSPages * meta.PageSize / kb,
+		CPUPercent:     meta.cpuPercent(stat),
+		Threads:        stat.Threads,
+		ElapsedSeconds: int64(meta.elapsed(stat)),
+	}
+}
+
+func userName(users map[int]string, uid int) string {
+	if name, ok := users[uid]; ok {
+		return name
+	}
+	return strconv.Itoa(uid)
+}
+
+// commandLine returns the command line of a process, or its name in brackets for kernel threads.
+func (p Process) commandLine() string {
+	if p.Command == "" {
+		return "[" + p.Name + "]"
+	}
+	return p.Command
+}
+
+func formatList(processes []Process, total int, target string, input Input) string {
+	var output strings.Builder
+	var filters []string
+	if input.Name != "" {
+		filters = append(filters, fmt.Sprintf("name =~ %q", input.Name))
+	}
+	if input.ProcessUser != "" {
+		filters = append(filters, "user "+input.ProcessUser)
+	}
+	if input.SocketPort > 0 {
+		filters = append(filters, fmt.Sprintf("socket port %d", input.SocketPort))
+	}
+	if len(filters) > 0 {
+		output.WriteString(fmt.Sprintf("Processes on %s: %d of %d (%s)\n", target, len(processes), total, strings.Join(filters, ", ")))
+	} else {
+		output.WriteString(fmt.Sprintf("Processes on %s: %d\n", target, total))
+	}
+	if input.SocketPort > 0 {
+		output.WriteString("Sockets of processes owned by other users are only visible to root.\n")
+	}
+	output.WriteString(fmt.Sprintf("%7s %7s %-12s %-1s %9s %6s %4s %10s  %s\n", "PID", "PPID", "USER", "S", "RSS MB", "CPU%", "THR", "ELAPSED", "COMMAND"))
+	for _, p := range processes {
+		output.WriteString(fmt.Sprintf("%7d %7d %-12s %-1s %9.1f %6.1f %4d %10s  %s\n",
+			p.PID, p.PPID, p.User, p.State, float64(p.RSSKB)/1024, p.CPUPercent, p.Threads,
+			formatElapsed(p.ElapsedSeconds), p.commandLine()))
+	}
+	return output.String()
+}
+
+// formatElapsed formats seconds as a duration, e.g. "3h2m5s" or "12d4h".
+func formatElapsed(seconds int64) string {
+	const day = 24 * 60 * 60
+	if seconds >= day {
+		return fmt.Sprintf("%dd%dh", seconds/day, seconds%day/3600)
+	}
+	return (time.Duration(seconds) * time.Second).String()
+}
+
+func New(logger zerolog.Logger) tools.Tool {
+	validate := validator.New()
+
+	return &Tool{
+		logger:    logger.With().Str("tool", "process").Logger(),
+		validator: validate,
+	}
+}
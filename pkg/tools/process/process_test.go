@@ -0,0 +1,207 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/local"
+)
+
+type ProcessTestSuite struct {
+	suite.Suite
+	tool *Tool
+}
+
+func TestProcessTestSuite(t *testing.T) {
+	suite.Run(t, new(ProcessTestSuite))
+}
+
+func (suite *ProcessTestSuite) SetupTest() {
+	suite.tool = &Tool{
+		logger:    zerolog.Nop(),
+		validator: validator.New(),
+	}
+}
+
+func (suite *ProcessTestSuite) call(input Input) (*mcp.CallToolResultFor[Output], error) {
+	return suite.tool.ProcessHandler(context.Background(), &mcp.ServerSession{}, &mcp.CallToolParamsFor[Input]{Arguments: input})
+}
+
+func (suite *ProcessTestSuite) TestInputValidation() {
+	testCases := []struct {
+		name     string
+		input    Input
+		errorMsg string
+	}{
+		{
+			name:     "host and pod",
+			input:    Input{Host: "192.168.1.100", Pod: "app-0"},
+			errorMsg: "cannot specify both host and pod",
+		},
+		{
+			name:     "namespace without pod",
+			input:    Input{Namespace: "prod"},
+			errorMsg: "namespace, container and kubeconfig require pod",
+		},
+		{
+			name:     "filters with pid",
+			input:    Input{PID: 1, Name: "app"},
+			errorMsg: "cannot be combined with pid",
+		},
+		{
+			name:     "invalid regex",
+			input:    Input{Name: "app("},
+			errorMsg: "invalid name regular expression",
+		},
+		{
+			name:     "invalid host",
+			input:    Input{Host: "bad host"},
+			errorMsg: "validation error",
+		},
+		{
+			name:     "invalid socket port",
+			input:    Input{SocketPort: 70000},
+			errorMsg: "validation error",
+		},
+		{
+			name:     "negative pid",
+			input:    Input{PID: -1},
+			errorMsg: "validation error",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			_, err := suite.call(tc.input)
+			suite.Require().Error(err)
+			suite.Contains(err.Error(), tc.errorMsg)
+		})
+	}
+}
+
+// serve starts a pprof endpoint in the test process and returns its port.
+func (suite *ProcessTestSuite) serve() int {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	server := &http.Server{Handler: mux}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	suite.T().Cleanup(func() {
+		_ = server.Close()
+	})
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func (suite *ProcessTestSuite) TestListLocal() {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		suite.T().Skip("requires /proc")
+	}
+	port := suite.serve()
+	pid := os.Getpid()
+
+	processes, total, err := list(context.Background(), local.New(), nil, "", 0)
+	suite.Require().NoError(err)
+	suite.Equal(total, len(processes))
+	found := false
+	for _, p := range processes {
+		if p.PID == pid {
+			found = true
+			suite.Equal(os.Getuid(), p.UID)
+			suite.Positive(p.RSSKB)
+			suite.Positive(p.Threads)
+		}
+	}
+	suite.True(found, "test process not listed")
+
+	// The socket port filter finds the process serving on it
+	processes, _, err = list(context.Background(), local.New(), regexp.MustCompile(`\.test`), strconv.Itoa(os.Getuid()), port)
+	suite.Require().NoError(err)
+	suite.Require().Len(processes, 1)
+	suite.Equal(pid, processes[0].PID)
+
+	result, err := suite.call(Input{SocketPort: port})
+	suite.Require().NoError(err)
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, "Processes on localhost: 1 of ")
+	suite.Contains(text, fmt.Sprintf("(socket port %d)", port))
+	suite.Regexp(fmt.Sprintf(`(?m)^\s+%d\s`, pid), text)
+}
+
+func (suite *ProcessTestSuite) TestInspectLocal() {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		suite.T().Skip("requires /proc")
+	}
+	port := suite.serve()
+
+	result, err := suite.call(Input{PID: os.Getpid()})
+	suite.Require().NoError(err)
+	details := result.StructuredContent.Details
+	suite.Require().NotNil(details)
+	suite.Equal(os.Getpid(), details.PID)
+	suite.Equal("yes", details.GoBinary)
+	suite.Regexp(`^go1\.\d+`, details.GoVersion)
+	suite.True(details.EnvReadable)
+	suite.Positive(details.FDCounts["socket"])
+	suite.NotEmpty(details.Tasks)
+	suite.Contains(details.PprofURLs, fmt.Sprintf("http://127.0.0.1:%d/debug/pprof/", port))
+
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.Contains(text, fmt.Sprintf("Process %d on localhost", os.Getpid()))
+	suite.Contains(text, "Go binary: yes (go1.")
+	suite.Contains(text, fmt.Sprintf("tcp   LISTEN       127.0.0.1:%d -> 0.0.0.0:0", port))
+
+	_, err = suite.call(Input{PID: 2147483647})
+	suite.ErrorContains(err, "process 2147483647 not found")
+}
+
+func (suite *ProcessTestSuite) TestCommandLineRedacted() {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		suite.T().Skip("requires /proc")
+	}
+	cmd := exec.Command("sh", "-c", "sleep 30", "app", "--password=hunter2", "--db", "postgres://app:s3cret@db/app")
+	suite.Require().NoError(cmd.Start())
+	suite.T().Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+
+	result, err := suite.call(Input{PID: cmd.Process.Pid})
+	suite.Require().NoError(err)
+	details := result.StructuredContent.Details
+	suite.Require().NotNil(details)
+	suite.Equal([]string{"sh", "-c", "sleep 30", "app", "--password=[REDACTED]", "--db", "postgres://app:[REDACTED]@db/app"}, details.Args)
+	text := result.Content[0].(*mcp.TextContent).Text
+	suite.NotContains(text, "hunter2")
+	suite.NotContains(text, "s3cret")
+
+	// Listed command lines are redacted before the name filter, which cannot find the secret
+	listed := func(name string) *Process {
+		processes, _, err := list(context.Background(), local.New(), regexp.MustCompile(name), "", 0)
+		suite.Require().NoError(err)
+		for _, p := range processes {
+			if p.PID == cmd.Process.Pid {
+				return &p
+			}
+		}
+		return nil
+	}
+	suite.Nil(listed(`hunter2`))
+	p := listed(`--password=\[REDACTED\]`)
+	suite.Require().NotNil(p)
+	suite.Equal("sh -c sleep 30 app --password=[REDACTED] --db postgres://app:[REDACTED]@db/app", p.Command)
+}
@@ -0,0 +1,182 @@
+package sshexec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const debugGCFlags = "all=-N -l" // Disables optimizations and inlining for delve
+
+var (
+	buildTagRe = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	// ldflagsXRe matches the importpath.name=value argument of -X; quotes would change how go splits -ldflags
+	ldflagsXRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_./-]*\.[A-Za-z0-9_]+=[^'"]*$`)
+)
+
+// platform is the GOOS/GOARCH (and GOARM) of a remote host.
+type platform struct {
+	GOOS   string
+	GOARCH string
+	GOARM  string
+}
+
+func (p platform) String() string {
+	if p.GOARM != "" {
+		return fmt.Sprintf("%s/%s (GOARM=%s)", p.GOOS, p.GOARCH, p.GOARM)
+	}
+	return p.GOOS + "/" + p.GOARCH
+}
+
+// unameOS maps `uname -s` to GOOS.
+var unameOS = map[string]string{
+	"Linux":     "linux",
+	"Darwin":    "darwin",
+	"FreeBSD":   "freebsd",
+	"OpenBSD":   "openbsd",
+	"NetBSD":    "netbsd",
+	"DragonFly": "dragonfly",
+	"SunOS":     "solaris",
+	"AIX":       "aix",
+}
+
+// unameArch maps `uname -m` to GOARCH and GOARM.
+var unameArch = map[string]platform{
+	"x86_64":      {GOARCH: "amd64"},
+	"amd64":       {GOARCH: "amd64"},
+	"i386":        {GOARCH: "386"},
+	"i686":        {GOARCH: "386"},
+	"aarch64":     {GOARCH: "arm64"},
+	"arm64":       {GOARCH: "arm64"},
+	"armv7l":      {GOARCH: "arm", GOARM: "7"},
+	"armv6l":      {GOARCH: "arm", GOARM: "6"},
+	"armv5tel":    {GOARCH: "arm", GOARM: "5"},
+	"riscv64":     {GOARCH: "riscv64"},
+	"ppc64le":     {GOARCH: "ppc64le"},
+	"ppc64":       {GOARCH: "ppc64"},
+	"s390x":       {GOARCH: "s390x"},
+	"mips64":      {GOARCH: "mips64"},
+	"loongarch64": {GOARCH: "loong64"},
+}
+
+// parseUname converts the output of `uname -sm` to a platform.
+func parseUname(output string) (platform, error) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return platform{}, fmt.Errorf("unexpected uname output: %q", strings.TrimSpace(output))
+	}
+	goos, ok := unameOS[fields[0]]
+	if !ok {
+		return platform{}, fmt.Errorf("unsupported remote OS: %s", fields[0])
+	}
+	p, ok := unameArch[fields[1]]
+	if !ok {
+		return platform{}, fmt.Errorf("unsupported remote architecture: %s", fields[1])
+	}
+	p.GOOS = goos
+	return p, nil
+}
+
+// remotePlatform detects the GOOS/GOARCH of the remote host.
+func remotePlatform(ctx context.Context, executor commandExecutor) (platform, error) {
+	output, err := executor.ExecuteCommand(ctx, "uname -sm")
+	if err != nil {
+		return platform{}, fmt.Errorf("failed to detect remote platform: %w", err)
+	}
+	return parseUname(output)
+}
+
+// buildOptions describes a build of a Go package for a remote host.
+type buildOptions struct {
+	Package string
+	Dir     string
+	Tags    []string
+	LDFlags string
+	Debug   bool
+}
+
+// validate rejects options that would pass other flags to go build or the linker: a package starting
+// with "-" would be parsed as a flag, and linker flags such as -extldflags run arbitrary commands.
+func (o buildOptions) validate() error {
+	if strings.HasPrefix(o.Package, "-") {
+		return fmt.Errorf("invalid package: %q", o.Package)
+	}
+	for _, tag := range o.Tags {
+		if !buildTagRe.MatchString(tag) {
+			return fmt.Errorf("invalid build tag: %q", tag)
+		}
+	}
+	flags := strings.Fields(o.LDFlags)
+	for i := 0; i < len(flags); i++ {
+		switch {
+		case flags[i] == "-s" || flags[i] == "-w":
+		case flags[i] == "-X" && i+1 < len(flags) && ldflagsXRe.MatchString(flags[i+1]):
+			i++
+		default:
+			return fmt.Errorf("invalid ldflags %q: only -s, -w and -X importpath.name=value are allowed", o.LDFlags)
+		}
+	}
+	return nil
+}
+
+// binaryName returns the name of the binary built from the package.
+func (o buildOptions) binaryName() (string, error) {
+	pkg := strings.TrimSuffix(o.Package, "/...")
+	if pkg == "." || pkg == "./" || pkg == "" {
+		dir, err := filepath.Abs(o.Dir)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Base(dir), nil
+	}
+	return filepath.Base(pkg), nil
+}
+
+// args returns the arguments of `go build` writing the binary to output.
+func (o buildOptions) args(output string) []string {
+	args := []string{"build", "-o", output}
+	if len(o.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(o.Tags, ","))
+	}
+	if o.LDFlags != "" {
+		args = append(args, "-ldflags", o.LDFlags)
+	}
+	if o.Debug {
+		args = append(args, "-gcflags", debugGCFlags)
+	}
+	return append(args, o.Package)
+}
+
+// buildBinary cross-compiles the package for target into a new directory under os.TempDir.
+// It returns the binary path; the caller removes its directory.
+func buildBinary(ctx context.Context, opts buildOptions, target platform) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	name, err := opts.binaryName()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve build directory: %w", err)
+	}
+
+	outDir, err := os.MkdirTemp("", "remote-debugger-mcp-build-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create build directory: %w", err)
+	}
+	binary := filepath.Join(outDir, name)
+
+	cmd := exec.CommandContext(ctx, "go", opts.args(binary)...)
+	cmd.Dir = opts.Dir
+	cmd.Env = append(os.Environ(), "GOOS="+target.GOOS, "GOARCH="+target.GOARCH, "CGO_ENABLED=0")
+	if target.GOARM != "" {
+		cmd.Env = append(cmd.Env, "GOARM="+target.GOARM)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.RemoveAll(outDir)
+		return "", fmt.Errorf("go build %s for %s failed: %v\n%s", opts.Package, target, err, strings.TrimSpace(string(output)))
+	}
+	return binary, nil
+}
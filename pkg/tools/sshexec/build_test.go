@@ -0,0 +1,113 @@
+package sshexec
+
+import (
+	"context"
+	"debug/buildinfo"
+	"os"
+	"path/filepath"
+)
+
+func (suite *SSHExecTestSuite) TestParseUname() {
+	testCases := []struct {
+		output   string
+		expected platform
+		errorMsg string
+	}{
+		{output: "Linux x86_64\n", expected: platform{GOOS: "linux", GOARCH: "amd64"}},
+		{output: "Linux aarch64", expected: platform{GOOS: "linux", GOARCH: "arm64"}},
+		{output: "Linux armv7l", expected: platform{GOOS: "linux", GOARCH: "arm", GOARM: "7"}},
+		{output: "Darwin arm64", expected: platform{GOOS: "darwin", GOARCH: "arm64"}},
+		{output: "FreeBSD amd64", expected: platform{GOOS: "freebsd", GOARCH: "amd64"}},
+		{output: "Linux sparc64", errorMsg: "unsupported remote architecture: sparc64"},
+		{output: "MINGW64_NT-10.0 x86_64", errorMsg: "unsupported remote OS"},
+		{output: "sh: uname: not found", errorMsg: "unexpected uname output"},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.output, func() {
+			p, err := parseUname(tc.output)
+			if tc.errorMsg != "" {
+				suite.ErrorContains(err, tc.errorMsg)
+				return
+			}
+			suite.Require().NoError(err)
+			suite.Equal(tc.expected, p)
+		})
+	}
+	suite.Equal("linux/arm (GOARM=7)", platform{GOOS: "linux", GOARCH: "arm", GOARM: "7"}.String())
+}
+
+func (suite *SSHExecTestSuite) TestBuildOptions() {
+	opts := buildOptions{Package: "./cmd/server", Tags: []string{"netgo", "debug"}, LDFlags: "-s -w", Debug: true}
+	suite.Equal([]string{"build", "-o", "/tmp/server", "-tags", "netgo,debug", "-ldflags", "-s -w", "-gcflags", "all=-N -l", "./cmd/server"}, opts.args("/tmp/server"))
+	name, err := opts.binaryName()
+	suite.Require().NoError(err)
+	suite.Equal("server", name)
+
+	opts = buildOptions{Package: ".", Dir: "/src/myapp"}
+	suite.Equal([]string{"build", "-o", "/tmp/myapp", "."}, opts.args("/tmp/myapp"))
+	name, err = opts.binaryName()
+	suite.Require().NoError(err)
+	suite.Equal("myapp", name)
+
+	_, err = buildBinary(context.Background(), buildOptions{Package: ".", Tags: []string{"a b"}}, platform{GOOS: "linux", GOARCH: "amd64"})
+	suite.ErrorContains(err, `invalid build tag: "a b"`)
+
+	// Nothing may smuggle other flags into go build or the linker
+	suite.NoError(buildOptions{Package: "./cmd/server", LDFlags: "-s -w -X main.version=1.2.3 -X github.com/org/app/internal/build.commit="}.validate())
+	for _, opts := range []buildOptions{
+		{Package: "-toolexec=/tmp/evil"},
+		{Package: "-o=/etc/cron.d/x"},
+		{Package: ".", LDFlags: "-extldflags=-Wl,--wrap"},
+		{Package: ".", LDFlags: "-s -linkmode=external"},
+		{Package: ".", LDFlags: "-X"},
+		{Package: ".", LDFlags: "-X -extld=/tmp/evil"},
+		{Package: ".", LDFlags: "-X 'main.version=a b'"},
+		{Package: ".", LDFlags: "-X=main.version=dev"},
+	} {
+		err = opts.validate()
+		suite.Error(err, "%+v", opts)
+		_, err = buildBinary(context.Background(), opts, platform{GOOS: "linux", GOARCH: "amd64"})
+		suite.Error(err, "%+v", opts)
+	}
+}
+
+func (suite *SSHExecTestSuite) TestBuildBinary() {
+	dir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/hello\n\ngo 1.21\n"), 0o644))
+	suite.Require().NoError(os.MkdirAll(filepath.Join(dir, "cmd", "hello"), 0o755))
+	suite.Require().NoError(os.WriteFile(filepath.Join(dir, "cmd", "hello", "main.go"), []byte("package main\n\nvar version = \"none\"\n\nfunc main() { println(version) }\n"), 0o644))
+
+	opts := buildOptions{
+		Package: "./cmd/hello",
+		Dir:     dir,
+		Tags:    []string{"netgo"},
+		LDFlags: "-X main.version=dev",
+		Debug:   true,
+	}
+	binary, err := buildBinary(context.Background(), opts, platform{GOOS: "linux", GOARCH: "arm", GOARM: "7"})
+	suite.Require().NoError(err)
+	defer func() {
+		_ = os.RemoveAll(filepath.Dir(binary))
+	}()
+	suite.Equal("hello", filepath.Base(binary))
+
+	info, err := buildinfo.ReadFile(binary)
+	suite.Require().NoError(err)
+	settings := make(map[string]string)
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	suite.Equal("linux", settings["GOOS"])
+	suite.Equal("arm", settings["GOARCH"])
+	suite.Equal("7", settings["GOARM"])
+	suite.Equal("0", settings["CGO_ENABLED"])
+	suite.Equal("netgo", settings["-tags"])
+	suite.Equal("-X main.version=dev", settings["-ldflags"])
+	suite.Equal("all=-N -l", settings["-gcflags"])
+
+	opts.Package = "./cmd/missing"
+	_, err = buildBinary(context.Background(), opts, platform{GOOS: "linux", GOARCH: "amd64"})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "go build ./cmd/missing for linux/amd64 failed")
+}
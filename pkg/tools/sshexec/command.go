@@ -0,0 +1,123 @@
+package sshexec
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+const timeoutKillAfter = 10 // Seconds between TERM and KILL once timeout is reached
+
+var envKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// buildRemoteCommand returns the shell command running remotePath with the arguments, environment,
+// working directory, timeout and resource limits of input.
+func buildRemoteCommand(input Input, remotePath string) (string, error) {
+	var setup []string
+	if input.Workdir != "" {
+		setup = append(setup, "cd "+ssh.EscapeArg(input.Workdir))
+	}
+
+	limits, err := resourceLimits(input)
+	if err != nil {
+		return "", err
+	}
+	if !input.SystemdScope {
+		for _, limit := range limits {
+			setup = append(setup, "ulimit "+limit)
+		}
+	}
+
+	var command []string
+	keys := make([]string, 0, len(input.Env))
+	for key := range input.Env {
+		if !envKeyRe.MatchString(key) {
+			return "", fmt.Errorf("invalid environment variable name: %q", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		command = append(command, key+"="+ssh.EscapeArg(input.Env[key]))
+	}
+	if input.Timeout > 0 {
+		command = append(command, "timeout", "-k", strconv.Itoa(timeoutKillAfter), strconv.Itoa(input.Timeout))
+	}
+	if input.SystemdScope {
+		// Scopes of unprivileged users go to the user's service manager
+		command = append(command, `systemd-run $([ "$(id -u)" = 0 ] || echo --user) --scope --quiet`)
+		command = append(command, limits...)
+		command = append(command, "--")
+	}
+	if input.Sudo {
+		elevated, err := ssh.SudoArgs(append([]string{remotePath}, input.Args...))
+		if err != nil {
+			return "", err
+		}
+		command = append(command, elevated)
+	} else {
+		command = append(command, remotePath)
+		command = append(command, ssh.EscapeArgs(input.Args)...)
+	}
+
+	return strings.Join(append(setup, strings.Join(command, " ")), " && "), nil
+}
+
+// resourceLimits returns the ulimit options, or the systemd-run properties with SystemdScope.
+func resourceLimits(input Input) ([]string, error) {
+	var limits []string
+	if !input.SystemdScope {
+		if input.CPUQuota > 0 || input.TasksLimit > 0 {
+			return nil, errors.New("cpu_quota and tasks_limit require systemd_scope")
+		}
+		if input.MemoryLimitMB > 0 {
+			limits = append(limits, fmt.Sprintf("-v %d", input.MemoryLimitMB*1024))
+		}
+		if input.CPUTimeLimit > 0 {
+			limits = append(limits, fmt.Sprintf("-t %d", input.CPUTimeLimit))
+		}
+		if input.OpenFilesLimit > 0 {
+			limits = append(limits, fmt.Sprintf("-n %d", input.OpenFilesLimit))
+		}
+		return limits, nil
+	}
+
+	if input.MemoryLimitMB > 0 {
+		limits = append(limits, fmt.Sprintf("-p MemoryMax=%dM", input.MemoryLimitMB))
+	}
+	if input.CPUQuota > 0 {
+		limits = append(limits, fmt.Sprintf("-p CPUQuota=%d%%", input.CPUQuota))
+	}
+	if input.TasksLimit > 0 {
+		limits = append(limits, fmt.Sprintf("-p TasksMax=%d", input.TasksLimit))
+	}
+	if input.CPUTimeLimit > 0 {
+		limits = append(limits, fmt.Sprintf("-p LimitCPU=%d", input.CPUTimeLimit))
+	}
+	if input.OpenFilesLimit > 0 {
+		limits = append(limits, fmt.Sprintf("-p LimitNOFILE=%d", input.OpenFilesLimit))
+	}
+	return limits, nil
+}
+
+// commandNotes describes the limits a binary runs with, for the result.
+func commandNotes(input Input) []string {
+	var notes []string
+	if input.Timeout > 0 {
+		notes = append(notes, fmt.Sprintf("Timeout: %ds (exit code 124 when reached)", input.Timeout))
+	}
+	limits, _ := resourceLimits(input)
+	if len(limits) > 0 {
+		via := "ulimit"
+		if input.SystemdScope {
+			via = "systemd-run --scope"
+		}
+		notes = append(notes, fmt.Sprintf("Limits (%s): %s", via, strings.Join(limits, " ")))
+	}
+	return notes
+}
@@ -0,0 +1,130 @@
+package sshexec
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+func (suite *SSHExecTestSuite) TestBuildRemoteCommand() {
+	testCases := []struct {
+		name     string
+		input    Input
+		expected string
+		errorMsg string
+	}{
+		{
+			name:     "binary only",
+			input:    Input{},
+			expected: "/tmp/app",
+		},
+		{
+			name: "env, workdir and args",
+			input: Input{
+				Args:    []string{"-config", "it's.yaml"},
+				Env:     map[string]string{"GOMAXPROCS": "2", "GODEBUG": "gctrace=1,madvdontneed=1"},
+				Workdir: "/srv/my app",
+			},
+			expected: `cd '/srv/my app' && GODEBUG='gctrace=1,madvdontneed=1' GOMAXPROCS='2' /tmp/app '-config' 'it'\''s.yaml'`,
+		},
+		{
+			name:     "env value with command substitution",
+			input:    Input{Env: map[string]string{"CONFIG": "$(reboot)"}},
+			expected: `CONFIG='$(reboot)' /tmp/app`,
+		},
+		{
+			name:     "timeout and ulimit",
+			input:    Input{Timeout: 30, MemoryLimitMB: 512, CPUTimeLimit: 60, OpenFilesLimit: 1024},
+			expected: "ulimit -v 524288 && ulimit -t 60 && ulimit -n 1024 && timeout -k 10 30 /tmp/app",
+		},
+		{
+			name:     "systemd scope",
+			input:    Input{SystemdScope: true, MemoryLimitMB: 256, CPUQuota: 50, TasksLimit: 64, Env: map[string]string{"GOTRACEBACK": "all"}},
+			expected: `GOTRACEBACK='all' systemd-run $([ "$(id -u)" = 0 ] || echo --user) --scope --quiet -p MemoryMax=256M -p CPUQuota=50% -p TasksMax=64 -- /tmp/app`,
+		},
+		{
+			name:     "invalid env key",
+			input:    Input{Env: map[string]string{"FOO;reboot": "1"}},
+			errorMsg: `invalid environment variable name: "FOO;reboot"`,
+		},
+		{
+			name:     "env key starting with a digit",
+			input:    Input{Env: map[string]string{"1FOO": "1"}},
+			errorMsg: "invalid environment variable name",
+		},
+		{
+			name:     "cgroup limits without systemd",
+			input:    Input{CPUQuota: 50},
+			errorMsg: "cpu_quota and tasks_limit require systemd_scope",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			command, err := buildRemoteCommand(tc.input, "/tmp/app")
+			if tc.errorMsg != "" {
+				suite.Require().Error(err)
+				suite.Contains(err.Error(), tc.errorMsg)
+				return
+			}
+			suite.Require().NoError(err)
+			suite.Equal(tc.expected, command)
+		})
+	}
+}
+
+func (suite *SSHExecTestSuite) TestBuildRemoteCommandSudo() {
+	previous := ssh.SudoAllowlist()
+	suite.Require().NoError(ssh.SetSudoAllowlist([]string{"/tmp/*"}))
+	suite.T().Cleanup(func() {
+		_ = ssh.SetSudoAllowlist(previous)
+	})
+
+	// Timeout and limits wrap sudo
+	input := Input{Sudo: true, Args: []string{"-v"}, Timeout: 5, OpenFilesLimit: 64}
+	command, err := buildRemoteCommand(input, "/tmp/app")
+	suite.Require().NoError(err)
+	suite.Equal(`ulimit -n 64 && timeout -k 10 5 sudo -n -- '/tmp/app' '-v'`, command)
+
+	_, err = buildRemoteCommand(Input{Sudo: true}, "/opt/app")
+	suite.ErrorIs(err, ssh.ErrSudoNotAllowed)
+}
+
+func (suite *SSHExecTestSuite) TestRemoteCommandRuns() {
+	dir := suite.T().TempDir()
+	input := Input{
+		Args:           []string{"-c", `echo "$GREETING"; pwd; ulimit -n; cat`},
+		Env:            map[string]string{"GREETING": "it's $HOME"},
+		Workdir:        dir,
+		OpenFilesLimit: 64,
+		Timeout:        30,
+	}
+	command, err := buildRemoteCommand(input, "sh")
+	suite.Require().NoError(err)
+	cmd := exec.CommandContext(context.Background(), "sh", "-c", command)
+	cmd.Stdin = strings.NewReader("from stdin\n")
+	output, err := cmd.CombinedOutput()
+	suite.Require().NoError(err, string(output))
+	suite.Equal("it's $HOME\n"+dir+"\n64\nfrom stdin\n", string(output))
+
+	// The timeout ends the binary with exit code 124
+	command, err = buildRemoteCommand(Input{Args: []string{"30"}, Timeout: 1}, "sleep")
+	suite.Require().NoError(err)
+	err = exec.Command("sh", "-c", command).Run()
+	exitErr, ok := err.(*exec.ExitError)
+	suite.Require().True(ok, "%v", err)
+	suite.Equal(124, exitErr.ExitCode())
+}
+
+func (suite *SSHExecTestSuite) TestCommandNotes() {
+	suite.Empty(commandNotes(Input{}))
+	suite.Equal([]string{
+		"Timeout: 30s (exit code 124 when reached)",
+		"Limits (ulimit): -v 1024 -n 100",
+	}, commandNotes(Input{Timeout: 30, MemoryLimitMB: 1, OpenFilesLimit: 100}))
+	suite.Equal([]string{
+		"Limits (systemd-run --scope): -p MemoryMax=1M",
+	}, commandNotes(Input{SystemdScope: true, MemoryLimitMB: 1}))
+}
@@ -0,0 +1,365 @@
+package sshexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+const (
+	defaultGracePeriod = 10  // Seconds between TERM and KILL in stop_job
+	maxJobs            = 100 // Jobs kept in the registry
+)
+
+// jobDir is the remote directory holding job logs and exit codes.
+var jobDir = "/tmp/remote-debugger-mcp-jobs"
+
+// commandExecutor runs shell commands on the job's host; *ssh.Connector implements it.
+type commandExecutor interface {
+	ExecuteCommand(ctx context.Context, command string) (string, error)
+	ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error)
+}
+
+// backgroundJob is a process started with run_in_background.
+type backgroundJob struct {
+	ID         string
+	Host       string
+	Target     string
+	Binary     string
+	RemotePath string
+	Args       []string
+	PID        int // PID of the shell wrapper that records the exit code; the binary is its child
+	LogFile    string
+	ExitFile   string
+	StdinFile  string // Holds the stdin content when there is any
+	Started    time.Time
+
+	// Last state seen by list_jobs, job_status or stop_job
+	State    string
+	ExitCode string
+}
+
+// update records the output of jobStateCommand.
+func (j *backgroundJob) update(output string) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return
+	}
+	j.State = fields[0]
+	j.ExitCode = ""
+	if j.State == "exited" && len(fields) > 1 {
+		j.ExitCode = fields[1]
+	}
+}
+
+// stateString describes the last known state of the job.
+func (j *backgroundJob) stateString() string {
+	switch j.State {
+	case "exited":
+		return "exited with code " + j.ExitCode
+	case "gone":
+		return "gone (killed without recording an exit code, or the host rebooted)"
+	case "":
+		return "unknown"
+	default:
+		return j.State
+	}
+}
+
+// startJobCommand wraps command so that it runs detached with its output in the job's log file,
+// and the exit code is recorded when it ends. It prints the PID of the wrapper.
+func startJobCommand(job *backgroundJob, command string) string {
+	script := command + `; echo $? > "$JOB_EXIT"`
+	stdin := "/dev/null"
+	if job.StdinFile != "" {
+		stdin = ssh.EscapeArg(job.StdinFile)
+	}
+	return fmt.Sprintf("mkdir -p %s && JOB_EXIT=%s nohup sh -c %s > %s 2>&1 < %s & echo $!",
+		jobDir, ssh.EscapeArg(job.ExitFile), ssh.EscapeArg(script), ssh.EscapeArg(job.LogFile), stdin)
+}
+
+// jobStateCommand prints "running", "exited <code>" or "gone".
+func jobStateCommand(job *backgroundJob) string {
+	return fmt.Sprintf(`if [ -s %[1]s ]; then echo "exited $(cat %[1]s)"; elif kill -0 %[2]d 2>/dev/null; then echo running; else echo gone; fi`,
+		ssh.EscapeArg(job.ExitFile), job.PID)
+}
+
+// jobStatusCommand prints the state, the size of the log and the binary's process details.
+func jobStatusCommand(job *backgroundJob) string {
+	return jobStateCommand(job) + fmt.Sprintf(`; echo "log_bytes $(wc -c < %s 2>/dev/null || echo 0)"; `+
+		`for pid in $(pgrep -P %d); do ps -o pid=,etime=,rss=,args= -p "$pid"; done`,
+		ssh.EscapeArg(job.LogFile), job.PID)
+}
+
+// jobLogsCommand prints the number of log lines, then maxLines lines ending offset lines before the end.
+func jobLogsCommand(job *backgroundJob, maxLines, offset int) string {
+	return fmt.Sprintf("wc -l < %[1]s && tail -n %[2]d %[1]s | head -n %[3]d", ssh.EscapeArg(job.LogFile), maxLines+offset, maxLines)
+}
+
+// stopJobCommand signals the binary, escalates to KILL after the grace period, then prints the final state.
+func stopJobCommand(job *backgroundJob, signal string, grace int) string {
+	return fmt.Sprintf(`pid=%[1]d
+if ! kill -0 $pid 2>/dev/null; then echo "not running"; else
+pkill -%[2]s -P $pid; echo "sent %[2]s"
+i=0; while [ $i -lt %[3]d ] && pgrep -P $pid >/dev/null; do sleep 1; i=$((i+1)); done
+if pgrep -P $pid >/dev/null; then pkill -KILL -P $pid; echo "escalated to KILL after %[3]ds"; fi
+i=0; while [ $i -lt 3 ] && kill -0 $pid 2>/dev/null; do sleep 1; i=$((i+1)); done
+kill -KILL $pid 2>/dev/null
+fi
+`, job.PID, signal, grace) + jobStateCommand(job)
+}
+
+// registerJob adds a job to the registry, forgetting the oldest finished jobs beyond maxJobs.
+func (s *Tool) registerJob(job *backgroundJob) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	s.jobs[job.ID] = job
+	if len(s.jobs) <= maxJobs {
+		return
+	}
+	jobs := make([]*backgroundJob, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		if j.State == "exited" || j.State == "gone" {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.Before(jobs[j].Started) })
+	for _, j := range jobs[:min(len(jobs), len(s.jobs)-maxJobs)] {
+		delete(s.jobs, j.ID)
+	}
+}
+
+// hostJobs returns the jobs started on host, oldest first.
+func (s *Tool) hostJobs(host string) []*backgroundJob {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	var jobs []*backgroundJob
+	for _, job := range s.jobs {
+		if job.Host == host {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Started.Before(jobs[j].Started) })
+	return jobs
+}
+
+// getJob looks up a job started on host.
+func (s *Tool) getJob(id, host string) (*backgroundJob, error) {
+	s.jobsMu.Lock()
+	defer s.jobsMu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Host != host {
+		return nil, fmt.Errorf("job %s not found on %s (use list_jobs)", id, host)
+	}
+	return job, nil
+}
+
+// startJob runs command in the background on the remote host and registers it. A non-empty stdin
+// is written to a file next to the log first.
+func (s *Tool) startJob(ctx context.Context, exec commandExecutor, job *backgroundJob, command, stdin string) error {
+	job.ID = newRunID()
+	job.LogFile = fmt.Sprintf("%s/%s.log", jobDir, job.ID)
+	job.ExitFile = fmt.Sprintf("%s/%s.exit", jobDir, job.ID)
+	job.Started = time.Now()
+
+	if stdin != "" {
+		job.StdinFile = fmt.Sprintf("%s/%s.stdin", jobDir, job.ID)
+		var output strings.Builder
+		exitCode, err := exec.ExecuteCommandStream(ctx, fmt.Sprintf("mkdir -p %s && cat > %s", jobDir, ssh.EscapeArg(job.StdinFile)),
+			strings.NewReader(stdin), &output, &output)
+		if err == nil && exitCode != 0 {
+			err = fmt.Errorf("exit code %d", exitCode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write stdin of background job: %w - %s", err, strings.TrimSpace(output.String()))
+		}
+	}
+
+	output, err := exec.ExecuteCommand(ctx, startJobCommand(job, command))
+	if err != nil {
+		return fmt.Errorf("failed to start background job: %w - %s", err, strings.TrimSpace(output))
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return fmt.Errorf("failed to start background job: unexpected output %q", strings.TrimSpace(output))
+	}
+	job.PID = pid
+	job.State = "running"
+	s.registerJob(job)
+	return nil
+}
+
+// handleJobAction runs list_jobs, job_status, job_logs or stop_job.
+func (s *Tool) handleJobAction(ctx context.Context, input Input, exec commandExecutor, maxLines, offset int) (*mcp.CallToolResultFor[Output], error) {
+	if input.Action == "list_jobs" {
+		return s.handleListJobs(ctx, input, exec)
+	}
+	if input.JobID == "" {
+		return nil, fmt.Errorf("job_id is required for %s", input.Action)
+	}
+	job, err := s.getJob(input.JobID, input.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	var resultText string
+	switch input.Action {
+	case "job_status":
+		resultText, err = s.jobStatus(ctx, job, exec)
+	case "job_logs":
+		resultText, err = s.jobLogs(ctx, job, exec, maxLines, offset)
+	case "stop_job":
+		resultText, err = s.stopJob(ctx, job, exec, input)
+	default:
+		err = fmt.Errorf("unsupported action: %s", input.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText,
+			},
+		},
+	}, nil
+}
+
+// handleListJobs refreshes the state of the host's jobs with one remote command and lists them.
+func (s *Tool) handleListJobs(ctx context.Context, input Input, exec commandExecutor) (*mcp.CallToolResultFor[Output], error) {
+	jobs := s.hostJobs(input.Host)
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Background jobs on %s: %d\n", input.Host, len(jobs)))
+
+	if len(jobs) > 0 {
+		commands := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			commands = append(commands, fmt.Sprintf("echo %s $(%s)", job.ID, jobStateCommand(job)))
+		}
+		output, err := exec.ExecuteCommand(ctx, strings.Join(commands, "; "))
+		if err != nil {
+			return nil, fmt.Errorf("failed to check jobs: %w", err)
+		}
+		states := make(map[string]string)
+		for _, line := range strings.Split(output, "\n") {
+			if id, state, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+				states[id] = state
+			}
+		}
+
+		s.jobsMu.Lock()
+		for _, job := range jobs {
+			if state, ok := states[job.ID]; ok {
+				job.update(state)
+			}
+			resultText.WriteString(fmt.Sprintf("\n%s  pid %d  started %s  %s\n  %s\n  log: %s\n",
+				job.ID, job.PID, job.Started.Format(time.RFC3339), job.stateString(),
+				strings.Join(append([]string{job.RemotePath}, job.Args...), " "), job.LogFile))
+		}
+		s.jobsMu.Unlock()
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText.String(),
+			},
+		},
+	}, nil
+}
+
+func (s *Tool) jobStatus(ctx context.Context, job *backgroundJob, exec commandExecutor) (string, error) {
+	output, err := exec.ExecuteCommand(ctx, jobStatusCommand(job))
+	if err != nil {
+		return "", fmt.Errorf("failed to get job status: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+
+	s.jobsMu.Lock()
+	job.update(lines[0])
+	state := job.stateString()
+	s.jobsMu.Unlock()
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Job %s on %s (binary: %s):\n", job.ID, job.Target, job.Binary))
+	resultText.WriteString(fmt.Sprintf("State: %s\n", state))
+	resultText.WriteString(fmt.Sprintf("PID: %d\nStarted: %s (%s ago)\n", job.PID, job.Started.Format(time.RFC3339), time.Since(job.Started).Round(time.Second)))
+	resultText.WriteString(fmt.Sprintf("Command: %s\n", strings.Join(append([]string{job.RemotePath}, job.Args...), " ")))
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if size, ok := strings.CutPrefix(line, "log_bytes "); ok {
+			resultText.WriteString(fmt.Sprintf("Log: %s (%s bytes)\n", job.LogFile, size))
+		} else if line != "" {
+			resultText.WriteString(fmt.Sprintf("Process: %s\n", line))
+		}
+	}
+	return resultText.String(), nil
+}
+
+func (s *Tool) jobLogs(ctx context.Context, job *backgroundJob, exec commandExecutor, maxLines, offset int) (string, error) {
+	output, err := exec.ExecuteCommand(ctx, jobLogsCommand(job, maxLines, offset))
+	if err != nil {
+		return "", fmt.Errorf("failed to read job log %s: %w", job.LogFile, err)
+	}
+	countLine, logs, _ := strings.Cut(output, "\n")
+	total, err := strconv.Atoi(strings.TrimSpace(countLine))
+	if err != nil {
+		return "", fmt.Errorf("failed to read job log %s: %s", job.LogFile, strings.TrimSpace(output))
+	}
+
+	last := max(total-offset, 0)
+	first := max(last-maxLines+1, 1)
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Log of job %s (%s):\n", job.ID, job.LogFile))
+	if last == 0 {
+		resultText.WriteString(fmt.Sprintf("[No lines before the last %d of %d]\n", offset, total))
+	} else {
+		resultText.WriteString(fmt.Sprintf("[Showing lines %d-%d of %d total lines. Increase offset to view earlier lines.]\n", first, last, total))
+	}
+	resultText.WriteString("\n" + strings.TrimRight(logs, "\n"))
+	return resultText.String(), nil
+}
+
+func (s *Tool) stopJob(ctx context.Context, job *backgroundJob, exec commandExecutor, input Input) (string, error) {
+	signal := "TERM"
+	if input.KillSignal != "" {
+		signal = input.KillSignal
+	}
+	grace := defaultGracePeriod
+	if input.GracePeriod > 0 {
+		grace = input.GracePeriod
+	}
+
+	output, err := exec.ExecuteCommand(ctx, stopJobCommand(job, signal, grace))
+	if err != nil {
+		return "", fmt.Errorf("failed to stop job: %w - %s", err, strings.TrimSpace(output))
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 0 {
+		return "", errors.New("failed to stop job: no output")
+	}
+
+	s.jobsMu.Lock()
+	job.update(lines[len(lines)-1])
+	state := job.stateString()
+	s.jobsMu.Unlock()
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Stopping job %s (pid %d) on %s:\n", job.ID, job.PID, job.Target))
+	for _, line := range lines[:len(lines)-1] {
+		resultText.WriteString(line + "\n")
+	}
+	resultText.WriteString(fmt.Sprintf("State: %s\nLog kept in %s\n", state, job.LogFile))
+	return resultText.String(), nil
+}
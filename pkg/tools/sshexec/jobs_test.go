@@ -0,0 +1,175 @@
+package sshexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// localExecutor runs job commands with the local shell in place of the remote host.
+type localExecutor struct{}
+
+func (localExecutor) ExecuteCommand(ctx context.Context, command string) (string, error) {
+	output, err := exec.CommandContext(ctx, "sh", "-c", command).CombinedOutput()
+	return string(output), err
+}
+
+func (localExecutor) ExecuteCommandStream(ctx context.Context, command string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+// startLocalJob starts script as a background job on "localhost" with its files in a temporary directory.
+func (suite *SSHExecTestSuite) startLocalJob(script, stdin string) *backgroundJob {
+	previous := jobDir
+	jobDir = suite.T().TempDir()
+	suite.T().Cleanup(func() {
+		jobDir = previous
+	})
+
+	job := &backgroundJob{
+		Host:       "localhost",
+		Target:     "user@localhost",
+		Binary:     "sh",
+		RemotePath: "sh",
+		Args:       []string{"-c", script},
+	}
+	suite.Require().NoError(suite.tool.startJob(context.Background(), localExecutor{}, job, "sh -c "+fmt.Sprintf("'%s'", script), stdin))
+	suite.Equal(filepath.Join(jobDir, job.ID+".log"), job.LogFile)
+	suite.Positive(job.PID)
+	return job
+}
+
+func (suite *SSHExecTestSuite) waitJobExit(job *backgroundJob) {
+	suite.Eventually(func() bool {
+		_, err := os.Stat(job.ExitFile)
+		return err == nil
+	}, 5*time.Second, 20*time.Millisecond)
+}
+
+func (suite *SSHExecTestSuite) TestJobLifecycle() {
+	ctx := context.Background()
+	job := suite.startLocalJob("for i in 1 2 3 4 5; do echo line $i; done; echo oops >&2; exit 3", "")
+	suite.waitJobExit(job)
+
+	input := Input{Host: "localhost", Action: "list_jobs"}
+	result, err := suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+	suite.Require().NoError(err)
+	text := resultText(result)
+	suite.Contains(text, "Background jobs on localhost: 1")
+	suite.Contains(text, job.ID)
+	suite.Contains(text, "exited with code 3")
+	suite.Contains(text, "log: "+job.LogFile)
+
+	input = Input{Host: "localhost", Action: "job_status", JobID: job.ID}
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+	suite.Require().NoError(err)
+	suite.Contains(resultText(result), "State: exited with code 3")
+	suite.Contains(resultText(result), "Log: "+job.LogFile+" (")
+
+	// The last lines of the log, then the two before them
+	input = Input{Host: "localhost", Action: "job_logs", JobID: job.ID}
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 2, 0)
+	suite.Require().NoError(err)
+	text = resultText(result)
+	suite.Contains(text, "[Showing lines 5-6 of 6 total lines.")
+	suite.True(strings.HasSuffix(text, "\nline 5\noops"), text)
+
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 2, 2)
+	suite.Require().NoError(err)
+	text = resultText(result)
+	suite.Contains(text, "[Showing lines 3-4 of 6 total lines.")
+	suite.True(strings.HasSuffix(text, "\nline 3\nline 4"), text)
+
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 2, 10)
+	suite.Require().NoError(err)
+	suite.Contains(resultText(result), "[No lines before the last 10 of 6]")
+
+	// Jobs are only visible on the host they were started on
+	input = Input{Host: "otherhost", Action: "job_logs", JobID: job.ID}
+	_, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 2, 0)
+	suite.ErrorContains(err, "not found on otherhost")
+	input = Input{Host: "otherhost", Action: "list_jobs"}
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 2, 0)
+	suite.Require().NoError(err)
+	suite.Contains(resultText(result), "Background jobs on otherhost: 0")
+}
+
+func (suite *SSHExecTestSuite) TestJobStdin() {
+	job := suite.startLocalJob("tr a-z A-Z", "first\nsecond\n")
+	suite.waitJobExit(job)
+	suite.Equal(filepath.Join(jobDir, job.ID+".stdin"), job.StdinFile)
+	content, err := os.ReadFile(job.LogFile)
+	suite.Require().NoError(err)
+	suite.Equal("FIRST\nSECOND\n", string(content))
+}
+
+func (suite *SSHExecTestSuite) TestStopJob() {
+	ctx := context.Background()
+
+	job := suite.startLocalJob("echo started; exec sleep 30", "")
+	input := Input{Host: "localhost", Action: "job_status", JobID: job.ID}
+	suite.Eventually(func() bool {
+		result, err := suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+		return err == nil && strings.Contains(resultText(result), "sleep 30")
+	}, 5*time.Second, 20*time.Millisecond)
+
+	input = Input{Host: "localhost", Action: "stop_job", JobID: job.ID, GracePeriod: 2}
+	result, err := suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+	suite.Require().NoError(err)
+	text := resultText(result)
+	suite.Contains(text, "sent TERM")
+	suite.NotContains(text, "escalated")
+	suite.Contains(text, "State: exited with code 143")
+
+	// A process ignoring TERM is killed after the grace period
+	job = suite.startLocalJob(`trap "" TERM; echo started; while :; do sleep 1; done`, "")
+	suite.Eventually(func() bool {
+		content, _ := os.ReadFile(job.LogFile)
+		return strings.Contains(string(content), "started")
+	}, 5*time.Second, 20*time.Millisecond)
+
+	input = Input{Host: "localhost", Action: "stop_job", JobID: job.ID, GracePeriod: 1}
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+	suite.Require().NoError(err)
+	text = resultText(result)
+	suite.Contains(text, "escalated to KILL after 1s")
+	suite.Contains(text, "State: exited with code 137")
+
+	result, err = suite.tool.handleJobAction(ctx, input, localExecutor{}, 10, 0)
+	suite.Require().NoError(err)
+	suite.Contains(resultText(result), "not running")
+}
+
+func (suite *SSHExecTestSuite) TestRegisterJobPrunesFinished() {
+	started := time.Now().Add(-time.Hour)
+	for i := range maxJobs + 5 {
+		state := "exited"
+		if i%2 == 0 {
+			state = "running"
+		}
+		suite.tool.registerJob(&backgroundJob{
+			ID:      fmt.Sprintf("%012x", i),
+			Host:    "localhost",
+			Started: started.Add(time.Duration(i) * time.Second),
+			State:   state,
+		})
+	}
+	suite.Len(suite.tool.jobs, maxJobs)
+	// The oldest finished jobs are forgotten first, running ones are kept
+	suite.NotContains(suite.tool.jobs, fmt.Sprintf("%012x", 1))
+	suite.Contains(suite.tool.jobs, fmt.Sprintf("%012x", 0))
+	suite.Contains(suite.tool.jobs, fmt.Sprintf("%012x", 11))
+}
@@ -0,0 +1,386 @@
+package sshexec
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
+)
+
+const (
+	defaultOutputBufferLines = 10000 // Lines of output kept per run for paging
+	maxFinishedRuns          = 20    // Finished runs kept for paging
+	stderrPrefix             = "[stderr] "
+	runStopTimeout           = 5 * time.Second // Wait for the session to end after the remote process was stopped
+)
+
+// runDir is the remote directory holding the PID files of runs.
+var runDir = "/tmp/remote-debugger-mcp-runs"
+
+// outputBuffer keeps the most recent lines of a run's output in a ring buffer.
+type outputBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int // Index of the slot written next
+	total   int // Lines seen since the run started
+	partial map[string]string
+	pending []string // Lines not sent to the client yet
+}
+
+func newOutputBuffer(size int) *outputBuffer {
+	return &outputBuffer{
+		lines:   make([]string, 0, size),
+		partial: make(map[string]string),
+	}
+}
+
+// add appends a line, overwriting the oldest one when the buffer is full.
+func (b *outputBuffer) add(line string) {
+	b.total++
+	b.pending = append(b.pending, line)
+	if len(b.pending) > cap(b.lines) {
+		// The client is not keeping up; it can page the lines that are still buffered
+		b.pending = b.pending[1:]
+	}
+	if len(b.lines) < cap(b.lines) {
+		b.lines = append(b.lines, line)
+		return
+	}
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+}
+
+// write splits the output of a stream into lines, keeping an unterminated line until it is complete.
+func (b *outputBuffer) write(stream string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	text := b.partial[stream] + string(data)
+	lines := strings.Split(text, "\n")
+	b.partial[stream] = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		b.add(streamLine(stream, line))
+	}
+}
+
+// flush adds the unterminated lines once the run is over.
+func (b *outputBuffer) flush() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, stream := range []string{"stdout", "stderr"} {
+		if line := b.partial[stream]; line != "" {
+			b.add(streamLine(stream, line))
+		}
+		delete(b.partial, stream)
+	}
+}
+
+func streamLine(stream, line string) string {
+	if stream == "stderr" {
+		return stderrPrefix + line
+	}
+	return line
+}
+
+// takePending returns the lines added since the last call and the total number of lines.
+func (b *outputBuffer) takePending() ([]string, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending := b.pending
+	b.pending = nil
+	return pending, b.total
+}
+
+// page returns up to limit lines starting at line offset, counted from the start of the run,
+// the total number of lines and the number of lines dropped from the buffer.
+func (b *outputBuffer) page(offset, limit int) ([]string, int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	dropped := b.total - len(b.lines)
+	start := max(offset-dropped, 0)
+	end := min(start+limit, len(b.lines))
+	if start >= end {
+		return nil, b.total, dropped
+	}
+	page := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, b.lines[(b.next+i)%len(b.lines)])
+	}
+	return page, b.total, dropped
+}
+
+// streamWriter writes one stream of a run into its output buffer.
+type streamWriter struct {
+	buffer *outputBuffer
+	stream string
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.buffer.write(w.stream, p)
+	return len(p), nil
+}
+
+// commandFunc runs a command, streaming its output, and returns its exit code.
+type commandFunc func(ctx context.Context, stdout, stderr io.Writer) (int, error)
+
+// killFunc signals the process of a run, escalating to KILL after grace seconds, and reports what it did.
+type killFunc func(ctx context.Context, signal string, grace int) (string, error)
+
+// runPIDFile returns a new PID file for a run in runDir.
+func runPIDFile() string {
+	return path.Join(runDir, newRunID()+".pid")
+}
+
+// remoteRunCommand wraps command so that the remote shell records its PID in pidFile while it runs.
+// pidFile and removeFiles are deleted when the command ends; the exit code is kept.
+func remoteRunCommand(command, pidFile string, removeFiles ...string) string {
+	files := ssh.EscapeArgs(append([]string{pidFile}, removeFiles...))
+	return fmt.Sprintf("mkdir -p %s && echo $$ > %s; %s; EXIT_CODE=$?; rm -f %s; exit $EXIT_CODE",
+		runDir, ssh.EscapeArg(pidFile), command, strings.Join(files, " "))
+}
+
+// stopRunCommand signals the processes started by the shell in pidFile, escalating to KILL after the
+// grace period. The shell itself is left to remove its files and exit, which ends the run's session.
+func stopRunCommand(pidFile, signal string, grace int) string {
+	return fmt.Sprintf(`pid=$(cat %[1]s 2>/dev/null)
+tree() { for c in $(pgrep -P "$1"); do echo "$c"; tree "$c"; done; }
+if [ -z "$pid" ] || [ -z "$(tree "$pid")" ]; then echo "not running"; else
+kill -%[2]s $(tree "$pid") 2>/dev/null; echo "sent %[2]s"
+i=0; while [ $i -lt %[3]d ] && [ -n "$(tree "$pid")" ]; do sleep 1; i=$((i+1)); done
+if [ -n "$(tree "$pid")" ]; then kill -KILL $(tree "$pid") 2>/dev/null; echo "escalated to KILL after %[3]ds"; fi
+fi
+`, ssh.EscapeArg(pidFile), signal, grace)
+}
+
+// remoteKill returns the killFunc of a run whose command was wrapped with remoteRunCommand.
+func remoteKill(exec commandExecutor, pidFile string) killFunc {
+	return func(ctx context.Context, signal string, grace int) (string, error) {
+		return exec.ExecuteCommand(ctx, stopRunCommand(pidFile, signal, grace))
+	}
+}
+
+// execRun is a foreground execution whose output is streamed and kept for paging.
+type execRun struct {
+	id      string
+	host    string
+	target  string
+	binary  string
+	started time.Time
+	output  *outputBuffer
+	cancel  context.CancelFunc
+	kill    killFunc // Stops the remote process; nil when it ends with the session
+	done    chan struct{}
+
+	mu       sync.Mutex
+	finished time.Time
+	exitCode int
+	err      error
+}
+
+// status returns a one-line description of the run.
+func (r *execRun) status() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.finished.IsZero():
+		return fmt.Sprintf("running for %s", time.Since(r.started).Round(time.Second))
+	case errors.Is(r.err, context.Canceled):
+		return fmt.Sprintf("stopped after %s", r.finished.Sub(r.started).Round(time.Second))
+	case r.err != nil:
+		return fmt.Sprintf("failed after %s: %v", r.finished.Sub(r.started).Round(time.Second), r.err)
+	default:
+		return fmt.Sprintf("exited with code %d after %s", r.exitCode, r.finished.Sub(r.started).Round(time.Second))
+	}
+}
+
+// result returns the exit code and error of a finished run.
+func (r *execRun) result() (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.exitCode, r.err
+}
+
+func newRunID() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// startRun starts a command in the background of the server, streaming its output to notifier.
+// The run is not bound to the request: it lasts until the command exits or stopRun is called.
+// Progress notifications are only sent until request is closed.
+func (s *Tool) startRun(host, target, binary string, command commandFunc, kill killFunc, notifier *notify.Notifier, request <-chan struct{}) *execRun {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &execRun{
+		id:      newRunID(),
+		host:    host,
+		target:  target,
+		binary:  binary,
+		started: time.Now(),
+		output:  newOutputBuffer(defaultOutputBufferLines),
+		cancel:  cancel,
+		kill:    kill,
+		done:    make(chan struct{}),
+	}
+
+	s.runsMu.Lock()
+	s.runs[run.id] = run
+	s.pruneRuns()
+	s.runsMu.Unlock()
+
+	go notifier.SendBatches(notify.Batches{
+		Key:    "run_id",
+		ID:     run.id,
+		Take:   run.output.takePending,
+		Status: run.status,
+		Progress: func(total int) string {
+			return fmt.Sprintf("%s: %d lines of output", run.binary, total)
+		},
+		Request: request,
+	}, run.done)
+	go func() {
+		exitCode, err := command(ctx, streamWriter{run.output, "stdout"}, streamWriter{run.output, "stderr"})
+		run.output.flush()
+		run.mu.Lock()
+		run.finished = time.Now()
+		run.exitCode = exitCode
+		run.err = err
+		run.mu.Unlock()
+		cancel()
+		close(run.done)
+	}()
+	return run
+}
+
+// stopRun stops the remote process of a run with signal, then KILL after grace seconds, and ends
+// its session. It returns notes on what was done for the result.
+func (s *Tool) stopRun(ctx context.Context, run *execRun, signal string, grace int) []string {
+	var notes []string
+	if run.kill != nil {
+		output, err := run.kill(ctx, signal, grace)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("run_id", run.id).Msg("failed to stop remote process")
+			notes = append(notes, fmt.Sprintf("Failed to stop the remote process, it may still be running: %v", err))
+		}
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			if line != "" {
+				notes = append(notes, "Stop: "+line)
+			}
+		}
+		// The session ends once the process is gone
+		select {
+		case <-run.done:
+		case <-time.After(runStopTimeout):
+		}
+	}
+	run.cancel()
+	<-run.done
+	return notes
+}
+
+// pruneRuns forgets the oldest finished runs beyond maxFinishedRuns. Callers hold runsMu.
+func (s *Tool) pruneRuns() {
+	var finished []*execRun
+	for _, run := range s.runs {
+		select {
+		case <-run.done:
+			finished = append(finished, run)
+		default:
+		}
+	}
+	if len(finished) <= maxFinishedRuns {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].started.Before(finished[j].started) })
+	for _, run := range finished[:len(finished)-maxFinishedRuns] {
+		delete(s.runs, run.id)
+	}
+}
+
+// getRun looks up a run started on host.
+func (s *Tool) getRun(id, host string) (*execRun, error) {
+	s.runsMu.Lock()
+	defer s.runsMu.Unlock()
+
+	run, ok := s.runs[id]
+	if !ok || run.host != host {
+		return nil, fmt.Errorf("run %s not found on %s (finished runs are kept for the last %d runs)", id, host, maxFinishedRuns)
+	}
+	return run, nil
+}
+
+// waitRun waits for a run to finish, for at most softTimeout when it is positive. It reports whether the run finished.
+func waitRun(ctx context.Context, run *execRun, softTimeout time.Duration) bool {
+	var timeout <-chan time.Time
+	if softTimeout > 0 {
+		timer := time.NewTimer(softTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-run.done:
+		return true
+	case <-timeout:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// runResult formats the state of a run and a page of its output. notes are added below the header.
+func runResult(run *execRun, offset, maxLines int, notes ...string) (*mcp.CallToolResultFor[Output], error) {
+	lines, total, dropped := run.output.page(offset, maxLines)
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("SSH Exec output for %s (binary: %s, run_id: %s):\n", run.target, run.binary, run.id))
+	for _, note := range notes {
+		resultText.WriteString(note + "\n")
+	}
+	select {
+	case <-run.done:
+		exitCode, err := run.result()
+		if errors.Is(err, context.Canceled) {
+			resultText.WriteString("Run stopped before the process exited.\n")
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute binary: %v", err)
+		}
+		resultText.WriteString(fmt.Sprintf("Exit Code: %d\n", exitCode))
+	default:
+		resultText.WriteString(fmt.Sprintf("Still %s; the process keeps running. Call sshexec with run_id=%s to page more output or wait for it.\n", run.status(), run.id))
+	}
+	if dropped > 0 && offset < dropped {
+		resultText.WriteString(fmt.Sprintf("[Lines 1-%d were dropped from the %d line buffer]\n", dropped, defaultOutputBufferLines))
+	}
+	if len(lines) > 0 {
+		first := max(offset, dropped) + 1
+		if first > 1 || first+len(lines)-1 < total {
+			resultText.WriteString(fmt.Sprintf("[Showing lines %d-%d of %d total lines. Use offset parameter to view more.]\n", first, first+len(lines)-1, total))
+		}
+	}
+	resultText.WriteString("\n" + strings.TrimSpace(strings.Join(lines, "\n")))
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText.String(),
+			},
+		},
+	}, nil
+}
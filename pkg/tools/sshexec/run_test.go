@@ -0,0 +1,169 @@
+package sshexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// localCommand runs a shell command locally in place of the remote binary.
+func localCommand(script string) commandFunc {
+	return func(ctx context.Context, stdout, stderr io.Writer) (int, error) {
+		cmd := exec.CommandContext(ctx, "sh", "-c", script)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		err := cmd.Run()
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+}
+
+func resultText(result *mcp.CallToolResultFor[Output]) string {
+	return result.Content[0].(*mcp.TextContent).Text
+}
+
+func (suite *SSHExecTestSuite) TestOutputBuffer() {
+	buffer := newOutputBuffer(3)
+	buffer.write("stdout", []byte("one\ntw"))
+	buffer.write("stderr", []byte("oops\n"))
+	buffer.write("stdout", []byte("o\nthree"))
+
+	lines, total, dropped := buffer.page(0, 10)
+	suite.Equal([]string{"one", "[stderr] oops", "two"}, lines)
+	suite.Equal(3, total)
+	suite.Zero(dropped)
+
+	// The unterminated line is added at the end and pushes out the oldest one
+	buffer.flush()
+	lines, total, dropped = buffer.page(0, 10)
+	suite.Equal([]string{"[stderr] oops", "two", "three"}, lines)
+	suite.Equal(4, total)
+	suite.Equal(1, dropped)
+
+	// Offsets count from the start of the run
+	lines, _, _ = buffer.page(2, 1)
+	suite.Equal([]string{"two"}, lines)
+	lines, _, _ = buffer.page(4, 10)
+	suite.Empty(lines)
+
+	// Lines not yet sent to the client are bounded by the buffer size too
+	pending, total := buffer.takePending()
+	suite.Equal([]string{"[stderr] oops", "two", "three"}, pending)
+	suite.Equal(4, total)
+	pending, _ = buffer.takePending()
+	suite.Empty(pending)
+}
+
+func (suite *SSHExecTestSuite) TestRunSoftTimeout() {
+	ctx := context.Background()
+	run := suite.tool.startRun("localhost", "user@localhost", "loadtest",
+		localCommand("echo started; echo warming up >&2; sleep 1; echo done; exit 3"), nil, nil, nil)
+
+	// The soft timeout returns what was printed so far while the process keeps running
+	suite.False(waitRun(ctx, run, 300*time.Millisecond))
+	result, err := runResult(run, 0, 100, "Transfer: skipped")
+	suite.Require().NoError(err)
+	text := resultText(result)
+	suite.Contains(text, "run_id: "+run.id)
+	suite.Contains(text, "Transfer: skipped\n")
+	suite.Contains(text, "Still running for")
+	// stdout and stderr are separate pipes, so their relative order is not guaranteed
+	suite.Contains(text, "\nstarted")
+	suite.Contains(text, "\n[stderr] warming up")
+	suite.NotContains(text, "done")
+
+	// Later calls with run_id page the output and wait for the exit
+	params := &mcp.CallToolParamsFor[Input]{Arguments: Input{Host: "localhost", RunID: run.id, SoftTimeout: 5, Offset: 2}}
+	result, err = suite.tool.SSHExecHandler(ctx, &mcp.ServerSession{}, params)
+	suite.Require().NoError(err)
+	text = resultText(result)
+	suite.Contains(text, "Exit Code: 3\n")
+	suite.Contains(text, "[Showing lines 3-3 of 3 total lines")
+	suite.True(strings.HasSuffix(text, "\ndone"))
+
+	// Runs belong to the host they were started on
+	params.Arguments.Host = "otherhost"
+	_, err = suite.tool.SSHExecHandler(ctx, &mcp.ServerSession{}, params)
+	suite.Error(err)
+}
+
+func (suite *SSHExecTestSuite) TestStopRun() {
+	ctx := context.Background()
+	run := suite.tool.startRun("localhost", "user@localhost", "server", localCommand("echo listening; exec sleep 30"), nil, nil, nil)
+	suite.Eventually(func() bool {
+		_, total, _ := run.output.page(0, 1)
+		return total == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	params := &mcp.CallToolParamsFor[Input]{Arguments: Input{Host: "localhost", RunID: run.id, StopRun: true}}
+	result, err := suite.tool.SSHExecHandler(ctx, &mcp.ServerSession{}, params)
+	suite.Require().NoError(err)
+	text := resultText(result)
+	suite.Contains(text, "Run stopped before the process exited.")
+	suite.Contains(text, "listening")
+	suite.Contains(run.status(), "stopped after")
+}
+
+func (suite *SSHExecTestSuite) TestStopRunKillsRemoteProcess() {
+	previous := runDir
+	runDir = suite.T().TempDir()
+	defer func() {
+		runDir = previous
+	}()
+
+	ctx := context.Background()
+	pidFile := runPIDFile()
+	command := remoteRunCommand(`sh -c 'echo listening; trap "echo stopping; exit 7" TERM; sleep 30 & wait'`, pidFile)
+	run := suite.tool.startRun("localhost", "user@localhost", "server", func(ctx context.Context, stdout, stderr io.Writer) (int, error) {
+		return localExecutor{}.ExecuteCommandStream(ctx, command, nil, stdout, stderr)
+	}, remoteKill(localExecutor{}, pidFile), nil, nil)
+	suite.Eventually(func() bool {
+		_, total, _ := run.output.page(0, 1)
+		return total == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	suite.FileExists(pidFile)
+
+	// The process gets the signal and exits on its own, and the wrapper removes the PID file
+	params := &mcp.CallToolParamsFor[Input]{Arguments: Input{Host: "localhost", RunID: run.id, StopRun: true, GracePeriod: 5}}
+	result, err := suite.tool.SSHExecHandler(ctx, &mcp.ServerSession{}, params)
+	suite.Require().NoError(err)
+	text := resultText(result)
+	suite.Contains(text, "Stop: sent TERM\n")
+	suite.Contains(text, "Exit Code: 7\n")
+	suite.Contains(text, "stopping")
+	suite.NoFileExists(pidFile)
+
+	// Stopping it again finds nothing to signal
+	output, err := run.kill(ctx, "TERM", 1)
+	suite.Require().NoError(err)
+	suite.Equal("not running\n", output)
+}
+
+func (suite *SSHExecTestSuite) TestRemoteRunCommand() {
+	suite.Equal(`mkdir -p /tmp/remote-debugger-mcp-runs && echo $$ > '/tmp/remote-debugger-mcp-runs/a.pid'; /tmp/app -v; EXIT_CODE=$?; rm -f '/tmp/remote-debugger-mcp-runs/a.pid' '/tmp/app'; exit $EXIT_CODE`,
+		remoteRunCommand("/tmp/app -v", "/tmp/remote-debugger-mcp-runs/a.pid", "/tmp/app"))
+}
+
+func (suite *SSHExecTestSuite) TestPruneRuns() {
+	var first *execRun
+	for i := 0; i < maxFinishedRuns+2; i++ {
+		run := suite.tool.startRun("localhost", "user@localhost", fmt.Sprintf("job%d", i), localCommand("true"), nil, nil, nil)
+		<-run.done
+		if first == nil {
+			first = run
+		}
+	}
+	suite.Len(suite.tool.runs, maxFinishedRuns+1)
+	_, err := suite.tool.getRun(first.id, "localhost")
+	suite.Error(err)
+}
@@ -4,33 +4,60 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/notify"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
 )
 
 type Input struct {
-	Host            string   `json:"host" validate:"required,hostname|ip"`  // SSH host (required)
-	Port            int      `json:"port,omitempty" validate:"min=0,max=65535"` // SSH port (default: 22)
-	User            string   `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"` // SSH user (default: current user)
-	BinaryPath      string   `json:"binary_path,omitempty" validate:"omitempty,filepath"` // Local binary path to transfer (required for exec, optional for kill)
-	RemotePath      string   `json:"remote_path,omitempty" validate:"omitempty,max=4096"` // Remote destination path (default: /tmp/<filename>)
-	Args            []string `json:"args,omitempty"`              // Arguments to pass to the binary
-	KeepBinary      bool     `json:"keep_binary,omitempty"`       // Keep binary after execution (default: false, meaning cleanup)
-	RunInBackground bool     `json:"run_in_background,omitempty"` // Run process in background (default: false)
-	KillPID         int      `json:"kill_pid,omitempty" validate:"min=0,max=2147483647"` // PID to kill on remote host (mutually exclusive with exec)
-	KillByName      string   `json:"kill_by_name,omitempty" validate:"omitempty,max=255"` // Kill processes by name pattern (mutually exclusive with exec and kill_pid)
-	KillSignal      string   `json:"kill_signal,omitempty" validate:"omitempty,alpha,max=16"` // Signal to send when killing (default: TERM)
-	MaxLines        int      `json:"max_lines,omitempty" validate:"min=0,max=100000"` // Maximum lines to return (default: 1000)
-	Offset          int      `json:"offset,omitempty" validate:"min=0"` // Line offset for pagination
+	Host            string            `json:"host" validate:"required,hostname|ip"`                                               // SSH host or ~/.ssh/config alias (required)
+	Port            int               `json:"port,omitempty" validate:"min=0,max=65535"`                                          // SSH port (default: from ssh config, else 22)
+	User            string            `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"`          // SSH user (default: from ssh config, else current user)
+	BinaryPath      string            `json:"binary_path,omitempty" validate:"omitempty,filepath"`                                // Local binary path to transfer (required for exec, optional for kill)
+	Package         string            `json:"package,omitempty" validate:"omitempty,max=4096"`                                    // Go package to cross-compile for the remote host and execute (instead of binary_path)
+	BuildDir        string            `json:"build_dir,omitempty" validate:"omitempty,max=4096"`                                  // Directory to run go build in (default: current directory)
+	BuildTags       []string          `json:"build_tags,omitempty" validate:"omitempty,max=32"`                                   // Build tags for package
+	LDFlags         string            `json:"ldflags,omitempty" validate:"omitempty,max=4096"`                                    // Linker flags for package: only -s, -w and -X, e.g. "-s -w -X main.version=dev"
+	DebugBuild      bool              `json:"debug_build,omitempty"`                                                              // Build package with -gcflags="all=-N -l" for delve
+	RemotePath      string            `json:"remote_path,omitempty" validate:"omitempty,max=4096"`                                // Remote destination path (default: /tmp/<filename>)
+	Args            []string          `json:"args,omitempty"`                                                                     // Arguments to pass to the binary
+	KeepBinary      bool              `json:"keep_binary,omitempty"`                                                              // Keep binary after execution (default: false, meaning cleanup)
+	RunInBackground bool              `json:"run_in_background,omitempty"`                                                        // Run process in background (default: false, not with sudo)
+	KillPID         int               `json:"kill_pid,omitempty" validate:"min=0,max=2147483647"`                                 // PID to kill on remote host (mutually exclusive with exec)
+	KillByName      string            `json:"kill_by_name,omitempty" validate:"omitempty,max=255"`                                // Kill processes by name pattern (mutually exclusive with exec and kill_pid)
+	KillSignal      string            `json:"kill_signal,omitempty" validate:"omitempty,alpha,max=16"`                            // Signal to send when killing (default: TERM)
+	MaxLines        int               `json:"max_lines,omitempty" validate:"min=0,max=100000"`                                    // Maximum lines to return (default: 1000)
+	Offset          int               `json:"offset,omitempty" validate:"min=0"`                                                  // Line offset for pagination
+	SoftTimeout     int               `json:"soft_timeout,omitempty" validate:"min=0,max=86400"`                                  // Seconds to wait for a foreground binary before returning partial output while it keeps running (default: wait until it exits)
+	RunID           string            `json:"run_id,omitempty" validate:"omitempty,hexadecimal,len=12"`                           // Page the output of a run that outlived soft_timeout; with soft_timeout, wait for it again
+	StopRun         bool              `json:"stop_run,omitempty"`                                                                 // With run_id: signal the binary with kill_signal, then KILL after grace_period, and end the run
+	Env             map[string]string `json:"env,omitempty" validate:"omitempty,max=100,dive,keys,max=256,endkeys,max=32768"`     // Environment variables for the binary, e.g. {"GODEBUG": "gctrace=1", "GOMAXPROCS": "2"} (not with sudo)
+	Workdir         string            `json:"workdir,omitempty" validate:"omitempty,max=4096"`                                    // Working directory of the binary (default: the SSH login directory)
+	Stdin           string            `json:"stdin,omitempty" validate:"omitempty,max=10485760"`                                  // Content written to the binary's stdin
+	Timeout         int               `json:"timeout,omitempty" validate:"min=0,max=604800"`                                      // Seconds after which the binary gets TERM, then KILL 10s later (exit code 124)
+	MemoryLimitMB   int               `json:"memory_limit_mb,omitempty" validate:"min=0,max=4194304"`                             // Memory cap: ulimit -v (virtual memory), or MemoryMax with systemd_scope
+	CPUTimeLimit    int               `json:"cpu_time_limit,omitempty" validate:"min=0,max=604800"`                               // CPU seconds: ulimit -t, or LimitCPU with systemd_scope
+	OpenFilesLimit  int               `json:"open_files_limit,omitempty" validate:"min=0,max=1048576"`                            // Open files: ulimit -n, or LimitNOFILE with systemd_scope
+	SystemdScope    bool              `json:"systemd_scope,omitempty"`                                                            // Apply the limits with systemd-run --scope (cgroup limits) instead of ulimit
+	CPUQuota        int               `json:"cpu_quota,omitempty" validate:"min=0,max=100000"`                                    // CPU percent, 100 is one core (requires systemd_scope)
+	TasksLimit      int               `json:"tasks_limit,omitempty" validate:"min=0,max=4194304"`                                 // Processes and threads (requires systemd_scope)
+	Action          string            `json:"action,omitempty" validate:"omitempty,oneof=list_jobs job_status job_logs stop_job"` // Manage jobs started with run_in_background: list_jobs, job_status, job_logs, stop_job
+	JobID           string            `json:"job_id,omitempty" validate:"omitempty,hexadecimal,len=12"`                           // Job for job_status, job_logs and stop_job
+	GracePeriod     int               `json:"grace_period,omitempty" validate:"min=0,max=600"`                                    // Seconds stop_job and stop_run wait after kill_signal before sending KILL (default: 10)
+	Sudo            bool              `json:"sudo,omitempty"`                                                                     // Run the binary or kill command with sudo -n; the program must be in the server's -sudo-allow list
 }
 
 type Output struct {
@@ -51,19 +78,25 @@ type Output struct {
 type Tool struct {
 	logger    zerolog.Logger
 	validator *validator.Validate
+
+	runsMu sync.Mutex
+	runs   map[string]*execRun // Foreground runs, kept for paging their output
+
+	jobsMu sync.Mutex
+	jobs   map[string]*backgroundJob // Background jobs; kept by the server so they outlive client sessions
 }
 
 func (s *Tool) Register(srv *server.Server) {
 	sshExecTool := &mcp.Tool{
 		Name:        "sshexec",
-		Description: "Transfer and execute a binary on a remote host via SSH, or kill remote processes",
+		Description: "Transfer and execute a binary (or a Go package cross-compiled for the host) on a remote host via SSH, manage background jobs, or kill remote processes",
 	}
 
 	mcp.AddTool(&srv.Server, sshExecTool, s.SSHExecHandler)
 	s.logger.Debug().Msg("sshexec tool registered")
 }
 
-func (s *Tool) SSHExecHandler(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
+func (s *Tool) SSHExecHandler(ctx context.Context, session *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
 	input := params.Arguments
 
 	// Validate input using validator
@@ -78,32 +111,60 @@ func (s *Tool) SSHExecHandler(ctx context.Context, _ *mcp.ServerSession, params
 
 	// Determine operation mode
 	isKillMode := input.KillPID > 0 || input.KillByName != ""
-	isExecMode := input.BinaryPath != ""
+	isBuildMode := input.Package != ""
+	isExecMode := input.BinaryPath != "" || isBuildMode
+	isRunMode := input.RunID != ""
+	isJobMode := input.Action != ""
 
 	// Validate operation mode
+	if isBuildMode && input.BinaryPath != "" {
+		return nil, errors.New("cannot specify both package and binary_path - choose one")
+	}
+	if !isBuildMode && (input.BuildDir != "" || len(input.BuildTags) > 0 || input.LDFlags != "" || input.DebugBuild) {
+		return nil, errors.New("build_dir, build_tags, ldflags and debug_build require package")
+	}
 	if isKillMode && isExecMode {
-		return nil, errors.New("cannot specify both kill parameters and binary_path - choose either kill or exec mode")
+		return nil, errors.New("cannot specify both kill parameters and binary_path or package - choose either kill or exec mode")
+	}
+	if isRunMode && (isKillMode || isExecMode) {
+		return nil, errors.New("run_id cannot be combined with kill parameters or binary_path")
+	}
+	if isJobMode && (isKillMode || isExecMode || isRunMode) {
+		return nil, errors.New("action cannot be combined with kill parameters, binary_path or run_id")
 	}
-	if !isKillMode && !isExecMode {
-		return nil, errors.New("must specify either kill parameters (kill_pid or kill_by_name) or binary_path for execution")
+	if input.JobID != "" && !isJobMode {
+		return nil, errors.New("job_id requires action")
+	}
+	if !isKillMode && !isExecMode && !isRunMode && !isJobMode {
+		return nil, errors.New("must specify either kill parameters (kill_pid or kill_by_name), binary_path for execution, run_id or action")
+	}
+	if input.StopRun && !isRunMode {
+		return nil, errors.New("stop_run requires run_id")
 	}
 	if input.KillPID > 0 && input.KillByName != "" {
 		return nil, errors.New("cannot specify both kill_pid and kill_by_name - choose one")
 	}
-
-	// Set defaults
-	port := 22
-	if input.Port != 0 {
-		port = input.Port
+	if input.Sudo && !isKillMode && !isExecMode {
+		return nil, errors.New("sudo requires kill parameters, binary_path or package")
+	}
+	if input.Sudo && len(input.Env) > 0 {
+		// sudo -n -l does not check whether the sudoers policy lets variables through, so the
+		// binary would be uploaded before sudo refuses to run it
+		return nil, errors.New("env cannot be combined with sudo")
+	}
+	if input.Sudo && input.RunInBackground {
+		// stop_job signals the job as the SSH user, which cannot reach a process running as root
+		return nil, errors.New("run_in_background cannot be combined with sudo")
 	}
 
-	user := input.User
-
-	// Create SSH connector
-	conn := ssh.New(input.Host, port, user)
+	// Create SSH connector; port and user default to the ssh config of the host, then 22 and $USER
+	conn := ssh.New(input.Host, input.Port, input.User)
+	defer func() {
+		_ = conn.Close()
+	}()
 
 	if isKillMode {
-		return s.handleKillMode(ctx, input, conn)
+		return s.handleKillMode(ctx, input, conn, caller(session))
 	}
 
 	maxLines := types.MaxDefaultLines
@@ -120,14 +181,31 @@ func (s *Tool) SSHExecHandler(ctx context.Context, _ *mcp.ServerSession, params
 	} else if input.Offset < 0 {
 		return nil, errors.New("offset cannot be negative")
 	}
-	return s.handleExecMode(ctx, input, conn, maxLines, offset)
+	if isRunMode {
+		return s.handleRunMode(ctx, input, maxLines, offset)
+	}
+	if isJobMode {
+		return s.handleJobAction(ctx, input, conn, maxLines, offset)
+	}
+	return s.handleExecMode(ctx, input, conn, maxLines, offset, caller(session), notify.New(session, params.Meta, "sshexec"))
+}
+
+// caller identifies the client session requesting a command, for the sudo audit log.
+func caller(session *mcp.ServerSession) string {
+	if session == nil || session.ID() == "" {
+		return "sshexec"
+	}
+	return "sshexec session " + session.ID()
 }
 
-func (s *Tool) handleKillMode(ctx context.Context, input Input, conn *ssh.Connector) (*mcp.CallToolResultFor[Output], error) {
+func (s *Tool) handleKillMode(ctx context.Context, input Input, conn *ssh.Connector, caller string) (*mcp.CallToolResultFor[Output], error) {
 	signal := "TERM"
 	if input.KillSignal != "" {
 		signal = input.KillSignal
 	}
+	if input.Sudo {
+		return s.handleElevatedKill(ctx, input, conn, caller, signal)
+	}
 
 	var remoteCommand string
 	var operation string
@@ -168,7 +246,53 @@ func (s *Tool) handleKillMode(ctx context.Context, input Input, conn *ssh.Connec
 	}, nil
 }
 
-func (s *Tool) handleExecMode(ctx context.Context, input Input, conn *ssh.Connector, maxLines, offset int) (*mcp.CallToolResultFor[Output], error) {
+// handleElevatedKill sends the signal with sudo, for processes of other users.
+func (s *Tool) handleElevatedKill(ctx context.Context, input Input, conn *ssh.Connector, caller, signal string) (*mcp.CallToolResultFor[Output], error) {
+	var argv []string
+	var operation string
+	if input.KillPID > 0 {
+		operation = fmt.Sprintf("kill PID %d", input.KillPID)
+		argv = []string{"kill", "-" + signal, strconv.Itoa(input.KillPID)}
+	} else {
+		operation = fmt.Sprintf("kill processes matching '%s'", input.KillByName)
+		argv = []string{"pkill", "-" + signal, "-x", input.KillByName}
+	}
+
+	output, exitCode, err := conn.ExecuteElevated(ctx, caller, argv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute kill command with sudo: %w", err)
+	}
+
+	resultText := fmt.Sprintf("SSH Kill output for %s (operation: %s, sudo):\n", conn.GetTarget(), operation)
+	resultText += fmt.Sprintf("Exit Code: %d\n", exitCode)
+	resultText += "\n" + strings.TrimSpace(output)
+	if exitCode != 0 {
+		resultText += "\nNo process was signalled"
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText,
+			},
+		},
+	}, nil
+}
+
+func (s *Tool) handleExecMode(ctx context.Context, input Input, conn *ssh.Connector, maxLines, offset int, caller string, notifier *notify.Notifier) (*mcp.CallToolResultFor[Output], error) {
+	var notes []string
+	if input.Package != "" {
+		binary, note, err := s.buildPackage(ctx, input, conn)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = os.RemoveAll(filepath.Dir(binary))
+		}()
+		input.BinaryPath = binary
+		notes = append(notes, note)
+	}
+
 	// Check if binary exists
 	if _, err := os.Stat(input.BinaryPath); err != nil {
 		return nil, fmt.Errorf("binary not found: %v", err)
@@ -178,6 +302,22 @@ func (s *Tool) handleExecMode(ctx context.Context, input Input, conn *ssh.Connec
 	if remotePath == "" {
 		remotePath = filepath.Join("/tmp", filepath.Base(input.BinaryPath))
 	}
+	if input.Workdir != "" && !filepath.IsAbs(remotePath) {
+		return nil, errors.New("remote_path must be absolute when workdir is set")
+	}
+	if input.Sudo && !filepath.IsAbs(remotePath) {
+		return nil, errors.New("remote_path must be absolute with sudo")
+	}
+	if input.Sudo {
+		// Nothing is uploaded for a program the server would not elevate
+		if err := ssh.CheckSudoAllowed(remotePath); err != nil {
+			return nil, err
+		}
+	}
+	remoteCommand, err := buildRemoteCommand(input, remotePath)
+	if err != nil {
+		return nil, err
+	}
 
 	cleanup := !input.KeepBinary // cleanup by default unless KeepBinary is true
 
@@ -189,10 +329,16 @@ func (s *Tool) handleExecMode(ctx context.Context, input Input, conn *ssh.Connec
 		Bool("cleanup", cleanup).
 		Msg("transferring and executing binary")
 
-	// Step 1: Transfer binary using scp
-	if err := conn.CopyFile(ctx, input.BinaryPath, remotePath); err != nil {
+	// Step 1: Transfer binary over SFTP, skipped when the remote copy is identical
+	transfer, err := conn.UploadFile(ctx, input.BinaryPath, remotePath)
+	if err != nil {
 		return nil, err
 	}
+	s.logger.Debug().
+		Int64("bytes", transfer.Transferred).
+		Int64("resumed_from", transfer.ResumedFrom).
+		Bool("skipped", transfer.Skipped).
+		Msg("binary transferred")
 
 	// Step 2: Make binary executable
 	if err := conn.MakeExecutable(ctx, remotePath); err != nil {
@@ -200,68 +346,139 @@ func (s *Tool) handleExecMode(ctx context.Context, input Input, conn *ssh.Connec
 		_ = conn.RemoveFile(ctx, remotePath)
 		return nil, fmt.Errorf("failed to make binary executable: %v", err)
 	}
-
-	// Step 3: Execute binary with arguments
-	remoteCommand := remotePath
-	if len(input.Args) > 0 {
-		escapedArgs := ssh.EscapeArgs(input.Args)
-		remoteCommand = fmt.Sprintf("%s %s", remotePath, strings.Join(escapedArgs, " "))
+	if input.Sudo {
+		// Fail before running when sudo would prompt for a password or the sudoers policy denies it
+		if err := conn.CheckSudo(ctx, append([]string{remotePath}, input.Args...)); err != nil {
+			if cleanup {
+				_ = conn.RemoveFile(ctx, remotePath)
+			}
+			return nil, err
+		}
+		conn.AuditSudo(caller, remoteCommand)
+		notes = append([]string{fmt.Sprintf("%s %s", ssh.SudoMarker, remotePath)}, notes...)
 	}
 
-	// Add background execution if requested
+	// Step 3: Execute binary with arguments, environment and limits
+	// Background processes are registered as jobs with their output in a log file
 	if input.RunInBackground {
-		remoteCommand = fmt.Sprintf("nohup %s > /dev/null 2>&1 & echo $!", remoteCommand)
 		if cleanup {
 			// For background processes, we can't easily clean up after exit, so warn user
 			s.logger.Warn().Msg("cleanup disabled for background processes - binary will remain on remote host")
 		}
-	} else if cleanup {
-		// Add cleanup command if requested (only for foreground processes)
-		remoteCommand = fmt.Sprintf("%s; EXIT_CODE=$?; rm -f %s; exit $EXIT_CODE", remoteCommand, remotePath)
-	}
-
-	output, exitCode, err := conn.ExecuteCommandWithExitCode(ctx, remoteCommand)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute binary: %v", err)
 	}
 
-	resultText := fmt.Sprintf("SSH Exec output for %s (binary: %s):\n", conn.GetTarget(), filepath.Base(input.BinaryPath))
-
+	notes = append(notes, fmt.Sprintf("Transfer: %s", transfer))
+	notes = append(notes, commandNotes(input)...)
 	if input.RunInBackground {
-		resultText += fmt.Sprintf("Process started in background. PID: %s\n", strings.TrimSpace(output))
+		job := &backgroundJob{
+			Host:       input.Host,
+			Target:     conn.GetTarget(),
+			Binary:     filepath.Base(input.BinaryPath),
+			RemotePath: remotePath,
+			Args:       input.Args,
+		}
+		if err := s.startJob(ctx, conn, job, remoteCommand, input.Stdin); err != nil {
+			return nil, err
+		}
+		resultText := fmt.Sprintf("SSH Exec output for %s (binary: %s):\n", conn.GetTarget(), job.Binary)
+		resultText += strings.Join(notes, "\n") + "\n"
+		resultText += fmt.Sprintf("Process started in background. PID: %d\n", job.PID)
+		resultText += fmt.Sprintf("Job ID: %s\nLog file: %s\n", job.ID, job.LogFile)
+		resultText += "Use action=job_status, job_logs or stop_job with this job_id to follow it.\n"
 		resultText += "Note: Binary will remain on remote host for background processes."
-	} else {
-		// Apply pagination
-		lines := strings.Split(output, "\n")
-		totalLines := len(lines)
-
-		if offset >= totalLines {
-			lines = []string{}
-		} else {
-			end := offset + maxLines
-			if end > totalLines {
-				end = totalLines
-			}
-			lines = lines[offset:end]
+		return &mcp.CallToolResultFor[Output]{
+			Content: []mcp.Content{
+				&mcp.TextContent{
+					Text: resultText,
+				},
+			},
+		}, nil
+	}
+
+	// Stream the output while the binary runs; the run outlives the request once soft_timeout has passed.
+	// The shell's PID is recorded so that stop_run can signal the binary, not just close the session
+	pidFile := runPIDFile()
+	var removeFiles []string
+	if cleanup {
+		removeFiles = append(removeFiles, remotePath)
+	}
+	remoteCommand = remoteRunCommand(remoteCommand, pidFile, removeFiles...)
+	request := make(chan struct{})
+	defer close(request)
+	run := s.startRun(input.Host, conn.GetTarget(), filepath.Base(input.BinaryPath), func(runCtx context.Context, stdout, stderr io.Writer) (int, error) {
+		if input.Timeout > 0 {
+			// Backstop in case the remote timeout command is missing or the connection hangs
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(runCtx, time.Duration(input.Timeout+2*timeoutKillAfter)*time.Second)
+			defer cancel()
 		}
+		var stdin io.Reader
+		if input.Stdin != "" {
+			stdin = strings.NewReader(input.Stdin)
+		}
+		return conn.ExecuteCommandStream(runCtx, remoteCommand, stdin, stdout, stderr)
+	}, remoteKill(conn, pidFile), notifier, request)
+	if !waitRun(ctx, run, time.Duration(input.SoftTimeout)*time.Second) && ctx.Err() != nil {
+		run.cancel()
+		return nil, ctx.Err()
+	}
+	return runResult(run, offset, maxLines, notes...)
+}
 
-		truncated := totalLines > (offset + maxLines)
-		paginatedOutput := strings.Join(lines, "\n")
+// buildPackage cross-compiles input.Package for the platform of the remote host.
+// It returns the local binary, in a temporary directory, and a note for the result.
+func (s *Tool) buildPackage(ctx context.Context, input Input, conn *ssh.Connector) (string, string, error) {
+	target, err := remotePlatform(ctx, conn)
+	if err != nil {
+		return "", "", err
+	}
+	opts := buildOptions{
+		Package: input.Package,
+		Dir:     input.BuildDir,
+		Tags:    input.BuildTags,
+		LDFlags: input.LDFlags,
+		Debug:   input.DebugBuild,
+	}
 
-		resultText += fmt.Sprintf("Exit Code: %d\n", exitCode)
-		if truncated {
-			resultText += fmt.Sprintf("[Showing lines %d-%d of %d total lines. Use offset parameter to view more.]\n", offset+1, offset+len(lines), totalLines)
-		}
-		resultText += "\n" + strings.TrimSpace(paginatedOutput)
+	s.logger.Debug().
+		Str("host", input.Host).
+		Str("package", input.Package).
+		Str("platform", target.String()).
+		Bool("debug", input.DebugBuild).
+		Msg("cross-compiling package")
+
+	binary, err := buildBinary(ctx, opts, target)
+	if err != nil {
+		return "", "", err
+	}
+	note := fmt.Sprintf("Build: %s for %s", input.Package, target)
+	if input.DebugBuild {
+		note += " (debug build: optimizations and inlining disabled)"
 	}
+	return binary, note, nil
+}
 
-	return &mcp.CallToolResultFor[Output]{
-		Content: []mcp.Content{
-			&mcp.TextContent{
-				Text: resultText,
-			},
-		},
-	}, nil
+// handleRunMode pages the output of an earlier run, waiting up to soft_timeout for it to finish, or stops it.
+func (s *Tool) handleRunMode(ctx context.Context, input Input, maxLines, offset int) (*mcp.CallToolResultFor[Output], error) {
+	run, err := s.getRun(input.RunID, input.Host)
+	if err != nil {
+		return nil, err
+	}
+	if input.StopRun {
+		signal := "TERM"
+		if input.KillSignal != "" {
+			signal = input.KillSignal
+		}
+		grace := defaultGracePeriod
+		if input.GracePeriod > 0 {
+			grace = input.GracePeriod
+		}
+		return runResult(run, offset, maxLines, s.stopRun(ctx, run, signal, grace)...)
+	}
+	if input.SoftTimeout > 0 && !waitRun(ctx, run, time.Duration(input.SoftTimeout)*time.Second) && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return runResult(run, offset, maxLines)
 }
 
 func New(logger zerolog.Logger) tools.Tool {
@@ -270,5 +487,7 @@ func New(logger zerolog.Logger) tools.Tool {
 	return &Tool{
 		logger:    logger.With().Str("tool", "sshexec").Logger(),
 		validator: validate,
+		runs:      make(map[string]*execRun),
+		jobs:      make(map[string]*backgroundJob),
 	}
 }
@@ -2,6 +2,7 @@ package sshexec
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
@@ -22,6 +23,8 @@ func (suite *SSHExecTestSuite) SetupTest() {
 	suite.tool = &Tool{
 		logger:    logger,
 		validator: suite.validator,
+		runs:      make(map[string]*execRun),
+		jobs:      make(map[string]*backgroundJob),
 	}
 }
 
@@ -320,6 +323,195 @@ func (suite *SSHExecTestSuite) TestSSHExecHandlerValidation() {
 			shouldError: true,
 			errorMsg:    "must specify either kill parameters",
 		},
+		{
+			name: "run_id with binary path",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				RunID:      "0123456789ab",
+			},
+			shouldError: true,
+			errorMsg:    "run_id cannot be combined",
+		},
+		{
+			name: "stop_run without run_id",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				StopRun:    true,
+			},
+			shouldError: true,
+			errorMsg:    "stop_run requires run_id",
+		},
+		{
+			name: "invalid run_id",
+			input: Input{
+				Host:  "localhost",
+				RunID: "../../etc",
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
+		{
+			name: "unknown run_id",
+			input: Input{
+				Host:  "localhost",
+				RunID: "0123456789ab",
+			},
+			shouldError: true,
+			errorMsg:    "run 0123456789ab not found on localhost",
+		},
+		{
+			name: "package with binary path",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				Package:    "./cmd/server",
+			},
+			shouldError: true,
+			errorMsg:    "cannot specify both package and binary_path",
+		},
+		{
+			name: "build flags without package",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				DebugBuild: true,
+			},
+			shouldError: true,
+			errorMsg:    "debug_build require package",
+		},
+		{
+			name: "package with kill",
+			input: Input{
+				Host:    "localhost",
+				Package: "./cmd/server",
+				KillPID: 1234,
+			},
+			shouldError: true,
+			errorMsg:    "cannot specify both kill parameters and binary_path",
+		},
+		{
+			name: "timeout too long",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				Timeout:    700000,
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
+		{
+			name: "env key too long",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				Env:        map[string]string{strings.Repeat("A", 300): "1"},
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
+		{
+			name: "unknown action",
+			input: Input{
+				Host:   "localhost",
+				Action: "restart_job",
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
+		{
+			name: "action with binary path",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/usr/bin/test",
+				Action:     "list_jobs",
+			},
+			shouldError: true,
+			errorMsg:    "action cannot be combined",
+		},
+		{
+			name: "sudo without kill or exec",
+			input: Input{
+				Host:   "localhost",
+				Action: "list_jobs",
+				Sudo:   true,
+			},
+			shouldError: true,
+			errorMsg:    "sudo requires kill parameters, binary_path or package",
+		},
+		{
+			name: "env with sudo",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/bin/sh",
+				Env:        map[string]string{"LD_PRELOAD": "/tmp/x.so"},
+				Sudo:       true,
+			},
+			shouldError: true,
+			errorMsg:    "env cannot be combined with sudo",
+		},
+		{
+			name: "run_in_background with sudo",
+			input: Input{
+				Host:            "localhost",
+				BinaryPath:      "/bin/sh",
+				RunInBackground: true,
+				Sudo:            true,
+			},
+			shouldError: true,
+			errorMsg:    "run_in_background cannot be combined with sudo",
+		},
+		{
+			name: "sudo refused before upload",
+			input: Input{
+				Host:       "localhost",
+				BinaryPath: "/bin/sh",
+				RemotePath: "/usr/local/bin/sh",
+				Sudo:       true,
+			},
+			shouldError: true,
+			errorMsg:    "command is not in the sudo allowlist of the server",
+		},
+		{
+			name: "job_id without action",
+			input: Input{
+				Host:  "localhost",
+				JobID: "0123456789ab",
+			},
+			shouldError: true,
+			errorMsg:    "job_id requires action",
+		},
+		{
+			name: "job_logs without job_id",
+			input: Input{
+				Host:   "localhost",
+				Action: "job_logs",
+			},
+			shouldError: true,
+			errorMsg:    "job_id is required for job_logs",
+		},
+		{
+			name: "unknown job_id",
+			input: Input{
+				Host:   "localhost",
+				Action: "stop_job",
+				JobID:  "0123456789ab",
+			},
+			shouldError: true,
+			errorMsg:    "job 0123456789ab not found on localhost",
+		},
+		{
+			name: "grace period too long",
+			input: Input{
+				Host:        "localhost",
+				Action:      "stop_job",
+				JobID:       "0123456789ab",
+				GracePeriod: 3600,
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,206 @@
+package sshtunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
+)
+
+const defaultRemoteHost = "127.0.0.1"
+
+type Input struct {
+	Action     string `json:"action" validate:"required,oneof=create list close pool"`                   // Action to perform: "create", "list", "close" or "pool"
+	Host       string `json:"host,omitempty" validate:"omitempty,hostname|ip"`                           // SSH host or ~/.ssh/config alias (required for create)
+	Port       int    `json:"port,omitempty" validate:"min=0,max=65535"`                                 // SSH port (default: from ssh config, else 22)
+	User       string `json:"user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"` // SSH user (default: from ssh config, else current user)
+	RemoteHost string `json:"remote_host,omitempty" validate:"omitempty,hostname|ip"`                    // Address to forward to, as seen from the SSH host (default: 127.0.0.1)
+	RemotePort int    `json:"remote_port,omitempty" validate:"min=0,max=65535"`                          // Port to forward to (required for create)
+	LocalPort  int    `json:"local_port,omitempty" validate:"min=0,max=65535"`                           // First local port to try (default: remote_port)
+	TunnelID   string `json:"tunnel_id,omitempty" validate:"omitempty,hexadecimal,len=8"`                // Tunnel to close
+}
+
+type Output struct {
+	Tunnels []ssh.TunnelInfo `json:"tunnels"`
+	Pool    *ssh.PoolStats   `json:"pool,omitempty"` // Set by the pool action
+}
+
+type Tool struct {
+	logger    zerolog.Logger
+	validator *validator.Validate
+	tunnels   *ssh.TunnelManager
+	pool      *ssh.Pool
+}
+
+func (s *Tool) Register(srv *server.Server) {
+	sshTunnelTool := &mcp.Tool{
+		Name:        "sshtunnel",
+		Description: "Forward local ports to services reachable from a remote host via SSH, so that pprof and delve can reach services listening on the remote loopback; the pool action reports the shared SSH connection pool",
+	}
+
+	mcp.AddTool(&srv.Server, sshTunnelTool, s.SSHTunnelHandler)
+	s.logger.Debug().Msg("sshtunnel tool registered")
+}
+
+func (s *Tool) SSHTunnelHandler(ctx context.Context, _ *mcp.ServerSession, params *mcp.CallToolParamsFor[Input]) (*mcp.CallToolResultFor[Output], error) {
+	input := params.Arguments
+
+	// Validate input using validator
+	if err := s.validator.Struct(input); err != nil {
+		return nil, fmt.Errorf("validation error: %w", err)
+	}
+
+	switch input.Action {
+	case "create":
+		return s.handleCreate(ctx, input)
+	case "list":
+		return s.handleList()
+	case "close":
+		return s.handleClose(input)
+	case "pool":
+		return s.handlePool()
+	default:
+		return nil, fmt.Errorf("unsupported action: %s. Supported actions: create, list, close, pool", input.Action)
+	}
+}
+
+func (s *Tool) handleCreate(ctx context.Context, input Input) (*mcp.CallToolResultFor[Output], error) {
+	if input.Host == "" {
+		return nil, errors.New("host is required for create")
+	}
+	if input.RemotePort == 0 {
+		return nil, errors.New("remote_port is required for create")
+	}
+	remoteHost := input.RemoteHost
+	if remoteHost == "" {
+		remoteHost = defaultRemoteHost
+	}
+	localPort := input.LocalPort
+	if localPort == 0 {
+		localPort = input.RemotePort
+	}
+
+	conn := ssh.New(input.Host, input.Port, input.User)
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	remoteAddr := net.JoinHostPort(remoteHost, strconv.Itoa(input.RemotePort))
+	s.logger.Debug().
+		Str("host", input.Host).
+		Str("remote", remoteAddr).
+		Int("local_port", localPort).
+		Msg("creating ssh tunnel")
+
+	info, err := s.tunnels.Create(ctx, conn, localPort, remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tunnel to %s via %s: %w", remoteAddr, conn.GetTarget(), err)
+	}
+	host, port, _ := net.SplitHostPort(info.LocalAddr)
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Tunnel %s created: %s -> %s via %s (%s backend)\n", info.ID, info.LocalAddr, info.RemoteAddr, info.Target, info.Backend))
+	if port != strconv.Itoa(localPort) {
+		resultText.WriteString(fmt.Sprintf("Port %d was not available, using %s instead\n", localPort, port))
+	}
+	resultText.WriteString(fmt.Sprintf("Connect other tools to host=%s port=%s, e.g. pprof host=%s port=%s\n", host, port, host, port))
+	resultText.WriteString("The tunnel is health-checked and restarted on the same local port until it is closed.")
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText.String(),
+			},
+		},
+		StructuredContent: Output{Tunnels: []ssh.TunnelInfo{info}},
+	}, nil
+}
+
+func (s *Tool) handleList() (*mcp.CallToolResultFor[Output], error) {
+	tunnels := s.tunnels.List()
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("SSH tunnels: %d\n", len(tunnels)))
+	for _, info := range tunnels {
+		resultText.WriteString(fmt.Sprintf("\n%s  %s -> %s via %s  %s", info.ID, info.LocalAddr, info.RemoteAddr, info.Target, info.State))
+		resultText.WriteString(fmt.Sprintf("  (up %s, %d restarts", time.Since(info.Created).Round(time.Second), info.Restarts))
+		if info.Backend == ssh.BackendNative {
+			resultText.WriteString(fmt.Sprintf(", %d connections", info.Connections))
+		}
+		resultText.WriteString(")\n")
+		if info.LastError != "" {
+			resultText.WriteString(fmt.Sprintf("  last error: %s\n", info.LastError))
+		}
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText.String(),
+			},
+		},
+		StructuredContent: Output{Tunnels: tunnels},
+	}, nil
+}
+
+func (s *Tool) handleClose(input Input) (*mcp.CallToolResultFor[Output], error) {
+	if input.TunnelID == "" {
+		return nil, errors.New("tunnel_id is required for close")
+	}
+	info, err := s.tunnels.Close(input.TunnelID)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Debug().Str("tunnel", info.ID).Msg("ssh tunnel closed")
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: fmt.Sprintf("Tunnel %s closed: %s -> %s via %s", info.ID, info.LocalAddr, info.RemoteAddr, info.Target),
+			},
+		},
+		StructuredContent: Output{Tunnels: []ssh.TunnelInfo{info}},
+	}, nil
+}
+
+func (s *Tool) handlePool() (*mcp.CallToolResultFor[Output], error) {
+	stats := s.pool.Stats()
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("SSH connections: %d open (idle timeout %s)\n", stats.Open, stats.IdleTimeout))
+	resultText.WriteString(fmt.Sprintf("Dials: %d, reuses: %d, failures: %d, evictions: %d\n", stats.Dials, stats.Reuses, stats.Failures, stats.Evictions))
+	for _, conn := range stats.Connections {
+		resultText.WriteString(fmt.Sprintf("\n%s  %s backend, %d in use, up %s, idle %s\n", conn.Key, conn.Backend, conn.InUse,
+			time.Since(conn.Created).Round(time.Second), time.Since(conn.LastUsed).Round(time.Second)))
+	}
+
+	return &mcp.CallToolResultFor[Output]{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: resultText.String(),
+			},
+		},
+		StructuredContent: Output{Pool: &stats},
+	}, nil
+}
+
+func New(logger zerolog.Logger) tools.Tool {
+	validate := validator.New()
+
+	return &Tool{
+		logger:    logger.With().Str("tool", "sshtunnel").Logger(),
+		validator: validate,
+		tunnels:   ssh.DefaultTunnels(),
+		pool:      ssh.DefaultPool(),
+	}
+}
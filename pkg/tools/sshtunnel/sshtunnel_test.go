@@ -0,0 +1,187 @@
+package sshtunnel
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/suite"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+)
+
+type SSHTunnelTestSuite struct {
+	suite.Suite
+	tool      *Tool
+	validator *validator.Validate
+}
+
+func (suite *SSHTunnelTestSuite) SetupTest() {
+	logger := zerolog.Nop()
+	suite.validator = validator.New()
+	suite.tool = &Tool{
+		logger:    logger,
+		validator: suite.validator,
+		tunnels:   ssh.NewTunnelManager(),
+		pool:      ssh.NewPool(time.Minute),
+	}
+}
+
+func (suite *SSHTunnelTestSuite) TestInputValidation() {
+	testCases := []struct {
+		name        string
+		input       Input
+		shouldError bool
+	}{
+		{
+			name: "valid create",
+			input: Input{
+				Action:     "create",
+				Host:       "192.168.1.100",
+				RemotePort: 6060,
+				LocalPort:  16060,
+			},
+			shouldError: false,
+		},
+		{
+			name: "valid create with remote host",
+			input: Input{
+				Action:     "create",
+				Host:       "bastion",
+				RemoteHost: "10.0.0.5",
+				RemotePort: 2345,
+			},
+			shouldError: false,
+		},
+		{
+			name:        "valid list",
+			input:       Input{Action: "list"},
+			shouldError: false,
+		},
+		{
+			name:        "valid pool",
+			input:       Input{Action: "pool"},
+			shouldError: false,
+		},
+		{
+			name:        "valid close",
+			input:       Input{Action: "close", TunnelID: "0a1b2c3d"},
+			shouldError: false,
+		},
+		{
+			name:        "missing action",
+			input:       Input{Host: "localhost"},
+			shouldError: true,
+		},
+		{
+			name:        "invalid action",
+			input:       Input{Action: "restart"},
+			shouldError: true,
+		},
+		{
+			name:        "invalid remote host",
+			input:       Input{Action: "create", Host: "localhost", RemoteHost: "bad host", RemotePort: 80},
+			shouldError: true,
+		},
+		{
+			name:        "remote port out of range",
+			input:       Input{Action: "create", Host: "localhost", RemotePort: 70000},
+			shouldError: true,
+		},
+		{
+			name:        "invalid tunnel id",
+			input:       Input{Action: "close", TunnelID: "../x"},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			err := suite.validator.Struct(tc.input)
+			if tc.shouldError {
+				suite.Error(err)
+			} else {
+				suite.NoError(err)
+			}
+		})
+	}
+}
+
+func (suite *SSHTunnelTestSuite) TestHandlerErrors() {
+	ctx := context.Background()
+	testCases := []struct {
+		name     string
+		input    Input
+		errorMsg string
+	}{
+		{
+			name:     "validation error",
+			input:    Input{Action: "open"},
+			errorMsg: "validation error",
+		},
+		{
+			name:     "create without host",
+			input:    Input{Action: "create", RemotePort: 6060},
+			errorMsg: "host is required for create",
+		},
+		{
+			name:     "create without remote port",
+			input:    Input{Action: "create", Host: "localhost"},
+			errorMsg: "remote_port is required for create",
+		},
+		{
+			name:     "close without tunnel id",
+			input:    Input{Action: "close"},
+			errorMsg: "tunnel_id is required for close",
+		},
+		{
+			name:     "close unknown tunnel",
+			input:    Input{Action: "close", TunnelID: "0a1b2c3d"},
+			errorMsg: "tunnel 0a1b2c3d not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			params := &mcp.CallToolParamsFor[Input]{Arguments: tc.input}
+			_, err := suite.tool.SSHTunnelHandler(ctx, &mcp.ServerSession{}, params)
+			suite.Require().Error(err)
+			suite.Contains(err.Error(), tc.errorMsg)
+		})
+	}
+}
+
+func (suite *SSHTunnelTestSuite) TestListEmpty() {
+	params := &mcp.CallToolParamsFor[Input]{Arguments: Input{Action: "list"}}
+	result, err := suite.tool.SSHTunnelHandler(context.Background(), &mcp.ServerSession{}, params)
+	suite.Require().NoError(err)
+	suite.Equal("SSH tunnels: 0\n", result.Content[0].(*mcp.TextContent).Text)
+	suite.Empty(result.StructuredContent.Tunnels)
+}
+
+func (suite *SSHTunnelTestSuite) TestPoolStats() {
+	params := &mcp.CallToolParamsFor[Input]{Arguments: Input{Action: "pool"}}
+	result, err := suite.tool.SSHTunnelHandler(context.Background(), &mcp.ServerSession{}, params)
+	suite.Require().NoError(err)
+	suite.Equal("SSH connections: 0 open (idle timeout 1m0s)\nDials: 0, reuses: 0, failures: 0, evictions: 0\n", result.Content[0].(*mcp.TextContent).Text)
+	suite.Require().NotNil(result.StructuredContent.Pool)
+	suite.Equal("1m0s", result.StructuredContent.Pool.IdleTimeout)
+	suite.Empty(result.StructuredContent.Tunnels)
+}
+
+func (suite *SSHTunnelTestSuite) TestNewCreatesValidTool() {
+	tool := New(zerolog.Nop())
+
+	suite.NotNil(tool)
+	sshTunnelTool, ok := tool.(*Tool)
+	suite.True(ok)
+	suite.NotNil(sshTunnelTool.validator)
+	suite.Same(ssh.DefaultTunnels(), sshTunnelTool.tunnels)
+	suite.Same(ssh.DefaultPool(), sshTunnelTool.pool)
+}
+
+func TestSSHTunnelTestSuite(t *testing.T) {
+	suite.Run(t, new(SSHTunnelTestSuite))
+}
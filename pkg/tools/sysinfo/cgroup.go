@@ -0,0 +1,507 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	// cgroupV1Unlimited is the smallest v1 limit treated as no limit; the kernel reports "no limit"
+	// as the largest page-aligned int64.
+	cgroupV1Unlimited = int64(1) << 62
+	// cgroupDetectScript prints the cgroups of the shell and whether the cgroup v2 unified hierarchy
+	// is mounted on its own.
+	cgroupDetectScript = `echo "== cgroup"; cat /proc/self/cgroup 2>/dev/null
+if [ -f /sys/fs/cgroup/cgroup.controllers ]; then echo "== unified"; fi
+true
+`
+)
+
+// Files read from each directory of a hierarchy. Limits apply from every ancestor; usage and
+// events are read from the cgroup itself.
+var (
+	cgroupV2Files = []string{"cpu.max", "cpu.stat", "cpuset.cpus.effective", "memory.max", "memory.current",
+		"memory.events", "memory.stat", "pids.max", "pids.current"}
+	cgroupV1Files = map[string][]string{
+		"cpu":     {"cpu.cfs_quota_us", "cpu.cfs_period_us", "cpu.stat"},
+		"cpuacct": {"cpuacct.usage"},
+		"cpuset":  {"cpuset.effective_cpus"},
+		"memory":  {"memory.limit_in_bytes", "memory.usage_in_bytes", "memory.failcnt", "memory.oom_control", "memory.stat"},
+		"pids":    {"pids.max", "pids.current"},
+	}
+)
+
+// CgroupInfo describes the cgroup the information was gathered from and its limits. In a container
+// these are the container's resources, where /proc/meminfo and /proc/cpuinfo report the host's.
+type CgroupInfo struct {
+	Version          int     `json:"version"`
+	Path             string  `json:"path"`                  // cgroup of the gathering shell, from /proc/self/cgroup
+	CPUQuotaMicros   int64   `json:"cpu_quota_us"`          // 0 when CPU time is not limited
+	CPUPeriodMicros  int64   `json:"cpu_period_us"`         // Period the quota applies to
+	CPULimit         float64 `json:"cpu_limit"`             // CPUs the quota allows, 0 when not limited
+	CPUSetCPUs       int     `json:"cpuset_cpus"`           // CPUs the cgroup may run on, 0 when unknown
+	CPUUsedCores     float64 `json:"cpu_used_cores"`        // CPUs used during the sample
+	CPULimitPercent  float64 `json:"cpu_limit_percent"`     // Used share of the quota during the sample
+	Periods          int64   `json:"nr_periods"`            // Quota periods with runnable tasks
+	ThrottledPeriods int64   `json:"nr_throttled"`          // Periods the quota ran out in
+	ThrottledPercent float64 `json:"throttled_percent"`     // Throttled share of the periods
+	ThrottledSeconds float64 `json:"throttled_seconds"`     // Time tasks waited for the next period
+	MemoryLimitMB    int     `json:"memory_limit_mb"`       // 0 when memory is not limited
+	MemoryUsedMB     int     `json:"memory_used_mb"`        // Including page cache
+	WorkingSetMB     int     `json:"memory_working_set_mb"` // Used minus inactive page cache, the memory the OOM killer weighs
+	MemoryPercent    float64 `json:"memory_limit_percent"`  // Working set share of the limit
+	MemoryLimitHits  int64   `json:"memory_limit_hits"`     // Times usage reached the limit
+	OOMEvents        int64   `json:"oom_events"`            // Times the OOM killer was invoked, cgroup v2 only
+	OOMKills         int64   `json:"oom_kills"`             // Processes killed by the OOM killer
+	PidsLimit        int64   `json:"pids_limit"`            // 0 when the number of tasks is not limited
+	PidsCurrent      int64   `json:"pids_current"`
+	PidsPercent      float64 `json:"pids_limit_percent"`
+
+	cpuUsageFile string // Cumulative CPU time of the cgroup, sampled with the disk and network counters
+}
+
+// cgroupDir holds the files read from a cgroup directory by name.
+type cgroupDir struct {
+	path  string
+	files map[string]string
+}
+
+// cgroupHierarchy is a cgroup and its ancestors up to the root of the hierarchy, the cgroup first.
+type cgroupHierarchy []cgroupDir
+
+// file returns a file of the cgroup itself.
+func (h cgroupHierarchy) file(name string) (string, bool) {
+	if len(h) == 0 {
+		return "", false
+	}
+	content, ok := h[0].files[name]
+	return content, ok
+}
+
+func (s *Tool) gatherCgroupInfo(ctx context.Context, conn *target.Target) *CgroupInfo {
+	output, err := s.executeCommand(ctx, cgroupDetectScript, conn)
+	if err != nil {
+		return nil
+	}
+	parts := procfs.Sections(output)
+	_, unified := parts["unified"]
+	cgroup, roots := parseProcCgroup(parts["cgroup"], unified)
+	if cgroup == nil {
+		return nil
+	}
+
+	output, err = s.executeCommand(ctx, cgroupScript(roots), conn)
+	if err != nil {
+		return nil
+	}
+	hierarchies := parseCgroupDirs(output)
+	if cgroup.Version == 2 {
+		applyCgroupV2(cgroup, hierarchies["unified"])
+	} else {
+		applyCgroupV1(cgroup, hierarchies)
+	}
+	return cgroup
+}
+
+// cgroupMount is the directory of a hierarchy and the cgroup's path in it.
+type cgroupMount struct {
+	name  string // "unified", or the controllers of a v1 hierarchy such as "cpu,cpuacct"
+	root  string
+	path  string
+	files []string
+}
+
+// parseProcCgroup parses /proc/self/cgroup into the hierarchies to read. The v2 line is used when the
+// unified hierarchy is mounted at the cgroup root, otherwise the v1 hierarchies of the known controllers.
+func parseProcCgroup(lines []string, unified bool) (*CgroupInfo, []cgroupMount) {
+	var mounts []cgroupMount
+	cgroup := &CgroupInfo{}
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 3)
+		const cgroupFields = 3
+		if len(parts) != cgroupFields {
+			continue
+		}
+		controllers, cgroupPath := parts[1], cleanCgroupPath(parts[2])
+		if unified {
+			if parts[0] == "0" && controllers == "" {
+				cgroup.Version = 2
+				cgroup.Path = cgroupPath
+				mounts = append(mounts, cgroupMount{name: "unified", root: cgroupRoot, path: cgroupPath, files: cgroupV2Files})
+			}
+			continue
+		}
+		var files []string
+		for _, controller := range strings.Split(controllers, ",") {
+			files = append(files, cgroupV1Files[controller]...)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		cgroup.Version = 1
+		// The memory cgroup is the one limits are most often set on; any other is used without it
+		if cgroup.Path == "" || strings.Contains(","+controllers+",", ",memory,") {
+			cgroup.Path = cgroupPath
+		}
+		mounts = append(mounts, cgroupMount{name: controllers, root: path.Join(cgroupRoot, controllers), path: cgroupPath, files: files})
+	}
+	if cgroup.Version == 0 {
+		return nil, nil
+	}
+	return cgroup, mounts
+}
+
+// cleanCgroupPath cleans a cgroup path. Paths outside the cgroup namespace of the reader are shown
+// relative to it with "..", and are of no use for finding the cgroup directory.
+func cleanCgroupPath(cgroupPath string) string {
+	if strings.Contains(cgroupPath, "..") {
+		return "/"
+	}
+	return path.Clean("/" + cgroupPath)
+}
+
+// cgroupScript prints the files of the cgroup of each mount and of its ancestors up to the root
+// of the hierarchy, after "== hierarchy", "== dir" and "== file" markers. When the cgroup is not
+// found under the root, as in containers without a cgroup namespace, the container's cgroup is
+// the one mounted at the root.
+func cgroupScript(mounts []cgroupMount) string {
+	var script strings.Builder
+	for _, mount := range mounts {
+		root := ssh.EscapeArg(mount.root)
+		script.WriteString(fmt.Sprintf("echo %s\n", ssh.EscapeArg("== hierarchy "+mount.name)))
+		script.WriteString(fmt.Sprintf("d=%s; [ -d \"$d\" ] || d=%s\n", ssh.EscapeArg(path.Join(mount.root, mount.path)), root))
+		script.WriteString("while [ -n \"$d\" ]; do\n")
+		script.WriteString("\techo \"== dir $d\"\n")
+		script.WriteString(fmt.Sprintf("\tfor f in %s; do [ -f \"$d/$f\" ] && echo \"== file $f\" && cat \"$d/$f\" 2>/dev/null; done\n",
+			strings.Join(mount.files, " ")))
+		script.WriteString(fmt.Sprintf("\t[ \"$d\" = %s ] && break\n", root))
+		script.WriteString("\td=${d%/*}\n")
+		script.WriteString("done\n")
+	}
+	script.WriteString("true\n")
+	return script.String()
+}
+
+// parseCgroupDirs parses cgroupScript output into hierarchies by name.
+func parseCgroupDirs(output string) map[string]cgroupHierarchy {
+	hierarchies := make(map[string]cgroupHierarchy)
+	name, file := "", ""
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case line == "STDERR:":
+			return hierarchies
+		case strings.HasPrefix(line, "== hierarchy "):
+			name, file = strings.TrimPrefix(line, "== hierarchy "), ""
+		case strings.HasPrefix(line, "== dir "):
+			if name != "" {
+				hierarchies[name] = append(hierarchies[name], cgroupDir{path: strings.TrimPrefix(line, "== dir "), files: make(map[string]string)})
+			}
+			file = ""
+		case strings.HasPrefix(line, "== file "):
+			file = strings.TrimPrefix(line, "== file ")
+			if dirs := hierarchies[name]; len(dirs) > 0 {
+				dirs[len(dirs)-1].files[file] = ""
+			}
+		case file != "" && line != "":
+			dirs := hierarchies[name]
+			if len(dirs) > 0 {
+				dir := dirs[len(dirs)-1]
+				if dir.files[file] != "" {
+					dir.files[file] += "\n"
+				}
+				dir.files[file] += line
+			}
+		}
+	}
+	return hierarchies
+}
+
+// applyCgroupV2 fills in the limits and usage of a cgroup v2 hierarchy.
+func applyCgroupV2(cgroup *CgroupInfo, hierarchy cgroupHierarchy) {
+	// The tightest quota of the cgroup and its ancestors applies
+	for _, dir := range hierarchy {
+		fields := strings.Fields(dir.files["cpu.max"])
+		const cpuMaxFields = 2
+		if len(fields) != cpuMaxFields || fields[0] == "max" {
+			continue
+		}
+		quota, err1 := strconv.ParseInt(fields[0], 10, 64)
+		period, err2 := strconv.ParseInt(fields[1], 10, 64)
+		if err1 == nil && err2 == nil {
+			setCPUQuota(cgroup, quota, period)
+		}
+	}
+	if len(hierarchy) > 0 {
+		cgroup.cpuUsageFile = hierarchy[0].path + "/cpu.stat"
+	}
+	if content, ok := hierarchy.file("cpu.stat"); ok {
+		stat := parseKeyValues(content)
+		const usecPerSecond = 1e6
+		setThrottling(cgroup, stat["nr_periods"], stat["nr_throttled"], float64(stat["throttled_usec"])/usecPerSecond)
+	}
+	if content, ok := hierarchy.file("cpuset.cpus.effective"); ok {
+		cgroup.CPUSetCPUs = countCPUList(content)
+	}
+
+	var limit int64
+	for _, dir := range hierarchy {
+		if value, err := strconv.ParseInt(strings.TrimSpace(dir.files["memory.max"]), 10, 64); err == nil && (limit == 0 || value < limit) {
+			limit = value
+		}
+	}
+	current, _ := hierarchy.file("memory.current")
+	stat, _ := hierarchy.file("memory.stat")
+	setMemory(cgroup, limit, current, parseKeyValues(stat)["inactive_file"])
+	if content, ok := hierarchy.file("memory.events"); ok {
+		events := parseKeyValues(content)
+		cgroup.MemoryLimitHits = events["max"]
+		cgroup.OOMEvents = events["oom"]
+		cgroup.OOMKills = events["oom_kill"]
+	}
+
+	setPids(cgroup, hierarchy, "pids.max")
+}
+
+// applyCgroupV1 fills in the limits and usage of the cgroup v1 hierarchies.
+func applyCgroupV1(cgroup *CgroupInfo, hierarchies map[string]cgroupHierarchy) {
+	find := func(controller string) cgroupHierarchy {
+		for name, hierarchy := range hierarchies {
+			if strings.Contains(","+name+",", ","+controller+",") {
+				return hierarchy
+			}
+		}
+		return nil
+	}
+
+	cpu := find("cpu")
+	for _, dir := range cpu {
+		quota, err1 := strconv.ParseInt(strings.TrimSpace(dir.files["cpu.cfs_quota_us"]), 10, 64)
+		period, err2 := strconv.ParseInt(strings.TrimSpace(dir.files["cpu.cfs_period_us"]), 10, 64)
+		// A quota of -1 is no limit
+		if err1 == nil && err2 == nil && quota > 0 {
+			setCPUQuota(cgroup, quota, period)
+		}
+	}
+	if content, ok := cpu.file("cpu.stat"); ok {
+		stat := parseKeyValues(content)
+		const nsecPerSecond = 1e9
+		setThrottling(cgroup, stat["nr_periods"], stat["nr_throttled"], float64(stat["throttled_time"])/nsecPerSecond)
+	}
+	if cpuacct := find("cpuacct"); len(cpuacct) > 0 {
+		cgroup.cpuUsageFile = cpuacct[0].path + "/cpuacct.usage"
+	}
+	if content, ok := find("cpuset").file("cpuset.effective_cpus"); ok {
+		cgroup.CPUSetCPUs = countCPUList(content)
+	}
+
+	memory := find("memory")
+	var limit int64
+	for _, dir := range memory {
+		value, err := strconv.ParseInt(strings.TrimSpace(dir.files["memory.limit_in_bytes"]), 10, 64)
+		if err == nil && value < cgroupV1Unlimited && (limit == 0 || value < limit) {
+			limit = value
+		}
+	}
+	current, _ := memory.file("memory.usage_in_bytes")
+	stat, _ := memory.file("memory.stat")
+	setMemory(cgroup, limit, current, parseKeyValues(stat)["total_inactive_file"])
+	if content, ok := memory.file("memory.failcnt"); ok {
+		cgroup.MemoryLimitHits, _ = strconv.ParseInt(strings.TrimSpace(content), 10, 64)
+	}
+	if content, ok := memory.file("memory.oom_control"); ok {
+		cgroup.OOMKills = parseKeyValues(content)["oom_kill"]
+	}
+
+	setPids(cgroup, find("pids"), "pids.max")
+}
+
+// setCPUQuota keeps the quota if it allows fewer CPUs than the one already set.
+func setCPUQuota(cgroup *CgroupInfo, quota, period int64) {
+	if quota <= 0 || period <= 0 {
+		return
+	}
+	limit := float64(quota) / float64(period)
+	if cgroup.CPULimit == 0 || limit < cgroup.CPULimit {
+		cgroup.CPUQuotaMicros = quota
+		cgroup.CPUPeriodMicros = period
+		cgroup.CPULimit = round2(limit)
+	}
+}
+
+func setThrottling(cgroup *CgroupInfo, periods, throttled int64, throttledSeconds float64) {
+	cgroup.Periods = periods
+	cgroup.ThrottledPeriods = throttled
+	cgroup.ThrottledSeconds = round1(throttledSeconds)
+	if periods > 0 {
+		const percentMultiplier = 100.0
+		cgroup.ThrottledPercent = round1(float64(throttled) * percentMultiplier / float64(periods))
+	}
+}
+
+func setMemory(cgroup *CgroupInfo, limit int64, current string, inactiveFile int64) {
+	const bytesPerMB = 1024 * 1024
+	used, _ := strconv.ParseInt(strings.TrimSpace(current), 10, 64)
+	workingSet := max(used-inactiveFile, 0)
+	cgroup.MemoryUsedMB = int(used / bytesPerMB)
+	cgroup.WorkingSetMB = int(workingSet / bytesPerMB)
+	if limit > 0 {
+		const percentMultiplier = 100.0
+		cgroup.MemoryLimitMB = int(limit / bytesPerMB)
+		cgroup.MemoryPercent = round1(float64(workingSet) * percentMultiplier / float64(limit))
+	}
+}
+
+// applyCgroupMemory makes a cgroup memory limit below the host's memory the total, with the working
+// set as used memory, and keeps the host's figures as secondary ones.
+func applyCgroupMemory(memory *MemoryInfo, cgroup *CgroupInfo) {
+	if cgroup == nil || cgroup.MemoryLimitMB == 0 || (memory.TotalMB > 0 && cgroup.MemoryLimitMB >= memory.TotalMB) {
+		return
+	}
+	memory.HostTotalMB = memory.TotalMB
+	memory.HostUsedMB = memory.UsedMB
+	memory.HostUsagePercent = memory.UsagePercent
+	memory.TotalMB = cgroup.MemoryLimitMB
+	memory.UsedMB = cgroup.WorkingSetMB
+	memory.AvailableMB = max(cgroup.MemoryLimitMB-cgroup.WorkingSetMB, 0)
+	memory.FreeMB = max(cgroup.MemoryLimitMB-cgroup.MemoryUsedMB, 0)
+	memory.CachedMB = cgroup.MemoryUsedMB - cgroup.WorkingSetMB
+	memory.UsagePercent = fmt.Sprintf("%.1f%%", cgroup.MemoryPercent)
+}
+
+func setPids(cgroup *CgroupInfo, hierarchy cgroupHierarchy, limitFile string) {
+	for _, dir := range hierarchy {
+		if value, err := strconv.ParseInt(strings.TrimSpace(dir.files[limitFile]), 10, 64); err == nil && (cgroup.PidsLimit == 0 || value < cgroup.PidsLimit) {
+			cgroup.PidsLimit = value
+		}
+	}
+	if content, ok := hierarchy.file("pids.current"); ok {
+		cgroup.PidsCurrent, _ = strconv.ParseInt(strings.TrimSpace(content), 10, 64)
+	}
+	if cgroup.PidsLimit > 0 {
+		const percentMultiplier = 100.0
+		cgroup.PidsPercent = round1(float64(cgroup.PidsCurrent) * percentMultiplier / float64(cgroup.PidsLimit))
+	}
+}
+
+// calculateCgroupCPU sets the CPU used by the cgroup between the samples: cpu.stat usage_usec on
+// cgroup v2, cpuacct.usage nanoseconds on v1.
+func calculateCgroupCPU(cgroup *CgroupInfo, samples *statSamples) {
+	if cgroup == nil || samples == nil {
+		return
+	}
+	usage := func(lines []string) (float64, bool) {
+		if cgroup.Version == 2 {
+			value, ok := parseKeyValues(strings.Join(lines, "\n"))["usage_usec"]
+			const usecPerSecond = 1e6
+			return float64(value) / usecPerSecond, ok
+		}
+		if len(lines) == 0 {
+			return 0, false
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+		const nsecPerSecond = 1e9
+		return float64(value) / nsecPerSecond, err == nil
+	}
+	before, ok1 := usage(samples.before["cgroup cpu"])
+	after, ok2 := usage(samples.after["cgroup cpu"])
+	if !ok1 || !ok2 || after < before {
+		return
+	}
+	used := (after - before) / samples.interval
+	cgroup.CPUUsedCores = round2(used)
+	if cgroup.CPULimit > 0 {
+		const percentMultiplier = 100.0
+		cgroup.CPULimitPercent = round1(used * percentMultiplier / cgroup.CPULimit)
+	}
+}
+
+// parseKeyValues parses "key value" lines such as those of cpu.stat and memory.events.
+func parseKeyValues(content string) map[string]int64 {
+	values := make(map[string]int64)
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		const keyValueFields = 2
+		if len(fields) != keyValueFields {
+			continue
+		}
+		if value, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// countCPUList counts the CPUs of a list such as "0-3,8,10-11".
+func countCPUList(list string) int {
+	count := 0
+	for _, part := range strings.Split(strings.TrimSpace(list), ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				continue
+			}
+		}
+		count += end - start + 1
+	}
+	return count
+}
+
+func round2(value float64) float64 {
+	const precision = 100
+	return math.Round(value*precision) / precision
+}
+
+func formatCgroupInfo(output *strings.Builder, cgroup *CgroupInfo) {
+	output.WriteString(fmt.Sprintf("Cgroup Limits (v%d, %s):\n", cgroup.Version, cgroup.Path))
+	if cgroup.CPULimit > 0 {
+		output.WriteString(fmt.Sprintf("  CPU Limit: %.2f CPUs (quota %dus per %dus), %.2f used (%.1f%%)\n",
+			cgroup.CPULimit, cgroup.CPUQuotaMicros, cgroup.CPUPeriodMicros, cgroup.CPUUsedCores, cgroup.CPULimitPercent))
+	} else {
+		output.WriteString(fmt.Sprintf("  CPU Limit: none, %.2f CPUs used\n", cgroup.CPUUsedCores))
+	}
+	if cgroup.CPUSetCPUs > 0 {
+		output.WriteString(fmt.Sprintf("  CPU Set: %d CPUs\n", cgroup.CPUSetCPUs))
+	}
+	if cgroup.Periods > 0 {
+		marker := ""
+		if cgroup.ThrottledPeriods > 0 {
+			marker = " !"
+		}
+		output.WriteString(fmt.Sprintf("  Throttled: %d of %d periods (%.1f%%), %.1fs total%s\n",
+			cgroup.ThrottledPeriods, cgroup.Periods, cgroup.ThrottledPercent, cgroup.ThrottledSeconds, marker))
+	}
+	if cgroup.MemoryLimitMB > 0 {
+		output.WriteString(fmt.Sprintf("  Memory Limit: %d MB, working set %d MB (%.1f%%), %d MB with page cache\n",
+			cgroup.MemoryLimitMB, cgroup.WorkingSetMB, cgroup.MemoryPercent, cgroup.MemoryUsedMB))
+	} else {
+		output.WriteString(fmt.Sprintf("  Memory Limit: none, working set %d MB, %d MB with page cache\n", cgroup.WorkingSetMB, cgroup.MemoryUsedMB))
+	}
+	marker := ""
+	if cgroup.OOMKills > 0 {
+		marker = " !"
+	}
+	if cgroup.Version == 2 {
+		output.WriteString(fmt.Sprintf("  OOM: %d limit hits, %d OOM events, %d OOM kills%s\n", cgroup.MemoryLimitHits, cgroup.OOMEvents, cgroup.OOMKills, marker))
+	} else {
+		output.WriteString(fmt.Sprintf("  OOM: %d limit hits, %d OOM kills%s\n", cgroup.MemoryLimitHits, cgroup.OOMKills, marker))
+	}
+	if cgroup.PidsLimit > 0 {
+		output.WriteString(fmt.Sprintf("  Tasks: %d of %d (%.1f%%)\n", cgroup.PidsCurrent, cgroup.PidsLimit, cgroup.PidsPercent))
+	} else if cgroup.PidsCurrent > 0 {
+		output.WriteString(fmt.Sprintf("  Tasks: %d, no limit\n", cgroup.PidsCurrent))
+	}
+}
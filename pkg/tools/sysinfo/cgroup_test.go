@@ -0,0 +1,274 @@
+package sysinfo
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// writeCgroupFiles creates the files of a fake cgroup directory.
+func (suite *SysinfoTestSuite) writeCgroupFiles(dir string, files map[string]string) {
+	suite.Require().NoError(os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		suite.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+}
+
+// runCgroupScript runs cgroupScript locally against fake hierarchies.
+func (suite *SysinfoTestSuite) runCgroupScript(mounts []cgroupMount) map[string]cgroupHierarchy {
+	output, err := exec.Command("sh", "-c", cgroupScript(mounts)).Output()
+	suite.Require().NoError(err)
+	return parseCgroupDirs(string(output))
+}
+
+func (suite *SysinfoTestSuite) TestParseProcCgroup() {
+	cgroup, mounts := parseProcCgroup([]string{"0::/kubepods/burstable/pod1/abc"}, true)
+	suite.Require().NotNil(cgroup)
+	suite.Equal(2, cgroup.Version)
+	suite.Equal("/kubepods/burstable/pod1/abc", cgroup.Path)
+	suite.Equal([]cgroupMount{{name: "unified", root: "/sys/fs/cgroup", path: "/kubepods/burstable/pod1/abc", files: cgroupV2Files}}, mounts)
+
+	// Hybrid hosts mount the unified hierarchy elsewhere; the v1 controllers are used
+	cgroup, mounts = parseProcCgroup([]string{
+		"12:pids:/user.slice/user-1000.slice",
+		"7:cpu,cpuacct:/docker/abc",
+		"4:memory:/docker/abc",
+		"1:name=systemd:/user.slice",
+		"0::/user.slice",
+	}, false)
+	suite.Require().NotNil(cgroup)
+	suite.Equal(1, cgroup.Version)
+	suite.Equal("/docker/abc", cgroup.Path, "the memory cgroup is reported")
+	suite.Require().Len(mounts, 3)
+	suite.Equal("/sys/fs/cgroup/cpu,cpuacct", mounts[1].root)
+	suite.Equal([]string{"cpu.cfs_quota_us", "cpu.cfs_period_us", "cpu.stat", "cpuacct.usage"}, mounts[1].files)
+
+	cgroup, _ = parseProcCgroup([]string{"0::/../../kubepods/pod1"}, true)
+	suite.Equal("/", cgroup.Path)
+
+	cgroup, mounts = parseProcCgroup(nil, false)
+	suite.Nil(cgroup)
+	suite.Nil(mounts)
+}
+
+func (suite *SysinfoTestSuite) TestCgroupV2() {
+	root := suite.T().TempDir()
+	pod := filepath.Join(root, "kubepods", "pod1")
+	container := filepath.Join(pod, "abc")
+	suite.writeCgroupFiles(root, map[string]string{"cpu.stat": "usage_usec 999999999\n"})
+	suite.writeCgroupFiles(pod, map[string]string{
+		"cpu.max":    "100000 100000\n",
+		"memory.max": "1073741824\n",
+		"pids.max":   "max\n",
+	})
+	suite.writeCgroupFiles(container, map[string]string{
+		"cpu.max":               "150000 100000\n",
+		"cpu.stat":              "usage_usec 5000000\nuser_usec 4000000\nsystem_usec 1000000\nnr_periods 200\nnr_throttled 50\nthrottled_usec 2500000\n",
+		"cpuset.cpus.effective": "0-3,6\n",
+		"memory.max":            "2147483648\n",
+		"memory.current":        "838860800\n",
+		"memory.stat":           "anon 419430400\nfile 419430400\ninactive_file 314572800\n",
+		"memory.events":         "low 0\nhigh 0\nmax 12\noom 2\noom_kill 1\n",
+		"pids.max":              "100\n",
+		"pids.current":          "25\n",
+	})
+
+	hierarchies := suite.runCgroupScript([]cgroupMount{{name: "unified", root: root, path: "/kubepods/pod1/abc", files: cgroupV2Files}})
+	suite.Require().Len(hierarchies["unified"], 4, "the cgroup and its ancestors up to the root")
+	suite.Equal(container, hierarchies["unified"][0].path)
+
+	cgroup := &CgroupInfo{Version: 2, Path: "/kubepods/pod1/abc"}
+	applyCgroupV2(cgroup, hierarchies["unified"])
+	suite.Equal(&CgroupInfo{
+		Version:          2,
+		Path:             "/kubepods/pod1/abc",
+		CPUQuotaMicros:   100000,
+		CPUPeriodMicros:  100000,
+		CPULimit:         1,
+		CPUSetCPUs:       5,
+		Periods:          200,
+		ThrottledPeriods: 50,
+		ThrottledPercent: 25,
+		ThrottledSeconds: 2.5,
+		MemoryLimitMB:    1024,
+		MemoryUsedMB:     800,
+		WorkingSetMB:     500,
+		MemoryPercent:    48.8,
+		MemoryLimitHits:  12,
+		OOMEvents:        2,
+		OOMKills:         1,
+		PidsLimit:        100,
+		PidsCurrent:      25,
+		PidsPercent:      25,
+		cpuUsageFile:     container + "/cpu.stat",
+	}, cgroup, "the pod's tighter CPU and memory limits apply")
+
+	samples := &statSamples{
+		before:   map[string][]string{"cgroup cpu": {"usage_usec 5000000", "nr_periods 200"}},
+		after:    map[string][]string{"cgroup cpu": {"usage_usec 6500000", "nr_periods 210"}},
+		interval: 2,
+	}
+	calculateCgroupCPU(cgroup, samples)
+	suite.Equal(0.75, cgroup.CPUUsedCores)
+	suite.Equal(75.0, cgroup.CPULimitPercent)
+}
+
+func (suite *SysinfoTestSuite) TestCgroupV1() {
+	root := suite.T().TempDir()
+	memory := filepath.Join(root, "memory")
+	cpu := filepath.Join(root, "cpu,cpuacct")
+	// Without a cgroup namespace the container's cgroup is mounted at the root of each hierarchy
+	suite.writeCgroupFiles(memory, map[string]string{
+		"memory.limit_in_bytes": "536870912\n",
+		"memory.usage_in_bytes": "268435456\n",
+		"memory.failcnt":        "3\n",
+		"memory.oom_control":    "oom_kill_disable 0\nunder_oom 0\noom_kill 4\n",
+		"memory.stat":           "cache 134217728\ntotal_inactive_file 67108864\n",
+	})
+	suite.writeCgroupFiles(cpu, map[string]string{
+		"cpu.cfs_quota_us":  "50000\n",
+		"cpu.cfs_period_us": "100000\n",
+		"cpu.stat":          "nr_periods 1000\nnr_throttled 10\nthrottled_time 3000000000\n",
+		"cpuacct.usage":     "123456789\n",
+	})
+
+	hierarchies := suite.runCgroupScript([]cgroupMount{
+		{name: "memory", root: memory, path: "/docker/abc", files: cgroupV1Files["memory"]},
+		{name: "cpu,cpuacct", root: cpu, path: "/docker/abc", files: append(cgroupV1Files["cpu"], cgroupV1Files["cpuacct"]...)},
+	})
+	suite.Require().Len(hierarchies["memory"], 1)
+
+	cgroup := &CgroupInfo{Version: 1, Path: "/docker/abc"}
+	applyCgroupV1(cgroup, hierarchies)
+	suite.Equal(&CgroupInfo{
+		Version:          1,
+		Path:             "/docker/abc",
+		CPUQuotaMicros:   50000,
+		CPUPeriodMicros:  100000,
+		CPULimit:         0.5,
+		Periods:          1000,
+		ThrottledPeriods: 10,
+		ThrottledPercent: 1,
+		ThrottledSeconds: 3,
+		MemoryLimitMB:    512,
+		MemoryUsedMB:     256,
+		WorkingSetMB:     192,
+		MemoryPercent:    37.5,
+		MemoryLimitHits:  3,
+		OOMKills:         4,
+		cpuUsageFile:     cpu + "/cpuacct.usage",
+	}, cgroup)
+
+	calculateCgroupCPU(cgroup, &statSamples{
+		before:   map[string][]string{"cgroup cpu": {"1000000000"}},
+		after:    map[string][]string{"cgroup cpu": {"1250000000"}},
+		interval: 1,
+	})
+	suite.Equal(0.25, cgroup.CPUUsedCores)
+	suite.Equal(50.0, cgroup.CPULimitPercent)
+
+	// No limits: a quota of -1 and the largest page-aligned int64
+	unlimited := cgroupHierarchy{{files: map[string]string{
+		"cpu.cfs_quota_us":      "-1",
+		"cpu.cfs_period_us":     "100000",
+		"memory.limit_in_bytes": "9223372036854771712",
+		"memory.usage_in_bytes": "1048576",
+	}}}
+	cgroup = &CgroupInfo{Version: 1}
+	applyCgroupV1(cgroup, map[string]cgroupHierarchy{"cpu": unlimited, "memory": unlimited})
+	suite.Zero(cgroup.CPULimit)
+	suite.Zero(cgroup.MemoryLimitMB)
+	suite.Equal(1, cgroup.MemoryUsedMB)
+}
+
+func (suite *SysinfoTestSuite) TestCountCPUList() {
+	suite.Equal(4, countCPUList("0-3\n"))
+	suite.Equal(5, countCPUList("0-3,6"))
+	suite.Equal(1, countCPUList("0"))
+	suite.Equal(0, countCPUList(""))
+	suite.Equal(2, countCPUList("3-1,4,x,7"))
+}
+
+func (suite *SysinfoTestSuite) TestFormatCgroupInfo() {
+	var output strings.Builder
+	formatCgroupInfo(&output, &CgroupInfo{
+		Version:          2,
+		Path:             "/kubepods/pod1/abc",
+		CPUQuotaMicros:   150000,
+		CPUPeriodMicros:  100000,
+		CPULimit:         1.5,
+		CPUUsedCores:     0.75,
+		CPULimitPercent:  50,
+		Periods:          200,
+		ThrottledPeriods: 50,
+		ThrottledPercent: 25,
+		ThrottledSeconds: 2.5,
+		MemoryLimitMB:    1024,
+		MemoryUsedMB:     800,
+		WorkingSetMB:     500,
+		MemoryPercent:    48.8,
+		OOMEvents:        2,
+		OOMKills:         1,
+		PidsLimit:        100,
+		PidsCurrent:      25,
+		PidsPercent:      25,
+	})
+	suite.Equal(`Cgroup Limits (v2, /kubepods/pod1/abc):
+  CPU Limit: 1.50 CPUs (quota 150000us per 100000us), 0.75 used (50.0%)
+  Throttled: 50 of 200 periods (25.0%), 2.5s total !
+  Memory Limit: 1024 MB, working set 500 MB (48.8%), 800 MB with page cache
+  OOM: 0 limit hits, 2 OOM events, 1 OOM kills !
+  Tasks: 25 of 100 (25.0%)
+`, output.String())
+
+	output.Reset()
+	formatCgroupInfo(&output, &CgroupInfo{Version: 1, Path: "/", MemoryUsedMB: 300, WorkingSetMB: 200, PidsCurrent: 12})
+	suite.Equal(`Cgroup Limits (v1, /):
+  CPU Limit: none, 0.00 CPUs used
+  Memory Limit: none, working set 200 MB, 300 MB with page cache
+  OOM: 0 limit hits, 0 OOM kills
+  Tasks: 12, no limit
+`, output.String())
+
+	// The CPU limit is shown next to the host's cores; the memory limit replaces the host's memory
+	info := &SystemInfo{
+		MemoryInfo: MemoryInfo{TotalMB: 16000, UsedMB: 8000, AvailableMB: 8000, FreeMB: 2000, CachedMB: 6000, UsagePercent: "50.0%"},
+		CgroupInfo: &CgroupInfo{Version: 2, Path: "/", CPULimit: 2, CPULimitPercent: 10, MemoryLimitMB: 512, MemoryUsedMB: 460, WorkingSetMB: 409, MemoryPercent: 80},
+	}
+	applyCgroupMemory(&info.MemoryInfo, info.CgroupInfo)
+	text := suite.tool.formatSystemInfo(info, "pod default/api-0")
+	suite.Contains(text, "  Cgroup Limit: 2.00 CPUs (10.0% used)\n")
+	suite.Contains(text, `Memory Information (cgroup limit):
+  Total: 512 MB (host: 16000 MB)
+  Used: 409 MB working set (80.0%) (host: 8000 MB, 50.0%)
+  Available: 103 MB
+  Free: 52 MB
+  Cached: 51 MB
+`)
+}
+
+func (suite *SysinfoTestSuite) TestApplyCgroupMemory() {
+	host := MemoryInfo{TotalMB: 16000, UsedMB: 8000, AvailableMB: 8000, FreeMB: 2000, CachedMB: 6000, UsagePercent: "50.0%"}
+
+	memory := host
+	applyCgroupMemory(&memory, &CgroupInfo{MemoryLimitMB: 1024, MemoryUsedMB: 800, WorkingSetMB: 500, MemoryPercent: 48.8})
+	suite.Equal(MemoryInfo{
+		TotalMB:          1024,
+		UsedMB:           500,
+		AvailableMB:      524,
+		FreeMB:           224,
+		CachedMB:         300,
+		UsagePercent:     "48.8%",
+		HostTotalMB:      16000,
+		HostUsedMB:       8000,
+		HostUsagePercent: "50.0%",
+	}, memory)
+
+	// Without a limit, or with one above the host's memory, the host's figures stay
+	for _, cgroup := range []*CgroupInfo{nil, {MemoryUsedMB: 800}, {MemoryLimitMB: 32000, MemoryUsedMB: 800}} {
+		memory = host
+		applyCgroupMemory(&memory, cgroup)
+		suite.Equal(host, memory)
+	}
+}
@@ -0,0 +1,378 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+)
+
+const (
+	defaultDiskThreshold = 90  // Mounts at or above this space or inode usage percent are flagged
+	sectorSize           = 512 // /proc/diskstats counts 512-byte sectors regardless of the device
+)
+
+// skippedFSTypes are filesystems whose usage says nothing about free space: read-only images are
+// always full.
+var skippedFSTypes = map[string]bool{
+	"squashfs": true,
+	"iso9660":  true,
+}
+
+type DiskInfo struct {
+	ThresholdPercent int         `json:"threshold_percent"`
+	Mounts           []MountInfo `json:"mounts"`
+	Devices          []DeviceIO  `json:"devices"`
+	IntervalSeconds  float64     `json:"interval_seconds"` // Time between the /proc/diskstats samples
+}
+
+type MountInfo struct {
+	Device            string  `json:"device"`
+	MountPoint        string  `json:"mount_point"`
+	FSType            string  `json:"fs_type"`
+	TotalMB           int     `json:"total_mb"`
+	UsedMB            int     `json:"used_mb"`
+	AvailableMB       int     `json:"available_mb"`
+	UsagePercent      float64 `json:"usage_percent"`
+	InodesTotal       int     `json:"inodes_total"`
+	InodesUsed        int     `json:"inodes_used"`
+	InodesFree        int     `json:"inodes_free"`
+	InodeUsagePercent float64 `json:"inode_usage_percent"`
+	OverThreshold     bool    `json:"over_threshold"` // Space or inode usage is at or above the threshold
+}
+
+type DeviceIO struct {
+	Name        string  `json:"name"`
+	ReadIOPS    float64 `json:"read_iops"`
+	WriteIOPS   float64 `json:"write_iops"`
+	ReadKBps    float64 `json:"read_kb_per_sec"`
+	WriteKBps   float64 `json:"write_kb_per_sec"`
+	AwaitMs     float64 `json:"await_ms"` // Average time a request took, queueing included
+	UtilPercent float64 `json:"util_percent"`
+	InFlight    int     `json:"in_flight"` // Requests in progress at the second sample
+}
+
+// mountEntry is a line of /proc/mounts.
+type mountEntry struct {
+	device string
+	fsType string
+}
+
+// dfEntry is a line of df -P output; values are 1K blocks for df -k and inodes for df -i.
+type dfEntry struct {
+	filesystem string
+	total      int
+	used       int
+	free       int
+	mountPoint string
+}
+
+// diskStat holds the /proc/diskstats counters of a device.
+type diskStat struct {
+	name         string
+	reads        int64
+	sectorsRead  int64
+	msReading    int64
+	writes       int64
+	sectorsWrite int64
+	msWriting    int64
+	inFlight     int64
+	msDoingIO    int64
+}
+
+func (s *Tool) gatherDiskInfo(ctx context.Context, conn *target.Target, threshold int, samples *statSamples) DiskInfo {
+	disk := DiskInfo{ThresholdPercent: threshold}
+
+	var mounts map[string]mountEntry
+	if output, err := s.executeCommand(ctx, "cat /proc/mounts", conn); err == nil {
+		mounts = parseMounts(output)
+	}
+
+	// df exits non-zero when a single mount cannot be read, but still reports the others
+	var inodes []dfEntry
+	if output, err := s.executeCommand(ctx, "df -P -i 2>/dev/null || true", conn); err == nil {
+		inodes = parseDF(output)
+	}
+	if output, err := s.executeCommand(ctx, "df -P -k 2>/dev/null || true", conn); err == nil {
+		disk.Mounts = buildMounts(parseDF(output), inodes, mounts, threshold)
+	}
+
+	var blockDevices map[string]bool
+	if output, err := s.executeCommand(ctx, "ls /sys/block 2>/dev/null", conn); err == nil {
+		blockDevices = make(map[string]bool)
+		for _, name := range strings.Fields(output) {
+			blockDevices[name] = true
+		}
+	}
+	if samples != nil {
+		disk.IntervalSeconds = round1(samples.interval)
+		disk.Devices = calculateDeviceIO(parseDiskstats(samples.before["diskstats"]),
+			parseDiskstats(samples.after["diskstats"]), samples.interval, blockDevices)
+	}
+
+	return disk
+}
+
+// parseMounts returns the /proc/mounts entries by mount point; the last mount on a point is the
+// visible one.
+func parseMounts(output string) map[string]mountEntry {
+	mounts := make(map[string]mountEntry)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		const minMountFields = 3
+		if len(fields) < minMountFields {
+			continue
+		}
+		mounts[unescapeMount(fields[1])] = mountEntry{device: unescapeMount(fields[0]), fsType: fields[2]}
+	}
+	return mounts
+}
+
+// unescapeMount decodes the octal escapes /proc/mounts uses for spaces, tabs and backslashes.
+func unescapeMount(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+4 <= len(value) {
+			if code, err := strconv.ParseUint(value[i+1:i+4], 8, 8); err == nil {
+				out.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		out.WriteByte(value[i])
+	}
+	return out.String()
+}
+
+// parseDF parses df -P output. The mount point is the rest of the line, so it may contain spaces.
+func parseDF(output string) []dfEntry {
+	var entries []dfEntry
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		const minDFFields = 6
+		if len(fields) < minDFFields || fields[0] == "Filesystem" {
+			continue
+		}
+		total, err1 := strconv.Atoi(fields[1])
+		used, err2 := strconv.Atoi(fields[2])
+		free, err3 := strconv.Atoi(fields[3])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		entries = append(entries, dfEntry{
+			filesystem: fields[0],
+			total:      total,
+			used:       used,
+			free:       free,
+			mountPoint: skipFields(line, minDFFields-1),
+		})
+	}
+	return entries
+}
+
+// skipFields returns what follows the first n whitespace-separated fields of line.
+func skipFields(line string, n int) string {
+	rest := strings.TrimSpace(line)
+	for i := 0; i < n; i++ {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return ""
+		}
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return rest
+}
+
+// buildMounts joins df space and inode usage with /proc/mounts, skipping pseudo filesystems without
+// blocks and read-only images.
+func buildMounts(space, inodes []dfEntry, mounts map[string]mountEntry, threshold int) []MountInfo {
+	inodesByMount := make(map[string]dfEntry, len(inodes))
+	for _, entry := range inodes {
+		inodesByMount[entry.mountPoint] = entry
+	}
+
+	const kbToMb = 1024
+	seen := make(map[string]bool)
+	var result []MountInfo
+	for _, entry := range space {
+		if entry.total == 0 || seen[entry.mountPoint] {
+			continue
+		}
+		mount := MountInfo{
+			Device:       entry.filesystem,
+			MountPoint:   entry.mountPoint,
+			TotalMB:      entry.total / kbToMb,
+			UsedMB:       entry.used / kbToMb,
+			AvailableMB:  entry.free / kbToMb,
+			UsagePercent: usagePercent(entry.used, entry.free),
+		}
+		if m, ok := mounts[entry.mountPoint]; ok {
+			mount.Device = m.device
+			mount.FSType = m.fsType
+		}
+		if skippedFSTypes[mount.FSType] {
+			continue
+		}
+		seen[entry.mountPoint] = true
+		// Some filesystems, such as btrfs and vfat, have no fixed inode count and report zero
+		if in, ok := inodesByMount[entry.mountPoint]; ok && in.total > 0 {
+			mount.InodesTotal = in.total
+			mount.InodesUsed = in.used
+			mount.InodesFree = in.free
+			mount.InodeUsagePercent = usagePercent(in.used, in.free)
+		}
+		mount.OverThreshold = mount.UsagePercent >= float64(threshold) || mount.InodeUsagePercent >= float64(threshold)
+		result = append(result, mount)
+	}
+	return result
+}
+
+// usagePercent is the used share of what is available to unprivileged users, as df reports it:
+// blocks reserved for root count neither as used nor as available.
+func usagePercent(used, free int) float64 {
+	if used+free == 0 {
+		return 0
+	}
+	const percentMultiplier = 100.0
+	return round1(float64(used) * percentMultiplier / float64(used+free))
+}
+
+// parseDiskstats parses the lines of /proc/diskstats into device counters.
+func parseDiskstats(lines []string) []diskStat {
+	var stats []diskStat
+	for _, line := range lines {
+		// major minor name reads merged sectors ms writes merged sectors ms in-flight io-ms weighted-ms ...
+		fields := strings.Fields(line)
+		const minDiskstatsFields = 14
+		if len(fields) < minDiskstatsFields {
+			continue
+		}
+		values := make([]int64, len(fields))
+		for i := 3; i < minDiskstatsFields; i++ {
+			values[i], _ = strconv.ParseInt(fields[i], 10, 64)
+		}
+		stats = append(stats, diskStat{
+			name:         fields[2],
+			reads:        values[3],
+			sectorsRead:  values[5],
+			msReading:    values[6],
+			writes:       values[7],
+			sectorsWrite: values[9],
+			msWriting:    values[10],
+			inFlight:     values[11],
+			msDoingIO:    values[12],
+		})
+	}
+	return stats
+}
+
+// calculateDeviceIO computes per-device rates between two diskstats samples taken interval seconds
+// apart. Only whole devices are reported when blockDevices lists them, and loop and ram devices and
+// devices that never did any I/O are left out.
+func calculateDeviceIO(before, after []diskStat, interval float64, blockDevices map[string]bool) []DeviceIO {
+	previous := make(map[string]diskStat, len(before))
+	for _, stat := range before {
+		previous[stat.name] = stat
+	}
+
+	var devices []DeviceIO
+	for _, stat := range after {
+		prev, ok := previous[stat.name]
+		switch {
+		case !ok,
+			stat.reads+stat.writes == 0,
+			strings.HasPrefix(stat.name, "loop"),
+			strings.HasPrefix(stat.name, "ram"),
+			blockDevices != nil && !blockDevices[stat.name]:
+			continue
+		}
+
+		reads := float64(stat.reads - prev.reads)
+		writes := float64(stat.writes - prev.writes)
+		const (
+			bytesPerKB        = 1024.0
+			msPerSecond       = 1000.0
+			percentMultiplier = 100.0
+		)
+		device := DeviceIO{
+			Name:      stat.name,
+			ReadIOPS:  round1(reads / interval),
+			WriteIOPS: round1(writes / interval),
+			ReadKBps:  round1(float64(stat.sectorsRead-prev.sectorsRead) * sectorSize / bytesPerKB / interval),
+			WriteKBps: round1(float64(stat.sectorsWrite-prev.sectorsWrite) * sectorSize / bytesPerKB / interval),
+			InFlight:  int(stat.inFlight),
+		}
+		if reads+writes > 0 {
+			device.AwaitMs = round1(float64(stat.msReading-prev.msReading+stat.msWriting-prev.msWriting) / (reads + writes))
+		}
+		util := float64(stat.msDoingIO-prev.msDoingIO) * percentMultiplier / (interval * msPerSecond)
+		device.UtilPercent = round1(math.Min(util, percentMultiplier))
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Name < devices[j].Name
+	})
+	return devices
+}
+
+func round1(value float64) float64 {
+	const precision = 10
+	return math.Round(value*precision) / precision
+}
+
+// formatSize formats a size in megabytes for the disk tables.
+func formatSize(mb int) string {
+	const mbPerGB = 1024
+	if mb >= mbPerGB {
+		return fmt.Sprintf("%.1fG", float64(mb)/mbPerGB)
+	}
+	return fmt.Sprintf("%dM", mb)
+}
+
+func formatDiskInfo(output *strings.Builder, disk DiskInfo) {
+	output.WriteString(fmt.Sprintf("Disk Usage (flagged at %d%%):\n", disk.ThresholdPercent))
+	if len(disk.Mounts) == 0 {
+		output.WriteString("  No mounts reported by df\n")
+	} else {
+		output.WriteString(fmt.Sprintf("  %-24s %-10s %8s %8s %8s %6s %6s\n", "Mount", "Type", "Size", "Used", "Avail", "Use%", "IUse%"))
+	}
+	var flagged []string
+	for _, mount := range disk.Mounts {
+		inodes := "-"
+		if mount.InodesTotal > 0 {
+			inodes = fmt.Sprintf("%.1f", mount.InodeUsagePercent)
+		}
+		marker := ""
+		if mount.OverThreshold {
+			marker = " !"
+			flagged = append(flagged, mount.MountPoint)
+		}
+		output.WriteString(fmt.Sprintf("  %-24s %-10s %8s %8s %8s %6.1f %6s%s\n",
+			mount.MountPoint, mount.FSType, formatSize(mount.TotalMB), formatSize(mount.UsedMB),
+			formatSize(mount.AvailableMB), mount.UsagePercent, inodes, marker))
+	}
+	if len(flagged) > 0 {
+		output.WriteString(fmt.Sprintf("  Mounts at or above %d%% space or inode usage: %s\n", disk.ThresholdPercent, strings.Join(flagged, ", ")))
+	}
+
+	if disk.IntervalSeconds == 0 {
+		return
+	}
+	output.WriteString(fmt.Sprintf("\nDisk I/O (over %.1fs):\n", disk.IntervalSeconds))
+	if len(disk.Devices) == 0 {
+		output.WriteString("  No block devices with I/O\n")
+		return
+	}
+	output.WriteString(fmt.Sprintf("  %-12s %8s %8s %10s %10s %9s %6s\n", "Device", "r/s", "w/s", "rKB/s", "wKB/s", "await ms", "%util"))
+	for _, device := range disk.Devices {
+		output.WriteString(fmt.Sprintf("  %-12s %8.1f %8.1f %10.1f %10.1f %9.1f %6.1f\n",
+			device.Name, device.ReadIOPS, device.WriteIOPS, device.ReadKBps, device.WriteKBps, device.AwaitMs, device.UtilPercent))
+	}
+}
@@ -0,0 +1,131 @@
+package sysinfo
+
+import (
+	"strings"
+)
+
+const (
+	testMounts = `/dev/sda1 / ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/sdb1 /srv/my\040data xfs rw,relatime 0 0
+/dev/loop0 /snap/core/1 squashfs ro,nodev,relatime 0 0
+/dev/sda2 /boot/efi vfat rw,relatime 0 0
+`
+	testDFSpace = `Filesystem     1024-blocks      Used Available Capacity Mounted on
+/dev/sda1        102400000  92160000   5120000      95% /
+/dev/sdb1         20971520   1048576  19922944       5% /srv/my data
+/dev/loop0          131072    131072         0     100% /snap/core/1
+/dev/sda2           524288      6144    518144       2% /boot/efi
+proc                     0         0         0        - /proc
+`
+	testDFInodes = `Filesystem       Inodes   IUsed    IFree IUse% Mounted on
+/dev/sda1        6553600  327680  6225920    5% /
+/dev/sdb1         100000   95000     5000   95% /srv/my data
+/dev/loop0          1000    1000        0  100% /snap/core/1
+/dev/sda2              0       0        0     - /boot/efi
+`
+	testDiskstats1 = `   7       0 loop0 100 0 800 10 0 0 0 0 0 10 10 0 0 0 0
+   8       0 sda 1000 0 80000 2000 2000 0 160000 6000 0 3000 8000 0 0 0 0
+   8       1 sda1 900 0 72000 1800 1900 0 150000 5800 0 2900 7600 0 0 0 0
+   8      16 sdb 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+`
+	testDiskstats2 = `   7       0 loop0 100 0 800 10 0 0 0 0 0 10 10 0 0 0 0
+   8       0 sda 1100 0 88000 2200 2300 0 184000 7000 3 4000 9500 0 0 0 0
+   8       1 sda1 990 0 79200 1980 2170 0 171600 6700 3 3800 9000 0 0 0 0
+   8      16 sdb 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0
+`
+)
+
+func (suite *SysinfoTestSuite) TestParseMounts() {
+	mounts := parseMounts(testMounts)
+	suite.Equal(mountEntry{device: "/dev/sdb1", fsType: "xfs"}, mounts["/srv/my data"])
+	suite.Equal("proc", mounts["/proc"].fsType)
+	suite.Equal(`a\b c`, unescapeMount(`a\134b\040c`))
+	suite.Equal(`broken\04`, unescapeMount(`broken\04`))
+}
+
+func (suite *SysinfoTestSuite) TestBuildMounts() {
+	mounts := buildMounts(parseDF(testDFSpace), parseDF(testDFInodes), parseMounts(testMounts), 90)
+
+	// proc has no blocks and the squashfs image is always full
+	suite.Require().Len(mounts, 3)
+
+	root := mounts[0]
+	suite.Equal(MountInfo{
+		Device:            "/dev/sda1",
+		MountPoint:        "/",
+		FSType:            "ext4",
+		TotalMB:           100000,
+		UsedMB:            90000,
+		AvailableMB:       5000,
+		UsagePercent:      94.7,
+		InodesTotal:       6553600,
+		InodesUsed:        327680,
+		InodesFree:        6225920,
+		InodeUsagePercent: 5,
+		OverThreshold:     true,
+	}, root)
+
+	data := mounts[1]
+	suite.Equal("/srv/my data", data.MountPoint)
+	suite.Equal(5.0, data.UsagePercent)
+	suite.Equal(95.0, data.InodeUsagePercent)
+	suite.True(data.OverThreshold, "inode usage alone flags a mount")
+
+	efi := mounts[2]
+	suite.Equal("vfat", efi.FSType)
+	suite.Zero(efi.InodesTotal)
+	suite.False(efi.OverThreshold)
+}
+
+func (suite *SysinfoTestSuite) TestCalculateDeviceIO() {
+	stats1 := parseDiskstats(strings.Split(testDiskstats1, "\n"))
+	stats2 := parseDiskstats(strings.Split(testDiskstats2, "\n"))
+	suite.Len(stats1, 4)
+	interval := 2.0
+
+	devices := calculateDeviceIO(stats1, stats2, interval, map[string]bool{"loop0": true, "sda": true, "sdb": true})
+	suite.Require().Len(devices, 1, "partitions, loop devices and idle devices are left out")
+	suite.Equal(DeviceIO{
+		Name:        "sda",
+		ReadIOPS:    50,
+		WriteIOPS:   150,
+		ReadKBps:    2000,
+		WriteKBps:   6000,
+		AwaitMs:     3,
+		UtilPercent: 50,
+		InFlight:    3,
+	}, devices[0])
+
+	// Without /sys/block partitions are reported too
+	devices = calculateDeviceIO(stats1, stats2, interval, nil)
+	suite.Require().Len(devices, 2)
+	suite.Equal("sda1", devices[1].Name)
+
+	// Utilization is capped when the busy time exceeds the measured interval
+	devices = calculateDeviceIO(stats1, stats2, 0.5, map[string]bool{"sda": true})
+	suite.Equal(100.0, devices[0].UtilPercent)
+}
+
+func (suite *SysinfoTestSuite) TestFormatDiskInfo() {
+	disk := DiskInfo{
+		ThresholdPercent: 90,
+		Mounts:           buildMounts(parseDF(testDFSpace), parseDF(testDFInodes), parseMounts(testMounts), 90),
+		IntervalSeconds:  2,
+		Devices:          []DeviceIO{{Name: "sda", ReadIOPS: 50, WriteIOPS: 150, AwaitMs: 3, UtilPercent: 50}},
+	}
+
+	var output strings.Builder
+	formatDiskInfo(&output, disk)
+	text := output.String()
+	suite.Contains(text, "Disk Usage (flagged at 90%):")
+	suite.Contains(text, "  /                        ext4          97.7G    87.9G     4.9G   94.7    5.0 !\n")
+	suite.Contains(text, "  /boot/efi                vfat           512M       6M     506M    1.2      -\n")
+	suite.Contains(text, "Mounts at or above 90% space or inode usage: /, /srv/my data")
+	suite.Contains(text, "Disk I/O (over 2.0s):")
+	suite.Contains(text, "  sda              50.0    150.0        0.0        0.0       3.0   50.0\n")
+
+	output.Reset()
+	formatDiskInfo(&output, DiskInfo{ThresholdPercent: 80})
+	suite.Equal("Disk Usage (flagged at 80%):\n  No mounts reported by df\n", output.String())
+}
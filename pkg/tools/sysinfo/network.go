@@ -0,0 +1,424 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+)
+
+// networkScript prints the TCP socket count by hex state and the listening TCP sockets: from ss with
+// their processes, or from /proc/net/tcp as address and tx_queue:rx_queue when ss is not installed,
+// as in many container images.
+const networkScript = `echo "== tcp states"
+cat /proc/net/tcp /proc/net/tcp6 2>/dev/null | awk '$4 ~ /^[0-9A-F][0-9A-F]$/ {n[$4]++} END {for (s in n) print s, n[s]}'
+if command -v ss >/dev/null 2>&1; then
+	echo "== ss"; ss -tlnp 2>/dev/null
+else
+	echo "== listen"; cat /proc/net/tcp /proc/net/tcp6 2>/dev/null | awk '$4 == "0A" {print $2, $5}'
+fi
+true
+`
+
+// ssProcessRe matches a process of the ss users column, e.g. ("sshd",pid=612,fd=3).
+var ssProcessRe = regexp.MustCompile(`\("((?:[^"\\]|\\.)*)",pid=(\d+)`)
+
+type NetworkInfo struct {
+	Interfaces      []InterfaceStats  `json:"interfaces"`
+	TCP             TCPStats          `json:"tcp"`
+	Listening       []ListeningSocket `json:"listening"`
+	ProcessesKnown  bool              `json:"processes_known"`  // Listening sockets were listed by ss, which reports their processes
+	IntervalSeconds float64           `json:"interval_seconds"` // Time between the /proc/net samples
+}
+
+type InterfaceStats struct {
+	Name            string  `json:"name"`
+	RxKBps          float64 `json:"rx_kb_per_sec"`
+	TxKBps          float64 `json:"tx_kb_per_sec"`
+	RxPacketsPerSec float64 `json:"rx_packets_per_sec"`
+	TxPacketsPerSec float64 `json:"tx_packets_per_sec"`
+	RxErrors        int64   `json:"rx_errors"` // Since boot, like the other error and drop counters
+	TxErrors        int64   `json:"tx_errors"`
+	RxDropped       int64   `json:"rx_dropped"`
+	TxDropped       int64   `json:"tx_dropped"`
+	NewErrors       int64   `json:"new_errors"` // Errors and drops in both directions during the sample
+}
+
+type TCPStats struct {
+	States               map[string]int `json:"states"` // Sockets by state, IPv4 and IPv6
+	CurrEstab            int64          `json:"curr_estab"`
+	ActiveOpens          int64          `json:"active_opens"` // Since boot, like the other counters
+	PassiveOpens         int64          `json:"passive_opens"`
+	AttemptFails         int64          `json:"attempt_fails"`
+	EstabResets          int64          `json:"estab_resets"`
+	InSegs               int64          `json:"in_segs"`
+	OutSegs              int64          `json:"out_segs"`
+	RetransSegs          int64          `json:"retrans_segs"`
+	InErrs               int64          `json:"in_errs"`
+	OutRsts              int64          `json:"out_rsts"`
+	ListenOverflows      int64          `json:"listen_overflows"` // Connections dropped because an accept queue was full
+	ListenDrops          int64          `json:"listen_drops"`
+	RetransPercent       float64        `json:"retrans_percent"`        // Retransmitted share of sent segments since boot
+	RetransPerSec        float64        `json:"retrans_per_sec"`        // During the sample
+	SampleRetransPercent float64        `json:"sample_retrans_percent"` // Retransmitted share of segments sent during the sample
+	NewListenOverflows   int64          `json:"new_listen_overflows"`   // During the sample
+}
+
+// ListeningSocket is a listening TCP socket.
+type ListeningSocket struct {
+	Address   string          `json:"address"`
+	Port      int             `json:"port"`
+	Queue     int             `json:"queue"`             // Connections waiting to be accepted
+	Backlog   int             `json:"backlog,omitempty"` // Accept queue limit, reported by ss only
+	Processes []SocketProcess `json:"processes,omitempty"`
+}
+
+// SocketProcess is a process holding a socket. ss only reports processes the user may inspect.
+type SocketProcess struct {
+	PID  int    `json:"pid"`
+	Name string `json:"name"`
+}
+
+func (s *Tool) gatherNetworkInfo(ctx context.Context, conn *target.Target, samples *statSamples) NetworkInfo {
+	network := NetworkInfo{}
+
+	if samples != nil {
+		network.IntervalSeconds = round1(samples.interval)
+		network.Interfaces = calculateInterfaceStats(parseNetDev(samples.before["net/dev"]),
+			parseNetDev(samples.after["net/dev"]), samples.interval)
+		network.TCP = calculateTCPStats(parseSNMP(samples.before["snmp"], samples.before["netstat"]),
+			parseSNMP(samples.after["snmp"], samples.after["netstat"]), samples.interval)
+	}
+
+	if output, err := s.executeCommand(ctx, networkScript, conn); err == nil {
+		parts := procfs.Sections(output)
+		network.TCP.States = parseTCPStates(parts["tcp states"])
+		if lines, ok := parts["ss"]; ok {
+			network.Listening = parseSSListening(lines)
+			network.ProcessesKnown = true
+		} else {
+			network.Listening = parseProcListening(parts["listen"])
+		}
+	}
+
+	return network
+}
+
+// netDevStat holds the /proc/net/dev counters of an interface.
+type netDevStat struct {
+	name      string
+	rxBytes   int64
+	rxPackets int64
+	rxErrors  int64
+	rxDropped int64
+	txBytes   int64
+	txPackets int64
+	txErrors  int64
+	txDropped int64
+}
+
+// parseNetDev parses the lines of /proc/net/dev. The interface name is followed by a colon that is
+// not always followed by a space.
+func parseNetDev(lines []string) []netDevStat {
+	var stats []netDevStat
+	for _, line := range lines {
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.Contains(line, "|") {
+			continue
+		}
+		fields := strings.Fields(counters)
+		const netDevFields = 16
+		if len(fields) < netDevFields {
+			continue
+		}
+		values := make([]int64, netDevFields)
+		for i := range values {
+			values[i], _ = strconv.ParseInt(fields[i], 10, 64)
+		}
+		// rx: bytes packets errs drop fifo frame compressed multicast, then tx: bytes packets errs drop ...
+		stats = append(stats, netDevStat{
+			name:      strings.TrimSpace(name),
+			rxBytes:   values[0],
+			rxPackets: values[1],
+			rxErrors:  values[2],
+			rxDropped: values[3],
+			txBytes:   values[8],
+			txPackets: values[9],
+			txErrors:  values[10],
+			txDropped: values[11],
+		})
+	}
+	return stats
+}
+
+// calculateInterfaceStats computes per-interface rates between two /proc/net/dev samples taken
+// interval seconds apart.
+func calculateInterfaceStats(before, after []netDevStat, interval float64) []InterfaceStats {
+	previous := make(map[string]netDevStat, len(before))
+	for _, stat := range before {
+		previous[stat.name] = stat
+	}
+
+	var interfaces []InterfaceStats
+	for _, stat := range after {
+		prev, ok := previous[stat.name]
+		if !ok {
+			continue
+		}
+		const bytesPerKB = 1024.0
+		interfaces = append(interfaces, InterfaceStats{
+			Name:            stat.name,
+			RxKBps:          round1(perSecond(prev.rxBytes, stat.rxBytes, interval) / bytesPerKB),
+			TxKBps:          round1(perSecond(prev.txBytes, stat.txBytes, interval) / bytesPerKB),
+			RxPacketsPerSec: round1(perSecond(prev.rxPackets, stat.rxPackets, interval)),
+			TxPacketsPerSec: round1(perSecond(prev.txPackets, stat.txPackets, interval)),
+			RxErrors:        stat.rxErrors,
+			TxErrors:        stat.txErrors,
+			RxDropped:       stat.rxDropped,
+			TxDropped:       stat.txDropped,
+			NewErrors: delta(prev.rxErrors, stat.rxErrors) + delta(prev.txErrors, stat.txErrors) +
+				delta(prev.rxDropped, stat.rxDropped) + delta(prev.txDropped, stat.txDropped),
+		})
+	}
+	sort.Slice(interfaces, func(i, j int) bool {
+		return interfaces[i].Name < interfaces[j].Name
+	})
+	return interfaces
+}
+
+// delta returns how much a counter grew; a counter that went back, e.g. after an interface was
+// recreated, did not grow.
+func delta(before, after int64) int64 {
+	if after < before {
+		return 0
+	}
+	return after - before
+}
+
+func perSecond(before, after int64, interval float64) float64 {
+	return float64(delta(before, after)) / interval
+}
+
+// parseSNMP parses the header and value line pairs of /proc/net/snmp and /proc/net/netstat into
+// counters named "Tcp.RetransSegs", "TcpExt.ListenOverflows" and so on.
+func parseSNMP(files ...[]string) map[string]int64 {
+	counters := make(map[string]int64)
+	for _, lines := range files {
+		for i := 0; i+1 < len(lines); i += 2 {
+			names := strings.Fields(lines[i])
+			values := strings.Fields(lines[i+1])
+			if len(names) != len(values) || len(names) == 0 || names[0] != values[0] {
+				continue
+			}
+			prefix := strings.TrimSuffix(names[0], ":")
+			for j := 1; j < len(names); j++ {
+				if value, err := strconv.ParseInt(values[j], 10, 64); err == nil {
+					counters[prefix+"."+names[j]] = value
+				}
+			}
+		}
+	}
+	return counters
+}
+
+// calculateTCPStats reports the TCP counters of the second sample and the retransmits and listen
+// overflows during the sample.
+func calculateTCPStats(before, after map[string]int64, interval float64) TCPStats {
+	tcp := TCPStats{
+		CurrEstab:       after["Tcp.CurrEstab"],
+		ActiveOpens:     after["Tcp.ActiveOpens"],
+		PassiveOpens:    after["Tcp.PassiveOpens"],
+		AttemptFails:    after["Tcp.AttemptFails"],
+		EstabResets:     after["Tcp.EstabResets"],
+		InSegs:          after["Tcp.InSegs"],
+		OutSegs:         after["Tcp.OutSegs"],
+		RetransSegs:     after["Tcp.RetransSegs"],
+		InErrs:          after["Tcp.InErrs"],
+		OutRsts:         after["Tcp.OutRsts"],
+		ListenOverflows: after["TcpExt.ListenOverflows"],
+		ListenDrops:     after["TcpExt.ListenDrops"],
+	}
+	const percentMultiplier = 100.0
+	if tcp.OutSegs > 0 {
+		tcp.RetransPercent = round1(float64(tcp.RetransSegs) * percentMultiplier / float64(tcp.OutSegs))
+	}
+	retrans := delta(before["Tcp.RetransSegs"], tcp.RetransSegs)
+	tcp.RetransPerSec = round1(float64(retrans) / interval)
+	if sent := delta(before["Tcp.OutSegs"], tcp.OutSegs); sent > 0 {
+		tcp.SampleRetransPercent = round1(float64(retrans) * percentMultiplier / float64(sent))
+	}
+	tcp.NewListenOverflows = delta(before["TcpExt.ListenOverflows"], tcp.ListenOverflows)
+	return tcp
+}
+
+// parseTCPStates parses "state count" lines with hex states, as printed by networkScript.
+func parseTCPStates(lines []string) map[string]int {
+	states := make(map[string]int)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		const stateFields = 2
+		if len(fields) != stateFields {
+			continue
+		}
+		state, ok := procfs.TCPState(fields[0])
+		if !ok {
+			continue
+		}
+		if count, err := strconv.Atoi(fields[1]); err == nil {
+			states[state] += count
+		}
+	}
+	return states
+}
+
+// parseSSListening parses ss -tlnp output. The process column is missing for sockets of processes
+// the user may not inspect.
+func parseSSListening(lines []string) []ListeningSocket {
+	var sockets []ListeningSocket
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		const minSSFields = 5
+		if len(fields) < minSSFields || fields[0] != "LISTEN" {
+			continue
+		}
+		address, port, ok := splitSSAddr(fields[3])
+		if !ok {
+			continue
+		}
+		queue, _ := strconv.Atoi(fields[1])
+		backlog, _ := strconv.Atoi(fields[2])
+		socket := ListeningSocket{Address: address, Port: port, Queue: queue, Backlog: backlog}
+		seen := make(map[int]bool)
+		for _, match := range ssProcessRe.FindAllStringSubmatch(strings.Join(fields[minSSFields:], " "), -1) {
+			pid, err := strconv.Atoi(match[2])
+			if err != nil || seen[pid] {
+				continue
+			}
+			seen[pid] = true
+			socket.Processes = append(socket.Processes, SocketProcess{PID: pid, Name: match[1]})
+		}
+		sockets = append(sockets, socket)
+	}
+	sortListening(sockets)
+	return sockets
+}
+
+// splitSSAddr splits a local address of ss, e.g. "0.0.0.0:22", "[::]:443", "*:80" or
+// "127.0.0.53%lo:53".
+func splitSSAddr(addr string) (string, int, bool) {
+	colon := strings.LastIndexByte(addr, ':')
+	if colon < 0 {
+		return "", 0, false
+	}
+	port, err := strconv.Atoi(addr[colon+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.Trim(addr[:colon], "[]"), port, true
+}
+
+// parseProcListening parses "address tx_queue:rx_queue" lines of listening sockets from /proc/net/tcp.
+// The rx_queue of a listening socket is its accept queue.
+func parseProcListening(lines []string) []ListeningSocket {
+	var sockets []ListeningSocket
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		const listenFields = 2
+		if len(fields) != listenFields {
+			continue
+		}
+		addr, port, err := procfs.ParseHexAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		socket := ListeningSocket{Address: addr, Port: port}
+		if _, rxQueue, ok := strings.Cut(fields[1], ":"); ok {
+			if queue, err := strconv.ParseInt(rxQueue, 16, 64); err == nil {
+				socket.Queue = int(queue)
+			}
+		}
+		sockets = append(sockets, socket)
+	}
+	sortListening(sockets)
+	return sockets
+}
+
+func sortListening(sockets []ListeningSocket) {
+	sort.Slice(sockets, func(i, j int) bool {
+		if sockets[i].Port != sockets[j].Port {
+			return sockets[i].Port < sockets[j].Port
+		}
+		return sockets[i].Address < sockets[j].Address
+	})
+}
+
+func formatNetworkInfo(output *strings.Builder, network NetworkInfo) {
+	if network.IntervalSeconds > 0 {
+		output.WriteString(fmt.Sprintf("Network Interfaces (over %.1fs):\n", network.IntervalSeconds))
+		output.WriteString(fmt.Sprintf("  %-16s %10s %10s %9s %9s %12s %12s\n", "Interface", "rxKB/s", "txKB/s", "rxpkt/s", "txpkt/s", "rx err/drop", "tx err/drop"))
+		for _, iface := range network.Interfaces {
+			marker := ""
+			if iface.NewErrors > 0 {
+				marker = fmt.Sprintf(" ! %d new", iface.NewErrors)
+			}
+			output.WriteString(fmt.Sprintf("  %-16s %10.1f %10.1f %9.1f %9.1f %12s %12s%s\n",
+				iface.Name, iface.RxKBps, iface.TxKBps, iface.RxPacketsPerSec, iface.TxPacketsPerSec,
+				fmt.Sprintf("%d/%d", iface.RxErrors, iface.RxDropped), fmt.Sprintf("%d/%d", iface.TxErrors, iface.TxDropped), marker))
+		}
+		output.WriteString("\n")
+	}
+
+	tcp := network.TCP
+	output.WriteString("TCP:\n")
+	if len(tcp.States) > 0 {
+		states := make([]string, 0, len(tcp.States))
+		for state := range tcp.States {
+			states = append(states, state)
+		}
+		sort.Strings(states)
+		counts := make([]string, 0, len(states))
+		for _, state := range states {
+			counts = append(counts, fmt.Sprintf("%s %d", state, tcp.States[state]))
+		}
+		output.WriteString(fmt.Sprintf("  Sockets: %s\n", strings.Join(counts, ", ")))
+	}
+	if network.IntervalSeconds > 0 {
+		output.WriteString(fmt.Sprintf("  Segments since boot: %d in, %d out, %d retransmitted (%.1f%%)\n",
+			tcp.InSegs, tcp.OutSegs, tcp.RetransSegs, tcp.RetransPercent))
+		output.WriteString(fmt.Sprintf("  Retransmits now: %.1f/s (%.1f%% of sent segments)\n", tcp.RetransPerSec, tcp.SampleRetransPercent))
+		output.WriteString(fmt.Sprintf("  Connections since boot: %d active opens, %d passive opens, %d failed attempts, %d resets, %d resets sent; %d established\n",
+			tcp.ActiveOpens, tcp.PassiveOpens, tcp.AttemptFails, tcp.EstabResets, tcp.OutRsts, tcp.CurrEstab))
+		marker := ""
+		if tcp.NewListenOverflows > 0 {
+			marker = " !"
+		}
+		output.WriteString(fmt.Sprintf("  Listen queue since boot: %d overflows, %d drops; %d overflows now%s\n",
+			tcp.ListenOverflows, tcp.ListenDrops, tcp.NewListenOverflows, marker))
+	}
+
+	output.WriteString("\nListening TCP Sockets:\n")
+	if len(network.Listening) == 0 {
+		output.WriteString("  None found\n")
+		return
+	}
+	if !network.ProcessesKnown {
+		output.WriteString("  ss is not available; owning processes are unknown\n")
+	}
+	output.WriteString(fmt.Sprintf("  %-40s %6s %11s  %s\n", "Address", "Port", "Queue", "Processes"))
+	for _, socket := range network.Listening {
+		queue := strconv.Itoa(socket.Queue)
+		if socket.Backlog > 0 {
+			queue = fmt.Sprintf("%d/%d", socket.Queue, socket.Backlog)
+		}
+		processes := make([]string, 0, len(socket.Processes))
+		for _, process := range socket.Processes {
+			processes = append(processes, fmt.Sprintf("%s(%d)", process.Name, process.PID))
+		}
+		output.WriteString(fmt.Sprintf("  %-40s %6d %11s  %s\n", socket.Address, socket.Port, queue, strings.Join(processes, ", ")))
+	}
+}
@@ -0,0 +1,176 @@
+package sysinfo
+
+import (
+	"strings"
+)
+
+const testSampleOutput = `== uptime 1
+1000.50 3000.00
+== diskstats 1
+   8       0 sda 1000 0 80000 2000 2000 0 160000 6000 0 3000 8000 0 0 0 0
+== net/dev 1
+Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  204800    2000    0    0    0     0          0         0   204800    2000    0    0    0     0       0          0
+  eth0:1048576   10000    5    1    0     0          0         0  2097152   12000    0    2    0     0       0          0
+== snmp 1
+Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 500 400 10 20 30 100000 200000 1000 3 50 0
+== netstat 1
+TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 7 9
+== uptime 2
+1002.50 3008.00
+== diskstats 2
+   8       0 sda 1100 0 88000 2200 2300 0 184000 7000 3 4000 9500 0 0 0 0
+== net/dev 2
+Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:  204800    2000    0    0    0     0          0         0   204800    2000    0    0    0     0       0          0
+  eth0:3145728   12000    6    1    0     0          0         0  2101248   12400    0    4    0     0       0          0
+  veth1:     0       0    0    0    0     0          0         0        0       0    0    0    0     0       0          0
+== snmp 2
+Ip: Forwarding DefaultTTL
+Ip: 1 64
+Tcp: RtoAlgorithm RtoMin RtoMax MaxConn ActiveOpens PassiveOpens AttemptFails EstabResets CurrEstab InSegs OutSegs RetransSegs InErrs OutRsts InCsumErrors
+Tcp: 1 200 120000 -1 510 420 10 20 32 101000 202000 1100 3 52 0
+== netstat 2
+TcpExt: SyncookiesSent ListenOverflows ListenDrops
+TcpExt: 0 10 12
+STDERR:
+== uptime 3
+`
+
+func (suite *SysinfoTestSuite) TestParseSamples() {
+	samples := parseSamples(testSampleOutput)
+	suite.Require().NotNil(samples)
+	suite.Equal(2.0, samples.interval)
+	suite.Len(samples.before["diskstats"], 1)
+	suite.Len(samples.after["net/dev"], 5)
+
+	suite.Nil(parseSamples("== uptime 1\n1000.50 3000.00\n"), "a single sample has no interval")
+}
+
+func (suite *SysinfoTestSuite) TestCalculateInterfaceStats() {
+	samples := parseSamples(testSampleOutput)
+	suite.Require().NotNil(samples)
+
+	interfaces := calculateInterfaceStats(parseNetDev(samples.before["net/dev"]), parseNetDev(samples.after["net/dev"]), samples.interval)
+	suite.Require().Len(interfaces, 2, "interfaces missing from the first sample are left out")
+	suite.Equal(InterfaceStats{
+		Name:            "eth0",
+		RxKBps:          1024,
+		TxKBps:          2,
+		RxPacketsPerSec: 1000,
+		TxPacketsPerSec: 200,
+		RxErrors:        6,
+		RxDropped:       1,
+		TxDropped:       4,
+		NewErrors:       3,
+	}, interfaces[0])
+	suite.Equal("lo", interfaces[1].Name)
+	suite.Zero(interfaces[1].RxKBps)
+}
+
+func (suite *SysinfoTestSuite) TestCalculateTCPStats() {
+	samples := parseSamples(testSampleOutput)
+	suite.Require().NotNil(samples)
+
+	before := parseSNMP(samples.before["snmp"], samples.before["netstat"])
+	suite.Equal(int64(-1), before["Tcp.MaxConn"])
+	suite.Equal(int64(64), before["Ip.DefaultTTL"])
+
+	tcp := calculateTCPStats(before, parseSNMP(samples.after["snmp"], samples.after["netstat"]), samples.interval)
+	suite.Equal(TCPStats{
+		CurrEstab:            32,
+		ActiveOpens:          510,
+		PassiveOpens:         420,
+		AttemptFails:         10,
+		EstabResets:          20,
+		InSegs:               101000,
+		OutSegs:              202000,
+		RetransSegs:          1100,
+		InErrs:               3,
+		OutRsts:              52,
+		ListenOverflows:      10,
+		ListenDrops:          12,
+		RetransPercent:       0.5,
+		RetransPerSec:        50,
+		SampleRetransPercent: 5,
+		NewListenOverflows:   3,
+	}, tcp)
+}
+
+func (suite *SysinfoTestSuite) TestParseListening() {
+	ss := strings.Split(`State  Recv-Q Send-Q Local Address:Port  Peer Address:Port Process
+LISTEN 0      4096   127.0.0.53%lo:53         0.0.0.0:*     users:(("systemd-resolve",pid=612,fd=14))
+LISTEN 3      128          0.0.0.0:22         0.0.0.0:*     users:(("sshd",pid=700,fd=3),("sshd",pid=700,fd=4),("sshd",pid=701,fd=3))
+LISTEN 0      511             [::]:8080          [::]:*
+LISTEN 0      128                *:9090             *:*     users:(("my \"app\"",pid=42,fd=7))`, "\n")
+
+	sockets := parseSSListening(ss)
+	suite.Require().Len(sockets, 4)
+	suite.Equal(ListeningSocket{
+		Address:   "0.0.0.0",
+		Port:      22,
+		Queue:     3,
+		Backlog:   128,
+		Processes: []SocketProcess{{PID: 700, Name: "sshd"}, {PID: 701, Name: "sshd"}},
+	}, sockets[0])
+	suite.Equal("127.0.0.53%lo", sockets[1].Address)
+	suite.Equal(ListeningSocket{Address: "::", Port: 8080, Backlog: 511}, sockets[2])
+	suite.Equal([]SocketProcess{{PID: 42, Name: `my \"app\"`}}, sockets[3].Processes)
+
+	// Without ss: "address tx_queue:rx_queue" from /proc/net/tcp and tcp6
+	sockets = parseProcListening([]string{
+		"0100007F:1F90 00000000:00000002",
+		"00000000000000000000000000000000:0016 00000000:00000000",
+		"invalid 00000000:00000000",
+	})
+	suite.Equal([]ListeningSocket{
+		{Address: "::", Port: 22},
+		{Address: "127.0.0.1", Port: 8080, Queue: 2},
+	}, sockets)
+
+	suite.Equal(map[string]int{"ESTABLISHED": 12, "LISTEN": 4, "TIME_WAIT": 3},
+		parseTCPStates([]string{"01 12", "0A 4", "06 3", "FF 1", "bad"}))
+}
+
+func (suite *SysinfoTestSuite) TestFormatNetworkInfo() {
+	network := NetworkInfo{
+		IntervalSeconds: 2,
+		Interfaces:      []InterfaceStats{{Name: "eth0", RxKBps: 1024, TxKBps: 2, RxPacketsPerSec: 1000, TxPacketsPerSec: 200, RxErrors: 6, RxDropped: 1, TxDropped: 4, NewErrors: 3}},
+		TCP: TCPStats{
+			States:             map[string]int{"LISTEN": 2, "ESTABLISHED": 10},
+			OutSegs:            202000,
+			RetransSegs:        1100,
+			RetransPercent:     0.5,
+			RetransPerSec:      50,
+			ListenOverflows:    10,
+			NewListenOverflows: 3,
+		},
+		Listening: []ListeningSocket{{Address: "0.0.0.0", Port: 22, Queue: 3, Backlog: 128,
+			Processes: []SocketProcess{{PID: 700, Name: "sshd"}}}},
+		ProcessesKnown: true,
+	}
+
+	var output strings.Builder
+	formatNetworkInfo(&output, network)
+	text := output.String()
+	suite.Contains(text, "Network Interfaces (over 2.0s):")
+	suite.Contains(text, "  eth0                 1024.0        2.0    1000.0     200.0          6/1          0/4 ! 3 new\n")
+	suite.Contains(text, "  Sockets: ESTABLISHED 10, LISTEN 2\n")
+	suite.Contains(text, "0 in, 202000 out, 1100 retransmitted (0.5%)")
+	suite.Contains(text, "Retransmits now: 50.0/s")
+	suite.Contains(text, "10 overflows, 0 drops; 3 overflows now !")
+	suite.Contains(text, "  0.0.0.0                                      22       3/128  sshd(700)\n")
+	suite.NotContains(text, "ss is not available")
+
+	output.Reset()
+	formatNetworkInfo(&output, NetworkInfo{Listening: []ListeningSocket{{Address: "::", Port: 80}}})
+	text = output.String()
+	suite.NotContains(text, "Network Interfaces")
+	suite.Contains(text, "ss is not available; owning processes are unknown")
+}
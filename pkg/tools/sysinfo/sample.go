@@ -0,0 +1,85 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/procfs"
+)
+
+// sampleScript prints the counters the disk, network and cgroup CPU rates are computed from twice,
+// one second apart, each file after a "== name N" marker with N the sample number. The %s verb is
+// the cgroup CPU usage file.
+const sampleScript = `for i in 1 2; do
+	if [ $i = 2 ]; then sleep 1; fi
+	echo "== uptime $i"; cat /proc/uptime
+	echo "== diskstats $i"; cat /proc/diskstats 2>/dev/null
+	echo "== net/dev $i"; cat /proc/net/dev 2>/dev/null
+	echo "== snmp $i"; cat /proc/net/snmp 2>/dev/null
+	echo "== netstat $i"; cat /proc/net/netstat 2>/dev/null
+	echo "== cgroup cpu $i"; cat %s 2>/dev/null
+done
+true
+`
+
+// statSamples holds the lines of each sampled file, by name, at the start and the end of the interval.
+type statSamples struct {
+	before   map[string][]string
+	after    map[string][]string
+	interval float64 // Seconds between the samples, from /proc/uptime
+}
+
+// sampleStats samples the counters; cgroupCPUFile is the CPU usage file of the target's cgroup, if any.
+func (s *Tool) sampleStats(ctx context.Context, conn *target.Target, cgroupCPUFile string) *statSamples {
+	if cgroupCPUFile == "" {
+		cgroupCPUFile = "/dev/null"
+	}
+	output, err := s.executeCommand(ctx, fmt.Sprintf(sampleScript, ssh.EscapeArg(cgroupCPUFile)), conn)
+	if err != nil {
+		return nil
+	}
+	return parseSamples(output)
+}
+
+// parseSamples splits sampleScript output into the two samples. It returns nil when the uptime of
+// either sample is missing.
+func parseSamples(output string) *statSamples {
+	samples := &statSamples{
+		before: make(map[string][]string),
+		after:  make(map[string][]string),
+	}
+	for name, lines := range procfs.Sections(output) {
+		cut := strings.LastIndexByte(name, ' ')
+		if cut < 0 {
+			continue
+		}
+		file, number := name[:cut], name[cut+1:]
+		switch {
+		case number == "1":
+			samples.before[file] = lines
+		case number == "2":
+			samples.after[file] = lines
+		}
+	}
+
+	uptime := func(lines []string) float64 {
+		if len(lines) == 0 {
+			return 0
+		}
+		fields := strings.Fields(lines[0])
+		if len(fields) == 0 {
+			return 0
+		}
+		value, _ := strconv.ParseFloat(fields[0], 64)
+		return value
+	}
+	samples.interval = uptime(samples.after["uptime"]) - uptime(samples.before["uptime"])
+	if samples.interval <= 0 {
+		return nil
+	}
+	return samples
+}
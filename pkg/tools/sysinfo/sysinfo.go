@@ -4,23 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os/exec"
 	"strconv"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/rs/zerolog"
-	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/ssh"
+	"github.com/tb0hdan/remote-debugger-mcp/pkg/connectors/target"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/server"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/tools"
 	"github.com/tb0hdan/remote-debugger-mcp/pkg/types"
 )
 
 type Input struct {
-	SSHHost  string `json:"ssh_host,omitempty" validate:"omitempty,hostname|ip"`  // SSH host for remote execution
-	SSHPort  int    `json:"ssh_port,omitempty" validate:"min=0,max=65535"`  // SSH port for remote execution (default: 22)
+	SSHHost  string `json:"ssh_host,omitempty" validate:"omitempty,hostname|ip"`  // SSH host or ~/.ssh/config alias for remote execution
+	SSHPort  int    `json:"ssh_port,omitempty" validate:"min=0,max=65535"`  // SSH port for remote execution (default: from ssh config, else 22)
 	SSHUser  string `json:"ssh_user,omitempty" validate:"omitempty,alphanum|contains=-|contains=_,max=32"`  // SSH user for remote execution
+	Pod        string `json:"pod,omitempty" validate:"omitempty,max=253"`                          // Pod to gather information from with kubectl exec, "name" or "pod/name" (instead of ssh_host)
+	Namespace  string `json:"namespace,omitempty" validate:"omitempty,alphanum|contains=-,max=63"` // Namespace of the pod (default: "default")
+	Container  string `json:"container,omitempty" validate:"omitempty,max=253"`                    // Container in the pod (default: kubectl's default container)
+	KubeConfig string `json:"kubeconfig,omitempty" validate:"omitempty,filepath"`                  // Path to kubeconfig file
+	DiskThreshold int `json:"disk_threshold,omitempty" validate:"min=0,max=100"` // Flag mounts at or above this space or inode usage percent (default: 90)
 	MaxLines int    `json:"max_lines,omitempty" validate:"min=0,max=100000"` // Maximum lines to return (default: 1000)
 	Offset   int    `json:"offset,omitempty" validate:"min=0"`    // Line offset for pagination
 }
@@ -28,6 +32,9 @@ type Input struct {
 type SystemInfo struct {
 	CPUInfo    CPUInfo    `json:"cpu_info"`
 	MemoryInfo MemoryInfo `json:"memory_info"`
+	DiskInfo    DiskInfo    `json:"disk_info"`
+	NetworkInfo NetworkInfo `json:"network_info"`
+	CgroupInfo  *CgroupInfo `json:"cgroup_info,omitempty"` // Set when the cgroup of the target was found
 	Hostname   string     `json:"hostname"`
 	Kernel     string     `json:"kernel"`
 	OS         string     `json:"os"`
@@ -54,6 +61,10 @@ type MemoryInfo struct {
 	SwapUsedMB   int    `json:"swap_used_mb"`
 	SwapFreeMB   int    `json:"swap_free_mb"`
 	UsagePercent string `json:"usage_percent"`
+	// Set when a cgroup memory limit below the host's memory is the total above
+	HostTotalMB      int    `json:"host_total_mb,omitempty"`
+	HostUsedMB       int    `json:"host_used_mb,omitempty"`
+	HostUsagePercent string `json:"host_usage_percent,omitempty"`
 }
 
 type Tool struct {
@@ -64,7 +75,7 @@ type Tool struct {
 func (s *Tool) Register(srv *server.Server) {
 	sysInfoTool := &mcp.Tool{
 		Name:        "sysinfo",
-		Description: "Gather system information (CPU and memory) from local or remote host",
+		Description: "Gather system information from a remote host via SSH, a pod or the local host: CPU, memory, cgroup v1/v2 limits with throttling and OOM kills, disk usage and I/O with mounts above a usage threshold flagged, and network interface rates, TCP states, retransmits, listen overflows and listening sockets with their processes",
 	}
 
 	mcp.AddTool(&srv.Server, sysInfoTool, s.SysInfoHandler)
@@ -79,33 +90,55 @@ func (s *Tool) SysInfoHandler(ctx context.Context, _ *mcp.ServerSession, params
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	// Determine if this is local or remote execution
-	var conn *ssh.Connector
-	target := "localhost"
+	options := target.Options{
+		Host:       input.SSHHost,
+		Port:       input.SSHPort,
+		User:       input.SSHUser,
+		Pod:        input.Pod,
+		Namespace:  input.Namespace,
+		Container:  input.Container,
+		KubeConfig: input.KubeConfig,
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
 
-	if input.SSHHost != "" {
-		port := 22
-		if input.SSHPort != 0 {
-			port = input.SSHPort
-		}
-		conn = ssh.New(input.SSHHost, port, input.SSHUser)
-		target = conn.GetTarget()
+	conn := target.New(options)
+	defer func() {
+		_ = conn.Close()
+	}()
+	name := conn.Name()
 
-		s.logger.Info().Msgf("Gathering system information from remote host: %s", target)
+	if conn.IsLocal() {
+		s.logger.Info().Msg("Gathering local system information")
+	} else {
+		s.logger.Info().Msgf("Gathering system information from remote target: %s", name)
 
 		// Test connection
-		if err := conn.TestConnection(ctx); err != nil {
-			return nil, fmt.Errorf("failed to connect to %s: %v", target, err)
+		if _, err := conn.ExecuteCommand(ctx, "echo 'connection test'"); err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %v", name, err)
 		}
-	} else {
-		s.logger.Info().Msg("Gathering local system information")
 	}
 
 	// Gather system information
 	info := s.gatherSystemInfo(ctx, conn)
+	threshold := defaultDiskThreshold
+	if input.DiskThreshold > 0 {
+		threshold = input.DiskThreshold
+	}
+	info.CgroupInfo = s.gatherCgroupInfo(ctx, conn)
+	applyCgroupMemory(&info.MemoryInfo, info.CgroupInfo)
+	cgroupCPUFile := ""
+	if info.CgroupInfo != nil {
+		cgroupCPUFile = info.CgroupInfo.cpuUsageFile
+	}
+	samples := s.sampleStats(ctx, conn, cgroupCPUFile)
+	calculateCgroupCPU(info.CgroupInfo, samples)
+	info.DiskInfo = s.gatherDiskInfo(ctx, conn, threshold, samples)
+	info.NetworkInfo = s.gatherNetworkInfo(ctx, conn, samples)
 
 	// Format output
-	output := s.formatSystemInfo(info, target)
+	output := s.formatSystemInfo(info, name)
 
 	// Apply pagination with validation
 	maxLines := types.MaxDefaultLines
@@ -153,21 +186,15 @@ func (s *Tool) SysInfoHandler(ctx context.Context, _ *mcp.ServerSession, params
 				Text: resultText,
 			},
 		},
+		StructuredContent: *info,
 	}, nil
 }
 
-func (s *Tool) executeCommand(ctx context.Context, command string, conn *ssh.Connector) (string, error) {
-	if conn != nil {
-		return conn.ExecuteCommand(ctx, command)
-	}
-
-	// Local execution
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	output, err := cmd.Output()
-	return string(output), err
+func (s *Tool) executeCommand(ctx context.Context, command string, conn *target.Target) (string, error) {
+	return conn.ExecuteCommand(ctx, command)
 }
 
-func (s *Tool) gatherSystemInfo(ctx context.Context, conn *ssh.Connector) *SystemInfo {
+func (s *Tool) gatherSystemInfo(ctx context.Context, conn *target.Target) *SystemInfo {
 	info := &SystemInfo{}
 
 	// Get hostname
@@ -365,12 +392,24 @@ func (s *Tool) formatSystemInfo(info *SystemInfo, target string) string {
 	if info.CPUInfo.Usage != "" {
 		output.WriteString(fmt.Sprintf("  Current Usage: %s\n", info.CPUInfo.Usage))
 	}
+	// In a container the host's cores are shown above; the cgroup limit is what the target may use
+	if info.CgroupInfo != nil && info.CgroupInfo.CPULimit > 0 {
+		output.WriteString(fmt.Sprintf("  Cgroup Limit: %.2f CPUs (%.1f%% used)\n", info.CgroupInfo.CPULimit, info.CgroupInfo.CPULimitPercent))
+	}
 	output.WriteString("\n")
 
 	// Memory info
-	output.WriteString("Memory Information:\n")
-	output.WriteString(fmt.Sprintf("  Total: %d MB\n", info.MemoryInfo.TotalMB))
-	output.WriteString(fmt.Sprintf("  Used: %d MB (%s)\n", info.MemoryInfo.UsedMB, info.MemoryInfo.UsagePercent))
+	// With a cgroup memory limit the figures are the cgroup's, and the host's come second
+	if info.MemoryInfo.HostTotalMB > 0 {
+		output.WriteString("Memory Information (cgroup limit):\n")
+		output.WriteString(fmt.Sprintf("  Total: %d MB (host: %d MB)\n", info.MemoryInfo.TotalMB, info.MemoryInfo.HostTotalMB))
+		output.WriteString(fmt.Sprintf("  Used: %d MB working set (%s) (host: %d MB, %s)\n",
+			info.MemoryInfo.UsedMB, info.MemoryInfo.UsagePercent, info.MemoryInfo.HostUsedMB, info.MemoryInfo.HostUsagePercent))
+	} else {
+		output.WriteString("Memory Information:\n")
+		output.WriteString(fmt.Sprintf("  Total: %d MB\n", info.MemoryInfo.TotalMB))
+		output.WriteString(fmt.Sprintf("  Used: %d MB (%s)\n", info.MemoryInfo.UsedMB, info.MemoryInfo.UsagePercent))
+	}
 	output.WriteString(fmt.Sprintf("  Available: %d MB\n", info.MemoryInfo.AvailableMB))
 	output.WriteString(fmt.Sprintf("  Free: %d MB\n", info.MemoryInfo.FreeMB))
 	output.WriteString(fmt.Sprintf("  Cached: %d MB\n", info.MemoryInfo.CachedMB))
@@ -382,6 +421,16 @@ func (s *Tool) formatSystemInfo(info *SystemInfo, target string) string {
 		output.WriteString(fmt.Sprintf("  Free: %d MB\n", info.MemoryInfo.SwapFreeMB))
 	}
 
+	if info.CgroupInfo != nil {
+		output.WriteString("\n")
+		formatCgroupInfo(&output, info.CgroupInfo)
+	}
+
+	output.WriteString("\n")
+	formatDiskInfo(&output, info.DiskInfo)
+	output.WriteString("\n")
+	formatNetworkInfo(&output, info.NetworkInfo)
+
 	return output.String()
 }
 
@@ -206,6 +206,21 @@ func (suite *SysinfoTestSuite) TestInputValidation() {
 			},
 			shouldError: false, // SSH port and user are optional
 		},
+		{
+			name: "disk threshold within range",
+			input: Input{
+				DiskThreshold: 80,
+			},
+			shouldError: false,
+		},
+		{
+			name: "disk threshold above 100",
+			input: Input{
+				DiskThreshold: 101,
+			},
+			shouldError: true,
+			errorMsg:    "validation error",
+		},
 		{
 			name: "default values",
 			input: Input{}, // All default values
@@ -260,6 +275,23 @@ func (suite *SysinfoTestSuite) TestSysInfoHandlerValidation() {
 			shouldError: true,
 			errorMsg:    "validation error",
 		},
+		{
+			name: "SSH host and pod",
+			input: Input{
+				SSHHost: "localhost",
+				Pod:     "api-0",
+			},
+			shouldError: true,
+			errorMsg:    "cannot specify both host and pod",
+		},
+		{
+			name: "namespace without pod",
+			input: Input{
+				Namespace: "production",
+			},
+			shouldError: true,
+			errorMsg:    "namespace, container and kubeconfig require pod",
+		},
 		{
 			name: "valid local execution",
 			input: Input{
@@ -297,6 +329,7 @@ func (suite *SysinfoTestSuite) TestSysInfoHandlerValidation() {
 				suite.NoError(err)
 				suite.NotNil(result)
 				suite.Len(result.Content, 1)
+				suite.Equal(defaultDiskThreshold, result.StructuredContent.DiskInfo.ThresholdPercent)
 			}
 		})
 	}
@@ -0,0 +1,25 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Paginate returns the lines of text from offset, at most maxLines, with a header when truncated.
+func Paginate(text string, maxLines, offset int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	totalLines := len(lines)
+	if offset >= totalLines {
+		return fmt.Sprintf("[No lines at offset %d of %d total lines]", offset, totalLines)
+	}
+	end := offset + maxLines
+	if end > totalLines {
+		end = totalLines
+	}
+	page := strings.Join(lines[offset:end], "\n")
+	if offset > 0 || end < totalLines {
+		return fmt.Sprintf("[Showing lines %d-%d of %d total lines. Use offset parameter to view more.]\n\n%s",
+			offset+1, end, totalLines, page)
+	}
+	return page
+}
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type PaginateTestSuite struct {
+	suite.Suite
+}
+
+func TestPaginateTestSuite(t *testing.T) {
+	suite.Run(t, new(PaginateTestSuite))
+}
+
+func (suite *PaginateTestSuite) TestPaginate() {
+	text := "one\ntwo\nthree\n"
+	suite.Equal("one\ntwo\nthree", Paginate(text, 10, 0))
+	suite.Equal("[Showing lines 2-2 of 3 total lines. Use offset parameter to view more.]\n\ntwo", Paginate(text, 1, 1))
+	suite.Equal("[Showing lines 1-2 of 3 total lines. Use offset parameter to view more.]\n\none\ntwo", Paginate(text, 2, 0))
+	suite.Equal("[No lines at offset 5 of 3 total lines]", Paginate(text, 10, 5))
+}